@@ -0,0 +1,32 @@
+package aesgcm
+
+// EncryptWithAAD encrypts the given plaintext using AES-GCM encryption with the provided key and
+// additional authenticated data (AAD). It returns the base64-encoded encrypted ciphertext and any error encountered.
+//
+// The AAD binds the ciphertext to a context, such as a record ID or a tenant name, without encrypting it.
+// The AAD is not stored inside the ciphertext, callers must supply the same value to DecryptWithAAD.
+// A nil or empty AAD produces ciphertexts that are compatible with Encrypt and Decrypt.
+func EncryptWithAAD(plaintext, key string, aad []byte) (string, error) {
+	return Encrypt(plaintext, key, WithAAD(aad))
+}
+
+// DecryptWithAAD decrypts the given base64-encoded encrypted text using AES-GCM decryption with the provided key
+// and additional authenticated data (AAD). It returns the decrypted plaintext and any error encountered.
+// If the AAD does not match the one used during encryption, the authentication error is returned as-is.
+func DecryptWithAAD(text, key string, aad []byte) (string, error) {
+	return Decrypt(text, key, WithAAD(aad))
+}
+
+// EncryptFileWithAAD encrypts the file located at 'path' using AES-GCM encryption with the provided key
+// and additional authenticated data (AAD). It returns an error if the file doesn't exist or if any encryption
+// operation fails. The AAD is not stored in the file, callers must supply the same value to DecryptFileWithAAD.
+func EncryptFileWithAAD(path, key string, aad []byte) error {
+	return EncryptFile(path, key, WithAAD(aad))
+}
+
+// DecryptFileWithAAD decrypts the file located at 'path' using AES-GCM decryption with the provided key
+// and additional authenticated data (AAD). It returns an error if the file doesn't exist or if any decryption
+// operation fails, including an AAD mismatch.
+func DecryptFileWithAAD(path, key string, aad []byte) error {
+	return DecryptFile(path, key, WithAAD(aad))
+}
@@ -0,0 +1,85 @@
+package aesgcm
+
+import (
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_aad(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+		key  string
+		aad  []byte
+	}{
+		{"aad 1", "../test_data/aad1.txt", "Hello World!", "myKey123", []byte("user-42")},
+		{"aad 2", "../test_data/aad2.txt", "Hello World!", "12345678", []byte("/etc/app/config.yml")},
+		{"aad 3", "../test_data/aad3.txt", "Hello World!", "11111111", []byte{0x00, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptWithAAD(tt.text, tt.key, tt.aad)
+			if err != nil {
+				t.Fatalf("could not encrypt with aad: %s\n", err)
+			}
+			d, err := DecryptWithAAD(e, tt.key, tt.aad)
+			if err != nil {
+				t.Fatalf("could not decrypt with aad: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt with aad failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := DecryptWithAAD(e, tt.key, append(tt.aad, '!')); err == nil {
+				t.Errorf("decrypt with wrong aad succeeded: %v\n", tt.name)
+			}
+			if _, err := Decrypt(e, tt.key); err == nil {
+				t.Errorf("decrypt without aad succeeded: %v\n", tt.name)
+			}
+
+			f := flo.File(tt.file)
+			defer func() { _ = f.Remove() }()
+			if err := f.StoreBytes([]byte(tt.text)); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFileWithAAD(tt.file, tt.key, tt.aad); err != nil {
+				t.Fatalf("could not encrypt file with aad: %s\n", err)
+			}
+			if err := DecryptFileWithAAD(tt.file, tt.key, nil); err == nil {
+				t.Errorf("decrypt file without aad succeeded: %v\n", tt.name)
+			}
+			if err := DecryptFileWithAAD(tt.file, tt.key, tt.aad); err != nil {
+				t.Fatalf("could not decrypt file with aad: %s\n", err)
+			}
+			if s := f.AsString(); s != tt.text {
+				t.Errorf("encrypt/decrypt file with aad failed: %v: expected %v, got %v!\n", tt.name, tt.text, s)
+			}
+		})
+	}
+}
+
+func Test_aadNilVsEmpty(t *testing.T) {
+	text, key := "Hello World!", "myKey123"
+
+	// GCM treats nil and empty AAD identically, and both are compatible with plain Encrypt/Decrypt.
+	for _, aad := range [][]byte{nil, {}} {
+		e, err := EncryptWithAAD(text, key, aad)
+		if err != nil {
+			t.Fatalf("could not encrypt with aad %#v: %s\n", aad, err)
+		}
+		for _, other := range [][]byte{nil, {}} {
+			if d, err := DecryptWithAAD(e, key, other); err != nil || d != text {
+				t.Errorf("decrypt with aad %#v of ciphertext with aad %#v failed: got %v (%v)\n", other, aad, d, err)
+			}
+		}
+		if d, err := Decrypt(e, key); err != nil || d != text {
+			t.Errorf("Decrypt of ciphertext with aad %#v failed: got %v (%v)\n", aad, d, err)
+		}
+	}
+
+	e, _ := Encrypt(text, key)
+	if d, err := DecryptWithAAD(e, key, []byte{}); err != nil || d != text {
+		t.Errorf("DecryptWithAAD with empty aad of Encrypt output failed: got %v (%v)\n", d, err)
+	}
+}
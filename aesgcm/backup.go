@@ -0,0 +1,200 @@
+package aesgcm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultBackupSuffix is appended to the path of the file to get the path of the backup
+// made by BackupAndEncryptFile, BackupAndDecryptFile and WithBackup if no suffix is given.
+const DefaultBackupSuffix = ".bak"
+
+// MaxBackupRotations is the number of older backups kept by BackupRotate, numbered from 'suffix'.1 (the newest)
+// to 'suffix'.MaxBackupRotations (the oldest).
+var MaxBackupRotations = 3
+
+// BackupPolicy decides what happens to an existing backup when a new one is made.
+type BackupPolicy int
+
+const (
+	// BackupRefuse keeps an existing backup and fails with an error wrapping ErrFileExists,
+	// leaving the file unmodified. It is the default.
+	BackupRefuse BackupPolicy = iota
+	// BackupRotate renames an existing backup to 'suffix'.1, shifting older ones up to MaxBackupRotations,
+	// where the oldest one is dropped, and then makes the new backup.
+	BackupRotate
+)
+
+// WithBackup makes the functions that modify files in place, such as EncryptFile, DecryptFile and EncryptDir
+// without WithDestDir, copy the original file to its path followed by 'suffix', or DefaultBackupSuffix if it is empty,
+// before it is replaced. The backup has the permissions and modification time of the original and is only made once
+// the file has been processed successfully, right before the original is replaced.
+//
+// Backups are never processed themselves: directory functions skip files ending with the suffix, optionally followed
+// by a rotation number such as ".bak.1", and the file functions refuse them, so no ".bak.bak" chains are created.
+// See WithBackupPolicy for existing backups and RemoveBackups to clean up. Backups of encrypted files hold the
+// plaintext, delete them once they are no longer needed.
+func WithBackup(suffix string) Option {
+	return func(o *options) error {
+		if suffix == "" {
+			suffix = DefaultBackupSuffix
+		}
+		if strings.ContainsAny(suffix, `/\`) {
+			return fmt.Errorf("backup suffix must not contain a path separator: '%s'", suffix)
+		}
+		o.backup = suffix
+		return nil
+	}
+}
+
+// WithBackupPolicy sets what happens to an existing backup when WithBackup, BackupAndEncryptFile or
+// BackupAndDecryptFile make a new one, defaulting to BackupRefuse.
+func WithBackupPolicy(p BackupPolicy) Option {
+	return func(o *options) error {
+		if p != BackupRefuse && p != BackupRotate {
+			return fmt.Errorf("invalid backup policy %d", p)
+		}
+		o.backupPolicy = p
+		return nil
+	}
+}
+
+// BackupAndEncryptFile copies the file located at 'path' to 'path'+'backupSuffix', or 'path'+".bak" if the suffix
+// is empty, and then encrypts 'path' in place like EncryptFile. The backup is completely written to disk before
+// the file is touched and is kept if encryption fails. It has the permissions and modification time of the
+// original and, unless WithOverwrite has been passed, an existing backup is handled according to WithBackupPolicy:
+// by default an error wrapping ErrFileExists is returned and the file is left unencrypted. WithBackup is ignored.
+//
+// Note: the backup holds the plaintext, delete it once it is no longer needed.
+func BackupAndEncryptFile(path, key, backupSuffix string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.backupFile(path, backupSuffix); err != nil {
+		return err
+	}
+	c.opts.backup = ""
+	return c.EncryptFile(path)
+}
+
+// BackupAndDecryptFile copies the file located at 'path' to 'path'+'backupSuffix', or 'path'+".bak" if the suffix
+// is empty, and then decrypts 'path' in place like DecryptFile. See BackupAndEncryptFile for how the backup is written.
+func BackupAndDecryptFile(path, key, backupSuffix string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.backupFile(path, backupSuffix); err != nil {
+		return err
+	}
+	c.opts.backup = ""
+	return c.DecryptFile(path)
+}
+
+// backupFile copies the file located at 'path' to 'path'+'suffix', defaulting to DefaultBackupSuffix,
+// rotating an existing backup with BackupRotate.
+func (c *Cipher) backupFile(path, suffix string) error {
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	f, r, err := openFile("back up", path, nil)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	wo, err := c.writeOptions(f, c.opts.overwrite)
+	if err != nil {
+		return err
+	}
+	backup := path + suffix
+	if c.opts.backupPolicy == BackupRotate {
+		if err := rotateBackups(backup); err != nil {
+			return err
+		}
+		wo.Overwrite = wo.Overwrite || MaxBackupRotations < 1
+	}
+	return writeFileFunc(backup, wo, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// rotateBackups shifts the backup 'backup' and its rotated predecessors by one number,
+// dropping the one beyond MaxBackupRotations.
+func rotateBackups(backup string) error {
+	if MaxBackupRotations < 1 {
+		return nil
+	}
+	for n := MaxBackupRotations; n >= 1; n-- {
+		from := backup
+		if n > 1 {
+			from += "." + strconv.Itoa(n-1)
+		}
+		if _, err := os.Lstat(from); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(from, backup+"."+strconv.Itoa(n)); err != nil {
+			return fmt.Errorf("can't rotate backup '%s': %w", from, err)
+		}
+	}
+	return nil
+}
+
+// isBackup reports whether the file located at 'path' is a backup with the suffix 'suffix', possibly rotated.
+func isBackup(path, suffix string) bool {
+	name := filepath.Base(path)
+	if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+		return true
+	}
+	i := strings.LastIndex(name, suffix+".")
+	if i <= 0 {
+		return false
+	}
+	n, err := strconv.Atoi(name[i+len(suffix)+1:])
+	return err == nil && n > 0
+}
+
+// refuseBackup returns an error if WithBackup is set and the file located at 'path' is one of its backups.
+func (c *Cipher) refuseBackup(op, path string) error {
+	if c.opts.backup != "" && isBackup(path, c.opts.backup) {
+		return fmt.Errorf("can't %s '%s', it is a backup", op, path)
+	}
+	return nil
+}
+
+// RemoveBackups removes the backups made by WithBackup, including rotated ones, from the directory tree below 'root'.
+// The suffix is DefaultBackupSuffix unless WithBackup is among 'opts'. Symlinks are neither followed nor removed.
+// Files that can't be removed don't stop the walk, all errors are returned together.
+func RemoveBackups(root string, opts ...Option) error {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return err
+	}
+	suffix := o.backup
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	var errs []error
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if d.Type().IsRegular() && isBackup(path, suffix) {
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
@@ -0,0 +1,222 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_BackupAndEncryptFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		suffix string
+		backup string
+	}{
+		{"default suffix", "", "secret.txt.bak"},
+		{"custom suffix", ".orig", "secret.txt.orig"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "secret.txt")
+			backup := filepath.Join(dir, tt.backup)
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := os.WriteFile(path, []byte("Hello World!"), 0600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatalf("could not set modification time: %s\n", err)
+			}
+
+			if err := BackupAndEncryptFile(path, "myKey123", tt.suffix); err != nil {
+				t.Fatalf("BackupAndEncryptFile() error = %v\n", err)
+			}
+			if d, err := os.ReadFile(backup); err != nil || string(d) != "Hello World!" {
+				t.Errorf("expected the plaintext backup, got %q (%v)\n", d, err)
+			}
+			if fi, err := os.Stat(backup); err != nil || fi.Mode().Perm() != 0600 || !fi.ModTime().Equal(mtime) {
+				t.Errorf("backup doesn't have the attributes of the original: %v\n", err)
+			}
+			if ok, _ := IsEncrypted(path); !ok {
+				t.Errorf("file hasn't been encrypted\n")
+			}
+
+			if err := BackupAndDecryptFile(path, "myKey123", tt.suffix); !errors.Is(err, ErrFileExists) {
+				t.Errorf("expected ErrFileExists for an existing backup, got %v\n", err)
+			}
+			if ok, _ := IsEncrypted(path); !ok {
+				t.Errorf("file has been decrypted although the backup failed\n")
+			}
+			if err := BackupAndDecryptFile(path, "myKey123", tt.suffix, WithOverwrite()); err != nil {
+				t.Fatalf("BackupAndDecryptFile() error = %v\n", err)
+			}
+			if d, err := os.ReadFile(path); err != nil || string(d) != "Hello World!" {
+				t.Errorf("expected the decrypted file, got %q (%v)\n", d, err)
+			}
+			if ok, _ := IsEncrypted(backup); !ok {
+				t.Errorf("expected the encrypted file as backup\n")
+			}
+		})
+	}
+}
+
+func Test_BackupAndEncryptFile_errors(t *testing.T) {
+	dir := t.TempDir()
+	if err := BackupAndEncryptFile(filepath.Join(dir, "missing.txt"), "myKey123", ""); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v\n", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no backup of a missing file, got %d entries\n", len(entries))
+	}
+
+	path := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(path, []byte("Hello World!"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := BackupAndDecryptFile(path, "myKey123", ""); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v\n", err)
+	}
+	if d, err := os.ReadFile(path + DefaultBackupSuffix); err != nil || string(d) != "Hello World!" {
+		t.Errorf("expected the backup to be kept after a failure, got %q (%v)\n", d, err)
+	}
+}
+
+func Test_WithBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.txt")
+	original := []byte("Hello World!")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	if err := EncryptFile(path, "myKey123", WithBackup("")); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	if d, err := os.ReadFile(path + DefaultBackupSuffix); err != nil || !bytes.Equal(d, original) {
+		t.Errorf("expected the original bytes as backup, got %q (%v)\n", d, err)
+	}
+	if fi, err := os.Stat(path + DefaultBackupSuffix); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("backup doesn't have the permissions of the original: %v\n", err)
+	}
+	encrypted, _ := os.ReadFile(path)
+
+	if err := DecryptFile(path, "myKey123", WithBackup("")); !errors.Is(err, ErrFileExists) {
+		t.Errorf("expected ErrFileExists for an existing backup, got %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, encrypted) {
+		t.Errorf("file has been modified although the backup failed\n")
+	}
+	if err := DecryptFile(path, "wrongKey", WithBackup(".old")); err == nil {
+		t.Errorf("expected error for the wrong key\n")
+	}
+	if _, err := os.Stat(path + ".old"); !os.IsNotExist(err) {
+		t.Errorf("expected no backup for a failed decryption, got %v\n", err)
+	}
+
+	if err := DecryptFile(path, "myKey123", WithBackup(""), WithBackupPolicy(BackupRotate)); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path + DefaultBackupSuffix); !bytes.Equal(d, encrypted) {
+		t.Errorf("expected the encrypted bytes as backup\n")
+	}
+	if d, _ := os.ReadFile(path + DefaultBackupSuffix + ".1"); !bytes.Equal(d, original) {
+		t.Errorf("expected the rotated backup to hold the original bytes\n")
+	}
+
+	if err := EncryptFile(path+DefaultBackupSuffix, "myKey123", WithBackup(""), WithForce()); err == nil {
+		t.Errorf("expected error for encrypting a backup\n")
+	}
+	if _, err := New("myKey123", WithBackup("x/y")); err == nil {
+		t.Errorf("expected error for a suffix with a path separator\n")
+	}
+	if _, err := New("myKey123", WithBackupPolicy(42)); err == nil {
+		t.Errorf("expected error for an invalid backup policy\n")
+	}
+}
+
+func Test_WithBackup_rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	var versions [][]byte
+	for i := 0; i < MaxBackupRotations+3; i++ {
+		data := []byte(fmt.Sprintf("version %d", i))
+		versions = append(versions, data)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if err := EncryptFile(path, "myKey123", WithBackup(""), WithBackupPolicy(BackupRotate)); err != nil {
+			t.Fatalf("EncryptFile() error = %v\n", err)
+		}
+	}
+	last := len(versions) - 1
+	if d, _ := os.ReadFile(path + DefaultBackupSuffix); !bytes.Equal(d, versions[last]) {
+		t.Errorf("expected the newest version as backup, got %q\n", d)
+	}
+	for n := 1; n <= MaxBackupRotations; n++ {
+		if d, _ := os.ReadFile(fmt.Sprintf("%s%s.%d", path, DefaultBackupSuffix, n)); !bytes.Equal(d, versions[last-n]) {
+			t.Errorf("expected %q as backup %d, got %q\n", versions[last-n], n, d)
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s%s.%d", path, DefaultBackupSuffix, MaxBackupRotations+1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d rotated backups, got %v\n", MaxBackupRotations, err)
+	}
+}
+
+func Test_WithBackup_dir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":     "Hello World!",
+		"sub/b.txt": "Hello Sub!",
+	}
+	for name, text := range files {
+		p := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(text), 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := EncryptDir(root, "myKey123", WithBackup("")); err != nil {
+			t.Fatalf("EncryptDir() error = %v\n", err)
+		}
+	}
+	var names []string
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(root, p)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	slices.Sort(names)
+	if want := []string{"a.txt", "a.txt.bak", "sub/b.txt", "sub/b.txt.bak"}; !slices.Equal(names, want) {
+		t.Errorf("expected files %v, got %v\n", want, names)
+	}
+	for name, text := range files {
+		if d, err := os.ReadFile(filepath.Join(root, name) + DefaultBackupSuffix); err != nil || string(d) != text {
+			t.Errorf("expected the plaintext backup of %s, got %q (%v)\n", name, d, err)
+		}
+	}
+
+	keep := filepath.Join(root, "notes.bak.txt")
+	_ = os.WriteFile(keep, nil, 0644)
+	_ = os.WriteFile(filepath.Join(root, "a.txt.bak.1"), nil, 0644)
+	if err := RemoveBackups(root); err != nil {
+		t.Fatalf("RemoveBackups() error = %v\n", err)
+	}
+	for _, name := range []string{"a.txt.bak", "a.txt.bak.1", "sub/b.txt.bak"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v\n", name, err)
+		}
+	}
+	for _, p := range []string{keep, filepath.Join(root, "a.txt")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to be kept: %v\n", p, err)
+		}
+	}
+}
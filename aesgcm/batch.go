@@ -0,0 +1,182 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// minItemsPerWorker is the number of items below which a batch isn't worth spreading across goroutines.
+const minItemsPerWorker = 256
+
+// EncryptStrings encrypts every item with the provided key and returns the encoded ciphertexts in the same order.
+// The key is derived only once. It is equivalent to calling Encrypt for every item with the same options.
+// If an item fails, an *ItemError reporting its index is returned.
+func EncryptStrings(items []string, key string, opts ...Option) ([]string, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.EncryptStrings(items)
+}
+
+// DecryptStrings decrypts every encoded ciphertext with the provided key and returns the plaintexts in the same order.
+// The key is derived only once. It is equivalent to calling Decrypt for every item with the same options.
+// If an item fails, an *ItemError reporting its index is returned.
+func DecryptStrings(items []string, key string, opts ...Option) ([]string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.DecryptStrings(items)
+}
+
+// EncryptStrings encrypts every item and returns the encoded ciphertexts in the same order.
+// Large batches are spread across GOMAXPROCS goroutines, unless a source of randomness has been set with WithRand,
+// which keeps the output reproducible. If an item fails, an *ItemError reporting its index is returned.
+func (c *Cipher) EncryptStrings(items []string) ([]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.mapStrings(items, c.Encrypt)
+}
+
+// DecryptStrings decrypts every encoded ciphertext and returns the plaintexts in the same order.
+// Large batches are spread across GOMAXPROCS goroutines. If an item fails, an *ItemError reporting
+// its index is returned.
+func (c *Cipher) DecryptStrings(items []string) ([]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.mapStrings(items, c.Decrypt)
+}
+
+// mapStrings applies 'fn' to every item. If several items fail, the error of the lowest index is returned.
+func (c *Cipher) mapStrings(items []string, fn func(string) (string, error)) ([]string, error) {
+	results := make([]string, len(items))
+	workers := min(runtime.GOMAXPROCS(0), len(items)/minItemsPerWorker)
+	if workers < 2 || c.opts.rand != rand.Reader {
+		for i, item := range items {
+			r, err := fn(item)
+			if err != nil {
+				return nil, &ItemError{Index: i, Err: err}
+			}
+			results[i] = r
+		}
+		return results, nil
+	}
+
+	errs := make([]*ItemError, workers)
+	size := (len(items) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w * size; i < min((w+1)*size, len(items)); i++ {
+				r, err := fn(items[i])
+				if err != nil {
+					errs[w] = &ItemError{Index: i, Err: err}
+					return
+				}
+				results[i] = r
+			}
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// EncryptMap encrypts every value of 'm' with the provided key and returns a new map holding the encoded
+// ciphertexts under the same, unencrypted map keys. The key is derived only once.
+// If a value fails, a *MapItemError reporting its map key is returned.
+//
+// Every value is bound to its map key as additional authenticated data, so a ciphertext copied to another
+// map key fails to decrypt. A single value can be decrypted with Decrypt and WithAAD([]byte(mapKey)).
+// If WithAAD is passed as well, the additional data is that AAD followed by a zero byte and the map key.
+func EncryptMap(m map[string]string, key string, opts ...Option) (map[string]string, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.EncryptMap(m)
+}
+
+// DecryptMap decrypts every value of 'm', as produced by EncryptMap, with the provided key and returns a new map
+// holding the plaintexts under the same map keys. The key is derived only once.
+// If a value fails, a *MapItemError reporting its map key is returned.
+func DecryptMap(m map[string]string, key string, opts ...Option) (map[string]string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.DecryptMap(m)
+}
+
+// EncryptMap encrypts every value of 'm' bound to its map key, see the package-level EncryptMap.
+func (c *Cipher) EncryptMap(m map[string]string) (map[string]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.mapValues(m, (*Cipher).Encrypt)
+}
+
+// DecryptMap decrypts every value of 'm' bound to its map key, see the package-level DecryptMap.
+func (c *Cipher) DecryptMap(m map[string]string) (map[string]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.mapValues(m, (*Cipher).Decrypt)
+}
+
+// mapValues applies 'fn' to every value of 'm' with the map key bound as additional data.
+// Map keys are processed in sorted order, so the same failing value is reported on every call.
+func (c *Cipher) mapValues(m map[string]string, fn func(*Cipher, string) (string, error)) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	mapKeys := make([]string, 0, len(m))
+	for k := range m {
+		mapKeys = append(mapKeys, k)
+	}
+	slices.Sort(mapKeys)
+
+	results := make(map[string]string, len(m))
+	for _, k := range mapKeys {
+		r, err := fn(c.withAAD(mapKeyAAD(c.opts.aad, k)), m[k])
+		if err != nil {
+			return nil, &MapItemError{Key: k, Err: err}
+		}
+		results[k] = r
+	}
+	return results, nil
+}
+
+// mapKeyAAD returns the additional data binding a value to the map key 'k'.
+func mapKeyAAD(aad []byte, k string) []byte {
+	if aad == nil {
+		return []byte(k)
+	}
+	return append(append(append([]byte{}, aad...), 0), k...)
+}
+
+// withAAD returns a copy of the Cipher that authenticates 'aad' instead of the configured additional data.
+func (c *Cipher) withAAD(aad []byte) *Cipher {
+	cc := *c
+	cc.opts.aad = aad
+	return &cc
+}
@@ -0,0 +1,186 @@
+package aesgcm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_EncryptStrings(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{"empty", 0},
+		{"single", 1},
+		{"sequential", minItemsPerWorker},
+		{"parallel", 8 * minItemsPerWorker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]string, tt.count)
+			for i := range items {
+				items[i] = fmt.Sprintf("row %d", i)
+			}
+			e, err := EncryptStrings(items, "myKey123")
+			if err != nil {
+				t.Fatalf("could not encrypt strings: %s\n", err)
+			}
+			if len(e) != len(items) {
+				t.Fatalf("expected %d ciphertexts, got %d\n", len(items), len(e))
+			}
+			d, err := DecryptStrings(e, "myKey123")
+			if err != nil {
+				t.Fatalf("could not decrypt strings: %s\n", err)
+			}
+			for i := range items {
+				if d[i] != items[i] {
+					t.Fatalf("encrypt/decrypt strings failed at index %d: expected %v, got %v!\n", i, items[i], d[i])
+				}
+			}
+			if len(items) > 0 {
+				if s, err := Decrypt(e[len(e)-1], "myKey123"); err != nil || s != items[len(items)-1] {
+					t.Errorf("batch ciphertext not compatible with Decrypt: %v, %v\n", s, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_DecryptStrings_errors(t *testing.T) {
+	items := make([]string, 4*minItemsPerWorker)
+	for i := range items {
+		items[i] = fmt.Sprintf("row %d", i)
+	}
+	e, err := EncryptStrings(items, "myKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt strings: %s\n", err)
+	}
+	for _, bad := range []int{0, 42, len(e) - 1} {
+		corrupted := append([]string{}, e...)
+		corrupted[bad] = "not base64!"
+		corrupted[len(e)-1-bad/2] = e[0][:8]
+		_, err := DecryptStrings(corrupted, "myKey123")
+		var itemErr *ItemError
+		if !errors.As(err, &itemErr) {
+			t.Fatalf("expected *ItemError, got %v\n", err)
+		}
+		if want := min(bad, len(e)-1-bad/2); itemErr.Index != want {
+			t.Errorf("expected failing index %d, got %d\n", want, itemErr.Index)
+		}
+	}
+	if _, err := EncryptStrings(items, ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+	if _, err := DecryptStrings(e, "myKey123", WithRand(nil)); err == nil {
+		t.Errorf("expected error for invalid option\n")
+	}
+}
+
+func benchmarkItems(n int) []string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf("user-%d@example.com", i)
+	}
+	return items
+}
+
+func Benchmark_Encrypt_loop(b *testing.B) {
+	items := benchmarkItems(10000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, item := range items {
+			if _, err := Encrypt(item, "myKey123"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Benchmark_EncryptStrings(b *testing.B) {
+	items := benchmarkItems(10000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := EncryptStrings(items, "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Decrypt_loop(b *testing.B) {
+	items, _ := EncryptStrings(benchmarkItems(10000), "myKey123")
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, item := range items {
+			if _, err := Decrypt(item, "myKey123"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Benchmark_DecryptStrings(b *testing.B) {
+	items, _ := EncryptStrings(benchmarkItems(10000), "myKey123")
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := DecryptStrings(items, "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Test_EncryptMap(t *testing.T) {
+	m := map[string]string{
+		"db.password": "s3cr3t",
+		"api.token":   "abc123",
+		"empty":       "",
+		"":            "value under empty key",
+	}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"aad", []Option{WithAAD([]byte("prod"))}},
+		{"hex", []Option{WithEncoding(Hex)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptMap(m, "myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not encrypt map: %s\n", err)
+			}
+			if len(e) != len(m) {
+				t.Fatalf("expected %d entries, got %d\n", len(m), len(e))
+			}
+			d, err := DecryptMap(e, "myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not decrypt map: %s\n", err)
+			}
+			for k, v := range m {
+				if d[k] != v {
+					t.Errorf("encrypt/decrypt map failed for '%s': expected %v, got %v!\n", k, v, d[k])
+				}
+			}
+
+			// a value copied under another map key must fail to authenticate
+			swapped := map[string]string{"db.password": e["api.token"], "api.token": e["api.token"]}
+			_, err = DecryptMap(swapped, "myKey123", tt.opts...)
+			var itemErr *MapItemError
+			if !errors.As(err, &itemErr) || itemErr.Key != "db.password" {
+				t.Errorf("expected *MapItemError for 'db.password', got %v\n", err)
+			}
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected error to wrap ErrAuthenticationFailed, got %v\n", err)
+			}
+		})
+	}
+
+	e, _ := EncryptMap(m, "myKey123")
+	if s, err := Decrypt(e["db.password"], "myKey123", WithAAD([]byte("db.password"))); err != nil || s != "s3cr3t" {
+		t.Errorf("map value not decryptable with its map key as AAD: %v, %v\n", s, err)
+	}
+	if d, err := DecryptMap(nil, "myKey123"); d != nil || err != nil {
+		t.Errorf("expected nil map for nil input, got %v, %v\n", d, err)
+	}
+}
@@ -0,0 +1,117 @@
+package aesgcm
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	kb = 1024
+	mb = 1024 * kb
+)
+
+func benchmarkEncrypt(b *testing.B, size int) {
+	plaintext := strings.Repeat("x", size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := Encrypt(plaintext, "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkDecrypt(b *testing.B, size int) {
+	ciphertext, err := Encrypt(strings.Repeat("x", size), "myKey123")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := Decrypt(ciphertext, "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// skipLarge skips the benchmarks of 100 MB inputs with -short, as they need several hundred MB of memory or take long.
+func skipLarge(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping 100 MB benchmark in short mode")
+	}
+}
+
+func BenchmarkEncrypt_1KB(b *testing.B) { benchmarkEncrypt(b, kb) }
+func BenchmarkEncrypt_1MB(b *testing.B) { benchmarkEncrypt(b, mb) }
+func BenchmarkEncrypt_100MB(b *testing.B) {
+	skipLarge(b)
+	benchmarkEncrypt(b, 100*mb)
+}
+func BenchmarkDecrypt_1KB(b *testing.B) { benchmarkDecrypt(b, kb) }
+func BenchmarkDecrypt_1MB(b *testing.B) { benchmarkDecrypt(b, mb) }
+
+func BenchmarkEncryptFile_1MB(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "bench.txt")
+	data := make([]byte, mb)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := EncryptFile(path, "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncryptStream_100MB(b *testing.B) {
+	skipLarge(b)
+	size := int64(100 * mb)
+	b.SetBytes(size)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := EncryptStream(io.Discard, io.LimitReader(zeroReader{}, size), "myKey123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkCompression(b *testing.B, opts ...Option) {
+	data := []byte(strings.Repeat(`{"id":42,"name":"ACME Corp","created":"2024-01-01T00:00:00Z","tags":["a","b"]}`+"\n", 100*kb))
+	c, err := New("myKey123", opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("encrypt", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for n := 0; n < b.N; n++ {
+			if err := c.encryptTo(io.Discard, bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	var encrypted bytes.Buffer
+	if err := c.encryptTo(&encrypted, bytes.NewReader(data)); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("decrypt", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for n := 0; n < b.N; n++ {
+			if err := c.decryptFileTo(io.Discard, bytes.NewReader(encrypted.Bytes())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCompression_none(b *testing.B) { benchmarkCompression(b) }
+func BenchmarkCompression_gzip(b *testing.B) { benchmarkCompression(b, WithCompression(Gzip)) }
+func BenchmarkCompression_zstd(b *testing.B) { benchmarkCompression(b, WithCompression(Zstd)) }
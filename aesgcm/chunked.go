@@ -0,0 +1,187 @@
+package aesgcm
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+// chunkedMagic flags files whose chunks have been compressed with zstd one by one, see WithCompression.
+// Like the other compression flags, it is authenticated as additional data.
+var chunkedMagic = []byte("AGZ\x03")
+
+const (
+	// chunkedStreamVersion is the stream version of files written by encryptChunkedTo, whose chunks are
+	// length-prefixed. Readers of version 1 reject them instead of misinterpreting the chunk boundaries.
+	chunkedStreamVersion = 2
+
+	// lastChunkFlag is set in the length of the final chunk of a chunked stream.
+	lastChunkFlag = 1 << 31
+)
+
+// chunkedBound returns the maximum length of a sealed chunk holding a zstd frame of at most 'chunkSize' bytes.
+func chunkedBound(aesGCM cipher.AEAD, chunkSize int) int {
+	return chunkSize + chunkSize>>8 + 64 + aesGCM.Overhead()
+}
+
+// encryptChunkedTo compresses the plaintext read from 'r' with zstd chunk by chunk, encrypts it and writes it to 'w'
+// preceded by chunkedMagic and the headers selected by the options. The stream that follows has the format of
+// EncryptStream with version 2, all integers are big-endian:
+//
+//	header:  "AGCS" (4 bytes) | version 2 (1 byte) | chunk size C (uint32) | base nonce N (12 bytes)
+//	chunk i: length L (uint32, the top bit is set for the final chunk) | AES-GCM ciphertext (L bytes)
+//
+// Every chunk holds a zstd frame of exactly C plaintext bytes, the final one fewer, and is sealed like the chunks of
+// EncryptStream. The frames are compressed at the default level of zstd, which corresponds to level 3, and independently
+// of each other, so every authenticated chunk can be decompressed on its own.
+func (c *Cipher) encryptChunkedTo(w io.Writer, r io.Reader) error {
+	if err := writeFull(w, chunkedMagic); err != nil {
+		return err
+	}
+	cc := c.compressedCipher(chunkedMagic)
+	prefix, err := cc.prefix()
+	if err != nil {
+		return err
+	}
+	if err := writeFull(w, prefix); err != nil {
+		return err
+	}
+	header, err := newStreamHeader(cc.aead, cc.opts.rand, cc.fingerprint, cc.opts.chunkSize)
+	if err != nil {
+		return err
+	}
+	header[len(streamMagic)] = chunkedStreamVersion
+	if err := writeFull(w, header); err != nil {
+		return err
+	}
+
+	r = cc.padReader(r)
+	buf := make([]byte, cc.opts.chunkSize)
+	var compressed, sealed []byte
+	defer func() {
+		clear(buf)
+		clear(compressed[:cap(compressed)])
+	}()
+	length := make([]byte, 4)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		compressed = zstdEncoder().EncodeAll(buf[:n], compressed[:0])
+		sealed = cc.aead.Seal(sealed[:0], stream.ChunkNonce(header[streamHeaderSize:], i), compressed, stream.ChunkAAD(header, last, cc.opts.aad))
+		l := uint32(len(sealed))
+		if last {
+			l |= lastChunkFlag
+		}
+		binary.BigEndian.PutUint32(length, l)
+		if err := writeFull(w, length); err != nil {
+			return err
+		}
+		if err := writeFull(w, sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// decryptChunkedTo decrypts data written by encryptChunkedTo from 'r', which must be positioned right after
+// chunkedMagic, and writes the plaintext to 'w'.
+func (c *Cipher) decryptChunkedTo(w io.Writer, r io.Reader) error {
+	cc := c.compressedCipher(chunkedMagic)
+	br := bufio.NewReaderSize(r, DefaultChunkSize)
+	head, _ := br.Peek(maxPrefixSize + len(streamMagic))
+	p, err := cc.parsePrefix(head)
+	if err != nil {
+		return err
+	}
+	if _, err := br.Discard(p.size); err != nil {
+		return err
+	}
+	if p.keyCheck != nil {
+		if err := verifyKeyCheck(cc.aead, p.keyCheck); err != nil {
+			return cc.keyMismatch(p.fingerprint, err)
+		}
+	}
+	return cc.keyMismatch(p.fingerprint, cc.decryptChunks(w, br))
+}
+
+// decryptChunks decrypts and decompresses the chunked stream read from 'r' and writes the plaintext to 'w'.
+// The decompressed size of every chunk is bounded by the chunk size recorded in the header.
+func (c *Cipher) decryptChunks(w io.Writer, r io.Reader) error {
+	header, chunkSize, err := stream.ReadHeader(c.aead, r, streamMagic, chunkedStreamVersion)
+	if err != nil {
+		return err
+	}
+	// frames report a window of at least zstd.MinWindowSize, the chunk size is enforced below
+	limit := uint64(max(chunkSize, zstd.MinWindowSize))
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(limit))
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
+
+	unpad := c.newUnpadder()
+	bound := chunkedBound(c.aead, chunkSize)
+	sealed := make([]byte, bound)
+	length := make([]byte, 4)
+	var plain []byte
+	defer func() {
+		clear(sealed)
+		clear(plain[:cap(plain)])
+		unpad.wipe()
+	}()
+	for i := uint64(0); ; i++ {
+		if _, err := io.ReadFull(r, length); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("%w, missing final chunk in chunk %d", ErrStreamTruncated, i)
+			}
+			return err
+		}
+		l := binary.BigEndian.Uint32(length)
+		last := l&lastChunkFlag != 0
+		n := int(l &^ lastChunkFlag)
+		if n < c.aead.Overhead() || n > bound {
+			return fmt.Errorf("can't decrypt chunk %d, invalid length %d: %w", i, n, ErrAuthenticationFailed)
+		}
+		if _, err := io.ReadFull(r, sealed[:n]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("%w in chunk %d", ErrStreamTruncated, i)
+			}
+			return err
+		}
+		compressed, err := openChunk(c.aead, header, i, sealed[:n], last, c.opts.aad)
+		if err != nil {
+			return err
+		}
+		if plain, err = dec.DecodeAll(compressed, plain[:0]); err != nil {
+			return fmt.Errorf("can't decompress chunk %d: %w", i, err)
+		}
+		if len(plain) > chunkSize || (last && len(plain) == chunkSize) || (!last && len(plain) < chunkSize) {
+			return fmt.Errorf("can't decompress chunk %d, it holds %d bytes for a chunk size of %d: %w", i, len(plain), chunkSize, ErrAuthenticationFailed)
+		}
+		out := plain
+		if unpad != nil {
+			if out, err = unpad.next(plain, last); err != nil {
+				return err
+			}
+		}
+		if err := writeFull(w, out); err != nil {
+			return err
+		}
+		if last {
+			if _, err := io.ReadFull(r, length[:1]); err != io.EOF {
+				return fmt.Errorf("can't decrypt, data follows the final chunk %d: %w", i, ErrAuthenticationFailed)
+			}
+			return nil
+		}
+	}
+}
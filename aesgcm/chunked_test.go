@@ -0,0 +1,119 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+func Test_WithCompression_chunked(t *testing.T) {
+	text := bytes.Repeat([]byte(`{"id":42,"name":"ACME Corp","tags":["a","b"]}`+"\n"), 200)
+	random := make([]byte, 2500)
+	_, _ = rand.Read(random)
+	tests := []struct {
+		name    string
+		data    []byte
+		encOpts []Option
+		opts    []Option
+	}{
+		{"empty", nil, nil, nil},
+		{"one chunk", text[:500], nil, nil},
+		{"exact chunks", text[:3000], nil, nil},
+		{"text", text, nil, nil},
+		{"random", random, nil, nil},
+		{"headers", text, []Option{WithFingerprint(), WithKeyCheck()}, []Option{WithKeySize(16)}},
+		{"aad and padding", text, nil, []Option{WithAAD([]byte("user-42")), WithPadding(512)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.bin")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			opts := append([]Option{WithChunkSize(1000)}, tt.opts...)
+			if err := EncryptFile(path, "myKey123", append(append(opts, tt.encOpts...), WithCompression(Zstd))...); err != nil {
+				t.Fatalf("EncryptFile() error = %v\n", err)
+			}
+			e, _ := os.ReadFile(path)
+			if !bytes.HasPrefix(e, chunkedMagic) {
+				t.Errorf("expected the chunked flag\n")
+			}
+			if ok, _ := IsEncrypted(path); !ok {
+				t.Errorf("IsEncrypted() = false\n")
+			}
+			if len(tt.data) == len(text) && len(e) > len(text)/5 {
+				t.Errorf("expected text to compress well, got %d bytes\n", len(e))
+			}
+			if err := VerifyFile(path, "myKey123", opts...); err != nil {
+				t.Errorf("VerifyFile() error = %v\n", err)
+			}
+			if err := DecryptFile(path, "wrongKey", opts...); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptFile() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+			}
+			if err := DecryptFile(path, "myKey123", opts...); err != nil {
+				t.Fatalf("DecryptFile() error = %v\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, tt.data) {
+				t.Errorf("encrypt/decrypt chunked file failed\n")
+			}
+		})
+	}
+}
+
+func Test_WithCompression_chunkedCorrupt(t *testing.T) {
+	text := bytes.Repeat([]byte("all work and no play makes jack a dull boy\n"), 100)
+	c, _ := New("myKey123", WithChunkSize(1000), WithCompression(Zstd))
+	var buf bytes.Buffer
+	if err := c.encryptTo(&buf, bytes.NewReader(text)); err != nil {
+		t.Fatalf("encryptTo() error = %v\n", err)
+	}
+	e := buf.Bytes()
+	first := len(chunkedMagic) + streamHeaderSize + 12
+	firstLen := int(binary.BigEndian.Uint32(e[first:]))
+
+	// a single chunk decompressing to far more than the chunk size
+	cc := c.compressedCipher(chunkedMagic)
+	header := bytes.Clone(e[len(chunkedMagic):first])
+	sealed := cc.aead.Seal(nil, stream.ChunkNonce(header[streamHeaderSize:], 0), zstdEncoder().EncodeAll(make([]byte, 1<<20), nil), stream.ChunkAAD(header, true, nil))
+	bomb := append(bytes.Clone(e[:first]), binary.BigEndian.AppendUint32(nil, uint32(len(sealed))|lastChunkFlag)...)
+	bomb = append(bomb, sealed...)
+
+	lastFlag := bytes.Clone(e)
+	lastFlag[first] |= 0x80
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated", e[:len(e)-10], ErrStreamTruncated},
+		{"missing final chunk", e[:first+4+firstLen], ErrStreamTruncated},
+		{"last flag set early", lastFlag, ErrAuthenticationFailed},
+		{"trailing data", append(bytes.Clone(e), 0), ErrAuthenticationFailed},
+		{"oversized chunk", bomb, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := c.decryptFileTo(&out, bytes.NewReader(tt.data))
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("decryptFileTo() error = %v, want %v\n", err, tt.want)
+			}
+			if out.Len() > len(text) {
+				t.Errorf("decryptFileTo() wrote %d bytes\n", out.Len())
+			}
+		})
+	}
+
+	// the limit of WithMaxSize applies to the whole file
+	d, _ := New("myKey123", WithMaxSize(2000))
+	if err := d.decryptFileTo(&bytes.Buffer{}, bytes.NewReader(e)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("decryptFileTo() with WithMaxSize error = %v, want ErrTooLarge\n", err)
+	}
+}
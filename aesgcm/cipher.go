@@ -0,0 +1,349 @@
+package aesgcm
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Cipher encrypts and decrypts data with a key that is derived only once.
+// It is safe for concurrent use by multiple goroutines.
+//
+// Use a Cipher instead of the package-level functions when many values are encrypted
+// or decrypted with the same key, as those derive the key on every call.
+type Cipher struct {
+	aead        cipher.AEAD
+	opts        options
+	fingerprint []byte
+	nameKey     []byte     // key of the opaque names of WithOpaqueNames, nil without it
+	created     *time.Time // receives the creation time of the ciphertext authenticated by openUnpadded, if not nil
+	state       *cipherState
+}
+
+// New creates a new Cipher for the provided key, configured by 'opts'.
+// The key undergoes scrambling using keys.WeakKeyScrambler, just like with the package-level functions,
+// so ciphertexts produced by a Cipher with the default options can be decrypted with the package-level
+// functions and vice versa. It returns an error if the key is weak, if an option is invalid or if key scrambling fails.
+func New(key string, opts ...Option) (*Cipher, error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newCipher(key, o)
+}
+
+// newCipher creates a new Cipher for the provided key and options.
+func newCipher(key string, o *options) (*Cipher, error) {
+	kc, err := newKDFKeyCipher(key, o)
+	if err != nil {
+		return nil, err
+	}
+	// the AES-GCM instance holds its own copy of the key, including the excess bytes of a KDF
+	defer clear(kc.key)
+	fp := fingerprint(kc.key)
+	kc.key = kc.key[:o.keySize]
+	aesGCM, err := kc.aead()
+	if err != nil {
+		return nil, err
+	}
+	c := &Cipher{aead: aesGCM, opts: *o, fingerprint: fp, state: &cipherState{}}
+	if o.padding > 0 {
+		c.opts.aad = paddingAAD(o.padding, o.aad)
+	}
+	if o.opaqueNames {
+		c.nameKey = nameKey(kc.key)
+		c.state.nameKey = c.nameKey
+	}
+	return c, nil
+}
+
+// newKDFKeyCipher creates a keyCipher with the key derived by the KDF of 'o', or by keys.WeakKeyScrambler
+// if no KDF has been set. It returns an error if the key is weak or if the derived key is too short.
+func newKDFKeyCipher(key string, o *options) (*keyCipher, error) {
+	if o.kdf == nil {
+		return newKeyCipher(key)
+	}
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	k, err := o.kdf(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyDerivation, err)
+	}
+	if len(k) < o.keySize {
+		return nil, fmt.Errorf("%w: kdf returned %d bytes, at least %d are required", ErrKeyDerivation, len(k), o.keySize)
+	}
+	return &keyCipher{key: k}, nil
+}
+
+// newDecryptCipher is like New but returns an error if an encryption-only option has been passed.
+func newDecryptCipher(key string, opts ...Option) (*Cipher, error) {
+	o, err := newDecryptOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newCipher(key, o)
+}
+
+// Encrypt encrypts the given plaintext and returns the encoded encrypted ciphertext and any error encountered.
+// The ciphertext is base64-encoded unless another encoding has been set with WithEncoding.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	encrypted, err := c.EncryptBytes(pt)
+	if err != nil {
+		return "", err
+	}
+	return c.opts.encoding.EncodeToString(encrypted), nil
+}
+
+// Decrypt decrypts the given encoded encrypted text and returns the decrypted plaintext and any error encountered.
+// The text is expected to be base64-encoded unless another encoding has been set with WithEncoding.
+func (c *Cipher) Decrypt(text string) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	encryptedData, err := c.decodeCiphertext(text)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := c.DecryptBytes(encryptedData)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
+
+// EncryptBytes encrypts the given bytes and returns the raw header||ciphertext bytes and any error encountered.
+// The header records the format version, the algorithm, the key derivation and the nonce, and is authenticated
+// along with the ciphertext, so the format can evolve without breaking existing ciphertexts.
+// With a non-default key size the result is prefixed with the key size header,
+// with WithFingerprint with the fingerprint header and with WithKeyCheck with the key check header.
+// With WithCompression, the plaintext is compressed first and the result is prefixed with the compression flag.
+func (c *Cipher) EncryptBytes(bytes []byte) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	if c.opts.compression != compressionNone {
+		return c.encryptCompressedBytes(bytes)
+	}
+	encrypted, err := c.seal(bytes)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := c.prefix()
+	if err != nil {
+		return nil, err
+	}
+	return append(prefix, encrypted...), nil
+}
+
+// prefix returns the headers selected by the options, which precede the payload:
+// the key size header, the fingerprint header and the key check header, in this order.
+func (c *Cipher) prefix() ([]byte, error) {
+	var prefix []byte
+	if c.opts.keySize != DefaultKeySize {
+		prefix = append(prefix, keySizeHeader(c.opts.keySize)...)
+	}
+	if c.opts.fingerprint {
+		prefix = append(prefix, fingerprintHeader(c.fingerprint)...)
+	}
+	if c.opts.keyCheck {
+		header, err := keyCheckHeader(c.aead, c.opts.rand, c.fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		prefix = append(prefix, header...)
+	}
+	return prefix, nil
+}
+
+// parsedPrefix describes the headers found in front of a payload.
+type parsedPrefix struct {
+	size        int    // total length of the headers
+	fingerprint []byte // recorded fingerprint, nil if absent
+	keyCheck    []byte // key check header, nil if absent
+}
+
+// parsePrefix parses the headers written by prefix at the start of 'head'.
+// It returns an error wrapping ErrKeySizeMismatch if the key size header doesn't match the Cipher.
+func (c *Cipher) parsePrefix(head []byte) (parsedPrefix, error) {
+	var p parsedPrefix
+	if c.opts.keySize != DefaultKeySize {
+		if size, ok := parseKeySizeHeader(head); !ok || size != c.opts.keySize {
+			return p, fmt.Errorf("%w: expected a ciphertext for a %d-byte key", ErrKeySizeMismatch, c.opts.keySize)
+		}
+		p.size = keySizeHeaderSize
+	}
+	if fp, ok := parseFingerprintHeader(head[p.size:]); ok {
+		p.fingerprint = fp
+		p.size += fingerprintHeaderSize
+	}
+	if hasKeyCheckHeader(head[p.size:]) {
+		p.keyCheck = bytes.Clone(head[p.size:min(len(head), p.size+keyCheckHeaderSize)])
+		p.size += keyCheckHeaderSize
+	}
+	return p, nil
+}
+
+// keyMismatch turns an authentication failure 'err' into a *KeyMismatchError if the recorded
+// fingerprint 'fp' differs from the one of the Cipher. Other errors are returned unchanged.
+func (c *Cipher) keyMismatch(fp []byte, err error) error {
+	if fp != nil && errors.Is(err, ErrAuthenticationFailed) && !bytes.Equal(fp, c.fingerprint) {
+		return &KeyMismatchError{Expected: hex.EncodeToString(fp), Actual: hex.EncodeToString(c.fingerprint), Err: err}
+	}
+	return err
+}
+
+// DecryptBytes decrypts the given raw bytes, as produced by EncryptBytes, and returns the decrypted bytes and any error
+// encountered. Headerless nonce||ciphertext bytes written by earlier versions are decrypted as well.
+// It returns an error wrapping ErrKeySizeMismatch if the bytes were encrypted with a different key size
+// and an error wrapping ErrUnsupportedVersion if they were written in a newer format.
+// The contents of files in the chunked format written by EncryptFile are decrypted as well.
+// With WithMaxSize, an error wrapping ErrTooLarge is returned if the data would decrypt to more plaintext than allowed.
+// Data encrypted with WithCompression is decompressed. Data encrypted with EncryptWithTTL is rejected with an
+// *ExpiredError once it has expired.
+func (c *Cipher) DecryptBytes(data []byte) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	if err := c.checkCiphertextLen(int64(len(data)), func() []byte { return data }); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, expiryMagic) {
+		return c.decryptExpiringBytes(data)
+	}
+	return c.decryptUnexpiringBytes(data)
+}
+
+// decryptUnexpiringBytes is DecryptBytes for data without expiry flag.
+func (c *Cipher) decryptUnexpiringBytes(data []byte) ([]byte, error) {
+	if magic := compressionMagic(data); magic != nil {
+		decrypted, err := c.decryptCompressedBytes(data, magic)
+		if err == nil {
+			return decrypted, nil
+		}
+		// a ciphertext without header whose nonce happens to start with the flag
+		if decrypted, legacyErr := c.decryptUncompressedBytes(data); legacyErr == nil {
+			return decrypted, nil
+		}
+		return nil, err
+	}
+	return c.decryptUncompressedBytes(data)
+}
+
+// decryptUncompressedBytes is DecryptBytes for data without compression flag.
+func (c *Cipher) decryptUncompressedBytes(data []byte) ([]byte, error) {
+	p, ok := c.streamPrefix(data)
+	if !ok {
+		return c.decryptSingle(data)
+	}
+	var buf bytes.Buffer
+	err := c.decryptPrefixedStream(&buf, bytes.NewReader(data[p.size:]), p)
+	if err == nil {
+		return buf.Bytes(), nil
+	}
+	if decrypted, legacyErr := c.decryptSingle(data); legacyErr == nil {
+		return decrypted, nil
+	}
+	return nil, err
+}
+
+// decryptSingle decrypts a single header||ciphertext or nonce||ciphertext, which may be preceded by the headers written by prefix.
+func (c *Cipher) decryptSingle(data []byte) ([]byte, error) {
+	size, hasHeader := parseKeySizeHeader(data)
+	if c.opts.keySize != DefaultKeySize {
+		if !hasHeader || size != c.opts.keySize {
+			return nil, fmt.Errorf("%w: expected a ciphertext for a %d-byte key", ErrKeySizeMismatch, c.opts.keySize)
+		}
+		return c.open(data[keySizeHeaderSize:])
+	}
+	decrypted, err := c.open(data)
+	if err != nil && hasHeader && size != DefaultKeySize {
+		return nil, fmt.Errorf("%w: ciphertext was encrypted with a %d-byte key", ErrKeySizeMismatch, size)
+	}
+	if err == nil && c.opts.maxSize > 0 && int64(len(decrypted)) > c.opts.maxSize {
+		return nil, errTooLarge(c.opts.maxSize)
+	}
+	return decrypted, err
+}
+
+// open decrypts the given nonce||ciphertext bytes, which may be prefixed with a fingerprint header.
+// If decryption fails and the recorded fingerprint differs from the one of the Cipher, a *KeyMismatchError
+// is returned. Should the nonce of a ciphertext without fingerprint header happen to start with the
+// fingerprint magic, it is still decrypted.
+func (c *Cipher) open(data []byte) ([]byte, error) {
+	fp, ok := parseFingerprintHeader(data)
+	if !ok {
+		return c.openChecked(data)
+	}
+	decrypted, err := c.openChecked(data[fingerprintHeaderSize:])
+	if err == nil {
+		return decrypted, nil
+	}
+	if decrypted, legacyErr := c.openChecked(data); legacyErr == nil {
+		return decrypted, nil
+	}
+	return nil, c.keyMismatch(fp, err)
+}
+
+// openChecked decrypts the given nonce||ciphertext bytes, which may be prefixed with a key check header.
+// A wrong key is reported by the key check before the payload is touched. Should the nonce of a ciphertext
+// without key check header happen to start with the key check magic, it is still decrypted.
+func (c *Cipher) openChecked(data []byte) ([]byte, error) {
+	if !hasKeyCheckHeader(data) {
+		return c.openVersioned(data)
+	}
+	err := verifyKeyCheck(c.aead, data)
+	if err == nil {
+		return c.openVersioned(data[keyCheckHeaderSize:])
+	}
+	if decrypted, legacyErr := c.openVersioned(data); legacyErr == nil {
+		return decrypted, nil
+	}
+	return nil, err
+}
+
+// EncryptFile encrypts the file located at 'path' in place.
+// It returns an error if the file doesn't exist or if any encryption operation fails.
+//
+// The file is encrypted in the chunked format of EncryptStream, preceded by the headers selected by the options,
+// so memory usage stays constant regardless of the file size. The encrypted data is written to a temporary file
+// which is then renamed over the original, so the original file is left intact if encryption fails.
+// The permissions and modification time of the original file are preserved, see WithOwnership for the owner.
+// Files that are already encrypted are refused with an error wrapping ErrAlreadyEncrypted, see WithForce.
+func (c *Cipher) EncryptFile(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.encryptFile(context.Background(), path, nil)
+}
+
+// DecryptFile decrypts the file located at 'path' in place.
+// It returns an error if the file doesn't exist or if any decryption operation fails.
+//
+// Files in the chunked format written by EncryptFile are decrypted with constant memory usage. Files without
+// the magic of that format are rejected with ErrNotEncrypted, unless WithLegacyFormat has been passed to also
+// decrypt files holding a single ciphertext, as written by EncryptRaw and by EncryptToFile and EncryptFile of earlier versions.
+// The decrypted data is written to a temporary file which is then renamed over the original,
+// so the original file is left intact if decryption fails. The permissions and modification time are preserved.
+func (c *Cipher) DecryptFile(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.decryptFile(context.Background(), path, nil)
+}
@@ -0,0 +1,82 @@
+package aesgcm
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_cipher(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+		key  string
+	}{
+		{"cipher 1", "../test_data/cipher1.txt", "Hello World!", "myKey123"},
+		{"cipher 2", "../test_data/cipher2.txt", "Hello World!", "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.key)
+			if err != nil {
+				t.Fatalf("could not create cipher: %s\n", err)
+			}
+			e, err := c.Encrypt(tt.text)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if d, err := Decrypt(e, tt.key); err != nil || d != tt.text {
+				t.Errorf("package Decrypt of Cipher output failed: %v: expected %v, got %v (%v)\n", tt.name, tt.text, d, err)
+			}
+			e, _ = Encrypt(tt.text, tt.key)
+			if d, err := c.Decrypt(e); err != nil || d != tt.text {
+				t.Errorf("Cipher.Decrypt of package output failed: %v: expected %v, got %v (%v)\n", tt.name, tt.text, d, err)
+			}
+
+			f := flo.File(tt.file)
+			defer func() { _ = f.Remove() }()
+			if err := f.StoreBytes([]byte(tt.text)); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := c.EncryptFile(tt.file); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			if err := c.DecryptFile(tt.file); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if s := f.AsString(); s != tt.text {
+				t.Errorf("encrypt/decrypt file failed: %v: expected %v, got %v!\n", tt.name, tt.text, s)
+			}
+		})
+	}
+}
+
+func Test_cipherConcurrent(t *testing.T) {
+	c, err := New("myKey123")
+	if err != nil {
+		t.Fatalf("could not create cipher: %s\n", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				text := fmt.Sprintf("record %d-%d", i, j)
+				e, err := c.Encrypt(text)
+				if err != nil {
+					t.Errorf("could not encrypt: %s\n", err)
+					return
+				}
+				if d, err := c.Decrypt(e); err != nil || d != text {
+					t.Errorf("concurrent encrypt/decrypt failed: expected %v, got %v (%v)\n", text, d, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,437 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressedMagic and gzipMagic flag data that has been compressed with zstd and gzip before encryption,
+// storedMagic data that has been left uncompressed by WithCompression because it wouldn't shrink.
+// They are authenticated as additional data, so they can't be stripped to pass off the compressed
+// data as a regular ciphertext.
+var (
+	compressedMagic = []byte("AGZ\x01")
+	gzipMagic       = []byte("AGZ\x02")
+	storedMagic     = []byte("AGZ\x00")
+)
+
+// Compression selects the algorithm used by WithCompression.
+type Compression int
+
+const (
+	compressionNone Compression = iota
+	Zstd                        // zstd, as used by EncryptCompressed
+	Gzip                        // compress/gzip at DefaultGzipLevel, as used by EncryptFileGzip
+)
+
+// WithCompression makes Encrypt, EncryptBytes, EncryptFile and the other encryption functions compress
+// the plaintext with 'algorithm' before encrypting it, and records a flag that is authenticated along
+// with the ciphertext. Decrypt, DecryptBytes, DecryptFile and the other decryption functions detect the flag
+// and decompress transparently, no option is needed for that.
+//
+// Strings and bytes that wouldn't shrink are stored uncompressed, files are always compressed. With Zstd, files are
+// compressed chunk by chunk, so every chunk can still be authenticated and decompressed on its own.
+// Decompression stops with an error wrapping ErrTooLarge once the plaintext exceeds the limit of WithMaxSize,
+// or 1 GiB for strings and bytes if there is none. See EncryptCompressed for the information compression can leak.
+func WithCompression(algorithm Compression) Option {
+	return func(o *options) error {
+		switch algorithm {
+		case Zstd, Gzip:
+			o.compression = algorithm
+			o.encryptOnly = append(o.encryptOnly, "WithCompression")
+			return nil
+		}
+		return fmt.Errorf("invalid compression %d", algorithm)
+	}
+}
+
+// DefaultGzipLevel is the compression level used by EncryptFileGzip, one of the levels of compress/gzip.
+var DefaultGzipLevel = gzip.DefaultCompression
+
+// maxDecompressedSize limits the memory used to decompress a single value, guarding against decompression bombs.
+const maxDecompressedSize = 1 << 30
+
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+		return dec
+	})
+)
+
+// compressedCipher returns a copy of the Cipher that authenticates 'magic' before the AAD set with WithAAD
+// and doesn't compress.
+func (c *Cipher) compressedCipher(magic []byte) *Cipher {
+	cc := c.withAAD(append(bytes.Clone(magic), c.opts.aad...))
+	cc.opts.compression = compressionNone
+	return cc
+}
+
+// compressionMagic returns the compression flag at the start of 'data', or nil if there is none.
+func compressionMagic(data []byte) []byte {
+	for _, magic := range [][]byte{compressedMagic, gzipMagic, chunkedMagic, storedMagic} {
+		if bytes.HasPrefix(data, magic) {
+			return magic
+		}
+	}
+	return nil
+}
+
+// encryptCompressedBytes compresses 'plaintext' with the algorithm set with WithCompression and encrypts it,
+// preceded by the compression flag. The plaintext is stored uncompressed if compression wouldn't shrink it.
+func (c *Cipher) encryptCompressedBytes(plaintext []byte) ([]byte, error) {
+	magic, compressed := compressedMagic, zstdEncoder().EncodeAll(plaintext, nil)
+	if c.opts.compression == Gzip {
+		var buf bytes.Buffer
+		zw, err := gzip.NewWriterLevel(&buf, DefaultGzipLevel)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(plaintext); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		magic, compressed = gzipMagic, buf.Bytes()
+	}
+	if len(compressed) >= len(plaintext) {
+		magic, compressed = storedMagic, plaintext
+	}
+	encrypted, err := c.compressedCipher(magic).EncryptBytes(compressed)
+	if err != nil {
+		return nil, err
+	}
+	return append(bytes.Clone(magic), encrypted...), nil
+}
+
+// decryptCompressedBytes decrypts 'data', which starts with the compression flag 'magic', and decompresses the result.
+func (c *Cipher) decryptCompressedBytes(data, magic []byte) ([]byte, error) {
+	compressed, err := c.compressedCipher(magic).DecryptBytes(data[len(magic):])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(magic, storedMagic):
+		return compressed, nil
+	case bytes.Equal(magic, gzipMagic):
+		return c.gunzip(compressed)
+	}
+	return c.decompress(compressed)
+}
+
+// EncryptCompressed compresses the given plaintext with zstd and encrypts the result using AES-GCM encryption
+// with the provided key. It returns the encoded ciphertext and any error encountered.
+//
+// Compression shrinks text and other redundant data considerably. Note that the ciphertext length then depends
+// on the content of the plaintext, which can leak information if an attacker controls part of the plaintext.
+func EncryptCompressed(plaintext, key string, opts ...Option) (string, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.EncryptCompressed(plaintext)
+}
+
+// DecryptCompressed decrypts a ciphertext produced by EncryptCompressed using AES-GCM decryption with the provided key
+// and decompresses the result. Ciphertexts produced by Encrypt are decrypted as well, so callers can switch
+// to compression without converting existing data. It returns the plaintext and any error encountered.
+func DecryptCompressed(ciphertext, key string, opts ...Option) (string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.DecryptCompressed(ciphertext)
+}
+
+// EncryptCompressed compresses the given plaintext with zstd, encrypts it and returns the encoded ciphertext.
+// See the package-level EncryptCompressed for details.
+func (c *Cipher) EncryptCompressed(plaintext string) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	compressed := zstdEncoder().EncodeAll(pt, nil)
+	defer clear(compressed)
+	encrypted, err := c.compressedCipher(compressedMagic).EncryptBytes(compressed)
+	if err != nil {
+		return "", err
+	}
+	return c.opts.encoding.EncodeToString(append(bytes.Clone(compressedMagic), encrypted...)), nil
+}
+
+// DecryptCompressed decrypts a ciphertext produced by EncryptCompressed or Encrypt.
+// See the package-level DecryptCompressed for details.
+func (c *Cipher) DecryptCompressed(ciphertext string) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	data, err := c.decodeCiphertext(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, compressedMagic) {
+		return c.decryptUncompressed(data)
+	}
+	compressed, err := c.compressedCipher(compressedMagic).DecryptBytes(data[len(compressedMagic):])
+	if err != nil {
+		// a regular ciphertext whose nonce happens to start with the flag
+		if d, plainErr := c.decryptUncompressed(data); plainErr == nil {
+			return d, nil
+		}
+		return "", err
+	}
+	defer clear(compressed)
+	decompressed, err := c.decompress(compressed)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decompressed)
+	return string(decompressed), nil
+}
+
+// decompress decompresses the zstd-compressed 'compressed'. With WithMaxSize, decompression stops
+// with an error wrapping ErrTooLarge as soon as the plaintext exceeds the limit.
+func (c *Cipher) decompress(compressed []byte) ([]byte, error) {
+	if c.opts.maxSize <= 0 {
+		decompressed, err := zstdDecoder().DecodeAll(compressed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress plaintext: %w", err)
+		}
+		return decompressed, nil
+	}
+	zr, err := zstd.NewReader(bytes.NewReader(compressed), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't decompress plaintext: %w", err)
+	}
+	defer zr.Close()
+	decompressed, err := readAllLimited(zr, c.opts.maxSize)
+	if err != nil && !errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("can't decompress plaintext: %w", err)
+	}
+	return decompressed, err
+}
+
+// gunzip decompresses the gzip-compressed 'compressed'. Decompression stops with an error wrapping ErrTooLarge
+// as soon as the plaintext exceeds the limit set with WithMaxSize, or maxDecompressedSize if there is none.
+func (c *Cipher) gunzip(compressed []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("can't decompress plaintext: %w", err)
+	}
+	limit := int64(maxDecompressedSize)
+	if c.opts.maxSize > 0 {
+		limit = c.opts.maxSize
+	}
+	decompressed, err := readAllLimited(zr, limit)
+	if err != nil && !errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("can't decompress plaintext: %w", err)
+	}
+	return decompressed, err
+}
+
+// decryptUncompressed decrypts 'data' produced by EncryptBytes.
+func (c *Cipher) decryptUncompressed(data []byte) (string, error) {
+	decrypted, err := c.DecryptBytes(data)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
+
+// EncryptFileCompressed compresses the file located at 'path' with zstd and encrypts it in place using
+// AES-GCM encryption with the provided key. It returns an error if the file doesn't exist or if any
+// compression or encryption operation fails.
+//
+// The file is compressed and encrypted as a stream, so memory usage stays constant regardless of the file size.
+// It starts with a flag followed by the format written by EncryptFile, and is replaced atomically like with EncryptFile.
+func EncryptFileCompressed(path, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptFileCompressed(path)
+}
+
+// DecryptFileCompressed decrypts a file written by EncryptFileCompressed in place using AES-GCM decryption
+// with the provided key and decompresses it. Files written by EncryptFileGzip and EncryptFile are decrypted as well.
+// It returns an error if the file doesn't exist or if any decryption or decompression operation fails.
+func DecryptFileCompressed(path, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptFileCompressed(path)
+}
+
+// EncryptFileCompressed compresses and encrypts the file located at 'path' in place.
+// See the package-level EncryptFileCompressed for details.
+func (c *Cipher) EncryptFileCompressed(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.processFile(context.Background(), "encrypt", path, nil, c.refuseEncrypted(path, c.encryptCompressedTo))
+}
+
+// DecryptFileCompressed decrypts and decompresses the file located at 'path' in place.
+// See the package-level DecryptFileCompressed for details.
+func (c *Cipher) DecryptFileCompressed(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.processFile(context.Background(), "decrypt", path, nil, c.decryptCompressedTo)
+}
+
+// EncryptFileGzip is like EncryptFileCompressed but compresses the file with compress/gzip at DefaultGzipLevel
+// instead of zstd, for environments that only allow the standard library's compression formats.
+func EncryptFileGzip(path, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptFileGzip(path)
+}
+
+// DecryptFileGzip decrypts a file written by EncryptFileGzip in place using AES-GCM decryption with the provided key
+// and decompresses it. A flag in the file tells whether it has been compressed, files written by EncryptFile are
+// decrypted without attempting to decompress them. It is interchangeable with DecryptFileCompressed.
+func DecryptFileGzip(path, key string, opts ...Option) error {
+	return DecryptFileCompressed(path, key, opts...)
+}
+
+// EncryptFileGzip compresses the file located at 'path' with gzip and encrypts it in place.
+// See the package-level EncryptFileGzip for details.
+func (c *Cipher) EncryptFileGzip(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.processFile(context.Background(), "encrypt", path, nil, c.refuseEncrypted(path, c.encryptGzipTo))
+}
+
+// DecryptFileGzip decrypts and decompresses the file located at 'path' in place.
+// See the package-level DecryptFileGzip for details.
+func (c *Cipher) DecryptFileGzip(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.DecryptFileCompressed(path)
+}
+
+// encryptCompressedTo compresses the plaintext read from 'r' with zstd and encrypts it into 'w'.
+func (c *Cipher) encryptCompressedTo(w io.Writer, r io.Reader) error {
+	return c.compressTo(w, r, compressedMagic, func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	})
+}
+
+// encryptGzipTo compresses the plaintext read from 'r' with gzip and encrypts it into 'w'.
+func (c *Cipher) encryptGzipTo(w io.Writer, r io.Reader) error {
+	return c.compressTo(w, r, gzipMagic, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, DefaultGzipLevel)
+	})
+}
+
+// compressTo compresses the plaintext read from 'r' with the compressor created by 'compress', encrypts it
+// into the file format and writes it to 'w', preceded by 'magic'.
+func (c *Cipher) compressTo(w io.Writer, r io.Reader, magic []byte, compress func(w io.Writer) (io.WriteCloser, error)) error {
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		cw, err := compress(pw)
+		if err == nil {
+			_, err = io.Copy(cw, r)
+			err = errors.Join(err, cw.Close())
+		}
+		pw.CloseWithError(err)
+	}()
+	err := c.compressedCipher(magic).encryptTo(w, pr)
+	pr.CloseWithError(err)
+	return err
+}
+
+// decryptCompressedTo decrypts data written by encryptCompressedTo, encryptGzipTo or encryptChunkedTo, or by encryptTo if it
+// lacks a compression flag, from 'r' and writes the plaintext to 'w'.
+func (c *Cipher) decryptCompressedTo(w io.Writer, r io.Reader) error {
+	w = c.limitOutput(w)
+	br := bufio.NewReaderSize(r, DefaultChunkSize)
+	head, _ := br.Peek(len(compressedMagic))
+	if bytes.Equal(head, chunkedMagic) {
+		if _, err := br.Discard(len(chunkedMagic)); err != nil {
+			return err
+		}
+		return c.decryptChunkedTo(w, br)
+	}
+	var magic []byte
+	var decompress func(r io.Reader) (io.ReadCloser, error)
+	switch {
+	case bytes.Equal(head, compressedMagic):
+		magic = compressedMagic
+		decompress = func(r io.Reader) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		}
+	case bytes.Equal(head, gzipMagic):
+		magic = gzipMagic
+		decompress = func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}
+	default:
+		return c.decryptFileTo(w, br)
+	}
+	if _, err := br.Discard(len(magic)); err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := c.compressedCipher(magic).decryptTo(pw, br)
+		pw.CloseWithError(err)
+		errc <- err
+	}()
+	dr, err := decompress(pr)
+	if err == nil {
+		defer dr.Close()
+		if _, err = io.Copy(w, dr); err == nil {
+			// the final chunk must be authenticated even if the decompressor stopped before it
+			_, err = io.Copy(io.Discard, pr)
+		}
+	}
+	if err != nil {
+		pr.CloseWithError(err)
+		if decErr := <-errc; decErr != nil && decErr != err {
+			return decErr
+		}
+		return fmt.Errorf("can't decompress file: %w", err)
+	}
+	return <-errc
+}
@@ -0,0 +1,307 @@
+package aesgcm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_compressed(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+		opts []Option
+	}{
+		{"compressed 1", "Hello World!", "myKey123", nil},
+		{"compressed 2", "", "12345678", nil},
+		{"compressed 3", strings.Repeat("all work and no play makes jack a dull boy\n", 1000), "11111111", nil},
+		{"compressed 4", strings.Repeat("a", 10000), "myKey123", []Option{WithAAD([]byte("user-42")), WithEncoding(Hex)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptCompressed(tt.text, tt.key, tt.opts...)
+			if err != nil {
+				t.Fatalf("could not encrypt compressed: %s\n", err)
+			}
+			d, err := DecryptCompressed(e, tt.key, tt.opts...)
+			if err != nil || d != tt.text {
+				t.Errorf("encrypt/decrypt compressed failed: %v: %v\n", tt.name, err)
+			}
+			if _, err := DecryptCompressed(e, "wrongKey", tt.opts...); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed with the wrong key, got %v\n", err)
+			}
+			if d, err := Decrypt(e, tt.key, tt.opts...); err != nil || d != tt.text {
+				t.Errorf("Decrypt() of a compressed ciphertext failed: %v\n", err)
+			}
+
+			plain, _ := Encrypt(tt.text, tt.key, tt.opts...)
+			if d, err := DecryptCompressed(plain, tt.key, tt.opts...); err != nil || d != tt.text {
+				t.Errorf("DecryptCompressed() of an uncompressed ciphertext failed: %v\n", err)
+			}
+			if len(tt.text) > 1000 && len(e) >= len(plain) {
+				t.Errorf("expected compression to shrink the ciphertext: %d >= %d\n", len(e), len(plain))
+			}
+		})
+	}
+
+	t.Run("stripped flag", func(t *testing.T) {
+		e, _ := EncryptCompressed(strings.Repeat("x", 100), "myKey123")
+		data, _ := StdBase64.DecodeString(e)
+		stripped := StdBase64.EncodeToString(data[len(compressedMagic):])
+		if _, err := DecryptCompressed(stripped, "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("expected ErrAuthenticationFailed without the flag, got %v\n", err)
+		}
+	})
+}
+
+func Test_fileCompressed(t *testing.T) {
+	text := bytes.Repeat([]byte("all work and no play makes jack a dull boy\n"), 10000)
+	random := make([]byte, 3*DefaultChunkSize)
+	_, _ = rand.Read(random)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"text", text},
+		{"random", random},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.bin")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFileCompressed(path, "myKey123"); err != nil {
+				t.Fatalf("could not encrypt file compressed: %s\n", err)
+			}
+			e, _ := os.ReadFile(path)
+			if !bytes.HasPrefix(e, compressedMagic) {
+				t.Errorf("expected the compressed flag\n")
+			}
+			if len(tt.data) == len(text) && len(e) > len(text)/10 {
+				t.Errorf("expected text to compress well, got %d bytes\n", len(e))
+			}
+			if err := DecryptFileCompressed(path, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed with the wrong key, got %v\n", err)
+			}
+			if err := DecryptFileCompressed(path, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt file compressed: %s\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, tt.data) {
+				t.Errorf("encrypt/decrypt file compressed failed\n")
+			}
+
+			if err := EncryptFile(path, "myKey123"); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			if err := DecryptFileCompressed(path, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt uncompressed file: %s\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, tt.data) {
+				t.Errorf("DecryptFileCompressed() of an uncompressed file failed\n")
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.bin")
+		if err := os.WriteFile(path, random, 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		c, _ := New("myKey123", WithChunkSize(1000))
+		if err := c.EncryptFileCompressed(path); err != nil {
+			t.Fatalf("could not encrypt file compressed: %s\n", err)
+		}
+		e, _ := os.ReadFile(path)
+		// drop the final chunk, the decompressor may already have everything it needs
+		if err := os.WriteFile(path, e[:len(e)-(len(e)-len(compressedMagic)-streamHeaderSize-12)%(1000+16)], 0o600); err != nil {
+			t.Fatalf("could not write file: %s\n", err)
+		}
+		if err := c.DecryptFileCompressed(path); !errors.Is(err, ErrStreamTruncated) {
+			t.Errorf("expected ErrStreamTruncated, got %v\n", err)
+		}
+	})
+}
+
+func Test_fileGzip(t *testing.T) {
+	text := bytes.Repeat([]byte("all work and no play makes jack a dull boy\n"), 10000)
+	tests := []struct {
+		name  string
+		data  []byte
+		level int
+	}{
+		{"empty", nil, DefaultGzipLevel},
+		{"text", text, DefaultGzipLevel},
+		{"text fastest", text, gzip.BestSpeed},
+		{"text no compression", text, gzip.NoCompression},
+	}
+	defer func(level int) { DefaultGzipLevel = level }(DefaultGzipLevel)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultGzipLevel = tt.level
+			path := filepath.Join(t.TempDir(), "data.bin")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFileGzip(path, "myKey123"); err != nil {
+				t.Fatalf("could not encrypt file gzip: %s\n", err)
+			}
+			e, _ := os.ReadFile(path)
+			if !bytes.HasPrefix(e, gzipMagic) {
+				t.Errorf("expected the gzip flag\n")
+			}
+			if tt.level != gzip.NoCompression && len(tt.data) == len(text) && len(e) > len(text)/10 {
+				t.Errorf("expected text to compress well, got %d bytes\n", len(e))
+			}
+			if err := DecryptFileGzip(path, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed with the wrong key, got %v\n", err)
+			}
+			if err := DecryptFileGzip(path, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt file gzip: %s\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, tt.data) {
+				t.Errorf("encrypt/decrypt file gzip failed\n")
+			}
+		})
+	}
+
+	t.Run("interchangeable", func(t *testing.T) {
+		DefaultGzipLevel = gzip.DefaultCompression
+		path := filepath.Join(t.TempDir(), "data.bin")
+		if err := os.WriteFile(path, text, 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		steps := []struct {
+			name    string
+			encrypt func(path, key string, opts ...Option) error
+			decrypt func(path, key string, opts ...Option) error
+		}{
+			{"gzip/compressed", EncryptFileGzip, DecryptFileCompressed},
+			{"compressed/gzip", EncryptFileCompressed, DecryptFileGzip},
+			{"plain/gzip", EncryptFile, DecryptFileGzip},
+		}
+		for _, s := range steps {
+			if err := s.encrypt(path, "myKey123"); err != nil {
+				t.Fatalf("%s: could not encrypt file: %s\n", s.name, err)
+			}
+			if err := s.decrypt(path, "myKey123"); err != nil {
+				t.Fatalf("%s: could not decrypt file: %s\n", s.name, err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, text) {
+				t.Errorf("%s: decrypted file differs\n", s.name)
+			}
+		}
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		DefaultGzipLevel = 42
+		path := filepath.Join(t.TempDir(), "data.bin")
+		if err := os.WriteFile(path, text, 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if err := EncryptFileGzip(path, "myKey123"); err == nil {
+			t.Errorf("expected an error for an invalid gzip level\n")
+		}
+		if d, _ := os.ReadFile(path); !bytes.Equal(d, text) {
+			t.Errorf("file changed despite the error\n")
+		}
+	})
+}
+
+func Test_WithCompression(t *testing.T) {
+	if _, err := New("myKey123", WithCompression(Compression(42))); err == nil {
+		t.Errorf("WithCompression() of an unknown algorithm expected an error\n")
+	}
+	if _, err := Decrypt("", "myKey123", WithCompression(Gzip)); err == nil {
+		t.Errorf("expected an error for WithCompression on decryption\n")
+	}
+
+	json := strings.Repeat(`{"id":42,"name":"ACME Corp","tags":["a","b"]},`, 1000)
+	random := make([]byte, 1000)
+	_, _ = rand.Read(random)
+	tests := []struct {
+		name      string
+		plaintext string
+		algorithm Compression
+		magic     []byte
+	}{
+		{"zstd", json, Zstd, compressedMagic},
+		{"gzip", json, Gzip, gzipMagic},
+		{"empty", "", Gzip, storedMagic},
+		{"incompressible", string(random), Zstd, storedMagic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := New("myKey123", WithCompression(tt.algorithm))
+			encrypted, err := c.EncryptBytes([]byte(tt.plaintext))
+			if err != nil {
+				t.Fatalf("EncryptBytes() error = %v\n", err)
+			}
+			if !bytes.HasPrefix(encrypted, tt.magic) {
+				t.Errorf("expected the flag %q, got %q\n", tt.magic, encrypted[:len(tt.magic)])
+			}
+			if bytes.Equal(tt.magic, storedMagic) && len(encrypted) > len(tt.plaintext)+100 {
+				t.Errorf("incompressible plaintext grew to %d bytes\n", len(encrypted))
+			}
+			if !bytes.Equal(tt.magic, storedMagic) && len(encrypted) > len(tt.plaintext)/10 {
+				t.Errorf("expected the plaintext to compress well, got %d bytes\n", len(encrypted))
+			}
+			decrypted, err := DecryptBytes(encrypted, "myKey123")
+			if err != nil || string(decrypted) != tt.plaintext {
+				t.Errorf("DecryptBytes() = %d bytes, %v\n", len(decrypted), err)
+			}
+			if _, err := DecryptBytes(encrypted[len(tt.magic):], "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed without the flag, got %v\n", err)
+			}
+		})
+	}
+
+	// strings and files
+	e, err := Encrypt(json, "myKey123", WithCompression(Gzip))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v\n", err)
+	}
+	if d, err := Decrypt(e, "myKey123"); err != nil || d != json {
+		t.Errorf("Decrypt() of a compressed ciphertext failed: %v\n", err)
+	}
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, []byte(json), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(path, "myKey123", WithCompression(Gzip)); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.HasPrefix(data, gzipMagic) {
+		t.Errorf("expected the gzip flag in the file\n")
+	}
+	if err := DecryptFile(path, "myKey123", WithMaxSize(1000)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("DecryptFile() with WithMaxSize error = %v, want ErrTooLarge\n", err)
+	}
+	if err := DecryptFile(path, "myKey123"); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != json {
+		t.Errorf("encrypt/decrypt file with WithCompression failed\n")
+	}
+}
+
+func Test_WithCompression_bomb(t *testing.T) {
+	for _, algorithm := range []Compression{Zstd, Gzip} {
+		c, _ := New("myKey123", WithCompression(algorithm))
+		e, err := c.EncryptBytes(make([]byte, 4*1024*1024))
+		if err != nil {
+			t.Fatalf("EncryptBytes() error = %v\n", err)
+		}
+		d, _ := New("myKey123", WithMaxSize(1024*1024))
+		if _, err := d.DecryptBytes(e); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("DecryptBytes() of %d with WithMaxSize error = %v, want ErrTooLarge\n", algorithm, err)
+		}
+	}
+}
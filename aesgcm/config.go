@@ -0,0 +1,81 @@
+package aesgcm
+
+import "io"
+
+// Config holds encryption settings, such as the encoding and key derivation, for use with varying keys.
+// It is the key-per-call counterpart of Cipher, which binds a single key, and a single entry point for
+// configuring behavior that would otherwise need a separate package-level function for every variant.
+// It is safe for concurrent use by multiple goroutines.
+type Config struct {
+	opts []Option
+}
+
+// NewConfig creates a new Config with the provided options, see WithEncoding, WithKDF, WithChunkSize and the
+// other Option functions. Invalid options are reported by the methods, since they are validated per call.
+func NewConfig(opts ...Option) *Config {
+	return &Config{opts: append([]Option{}, opts...)}
+}
+
+// Cipher returns a Cipher for the provided key with the settings of the Config.
+// It is the preferred way to encrypt or decrypt many values with the same key.
+// Options that only apply to encryption, such as WithRand, are accepted, since a Config
+// is usually shared between encryption and decryption.
+func (cfg *Config) Cipher(key string) (*Cipher, error) {
+	return New(key, cfg.opts...)
+}
+
+// Encrypt encrypts the given plaintext with the provided key and returns the encoded ciphertext and any error encountered.
+func (cfg *Config) Encrypt(plaintext, key string) (string, error) {
+	return Encrypt(plaintext, key, cfg.opts...)
+}
+
+// Decrypt decrypts the given encoded ciphertext with the provided key and returns the plaintext and any error encountered.
+// Options that only apply to encryption, such as WithRand, are ignored.
+func (cfg *Config) Decrypt(ciphertext, key string) (string, error) {
+	c, err := cfg.Cipher(key)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.Decrypt(ciphertext)
+}
+
+// EncryptFile encrypts the file located at 'path' in place with the provided key.
+// It returns an error if the file doesn't exist or if any encryption operation fails.
+func (cfg *Config) EncryptFile(path, key string) error {
+	return EncryptFile(path, key, cfg.opts...)
+}
+
+// DecryptFile decrypts the file located at 'path' in place with the provided key.
+// It returns an error if the file doesn't exist or if any decryption operation fails.
+// Options that only apply to encryption, such as WithRand, are ignored.
+func (cfg *Config) DecryptFile(path, key string) error {
+	c, err := cfg.Cipher(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptFile(path)
+}
+
+// EncryptStream reads plaintext from 'src', encrypts it with the provided key and writes the result to 'dst'
+// in the format of the package-level EncryptStream, using the chunk size set with WithChunkSize.
+func (cfg *Config) EncryptStream(dst io.Writer, src io.Reader, key string) error {
+	c, err := cfg.Cipher(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptStream(dst, src)
+}
+
+// DecryptStream reads a stream produced by EncryptStream from 'src', decrypts it with the provided key
+// and writes the plaintext to 'dst'. See the package-level DecryptStream for how errors are reported.
+func (cfg *Config) DecryptStream(dst io.Writer, src io.Reader, key string) error {
+	c, err := cfg.Cipher(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptStream(dst, src)
+}
@@ -0,0 +1,116 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Config(t *testing.T) {
+	sha := func(key string) ([]byte, error) {
+		sum := sha256.Sum256([]byte(key))
+		return sum[:], nil
+	}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"hex", []Option{WithEncoding(Hex)}},
+		{"kdf", []Option{WithKDF(sha)}},
+		{"kdf + key size", []Option{WithKDF(sha), WithKeySize(16), WithEncoding(RawURL)}},
+		{"chunk size", []Option{WithChunkSize(100)}},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig(tt.opts...)
+			e, err := cfg.Encrypt("Hello World!", "myKey123")
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if d, err := cfg.Decrypt(e, "myKey123"); err != nil || d != "Hello World!" {
+				t.Errorf("encrypt/decrypt failed: %v: got %v (%v)\n", tt.name, d, err)
+			}
+			if _, err := cfg.Decrypt(e, "wrongKey"); err == nil {
+				t.Errorf("decrypt with wrong key succeeded: %v\n", tt.name)
+			}
+
+			file := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".txt")
+			if err := os.WriteFile(file, []byte("Hello World!"), 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := cfg.EncryptFile(file, "myKey123"); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			if err := cfg.DecryptFile(file, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if b, _ := os.ReadFile(file); string(b) != "Hello World!" {
+				t.Errorf("encrypt/decrypt file failed: %v: got %s\n", tt.name, b)
+			}
+
+			data := bytes.Repeat([]byte("Hello World!"), 1000)
+			var encrypted, decrypted bytes.Buffer
+			if err := cfg.EncryptStream(&encrypted, bytes.NewReader(data), "myKey123"); err != nil {
+				t.Fatalf("could not encrypt stream: %s\n", err)
+			}
+			if err := cfg.DecryptStream(&decrypted, &encrypted, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt stream: %s\n", err)
+			}
+			if !bytes.Equal(data, decrypted.Bytes()) {
+				t.Errorf("encrypt/decrypt stream failed: %v: plaintext mismatch\n", tt.name)
+			}
+		})
+	}
+
+	// the KDF changes the derived key, so ciphertexts aren't interchangeable
+	e, _ := NewConfig(WithKDF(sha)).Encrypt("Hello World!", "myKey123")
+	if _, err := Decrypt(e, "myKey123"); err == nil {
+		t.Errorf("decrypt without the KDF succeeded\n")
+	}
+
+	// the chunk size is recorded in the stream, so DecryptStream doesn't need it
+	var small, large bytes.Buffer
+	data := bytes.Repeat([]byte("x"), 1000)
+	_ = NewConfig(WithChunkSize(100)).EncryptStream(&small, bytes.NewReader(data), "myKey123")
+	_ = EncryptStream(&large, bytes.NewReader(data), "myKey123")
+	if small.Len() <= large.Len() {
+		t.Errorf("expected more chunk overhead with a smaller chunk size: %d <= %d\n", small.Len(), large.Len())
+	}
+	var d bytes.Buffer
+	if err := DecryptStream(&d, &small, "myKey123"); err != nil || !bytes.Equal(d.Bytes(), data) {
+		t.Errorf("could not decrypt stream with custom chunk size: %v\n", err)
+	}
+}
+
+func Test_Config_errors(t *testing.T) {
+	short := func(key string) ([]byte, error) { return []byte("short"), nil }
+	failing := func(key string) ([]byte, error) { return nil, errors.New("boom") }
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{"short kdf output", []Option{WithKDF(short)}, ErrKeyDerivation},
+		{"failing kdf", []Option{WithKDF(failing)}, ErrKeyDerivation},
+		{"nil kdf", []Option{WithKDF(nil)}, nil},
+		{"zero chunk size", []Option{WithChunkSize(0)}, nil},
+		{"huge chunk size", []Option{WithChunkSize(maxChunkSize + 1)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewConfig(tt.opts...).Encrypt("Hello World!", "myKey123")
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("expected error %v, got %v\n", tt.wantErr, err)
+			}
+		})
+	}
+	if _, err := NewConfig().Encrypt("Hello World!", ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+}
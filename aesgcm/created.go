@@ -0,0 +1,77 @@
+package aesgcm
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultClockSkew is the default tolerance of DecryptWithMaxAge for differences between the clocks
+// of the machines encrypting and decrypting, see WithClockSkew.
+const DefaultClockSkew = time.Minute
+
+// WithClockSkew sets the tolerance of DecryptWithMaxAge for differences between the clocks of the machines
+// encrypting and decrypting, which defaults to DefaultClockSkew. Ciphertexts are accepted if they were created
+// at most 'maxAge' plus 'skew' ago and at most 'skew' in the future.
+func WithClockSkew(skew time.Duration) Option {
+	return func(o *options) error {
+		if skew < 0 {
+			return fmt.Errorf("invalid clock skew %s, must not be negative", skew)
+		}
+		o.clockSkew = skew
+		return nil
+	}
+}
+
+// DecryptWithMaxAge decrypts the given encoded encrypted text using AES-GCM decryption with the provided key,
+// like Decrypt, but rejects ciphertexts created more than 'maxAge' ago with an error wrapping ErrTooOld.
+// The creation time is recorded in the ciphertext header by Encrypt and EncryptBytes and authenticated along
+// with the ciphertext, so it can't be forged. Ciphertexts without creation time, written by earlier versions,
+// as streams or as files, are rejected with an error wrapping ErrTooOld as well. See WithClockSkew for the
+// tolerance for differing clocks. It returns the decrypted plaintext and any error encountered.
+func DecryptWithMaxAge(ciphertext, key string, maxAge time.Duration, opts ...Option) (string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.DecryptWithMaxAge(ciphertext, maxAge)
+}
+
+// DecryptWithMaxAge decrypts the given encoded encrypted text and rejects it if it was created more than
+// 'maxAge' ago. See the package-level DecryptWithMaxAge for details.
+func (c *Cipher) DecryptWithMaxAge(ciphertext string, maxAge time.Duration) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	if maxAge <= 0 {
+		return "", fmt.Errorf("invalid maximum age %s, must be positive", maxAge)
+	}
+	var created time.Time
+	cc := *c
+	cc.created = &created
+	decrypted, err := cc.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkAge(created, maxAge); err != nil {
+		return "", err
+	}
+	return decrypted, nil
+}
+
+// checkAge returns an error if the authenticated creation time 'created' is more than 'maxAge' ago,
+// zero or in the future, allowing for the clock skew set with WithClockSkew.
+func (c *Cipher) checkAge(created time.Time, maxAge time.Duration) error {
+	if created.IsZero() {
+		return fmt.Errorf("%w: the ciphertext has no creation time", ErrTooOld)
+	}
+	now := c.opts.clock.Now()
+	if created.After(now.Add(c.opts.clockSkew)) {
+		return fmt.Errorf("%w: created at %s", ErrClockSkew, created.Format(time.RFC3339))
+	}
+	if now.Sub(created) > maxAge+c.opts.clockSkew {
+		return fmt.Errorf("%w: created at %s, more than %s ago", ErrTooOld, created.Format(time.RFC3339), maxAge)
+	}
+	return nil
+}
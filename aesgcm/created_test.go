@@ -0,0 +1,103 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/toxyl/keys"
+)
+
+func Test_DecryptWithMaxAge(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) Option {
+		return WithClock(ClockFunc(func() time.Time { return created.Add(d) }))
+	}
+	e, err := Encrypt("Hello World!", "myKey123", at(0))
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		opts    []Option
+		want    error
+	}{
+		{"fresh", 0, nil, nil},
+		{"within max age", time.Hour, nil, nil},
+		{"within skew", time.Hour + 30*time.Second, nil, nil},
+		{"too old", time.Hour + 2*time.Minute, nil, ErrTooOld},
+		{"too old without skew", time.Hour + time.Second, []Option{WithClockSkew(0)}, ErrTooOld},
+		{"larger skew", time.Hour + 2*time.Minute, []Option{WithClockSkew(5 * time.Minute)}, nil},
+		{"slightly in the future", -30 * time.Second, nil, nil},
+		{"in the future", -2 * time.Minute, nil, ErrClockSkew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := DecryptWithMaxAge(e, "myKey123", time.Hour, append(tt.opts, at(tt.elapsed))...)
+			if tt.want == nil {
+				if err != nil || d != "Hello World!" {
+					t.Errorf("DecryptWithMaxAge() = %q, %v\n", d, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || d != "" {
+				t.Errorf("DecryptWithMaxAge() = %q, %v, want %v\n", d, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := DecryptWithMaxAge(e, "wrongKey", time.Hour, at(0)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptWithMaxAge() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	if _, err := DecryptWithMaxAge(e, "myKey123", 0); err == nil {
+		t.Errorf("DecryptWithMaxAge() with a max age of 0 expected an error\n")
+	}
+	if _, err := New("myKey123", WithClockSkew(-time.Second)); err == nil {
+		t.Errorf("WithClockSkew() with a negative skew expected an error\n")
+	}
+
+	// the creation time is authenticated
+	raw, _ := base64.StdEncoding.DecodeString(e)
+	raw[len(raw)-16-len("Hello World!")-1]++
+	if _, err := DecryptWithMaxAge(base64.StdEncoding.EncodeToString(raw), "myKey123", time.Hour, at(0)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptWithMaxAge() with a modified creation time error = %v, want ErrAuthenticationFailed\n", err)
+	}
+
+	// the creation time is found behind the flags of other options
+	c, _ := New("myKey123", at(0), WithCompression(Zstd), WithFingerprint(), WithKeySize(16))
+	ce, _ := c.EncryptWithTTL("Hello World!", 24*time.Hour)
+	if d, err := DecryptWithMaxAge(ce, "myKey123", time.Hour, at(time.Hour), WithKeySize(16)); err != nil || d != "Hello World!" {
+		t.Errorf("DecryptWithMaxAge() with other options = %q, %v\n", d, err)
+	}
+	if _, err := DecryptWithMaxAge(ce, "myKey123", time.Hour, at(2*time.Hour), WithKeySize(16)); !errors.Is(err, ErrTooOld) {
+		t.Errorf("DecryptWithMaxAge() with other options error = %v, want ErrTooOld\n", err)
+	}
+}
+
+func Test_DecryptWithMaxAge_legacy(t *testing.T) {
+	nonce := []byte("0123456789ab")
+	k, _ := keys.WeakKeyScrambler("myKey123")
+	block, _ := aes.NewCipher([]byte(k))
+	gcm, _ := cipher.NewGCM(block)
+	v1 := append([]byte("AGH\x01\x03\x01\x00\x0c"), nonce...)
+	legacy := base64.StdEncoding.EncodeToString(gcm.Seal(bytes.Clone(v1), nonce, []byte("Hello World!"), v1))
+	headerless := base64.StdEncoding.EncodeToString(gcm.Seal(bytes.Clone(nonce), nonce, []byte("Hello World!"), nil))
+
+	for name, e := range map[string]string{"version 1": legacy, "headerless": headerless} {
+		if d, err := Decrypt(e, "myKey123"); err != nil || d != "Hello World!" {
+			t.Errorf("%s: Decrypt() = %q, %v\n", name, d, err)
+		}
+		if m, err := Inspect(e); err != nil || !m.Created.IsZero() {
+			t.Errorf("%s: Inspect() = %+v, %v, want a zero creation time\n", name, m, err)
+		}
+		if _, err := DecryptWithMaxAge(e, "myKey123", time.Hour); !errors.Is(err, ErrTooOld) {
+			t.Errorf("%s: DecryptWithMaxAge() error = %v, want ErrTooOld\n", name, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_EncryptCSV(t *testing.T) {
+	input := "id,created,email,ssn\n" +
+		"1,2024-01-01,alice@example.com,123-45-6789\n" +
+		"2,2024-01-02,\"bob, jr.@example.com\",\n" +
+		"3,2024-01-03,\"multi\nline\",987-65-4321,extra\n"
+
+	var encrypted bytes.Buffer
+	if err := EncryptCSV(strings.NewReader(input), &encrypted, "myKey123", []int{3, 2, 2}); err != nil {
+		t.Fatalf("EncryptCSV() error = %v\n", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(encrypted.Bytes())).ReadAll()
+	if err == nil {
+		t.Errorf("expected rows of different lengths to be kept\n")
+	}
+	r := csv.NewReader(bytes.NewReader(encrypted.Bytes()))
+	r.FieldsPerRecord = -1
+	if rows, err = r.ReadAll(); err != nil || len(rows) != 4 {
+		t.Fatalf("could not read encrypted CSV: %d rows, %v\n", len(rows), err)
+	}
+	for i, row := range rows {
+		if strings.Contains(input, row[2]) || strings.Contains(input, row[3]) {
+			t.Errorf("row %d: expected columns 2 and 3 to be encrypted: %q\n", i, row)
+		}
+	}
+	if rows[1][1] != "2024-01-01" || rows[3][4] != "extra" {
+		t.Errorf("expected other columns to be unchanged: %q\n", rows)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptCSV(bytes.NewReader(encrypted.Bytes()), &decrypted, "myKey123", []int{2, 3}); err != nil {
+		t.Fatalf("DecryptCSV() error = %v\n", err)
+	}
+	if decrypted.String() != input {
+		t.Errorf("DecryptCSV() = %q, want %q\n", decrypted.String(), input)
+	}
+
+	var cellErr *CSVCellError
+	err = DecryptCSV(bytes.NewReader(encrypted.Bytes()), &bytes.Buffer{}, "wrongKey", []int{2})
+	if !errors.As(err, &cellErr) || cellErr.Row != 0 || cellErr.Column != 2 || !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptCSV() with the wrong key error = %v, want a *CSVCellError for row 0, column 2\n", err)
+	}
+	err = DecryptCSV(bytes.NewReader(encrypted.Bytes()), &bytes.Buffer{}, "myKey123", []int{1})
+	if !errors.As(err, &cellErr) || cellErr.Column != 1 {
+		t.Errorf("DecryptCSV() of an unencrypted column error = %v, want a *CSVCellError for column 1\n", err)
+	}
+}
+
+func Test_EncryptCSV_errors(t *testing.T) {
+	var out bytes.Buffer
+	err := EncryptCSV(strings.NewReader("a,b,c\nd,e\n"), &out, "myKey123", []int{2})
+	var cellErr *CSVCellError
+	if !errors.Is(err, ErrColumnOutOfRange) || !errors.As(err, &cellErr) || cellErr.Row != 1 || cellErr.Column != 2 {
+		t.Errorf("EncryptCSV() of a short row error = %v, want ErrColumnOutOfRange in row 1\n", err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 1 {
+		t.Errorf("expected the rows before the error to be written, got %d\n", lines)
+	}
+	if err := EncryptCSV(strings.NewReader("a,b\n"), &out, "myKey123", []int{-1}); err == nil {
+		t.Errorf("EncryptCSV() with a negative column expected an error\n")
+	}
+	if err := EncryptCSV(strings.NewReader("a,\"b\n"), &out, "myKey123", []int{0}); err == nil {
+		t.Errorf("EncryptCSV() of malformed CSV expected an error\n")
+	}
+
+	// without columns, the rows are copied
+	out.Reset()
+	if err := EncryptCSV(strings.NewReader("a,b\n"), &out, "myKey123", nil); err != nil || out.String() != "a,b\n" {
+		t.Errorf("EncryptCSV() without columns = %q, %v\n", out.String(), err)
+	}
+}
@@ -0,0 +1,192 @@
+package aesgcm
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ctxReader is an io.Reader that stops reading once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := ctxErr(r.ctx); err != nil {
+		return 0, err
+	}
+	if len(p) > DefaultChunkSize {
+		p = p[:DefaultChunkSize]
+	}
+	return r.r.Read(p)
+}
+
+// ctxErr returns an error wrapping ErrCanceled and the error of 'ctx' if 'ctx' is done, nil otherwise.
+func ctxErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+	return nil
+}
+
+// EncryptFileCtx is like EncryptFile but can be cancelled through 'ctx'.
+// It returns an error if the file doesn't exist, if any encryption operation fails or an error
+// wrapping ErrCanceled and the error of 'ctx' if 'ctx' is done.
+//
+// The context is checked between chunk reads and before the file is replaced. The encrypted data is
+// written to a temporary file which is then renamed over the original, so the original file is left
+// intact on cancellation. The output is in the same format as that of EncryptFile.
+func EncryptFileCtx(ctx context.Context, path, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptFileCtx(ctx, path)
+}
+
+// EncryptFileCtx is like EncryptFile but can be cancelled through 'ctx'.
+// See the package-level EncryptFileCtx for details.
+func (c *Cipher) EncryptFileCtx(ctx context.Context, path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.encryptFile(ctx, path, ctxWrapper(ctx))
+}
+
+// DecryptFileCtx is like DecryptFile but can be cancelled through 'ctx'.
+// It returns an error if the file doesn't exist, if any decryption operation fails or an error
+// wrapping ErrCanceled and the error of 'ctx' if 'ctx' is done.
+//
+// The context is checked between chunk reads and before the file is replaced. The decrypted data is
+// written to a temporary file which is then renamed over the original, so the original file is left
+// intact on cancellation.
+func DecryptFileCtx(ctx context.Context, path, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptFileCtx(ctx, path)
+}
+
+// DecryptFileCtx is like DecryptFile but can be cancelled through 'ctx'.
+// See the package-level DecryptFileCtx for details.
+func (c *Cipher) DecryptFileCtx(ctx context.Context, path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.decryptFile(ctx, path, ctxWrapper(ctx))
+}
+
+// EncryptStreamCtx is like EncryptStream but can be cancelled through 'ctx'.
+// The context is checked before each read of up to DefaultChunkSize bytes from 'src'. If 'ctx' is done,
+// it returns an error wrapping ErrCanceled and the error of 'ctx', and the data written to 'dst' so far
+// is an incomplete stream that DecryptStream rejects.
+func EncryptStreamCtx(ctx context.Context, dst io.Writer, src io.Reader, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptStreamCtx(ctx, dst, src)
+}
+
+// EncryptStreamCtx is like EncryptStream but can be cancelled through 'ctx'.
+// See the package-level EncryptStreamCtx for details.
+func (c *Cipher) EncryptStreamCtx(ctx context.Context, dst io.Writer, src io.Reader) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	return c.EncryptStream(dst, &ctxReader{ctx: ctx, r: src})
+}
+
+// DecryptStreamCtx is like DecryptStream but can be cancelled through 'ctx'.
+// The context is checked before each read of up to DefaultChunkSize bytes from 'src'. If 'ctx' is done,
+// it returns an error wrapping ErrCanceled and the error of 'ctx'. The plaintext written to 'dst' so far
+// must be discarded, as with any other error of DecryptStream.
+func DecryptStreamCtx(ctx context.Context, dst io.Writer, src io.Reader, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptStreamCtx(ctx, dst, src)
+}
+
+// DecryptStreamCtx is like DecryptStream but can be cancelled through 'ctx'.
+// See the package-level DecryptStreamCtx for details.
+func (c *Cipher) DecryptStreamCtx(ctx context.Context, dst io.Writer, src io.Reader) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	return c.DecryptStream(dst, &ctxReader{ctx: ctx, r: src})
+}
+
+// ctxWrapper returns a readerWrapper that stops reading once 'ctx' is done.
+// It returns nil for a context that can never be done, such as context.Background().
+func ctxWrapper(ctx context.Context) readerWrapper {
+	if ctx.Done() == nil {
+		return nil
+	}
+	return func(r io.Reader, _ int64) io.Reader {
+		return &ctxReader{ctx: ctx, r: r}
+	}
+}
+
+// EncryptDirCtx is like EncryptDir but can be cancelled through 'ctx'. Once 'ctx' is done, no more files
+// are started and the files being encrypted are left unchanged, the returned error then wraps ErrCanceled
+// and the error of 'ctx'. Files that have already been encrypted stay encrypted.
+func EncryptDirCtx(ctx context.Context, root, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.EncryptDirCtx(ctx, root)
+	return err
+}
+
+// EncryptDirCtx is like EncryptDir but can be cancelled through 'ctx'.
+// See the package-level EncryptDirCtx for details, files interrupted by the cancellation are not counted.
+func (c *Cipher) EncryptDirCtx(ctx context.Context, root string) (DirSummary, error) {
+	if err := c.acquire(); err != nil {
+		return DirSummary{}, err
+	}
+	defer c.release()
+	return c.processDir(ctx, root, nil, true)
+}
+
+// DecryptDirCtx is like DecryptDir but can be cancelled through 'ctx'. Once 'ctx' is done, no more files
+// are started and the files being decrypted are left unchanged, the returned error then wraps ErrCanceled
+// and the error of 'ctx'. Files that have already been decrypted stay decrypted.
+func DecryptDirCtx(ctx context.Context, root, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.DecryptDirCtx(ctx, root)
+	return err
+}
+
+// DecryptDirCtx is like DecryptDir but can be cancelled through 'ctx'.
+// See the package-level DecryptDirCtx for details, files interrupted by the cancellation are not counted.
+func (c *Cipher) DecryptDirCtx(ctx context.Context, root string) (DirSummary, error) {
+	if err := c.acquire(); err != nil {
+		return DirSummary{}, err
+	}
+	defer c.release()
+	return c.processDir(ctx, root, nil, false)
+}
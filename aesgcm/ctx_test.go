@@ -0,0 +1,175 @@
+package aesgcm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_fileCtx(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+		key  string
+	}{
+		{"ctx 1", "../test_data/ctx1.txt", "Hello World!", "myKey123"},
+		{"ctx 2", "../test_data/ctx2.txt", "", "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := flo.File(tt.file)
+			defer func() { _ = f.Remove() }()
+			if err := f.StoreBytes([]byte(tt.text)); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := EncryptFileCtx(ctx, tt.file, tt.key); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v\n", err)
+			}
+			if s := f.AsString(); s != tt.text {
+				t.Errorf("cancelled encryption modified the file: expected %v, got %v\n", tt.text, s)
+			}
+
+			if err := EncryptFileCtx(context.Background(), tt.file, tt.key); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			encrypted := f.AsString()
+			if err := DecryptFileCtx(ctx, tt.file, tt.key); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v\n", err)
+			}
+			if s := f.AsString(); s != encrypted {
+				t.Errorf("cancelled decryption modified the file\n")
+			}
+
+			if err := DecryptFile(tt.file, tt.key); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if s := f.AsString(); s != tt.text {
+				t.Errorf("encrypt/decrypt file failed: %v: expected %v, got %v!\n", tt.name, tt.text, s)
+			}
+		})
+	}
+}
+
+func Test_fileCtx_midway(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4*DefaultChunkSize)
+	path := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	// cancel once the first chunk has been read and count the reads that follow
+	cancelAfterFirst := func(cancel context.CancelFunc, calls *int) Option {
+		return WithProgress(func(done, total int64) {
+			*calls++
+			if *calls == 1 {
+				cancel()
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := EncryptFileCtx(ctx, path, "myKey123", cancelAfterFirst(cancel, &calls))
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptFileCtx() error = %v, want ErrCanceled and context.Canceled\n", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected encryption to stop after the first chunk, got %d progress reports\n", calls)
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, data) {
+		t.Errorf("cancelled encryption modified the file\n")
+	}
+
+	if err := EncryptFile(path, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	encrypted, _ := os.ReadFile(path)
+	ctx, cancel = context.WithCancel(context.Background())
+	calls = 0
+	err = DecryptFileCtx(ctx, path, "myKey123", cancelAfterFirst(cancel, &calls))
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("DecryptFileCtx() error = %v, want ErrCanceled and context.Canceled\n", err)
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, encrypted) {
+		t.Errorf("cancelled decryption modified the file\n")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if err := DecryptFileCtx(ctx, path, "myKey123"); !errors.Is(err, ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DecryptFileCtx() error = %v, want ErrCanceled and context.DeadlineExceeded\n", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected cancelled operations to remove their temporary files, got %d entries\n", len(entries))
+	}
+}
+
+func Test_streamCtx(t *testing.T) {
+	data := bytes.Repeat([]byte{0x42}, 4*DefaultChunkSize)
+
+	var e bytes.Buffer
+	if err := EncryptStreamCtx(context.Background(), &e, bytes.NewReader(data), "myKey123"); err != nil {
+		t.Fatalf("EncryptStreamCtx() error = %v\n", err)
+	}
+	var d bytes.Buffer
+	if err := DecryptStreamCtx(context.Background(), &d, bytes.NewReader(e.Bytes()), "myKey123"); err != nil || !bytes.Equal(d.Bytes(), data) {
+		t.Fatalf("DecryptStreamCtx() error = %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cancelReader{r: bytes.NewReader(data), cancel: cancel, after: DefaultChunkSize}
+	var partial bytes.Buffer
+	err := EncryptStreamCtx(ctx, &partial, r, "myKey123")
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptStreamCtx() error = %v, want ErrCanceled and context.Canceled\n", err)
+	}
+	if r.read > 2*DefaultChunkSize {
+		t.Errorf("expected encryption to stop promptly, read %d bytes\n", r.read)
+	}
+	if err := DecryptStream(io.Discard, &partial, "myKey123"); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected the cancelled stream to be rejected as truncated, got %v\n", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	r = &cancelReader{r: bytes.NewReader(e.Bytes()), cancel: cancel, after: DefaultChunkSize}
+	err = DecryptStreamCtx(ctx, io.Discard, r, "myKey123")
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("DecryptStreamCtx() error = %v, want ErrCanceled and context.Canceled\n", err)
+	}
+	if r.read > 2*DefaultChunkSize {
+		t.Errorf("expected decryption to stop promptly, read %d bytes\n", r.read)
+	}
+
+	if err := EncryptStreamCtx(ctx, io.Discard, bytes.NewReader(data), "myKey123"); !errors.Is(err, ErrCanceled) {
+		t.Errorf("EncryptStreamCtx() with a done context error = %v, want ErrCanceled\n", err)
+	}
+}
+
+// cancelReader calls 'cancel' once 'after' bytes have been read from 'r'.
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+	after  int
+	read   int
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += n
+	if r.read >= r.after {
+		r.cancel()
+	}
+	return n, err
+}
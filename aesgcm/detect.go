@@ -0,0 +1,119 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// magicPeekSize is the number of bytes needed to detect the magic and version byte of the chunked format,
+// which may follow a compression flag and the headers written by Cipher.prefix.
+var magicPeekSize = len(compressedMagic) + maxPrefixSize + len(streamMagic) + 1
+
+// IsEncrypted reports whether the file located at 'path' has been written by EncryptFile or one of its variants,
+// such as EncryptFileCompressed. See IsEncryptedData for how files are detected.
+// It returns an error if the file can't be read.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, magicPeekSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return IsEncryptedData(head[:n]), nil
+}
+
+// IsEncryptedData reports whether 'data' starts like the output of EncryptFile: the magic "AGCS" and version byte
+// of the chunked format, optionally preceded by the headers added by options and by the flag of a compressed file.
+// No key is needed, so it doesn't tell whether the data can be decrypted or has been tampered with.
+// Data holding a single ciphertext, such as the output of EncryptBytes or EncryptRaw, has no magic
+// and is reported as not encrypted.
+func IsEncryptedData(data []byte) bool {
+	version := byte(streamVersion)
+	for _, magic := range [][]byte{compressedMagic, gzipMagic, chunkedMagic} {
+		if bytes.HasPrefix(data, magic) {
+			data = data[len(magic):]
+			if bytes.Equal(magic, chunkedMagic) {
+				version = chunkedStreamVersion
+			}
+			break
+		}
+	}
+	if _, ok := parseKeySizeHeader(data); ok {
+		data = data[keySizeHeaderSize:]
+	}
+	if _, ok := parseFingerprintHeader(data); ok {
+		data = data[fingerprintHeaderSize:]
+	}
+	if hasKeyCheckHeader(data) {
+		data = data[min(len(data), keyCheckHeaderSize):]
+	}
+	return len(data) > len(streamMagic) && bytes.HasPrefix(data, streamMagic) && data[len(streamMagic)] == version
+}
+
+// WithLegacyFormat makes DecryptFile and the other functions decrypting files in place or into another file
+// accept files holding a single ciphertext without the magic of the chunked format, as written by EncryptFile
+// of earlier versions. Without it, such files are rejected with ErrNotEncrypted so plaintext files aren't
+// mistaken for ciphertexts.
+func WithLegacyFormat() Option {
+	return func(o *options) error {
+		o.legacyFormat = true
+		return nil
+	}
+}
+
+// decryptFileTo is decryptTo for files written by EncryptFile, which are decompressed if they have been compressed.
+// Data without the magic of the chunked format is rejected with ErrNotEncrypted unless WithLegacyFormat has been passed.
+func (c *Cipher) decryptFileTo(w io.Writer, r io.Reader) error {
+	br := bufio.NewReaderSize(r, DefaultChunkSize)
+	head, err := br.Peek(maxPrefixSize + len(streamMagic) + 1)
+	if magic := compressionMagic(head); magic != nil && !bytes.Equal(magic, storedMagic) {
+		return c.decryptCompressedTo(w, br)
+	}
+	if c.opts.legacyFormat {
+		return c.decryptTo(w, br)
+	}
+	if !IsEncryptedData(head) {
+		if err != nil && err != io.EOF {
+			return err
+		}
+		return ErrNotEncrypted
+	}
+	return c.decryptTo(w, br)
+}
+
+// WithForce makes EncryptFile and the other functions encrypting files encrypt files that are already encrypted,
+// which they otherwise refuse with ErrAlreadyEncrypted. EncryptDir then encrypts them too instead of skipping them.
+// Only use it when double encryption is intended: decrypting such a file once yields the inner ciphertext.
+func WithForce() Option {
+	return func(o *options) error {
+		o.force = true
+		o.encryptOnly = append(o.encryptOnly, "WithForce")
+		return nil
+	}
+}
+
+// refuseEncrypted returns 'fn' preceded by a check that the data read from the file located at 'path'
+// isn't encrypted yet, which fails with an error wrapping ErrAlreadyEncrypted unless WithForce has been passed.
+func (c *Cipher) refuseEncrypted(path string, fn func(w io.Writer, r io.Reader) error) func(w io.Writer, r io.Reader) error {
+	if c.opts.force {
+		return fn
+	}
+	return func(w io.Writer, r io.Reader) error {
+		br := bufio.NewReaderSize(r, DefaultChunkSize)
+		head, err := br.Peek(magicPeekSize)
+		if IsEncryptedData(head) {
+			return fmt.Errorf("can't encrypt, %w: '%s'", ErrAlreadyEncrypted, path)
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		return fn(w, br)
+	}
+}
@@ -0,0 +1,139 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_IsEncrypted(t *testing.T) {
+	text := []byte("Hello World!")
+	single, _ := EncryptRaw(text, "myKey123")
+	tests := []struct {
+		name    string
+		data    []byte
+		encrypt func(path string) error
+		want    bool
+	}{
+		{"empty", nil, nil, false},
+		{"shorter than the magic", []byte("AG"), nil, false},
+		{"magic without version", []byte("AGCS"), nil, false},
+		{"magic with wrong version", []byte("AGCS\x02"), nil, false},
+		{"plaintext", text, nil, false},
+		{"single ciphertext", single, nil, false},
+		{"EncryptFile", text, func(path string) error { return EncryptFile(path, "myKey123") }, true},
+		{"EncryptFile empty", nil, func(path string) error { return EncryptFile(path, "myKey123") }, true},
+		{"EncryptFile with headers", text, func(path string) error {
+			return EncryptFile(path, "myKey123", WithKeySize(16), WithFingerprint(), WithKeyCheck())
+		}, true},
+		{"EncryptFileCompressed", text, func(path string) error { return EncryptFileCompressed(path, "myKey123") }, true},
+		{"EncryptFileGzip", text, func(path string) error { return EncryptFileGzip(path, "myKey123") }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.bin")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if tt.encrypt != nil {
+				if err := tt.encrypt(path); err != nil {
+					t.Fatalf("could not encrypt file: %s\n", err)
+				}
+			}
+			got, err := IsEncrypted(path)
+			if err != nil || got != tt.want {
+				t.Errorf("IsEncrypted() = %v, %v, want %v\n", got, err, tt.want)
+			}
+			data, _ := os.ReadFile(path)
+			if got := IsEncryptedData(data); got != tt.want {
+				t.Errorf("IsEncryptedData() = %v, want %v\n", got, tt.want)
+			}
+			if tt.want {
+				return
+			}
+			if err := DecryptFile(path, "myKey123"); !errors.Is(err, ErrNotEncrypted) {
+				t.Errorf("DecryptFile() error = %v, want ErrNotEncrypted\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, data) {
+				t.Errorf("file was modified although decryption failed\n")
+			}
+		})
+	}
+
+	if _, err := IsEncrypted(filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Errorf("IsEncrypted() of a missing file expected an error\n")
+	}
+}
+
+func Test_WithForce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	text := []byte("Hello World!")
+	if err := os.WriteFile(path, text, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(path, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	encrypted, _ := os.ReadFile(path)
+
+	refused := []struct {
+		name    string
+		encrypt func() error
+	}{
+		{"EncryptFile", func() error { return EncryptFile(path, "myKey123") }},
+		{"EncryptFileCompressed", func() error { return EncryptFileCompressed(path, "myKey123") }},
+		{"EncryptFileGzip", func() error { return EncryptFileGzip(path, "myKey123") }},
+		{"EncryptFileTo", func() error { return EncryptFileTo(path, filepath.Join(dir, "copy.bin"), "myKey123") }},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.encrypt()
+			if !errors.Is(err, ErrAlreadyEncrypted) || !strings.Contains(err.Error(), path) {
+				t.Errorf("expected ErrAlreadyEncrypted naming the file, got %v\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, encrypted) {
+				t.Errorf("file was modified although encryption was refused\n")
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "copy.bin")); err == nil {
+		t.Errorf("EncryptFileTo() wrote a file although encryption was refused\n")
+	}
+
+	if _, err := newDecryptCipher("myKey123", WithForce()); err == nil {
+		t.Errorf("WithForce() for decryption expected an error\n")
+	}
+	if err := EncryptFile(path, "myKey123", WithForce()); err != nil {
+		t.Fatalf("could not encrypt file twice with WithForce: %s\n", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := DecryptFile(path, "myKey123"); err != nil {
+			t.Fatalf("could not decrypt file: %s\n", err)
+		}
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, text) {
+		t.Errorf("expected %s after decrypting twice, got %s\n", text, d)
+	}
+}
+
+func Test_WithForce_dir(t *testing.T) {
+	root := dirTree(t, map[string]string{
+		"a.txt":     "Hello World!",
+		"sub/b.txt": "Hello Sub!",
+	})
+	c, _ := New("myKey123")
+	if err := c.EncryptFile(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	if s, err := c.EncryptDir(root); err != nil || s != (DirSummary{Processed: 1, Skipped: 1}) {
+		t.Errorf("EncryptDir() = %+v, %v, want 1 processed and 1 skipped\n", s, err)
+	}
+	forced, _ := New("myKey123", WithForce())
+	if s, err := forced.EncryptDir(root); err != nil || s != (DirSummary{Processed: 2}) {
+		t.Errorf("EncryptDir() with WithForce = %+v, %v, want 2 processed\n", s, err)
+	}
+}
@@ -0,0 +1,66 @@
+package aesgcm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// deterministicNonce returns the nonce used by EncryptDeterministic for 'plaintext':
+// the first 'size' bytes of HMAC-SHA256(key, plaintext).
+func deterministicNonce(key string, plaintext []byte, size int) []byte {
+	k := []byte(key)
+	defer clear(k)
+	mac := hmac.New(sha256.New, k)
+	mac.Write(plaintext)
+	return mac.Sum(nil)[:size]
+}
+
+// EncryptDeterministic encrypts the given plaintext using AES-GCM encryption with the provided key and a nonce
+// derived from the plaintext as HMAC-SHA256(key, plaintext)[:12] instead of a random one. It returns the
+// base64-encoded nonce||ciphertext and any error encountered. Unlike Encrypt, it writes no versioned header,
+// whose creation time would make the output differ between calls, but Decrypt accepts the format all the same.
+//
+// The same plaintext and key always produce the same ciphertext, which allows looking up encrypted values
+// in an index or database column by encrypting the search term.
+//
+// Warning: deterministic encryption leaks which ciphertexts hold equal plaintexts, and with it the frequency
+// of values, to anyone who can see the ciphertexts. Only use it for values that have to be searchable,
+// never for general-purpose encryption. Use Encrypt for everything else.
+func EncryptDeterministic(plaintext, key string) (string, error) {
+	c, err := New(key)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	nonce := deterministicNonce(key, pt, c.aead.NonceSize())
+	return c.opts.encoding.EncodeToString(c.aead.Seal(nonce, nonce, pt, nil)), nil
+}
+
+// DecryptDeterministic decrypts a ciphertext produced by EncryptDeterministic using AES-GCM decryption
+// with the provided key and returns the plaintext. Besides authenticating the ciphertext, it checks that
+// the nonce has been derived from the plaintext, so ciphertexts produced by Encrypt are rejected with
+// an error wrapping ErrAuthenticationFailed. Decrypt accepts ciphertexts of EncryptDeterministic as well.
+func DecryptDeterministic(ciphertext, key string) (string, error) {
+	c, err := newDecryptCipher(key)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	data, err := c.decodeCiphertext(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(c.aead, data, nil)
+	if err != nil {
+		return "", err
+	}
+	defer clear(plaintext)
+	nonceSize := c.aead.NonceSize()
+	if !hmac.Equal(data[:nonceSize], deterministicNonce(key, plaintext, nonceSize)) {
+		return "", fmt.Errorf("%w: nonce wasn't derived from the plaintext", ErrAuthenticationFailed)
+	}
+	return string(plaintext), nil
+}
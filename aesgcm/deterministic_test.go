@@ -0,0 +1,49 @@
+package aesgcm
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_deterministic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"deterministic 1", "Hello World!", "myKey123"},
+		{"deterministic 2", "", "12345678"},
+		{"deterministic 3", "alice@example.com", "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e1, err := EncryptDeterministic(tt.text, tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt deterministic: %s\n", err)
+			}
+			e2, _ := EncryptDeterministic(tt.text, tt.key)
+			if e1 != e2 {
+				t.Errorf("expected the same ciphertext for the same plaintext, got %s and %s\n", e1, e2)
+			}
+			if e3, _ := EncryptDeterministic(tt.text+"!", tt.key); e3 == e1 {
+				t.Errorf("expected different ciphertexts for different plaintexts\n")
+			}
+			if e4, _ := EncryptDeterministic(tt.text, tt.key+"!"); e4 == e1 {
+				t.Errorf("expected different ciphertexts for different keys\n")
+			}
+			if d, err := DecryptDeterministic(e1, tt.key); err != nil || d != tt.text {
+				t.Errorf("DecryptDeterministic() = %q, %v, want %q\n", d, err, tt.text)
+			}
+			if d, err := Decrypt(e1, tt.key); err != nil || d != tt.text {
+				t.Errorf("Decrypt() of a deterministic ciphertext = %q, %v, want %q\n", d, err, tt.text)
+			}
+			if _, err := DecryptDeterministic(e1, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed with the wrong key, got %v\n", err)
+			}
+			random, _ := Encrypt(tt.text, tt.key)
+			if _, err := DecryptDeterministic(random, tt.key); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed for a random-nonce ciphertext, got %v\n", err)
+			}
+		})
+	}
+}
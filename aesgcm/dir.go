@@ -0,0 +1,407 @@
+package aesgcm
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// DirSummary reports the outcome of a directory operation.
+type DirSummary struct {
+	Processed int // files that have been encrypted, decrypted or re-encrypted
+	Skipped   int // files that were left alone: symlinks, other non-regular files and already encrypted files
+	Filtered  int // files and directories left out by a filter, the contents of excluded directories are not counted
+	Failed    int // files and directories that could not be processed, see the returned error for details
+	Dangling  int // symlinks whose target doesn't exist or can't be resolved, which are left alone
+}
+
+// errSkipFile is returned by the callback of walkFiles to count a file as skipped.
+var errSkipFile = errors.New("skip file")
+
+// walkJob is an entry found by walkFiles at position 'seq' of the walk.
+type walkJob struct {
+	seq  int
+	path string
+}
+
+// walkFailure is the error of the entry at position 'seq' of the walk.
+type walkFailure struct {
+	seq int
+	err error
+}
+
+// walkFiles calls 'fn' for every regular file below 'root' and, if not nil, 'dir' for every directory.
+// 'dir' may return filepath.SkipDir to leave out a directory. Entries for which 'filter' returns false
+// are left out, excluded directories are not walked. A nil 'filter' selects all entries.
+// Non-regular files and temporary files of interrupted writes are skipped, symlinks are handled according
+// to 'symlinks': with FollowSymlinks, symlinks to regular files are passed to 'fn' unless their target has
+// already been passed to it. Dangling symlinks are only counted.
+//
+// Up to 'workers' files are passed to 'fn' concurrently, 'filter' and 'dir' are called from the walking goroutine
+// and a directory is always passed to 'dir' before its files are passed to 'fn'. Errors are accumulated rather
+// than aborting the walk and returned joined together in the order of the walk, regardless of the number of workers.
+// Once 'ctx' is done, no more files are started and the error wraps ErrCanceled. Files interrupted by 'fn'
+// returning an error wrapping ErrCanceled count neither as processed nor as failed.
+func walkFiles(ctx context.Context, root string, workers int, symlinks SymlinkPolicy, filter func(path string, isDir bool) bool, dir, fn func(path string) error) (DirSummary, error) {
+	var (
+		mu       sync.Mutex
+		s        DirSummary
+		failures []walkFailure
+		canceled bool
+		seen     = map[string]bool{} // resolved paths passed to 'fn' while following symlinks
+	)
+	record := func(job walkJob, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err == errSkipFile:
+			s.Skipped++
+		case errors.Is(err, ErrCanceled):
+			canceled = true
+		case err != nil:
+			s.Failed++
+			failures = append(failures, walkFailure{job.seq, fmt.Errorf("%s: %w", job.path, err)})
+		default:
+			s.Processed++
+		}
+	}
+	count := func(n *int) {
+		mu.Lock()
+		defer mu.Unlock()
+		(*n)++
+	}
+
+	jobs := make(chan walkJob)
+	var wg sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				record(job, fn(job.path))
+			}
+		}()
+	}
+
+	seq := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		seq++
+		job := walkJob{seq: seq, path: path}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		if err != nil {
+			record(job, err)
+			return nil
+		}
+		if filter != nil && !filter(path, d.IsDir()) {
+			count(&s.Filtered)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if dir == nil {
+				return nil
+			}
+			if err := dir(path); err == filepath.SkipDir {
+				return err
+			} else if err != nil {
+				record(job, err)
+			}
+			return nil
+		}
+		if atomicfile.IsTempFile(path) {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			fi, err := os.Stat(path)
+			switch {
+			case err != nil:
+				count(&s.Dangling)
+				return nil
+			case symlinks == ErrorOnSymlinks:
+				record(job, ErrSymlink)
+				return nil
+			case symlinks != FollowSymlinks || !fi.Mode().IsRegular():
+				record(job, errSkipFile)
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			record(job, errSkipFile)
+			return nil
+		}
+		if symlinks == FollowSymlinks {
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				record(job, err)
+				return nil
+			}
+			if seen[target] {
+				record(job, errSkipFile)
+				return nil
+			}
+			seen[target] = true
+		}
+		select {
+		case jobs <- job:
+			return nil
+		case <-ctx.Done():
+			return ctxErr(ctx)
+		}
+	})
+	close(jobs)
+	wg.Wait()
+
+	slices.SortFunc(failures, func(a, b walkFailure) int { return a.seq - b.seq })
+	errs := make([]error, 0, len(failures)+1)
+	for _, f := range failures {
+		errs = append(errs, f.err)
+	}
+	switch {
+	case errors.Is(err, ErrCanceled):
+		canceled = true
+	case err != nil:
+		errs = append(errs, err)
+	}
+	if canceled {
+		errs = append(errs, ctxErr(ctx))
+	}
+	return s, errors.Join(errs...)
+}
+
+// WithConcurrency makes EncryptDir and DecryptDir process up to 'n' files at the same time.
+// The default is runtime.NumCPU(). Every file is still replaced atomically and the returned errors are
+// in the same order as when processing one file at a time. If a source of randomness has been set with
+// WithRand, files are processed one at a time to keep the output reproducible.
+//
+// The callback set with WithProgress is never invoked concurrently, but the reports of files
+// processed at the same time interleave.
+func WithConcurrency(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", n)
+		}
+		o.concurrency = n
+		return nil
+	}
+}
+
+// dirWorkers returns the number of files directory operations process at the same time.
+func (c *Cipher) dirWorkers() int {
+	switch {
+	case c.opts.rand != rand.Reader:
+		return 1
+	case c.opts.concurrency > 0:
+		return c.opts.concurrency
+	}
+	return runtime.NumCPU()
+}
+
+// WithDestDir makes EncryptDir and DecryptDir write the results into a mirror of the source tree below 'dir'
+// instead of processing the files in place. The source tree is left untouched and empty directories are mirrored.
+// Existing files in the mirror are only replaced with WithOverwrite. If 'dir' is inside the source tree,
+// it is not walked itself.
+func WithDestDir(dir string) Option {
+	return func(o *options) error {
+		if dir == "" {
+			return fmt.Errorf("destination directory must not be empty")
+		}
+		o.destDir = dir
+		return nil
+	}
+}
+
+// EncryptDir encrypts every regular file in the directory tree below 'root' using AES-GCM encryption
+// with the provided key, in place or into the mirror set with WithDestDir. Symlinks, unless set otherwise with
+// WithSymlinks, and files that are already encrypted in the chunked format of EncryptFile are skipped,
+// the latter unless WithForce has been passed.
+// Files can be selected with WithInclude and WithExclude. If a file fails to encrypt, the remaining files are still processed and all errors are
+// returned together. See Cipher.EncryptDir for a summary of the results.
+func EncryptDir(root, key string, opts ...Option) error {
+	c, err := New(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.EncryptDir(root)
+	return err
+}
+
+// EncryptDirConcurrent is like EncryptDir but encrypts up to 'workers' files at the same time, see WithConcurrency.
+// If 'workers' is 0 or negative, runtime.NumCPU() workers are used. The errors of all files that fail
+// to encrypt are returned together once every file has been processed.
+func EncryptDirConcurrent(root, key string, workers int) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return EncryptDir(root, key, WithConcurrency(workers))
+}
+
+// EncryptDirFiltered is like EncryptDir but only encrypts files for which 'include' returns true,
+// so callers can skip files by extension or pattern. A nil 'include' selects all files.
+func EncryptDirFiltered(root, key string, include func(path string) bool) error {
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.processDir(context.Background(), root, include, true)
+	return err
+}
+
+// DecryptDir decrypts every regular file in the directory tree below 'root' using AES-GCM decryption
+// with the provided key, in place or into the mirror set with WithDestDir. Symlinks are skipped unless set
+// otherwise with WithSymlinks, files can be selected with WithInclude and WithExclude. If a file fails to decrypt,
+// the remaining files are still processed and all errors are returned together.
+// See Cipher.DecryptDir for a summary of the results.
+func DecryptDir(root, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.DecryptDir(root)
+	return err
+}
+
+// DecryptDirFiltered is like DecryptDir but only decrypts files for which 'include' returns true.
+// A nil 'include' selects all files.
+func DecryptDirFiltered(root, key string, include func(path string) bool) error {
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.processDir(context.Background(), root, include, false)
+	return err
+}
+
+// EncryptDir encrypts every regular file in the directory tree below 'root' like the package-level EncryptDir
+// and returns how many files have been encrypted, skipped and failed along with the joined errors.
+//
+// Files are detected as already encrypted with IsEncrypted, files in the single-shot format
+// of earlier versions are encrypted again. Unreadable files and directories count as failed, and so do files that
+// are modified while being encrypted: they are left as they are and the result is discarded.
+func (c *Cipher) EncryptDir(root string) (DirSummary, error) {
+	if err := c.acquire(); err != nil {
+		return DirSummary{}, err
+	}
+	defer c.release()
+	return c.processDir(context.Background(), root, nil, true)
+}
+
+// DecryptDir decrypts every regular file in the directory tree below 'root' like the package-level DecryptDir
+// and returns how many files have been decrypted, skipped and failed along with the joined errors.
+// Files that aren't encrypted count as failed, as do unreadable files and directories and files that are
+// modified while being decrypted.
+func (c *Cipher) DecryptDir(root string) (DirSummary, error) {
+	if err := c.acquire(); err != nil {
+		return DirSummary{}, err
+	}
+	defer c.release()
+	return c.processDir(context.Background(), root, nil, false)
+}
+
+// processDir encrypts or decrypts the files below 'root' for which 'include' returns true,
+// in place or into the mirror set with WithDestDir, until 'ctx' is done.
+func (c *Cipher) processDir(ctx context.Context, root string, include func(path string) bool, encrypt bool) (DirSummary, error) {
+	// paths are bound relative to the root of the encrypted tree
+	pathRoot := root
+	if encrypt && c.opts.destDir != "" {
+		pathRoot = c.opts.destDir
+	}
+	c, err := c.dirPathCipher(pathRoot)
+	if err != nil {
+		return DirSummary{}, err
+	}
+	workers := c.dirWorkers()
+	if progress := c.opts.progress; progress != nil && workers > 1 {
+		var mu sync.Mutex
+		cc := *c
+		cc.opts.progress = func(bytesProcessed, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress(bytesProcessed, total)
+		}
+		c = &cc
+	}
+	dest := c.opts.destDir
+	target := func(path string) (string, error) {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dest, rel), nil
+	}
+
+	var dir func(path string) error
+	if dest != "" {
+		dir = func(path string) error {
+			if path != root && samePath(path, dest) {
+				return filepath.SkipDir
+			}
+			t, err := target(path)
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(t, 0755)
+		}
+	}
+	process := func(path string) error {
+		switch {
+		case dest == "" && encrypt:
+			return c.encryptFile(ctx, path, ctxWrapper(ctx))
+		case dest == "":
+			return c.decryptFile(ctx, path, ctxWrapper(ctx))
+		}
+		t, err := target(path)
+		if err != nil {
+			return err
+		}
+		if encrypt {
+			return c.encryptFileTo(ctx, path, t+c.opts.extension, nil)
+		}
+		if t, err = c.stripExtension(t); err != nil {
+			return err
+		}
+		cc, err := c.pathCipher(path)
+		if err != nil {
+			return err
+		}
+		return cc.transferFile(ctx, "decrypt", path, t, nil, cc.decryptFileTo)
+	}
+	if c.opts.opaqueNames {
+		var err error
+		if dir, process, err = c.opaqueDir(ctx, root, dest, encrypt); err != nil {
+			return DirSummary{}, err
+		}
+	}
+
+	return walkFiles(ctx, root, workers, c.dirSymlinks(), c.dirFilter(root, include), dir, func(path string) error {
+		if c.opts.backup != "" && isBackup(path, c.opts.backup) {
+			return errSkipFile
+		}
+		if c.opts.extension != "" && c.hasExtension(path) == encrypt {
+			return errSkipFile
+		}
+		if encrypt && !c.opts.force {
+			if done, err := IsEncrypted(path); err != nil {
+				return err
+			} else if done {
+				return errSkipFile
+			}
+		}
+		return process(path)
+	})
+}
@@ -0,0 +1,397 @@
+package aesgcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_dir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":         "Hello World!",
+		"b.yml":         "key: value",
+		"sub/c.txt":     "Hello Sub!",
+		"sub/deep/d.md": "# Title",
+	}
+	for name, text := range files {
+		if err := flo.File(filepath.Join(root, name)).StoreBytes([]byte(text)); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+	}
+	link := filepath.Join(root, "link.txt")
+	if err := os.Symlink(filepath.Join(root, "a.txt"), link); err != nil {
+		t.Fatalf("could not create symlink: %s\n", err)
+	}
+
+	if err := EncryptDir(root, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt dir: %s\n", err)
+	}
+	for name, text := range files {
+		if s := flo.File(filepath.Join(root, name)).AsString(); s == text {
+			t.Errorf("file was not encrypted: %s\n", name)
+		}
+	}
+	if err := DecryptDir(root, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt dir: %s\n", err)
+	}
+	for name, text := range files {
+		if s := flo.File(filepath.Join(root, name)).AsString(); s != text {
+			t.Errorf("encrypt/decrypt dir failed: %s: expected %v, got %v\n", name, text, s)
+		}
+	}
+
+	txtOnly := func(path string) bool { return strings.HasSuffix(path, ".txt") }
+	if err := EncryptDirFiltered(root, "myKey123", txtOnly); err != nil {
+		t.Fatalf("could not encrypt dir filtered: %s\n", err)
+	}
+	if s := flo.File(filepath.Join(root, "b.yml")).AsString(); s != files["b.yml"] {
+		t.Errorf("filtered file was encrypted: b.yml\n")
+	}
+
+	// decrypting everything fails for the files that were skipped, but the others are still processed
+	err := DecryptDir(root, "myKey123")
+	if err == nil || !strings.Contains(err.Error(), "b.yml") || !strings.Contains(err.Error(), "d.md") {
+		t.Errorf("expected errors for unencrypted files, got %v\n", err)
+	}
+	for _, name := range []string{"a.txt", "sub/c.txt"} {
+		if s := flo.File(filepath.Join(root, name)).AsString(); s != files[name] {
+			t.Errorf("decrypt dir did not continue after errors: %s: got %v\n", name, s)
+		}
+	}
+}
+
+// dirTree creates the files 'files' below a new temporary directory and returns its path.
+func dirTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create directory: %s\n", err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+	}
+	return root
+}
+
+func Test_Cipher_EncryptDir(t *testing.T) {
+	files := map[string]string{
+		"a.txt":         "Hello World!",
+		"sub/c.txt":     "Hello Sub!",
+		"sub/deep/d.md": "# Title",
+	}
+	c, _ := New("myKey123")
+
+	t.Run("in place", func(t *testing.T) {
+		root := dirTree(t, files)
+		if err := os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "link.txt")); err != nil {
+			t.Fatalf("could not create symlink: %s\n", err)
+		}
+		if err := c.EncryptFile(filepath.Join(root, "a.txt")); err != nil {
+			t.Fatalf("could not encrypt file: %s\n", err)
+		}
+		s, err := c.EncryptDir(root)
+		if err != nil || s != (DirSummary{Processed: 2, Skipped: 2}) {
+			t.Errorf("EncryptDir() = %+v, %v, want 2 processed and 2 skipped\n", s, err)
+		}
+		s, err = c.DecryptDir(root)
+		if err != nil || s != (DirSummary{Processed: 3, Skipped: 1}) {
+			t.Errorf("DecryptDir() = %+v, %v, want 3 processed and 1 skipped\n", s, err)
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+				t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+			}
+		}
+		s, err = c.DecryptDir(root)
+		if err == nil || s != (DirSummary{Failed: 3, Skipped: 1}) {
+			t.Errorf("DecryptDir() of plaintext files = %+v, %v, want 3 failed and 1 skipped\n", s, err)
+		}
+	})
+
+	t.Run("mirror", func(t *testing.T) {
+		root := dirTree(t, files)
+		if err := os.MkdirAll(filepath.Join(root, "empty"), 0o755); err != nil {
+			t.Fatalf("could not create directory: %s\n", err)
+		}
+		enc := filepath.Join(root, "encrypted") // inside the source tree, must not be walked
+		dec := filepath.Join(t.TempDir(), "decrypted")
+
+		if err := EncryptDir(root, "myKey123", WithDestDir(enc)); err != nil {
+			t.Fatalf("EncryptDir() error = %v\n", err)
+		}
+		if fi, err := os.Stat(filepath.Join(enc, "empty")); err != nil || !fi.IsDir() {
+			t.Errorf("expected empty directories to be mirrored: %v\n", err)
+		}
+		if _, err := os.Stat(filepath.Join(enc, "encrypted")); err == nil {
+			t.Errorf("expected the destination not to be walked\n")
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+				t.Errorf("source file has been modified: %s\n", name)
+			}
+			if d, _ := os.ReadFile(filepath.Join(enc, name)); string(d) == text {
+				t.Errorf("mirrored file was not encrypted: %s\n", name)
+			}
+		}
+
+		err := EncryptDir(root, "myKey123", WithDestDir(enc))
+		if !errors.Is(err, ErrFileExists) {
+			t.Errorf("EncryptDir() into an existing mirror error = %v, want ErrFileExists\n", err)
+		}
+		if err := EncryptDir(root, "myKey123", WithDestDir(enc), WithOverwrite()); err != nil {
+			t.Errorf("EncryptDir() with WithOverwrite error = %v\n", err)
+		}
+
+		s, err := c.DecryptDir(enc)
+		if err != nil || s.Processed != 3 {
+			t.Fatalf("DecryptDir() in place = %+v, %v\n", s, err)
+		}
+		if err := EncryptDir(enc, "myKey123"); err != nil {
+			t.Fatalf("EncryptDir() in place error = %v\n", err)
+		}
+		if err := DecryptDir(enc, "myKey123", WithDestDir(dec)); err != nil {
+			t.Fatalf("DecryptDir() error = %v\n", err)
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(dec, name)); string(d) != text {
+				t.Errorf("encrypt/decrypt dir mirror failed: %s: got %q\n", name, d)
+			}
+		}
+	})
+
+	t.Run("modified during walk", func(t *testing.T) {
+		root := dirTree(t, map[string]string{
+			"small.txt": "Hello World!",
+			"large.bin": strings.Repeat("x", 3*DefaultChunkSize),
+		})
+		large := filepath.Join(root, "large.bin")
+		modified := false
+		c, _ := New("myKey123", WithProgress(func(done, total int64) {
+			if done < total && !modified {
+				modified = true
+				f, _ := os.OpenFile(large, os.O_APPEND|os.O_WRONLY, 0)
+				_, _ = f.WriteString("appended")
+				_ = f.Close()
+			}
+		}))
+		s, err := c.EncryptDir(root)
+		if !errors.Is(err, ErrFileModified) || !strings.Contains(err.Error(), "large.bin") {
+			t.Errorf("EncryptDir() error = %v, want ErrFileModified for large.bin\n", err)
+		}
+		if s != (DirSummary{Processed: 1, Failed: 1}) {
+			t.Errorf("EncryptDir() = %+v, want 1 processed and 1 failed\n", s)
+		}
+		if d, _ := os.ReadFile(large); string(d) != strings.Repeat("x", 3*DefaultChunkSize)+"appended" {
+			t.Errorf("expected the modified file to be left as it is\n")
+		}
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		s, err := c.EncryptDir(filepath.Join(t.TempDir(), "missing"))
+		if err == nil || s.Failed != 1 {
+			t.Errorf("EncryptDir() of a missing root = %+v, %v, want an error\n", s, err)
+		}
+	})
+}
+
+func Test_WithConcurrency(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 100; i++ {
+		files[fmt.Sprintf("dir%d/file%02d.txt", i%7, i)] = fmt.Sprintf("Hello %d!", i)
+	}
+
+	if _, err := New("myKey123", WithConcurrency(0)); err == nil {
+		t.Errorf("WithConcurrency(0) expected an error\n")
+	}
+
+	// decrypting plaintext files fails for every file, the errors must not depend on the number of workers
+	var want string
+	for _, n := range []int{1, 2, 8, 32} {
+		t.Run(fmt.Sprintf("%d workers", n), func(t *testing.T) {
+			root := dirTree(t, files)
+			var running, overlaps atomic.Int32
+			c, _ := New("myKey123", WithConcurrency(n), WithProgress(func(done, total int64) {
+				if running.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				running.Add(-1)
+			}))
+			if s, err := c.EncryptDir(root); err != nil || s != (DirSummary{Processed: len(files)}) {
+				t.Errorf("EncryptDir() = %+v, %v, want %d processed\n", s, err, len(files))
+			}
+			if s, err := c.DecryptDir(root); err != nil || s != (DirSummary{Processed: len(files)}) {
+				t.Errorf("DecryptDir() = %+v, %v, want %d processed\n", s, err, len(files))
+			}
+			for name, text := range files {
+				if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+					t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+				}
+			}
+			if overlaps.Load() > 0 {
+				t.Errorf("progress callback was invoked concurrently\n")
+			}
+
+			s, err := c.DecryptDir(root)
+			if err == nil || s != (DirSummary{Failed: len(files)}) {
+				t.Fatalf("DecryptDir() of plaintext files = %+v, %v, want %d failed\n", s, err, len(files))
+			}
+			got := strings.ReplaceAll(err.Error(), root, "")
+			if want == "" {
+				want = got
+			} else if got != want {
+				t.Errorf("errors differ from a single worker:\n%s\nwant:\n%s\n", got, want)
+			}
+		})
+	}
+}
+
+func Test_EncryptDirConcurrent(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("dir%d/file%02d.txt", i%5, i)] = fmt.Sprintf("Hello %d!", i)
+	}
+	for _, n := range []int{-1, 0, 1, 4} {
+		t.Run(fmt.Sprintf("%d workers", n), func(t *testing.T) {
+			root := dirTree(t, files)
+			if err := EncryptDirConcurrent(root, "myKey123", n); err != nil {
+				t.Fatalf("EncryptDirConcurrent() error = %v\n", err)
+			}
+			for name, text := range files {
+				path := filepath.Join(root, name)
+				if ok, _ := IsEncrypted(path); !ok {
+					t.Errorf("file not encrypted: %s\n", name)
+				}
+				if err := DecryptFile(path, "myKey123"); err != nil {
+					t.Errorf("DecryptFile() error = %v\n", err)
+				}
+				if d, _ := os.ReadFile(path); string(d) != text {
+					t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+				}
+			}
+		})
+	}
+
+	// every failing file is reported
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := dirTree(t, files)
+	for _, name := range []string{"dir0/file00.txt", "dir1/file01.txt", "dir2/file02.txt"} {
+		if err := os.Chmod(filepath.Join(root, name), 0); err != nil {
+			t.Fatalf("could not change permissions: %s\n", err)
+		}
+	}
+	err := EncryptDirConcurrent(root, "myKey123", 4)
+	if err == nil {
+		t.Fatalf("EncryptDirConcurrent() with unreadable files expected an error\n")
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 3 {
+		t.Errorf("expected 3 errors, got %d: %v\n", n, err)
+	}
+}
+
+func Test_DirCtx(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("file%02d.txt", i)] = fmt.Sprintf("Hello %d!", i)
+	}
+
+	t.Run("canceled before", func(t *testing.T) {
+		root := dirTree(t, files)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := EncryptDirCtx(ctx, root, "myKey123"); !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Errorf("EncryptDirCtx() error = %v, want ErrCanceled\n", err)
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+				t.Errorf("file was changed despite the cancellation: %s\n", name)
+			}
+		}
+	})
+
+	t.Run("canceled midway", func(t *testing.T) {
+		root := dirTree(t, files)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var calls atomic.Int32
+		c, _ := New("myKey123", WithConcurrency(4), WithProgress(func(done, total int64) {
+			if calls.Add(1) == 10 {
+				cancel()
+			}
+		}))
+		s, err := c.EncryptDirCtx(ctx, root)
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("EncryptDirCtx() error = %v, want ErrCanceled\n", err)
+		}
+		if s.Processed == 0 || s.Processed >= len(files) || s.Failed != 0 {
+			t.Errorf("EncryptDirCtx() = %+v, want some files processed and none failed\n", s)
+		}
+		// every file is either fully encrypted or untouched
+		if s, err := c.DecryptDir(root); s.Processed+s.Failed != len(files) || s.Failed != len(files)-s.Processed {
+			t.Errorf("DecryptDir() = %+v, %v\n", s, err)
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+				t.Errorf("file was corrupted by the cancellation: %s: got %q\n", name, d)
+			}
+		}
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		root := dirTree(t, files)
+		if err := EncryptDir(root, "myKey123"); err != nil {
+			t.Fatalf("could not encrypt dir: %s\n", err)
+		}
+		if err := DecryptDirCtx(context.Background(), root, "myKey123"); err != nil {
+			t.Errorf("DecryptDirCtx() error = %v\n", err)
+		}
+		for name, text := range files {
+			if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+				t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+			}
+		}
+	})
+}
+
+func Benchmark_EncryptDir(b *testing.B) {
+	root := b.TempDir()
+	for i := 0; i < 10000; i++ {
+		path := filepath.Join(root, fmt.Sprintf("dir%02d", i%100), fmt.Sprintf("file%d.txt", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 1024)), 0o600); err != nil {
+			b.Fatal(err)
+		}
+	}
+	workers := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		workers = append(workers, n)
+	}
+	for _, n := range workers {
+		b.Run(fmt.Sprintf("%d workers", n), func(b *testing.B) {
+			c, _ := New("myKey123", WithConcurrency(n))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.EncryptDir(root); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := c.DecryptDir(root); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package aesgcm
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encoding converts raw ciphertexts to and from their textual representation.
+// The encodings of the standard library, such as base64.StdEncoding, satisfy this interface.
+type Encoding interface {
+	EncodeToString(src []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+var (
+	// StdBase64 is the standard base64 encoding with padding, as used by Encrypt and Decrypt.
+	StdBase64 Encoding = base64.StdEncoding
+
+	// URL is the URL-safe base64 encoding with padding.
+	URL Encoding = base64.URLEncoding
+
+	// RawURL is the unpadded URL-safe base64 encoding, as used by EncryptURL and DecryptURL.
+	// Padded input is accepted when decoding.
+	RawURL Encoding = rawURLEncoding{}
+
+	// Hex is the lowercase hex encoding, as used by EncryptHex and DecryptHex.
+	Hex Encoding = hexEncoding{}
+)
+
+// rawURLEncoding is the unpadded URL-safe base64 encoding which tolerates padded input.
+type rawURLEncoding struct{}
+
+func (rawURLEncoding) EncodeToString(src []byte) string {
+	return base64.RawURLEncoding.EncodeToString(src)
+}
+
+func (rawURLEncoding) DecodeString(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (rawURLEncoding) DecodedLen(n int) int {
+	return base64.RawURLEncoding.DecodedLen(n)
+}
+
+// hexEncoding is the lowercase hex encoding.
+type hexEncoding struct{}
+
+func (hexEncoding) EncodeToString(src []byte) string {
+	return hex.EncodeToString(src)
+}
+
+func (hexEncoding) DecodeString(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex input: %w", err)
+	}
+	return b, nil
+}
+
+func (hexEncoding) DecodedLen(n int) int {
+	return hex.DecodedLen(n)
+}
+
+// decodeCiphertext decodes the given text with 'enc'.
+// It returns a *MalformedCiphertextError holding the offending offset, if known, when decoding fails.
+func decodeCiphertext(text string, enc Encoding) ([]byte, error) {
+	data, err := enc.DecodeString(text)
+	if err == nil {
+		return data, nil
+	}
+	offset := int64(-1)
+	var corrupt base64.CorruptInputError
+	var invalid hex.InvalidByteError
+	switch {
+	case errors.As(err, &corrupt):
+		offset = int64(corrupt)
+	case errors.As(err, &invalid):
+		offset = int64(strings.IndexByte(text, byte(invalid)))
+	case errors.Is(err, hex.ErrLength):
+		offset = int64(len(text))
+	}
+	return nil, &MalformedCiphertextError{Offset: offset, Err: err}
+}
+
+// EncryptWithEncoding encrypts the given plaintext using AES-GCM encryption with the provided key.
+// It returns the encrypted ciphertext encoded with 'enc' and any error encountered.
+func EncryptWithEncoding(plaintext, key string, enc Encoding) (string, error) {
+	return Encrypt(plaintext, key, WithEncoding(enc))
+}
+
+// DecryptWithEncoding decrypts the given encrypted text, encoded with 'enc', using AES-GCM decryption
+// with the provided key. It returns the decrypted plaintext and any error encountered.
+// If the text can't be decoded, a *MalformedCiphertextError is returned.
+func DecryptWithEncoding(text, key string, enc Encoding) (string, error) {
+	return Decrypt(text, key, WithEncoding(enc))
+}
@@ -0,0 +1,49 @@
+package aesgcm
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+)
+
+func Test_encoding(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  Encoding
+		text string
+		key  string
+	}{
+		{"std base64", StdBase64, "Hello World!", "myKey123"},
+		{"url", URL, "Hello World!!", "12345678"},
+		{"raw url", RawURL, "Hello World!", "12345678"},
+		{"hex", Hex, "Hello World!", "1234567890"},
+		{"custom", base32.StdEncoding, "Hello World!", "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptWithEncoding(tt.text, tt.key, tt.enc)
+			if err != nil {
+				t.Fatalf("could not encrypt with encoding: %s\n", err)
+			}
+			d, err := DecryptWithEncoding(e, tt.key, tt.enc)
+			if err != nil {
+				t.Fatalf("could not decrypt with encoding: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt with encoding failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+
+			if _, err := DecryptWithEncoding("!"+e, tt.key, tt.enc); !errors.Is(err, ErrInvalidEncoding) {
+				t.Errorf("expected ErrInvalidEncoding for malformed input: %v: got %v\n", tt.name, err)
+			}
+			if _, err := DecryptWithEncoding(e, tt.key+"x", tt.enc); err == nil || errors.Is(err, ErrInvalidEncoding) {
+				t.Errorf("expected non-encoding error for wrong key: %v: got %v\n", tt.name, err)
+			}
+		})
+	}
+
+	e, _ := Encrypt("Hello World!", "myKey123")
+	if d, err := DecryptWithEncoding(e, "myKey123", StdBase64); err != nil || d != "Hello World!" {
+		t.Errorf("expected Encrypt output to decrypt with StdBase64: got %v (%v)\n", d, err)
+	}
+}
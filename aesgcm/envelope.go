@@ -0,0 +1,55 @@
+package aesgcm
+
+import "fmt"
+
+// envelopeDEKBytes is the length of the random data encryption keys generated by SealEnvelope.
+const envelopeDEKBytes = 32
+
+// SealEnvelope encrypts 'plaintext' using envelope encryption: a random 32-byte data encryption key (DEK)
+// encrypts the plaintext, and the DEK itself is encrypted with 'masterKey'. It returns the encrypted DEK
+// and the ciphertext, both base64-encoded, which must be stored together and passed to OpenEnvelope.
+//
+// The master key never touches the data, so it can be rotated by re-encrypting only the DEK with RewrapEnvelope,
+// and it can be held by a key management service that only ever sees DEKs.
+func SealEnvelope(plaintext, masterKey string) (encryptedDEK, ciphertext string, err error) {
+	master, err := New(masterKey)
+	if err != nil {
+		return "", "", err
+	}
+	defer master.Close()
+	dek, err := GenerateKey(envelopeDEKBytes)
+	if err != nil {
+		return "", "", err
+	}
+	ciphertext, err = Encrypt(plaintext, dek)
+	if err != nil {
+		return "", "", err
+	}
+	encryptedDEK, err = master.Encrypt(dek)
+	if err != nil {
+		return "", "", err
+	}
+	return encryptedDEK, ciphertext, nil
+}
+
+// OpenEnvelope decrypts a ciphertext sealed by SealEnvelope: 'encryptedDEK' is decrypted with 'masterKey'
+// and the resulting data encryption key decrypts 'ciphertext'. It returns the plaintext or an error
+// if either step fails, for example because of a wrong master key or a DEK from another envelope.
+func OpenEnvelope(encryptedDEK, ciphertext, masterKey string) (string, error) {
+	dek, err := Decrypt(encryptedDEK, masterKey)
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt data encryption key: %w", err)
+	}
+	return Decrypt(ciphertext, dek)
+}
+
+// RewrapEnvelope re-encrypts the data encryption key 'encryptedDEK' of an envelope from 'oldMasterKey'
+// to 'newMasterKey' and returns the new encrypted DEK. The ciphertext of the envelope stays valid
+// and doesn't need to be touched, which makes rotating the master key cheap.
+func RewrapEnvelope(encryptedDEK, oldMasterKey, newMasterKey string) (string, error) {
+	dek, err := Decrypt(encryptedDEK, oldMasterKey)
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt data encryption key: %w", err)
+	}
+	return Encrypt(dek, newMasterKey)
+}
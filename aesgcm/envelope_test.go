@@ -0,0 +1,57 @@
+package aesgcm
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_envelope(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		masterKey string
+	}{
+		{"envelope 1", "Hello World!", "myKey123"},
+		{"envelope 2", "", "12345678"},
+		{"envelope 3", strings.Repeat("secret ", 1000), MustGenerateKey(32)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dek, e, err := SealEnvelope(tt.plaintext, tt.masterKey)
+			if err != nil {
+				t.Fatalf("SealEnvelope() error = %v\n", err)
+			}
+			if d, err := OpenEnvelope(dek, e, tt.masterKey); err != nil || d != tt.plaintext {
+				t.Errorf("OpenEnvelope() = %q, %v, want %q\n", d, err, tt.plaintext)
+			}
+			if _, err := Decrypt(e, tt.masterKey); err == nil {
+				t.Errorf("expected the data not to be encrypted with the master key\n")
+			}
+			if _, err := OpenEnvelope(dek, e, "wrongKey"); err == nil {
+				t.Errorf("OpenEnvelope() with the wrong master key expected an error\n")
+			}
+
+			otherDEK, otherE, _ := SealEnvelope(tt.plaintext, tt.masterKey)
+			if otherDEK == dek || otherE == e {
+				t.Errorf("expected every envelope to use a new data encryption key\n")
+			}
+			if _, err := OpenEnvelope(otherDEK, e, tt.masterKey); err == nil {
+				t.Errorf("OpenEnvelope() with the DEK of another envelope expected an error\n")
+			}
+
+			rewrapped, err := RewrapEnvelope(dek, tt.masterKey, "newMaster456")
+			if err != nil {
+				t.Fatalf("RewrapEnvelope() error = %v\n", err)
+			}
+			if d, err := OpenEnvelope(rewrapped, e, "newMaster456"); err != nil || d != tt.plaintext {
+				t.Errorf("OpenEnvelope() after rewrapping = %q, %v, want %q\n", d, err, tt.plaintext)
+			}
+			if _, err := OpenEnvelope(rewrapped, e, tt.masterKey); err == nil {
+				t.Errorf("OpenEnvelope() with the old master key expected an error\n")
+			}
+			if _, err := RewrapEnvelope(dek, "wrongKey", "newMaster456"); err == nil {
+				t.Errorf("RewrapEnvelope() with the wrong master key expected an error\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package aesgcm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+var (
+	// ErrKeyDerivation is returned when the key can't be derived from the provided key or password.
+	ErrKeyDerivation = errors.New("key derivation failed")
+
+	// ErrWeakKey is returned when a key or password is empty, consists only of whitespace or
+	// control characters, or is shorter than MinKeyLength.
+	ErrWeakKey = errors.New("weak key")
+
+	// ErrKeyTooShort is returned when a key or password has fewer than MinKeyLength characters, including empty keys.
+	// It wraps ErrWeakKey.
+	ErrKeyTooShort = fmt.Errorf("%w: key too short", ErrWeakKey)
+
+	// ErrWeakKDFParams is returned when the parameters of a password-based key derivation function are too weak.
+	ErrWeakKDFParams = errors.New("weak key derivation parameters")
+
+	// ErrWeakScryptParams is returned when the scrypt cost parameter N is below 16384.
+	// It wraps ErrWeakKDFParams.
+	ErrWeakScryptParams = fmt.Errorf("%w: scrypt", ErrWeakKDFParams)
+
+	// ErrCiphertextTooShort is returned when a ciphertext is too short to hold the nonce or salt.
+	ErrCiphertextTooShort = errors.New("data too short")
+
+	// ErrAuthenticationFailed is returned when a ciphertext can't be authenticated,
+	// which means the key or additional data is wrong or the ciphertext has been tampered with.
+	ErrAuthenticationFailed = errors.New("authentication failed")
+
+	// ErrDecryptionFailed is an alias of ErrAuthenticationFailed, for callers telling a wrong key
+	// apart from I/O errors with errors.Is(err, ErrDecryptionFailed).
+	ErrDecryptionFailed = ErrAuthenticationFailed
+
+	// ErrInvalidPadding is returned when the padding of a plaintext decrypted with WithPadding is malformed.
+	// It wraps ErrAuthenticationFailed, as it can only be caused by a forged or otherwise corrupt ciphertext.
+	ErrInvalidPadding = fmt.Errorf("%w: invalid padding", ErrAuthenticationFailed)
+
+	// ErrKeySizeMismatch is returned when a ciphertext was encrypted with a different key size
+	// than the one the Cipher has been configured with.
+	ErrKeySizeMismatch = errors.New("key size mismatch")
+
+	// ErrCorruptHeader is returned when a ciphertext header is truncated or damaged.
+	ErrCorruptHeader = errors.New("corrupt header")
+
+	// ErrNoKeyCheck is returned by VerifyKey and VerifyKeyCiphertext for ciphertexts without a key check value,
+	// for which it is unknown whether the key is correct.
+	ErrNoKeyCheck = errors.New("ciphertext has no key check value")
+
+	// ErrStreamTruncated is returned when a stream ends before its final chunk.
+	ErrStreamTruncated = stream.ErrTruncated
+
+	// ErrClosed is returned when using a Cipher after Close, and when writing to or closing a stream writer
+	// that has already been closed.
+	ErrClosed = errors.New("already closed")
+
+	// ErrCanceled is returned when an operation is aborted because its context is done.
+	// The error returned by the context is wrapped as well, so context.Canceled and
+	// context.DeadlineExceeded can still be told apart.
+	ErrCanceled = errors.New("operation canceled")
+
+	// ErrFileNotFound is returned when the file to encrypt or decrypt does not exist.
+	ErrFileNotFound = errors.New("file does not exist")
+
+	// ErrFileModified is returned when a file is written to by someone else while it is being encrypted
+	// or decrypted. The file is left as it is and the result of the operation is discarded.
+	ErrFileModified = errors.New("file modified during operation")
+
+	// ErrUnsupportedVersion is returned when a ciphertext has been written by a newer version of the package,
+	// in a format version, algorithm or key derivation this version doesn't know.
+	ErrUnsupportedVersion = errors.New("unsupported ciphertext version")
+
+	// ErrNotEncrypted is returned when a file to decrypt doesn't start with the magic of the format written
+	// by EncryptFile, which usually means it holds plaintext. See WithLegacyFormat for files of earlier versions.
+	ErrNotEncrypted = errors.New("file is not encrypted")
+
+	// ErrAlreadyEncrypted is returned when a file to encrypt already starts with the magic of the format written
+	// by EncryptFile. Encrypting it again would make a successful decryption yield ciphertext, see WithForce.
+	ErrAlreadyEncrypted = errors.New("file is already encrypted")
+
+	// ErrFileExists is returned when the destination file of an operation exists and overwriting is not allowed.
+	// It wraps fs.ErrExist.
+	ErrFileExists = fmt.Errorf("destination %w", fs.ErrExist)
+
+	// ErrInvalidEncoding is returned when a ciphertext can't be decoded with the expected Encoding.
+	// It allows callers to tell malformed input apart from a wrong key or tampered data.
+	ErrInvalidEncoding = errors.New("invalid ciphertext encoding")
+
+	// ErrNonceReused is returned by a NonceRegistry, and by encryption while nonce tracking is enabled,
+	// when a nonce is about to be used a second time with the same key.
+	ErrNonceReused = errors.New("nonce reused")
+
+	// ErrTooLarge is returned when decryption would produce more plaintext than allowed by WithMaxSize or DefaultMaxSize.
+	ErrTooLarge = errors.New("data exceeds the size limit")
+
+	// ErrSymlink is returned for symlinks when ErrorOnSymlinks has been set with WithSymlinks.
+	ErrSymlink = errors.New("file is a symlink")
+
+	// ErrExpired is returned when a ciphertext encrypted with EncryptWithTTL is decrypted after its expiry.
+	ErrExpired = errors.New("ciphertext expired")
+
+	// ErrTooOld is returned by DecryptWithMaxAge for ciphertexts created longer ago than the maximum age,
+	// or without creation time.
+	ErrTooOld = errors.New("ciphertext too old")
+
+	// ErrColumnOutOfRange is returned by EncryptCSV and DecryptCSV for rows lacking one of the selected columns.
+	ErrColumnOutOfRange = errors.New("column index out of range")
+
+	// ErrClockSkew is returned by DecryptWithMaxAge for ciphertexts created further in the future than
+	// the clock skew tolerance set with WithClockSkew.
+	ErrClockSkew = errors.New("ciphertext created in the future")
+)
+
+// ExpiredError is returned when a ciphertext encrypted with EncryptWithTTL is decrypted after its expiry.
+// It wraps ErrExpired. The ciphertext has been authenticated, so the expiry is the one set on encryption.
+type ExpiredError struct {
+	Expiry time.Time // time at which the ciphertext expired
+}
+
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("ciphertext expired at %s", e.Expiry.Format(time.RFC3339))
+}
+
+func (e *ExpiredError) Unwrap() error {
+	return ErrExpired
+}
+
+// MalformedCiphertextError is returned when a ciphertext can't be decoded, for example because it has been truncated
+// or contains characters that don't belong to the encoding. It wraps ErrInvalidEncoding and the decoder's error,
+// which allows callers to tell garbled input apart from a wrong key, reported as ErrAuthenticationFailed.
+type MalformedCiphertextError struct {
+	Offset int64 // offset of the offending input byte, or -1 if unknown
+	Err    error // error returned by the decoder
+}
+
+func (e *MalformedCiphertextError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("malformed ciphertext: %v", e.Err)
+	}
+	return fmt.Sprintf("malformed ciphertext at input byte %d: %v", e.Offset, e.Err)
+}
+
+func (e *MalformedCiphertextError) Unwrap() []error {
+	return []error{ErrInvalidEncoding, e.Err}
+}
+
+// KeyMismatchError is returned when a ciphertext that records the fingerprint of its key, see WithFingerprint,
+// can't be decrypted and the fingerprint of the supplied key differs. It wraps the error of the failed
+// decryption, which wraps ErrAuthenticationFailed.
+type KeyMismatchError struct {
+	Expected string // fingerprint recorded in the ciphertext
+	Actual   string // fingerprint of the supplied key
+	Err      error  // error returned by the decryption
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("wrong key, ciphertext was encrypted with key %s, you supplied %s: %v", e.Expected, e.Actual, e.Err)
+}
+
+func (e *KeyMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// CorruptChunkError is returned by VerifyFile when a chunk of an encrypted file is damaged: it fails authentication
+// although the key has been confirmed, or the file ends before its final chunk. It wraps the error of the chunk,
+// which wraps ErrAuthenticationFailed or ErrStreamTruncated.
+type CorruptChunkError struct {
+	Chunk  uint64 // index of the chunk
+	Offset int64  // offset of the chunk in the file
+	Err    error  // error returned for the chunk
+}
+
+func (e *CorruptChunkError) Error() string {
+	return fmt.Sprintf("corrupted chunk %d at offset %d: %v", e.Chunk, e.Offset, e.Err)
+}
+
+func (e *CorruptChunkError) Unwrap() error {
+	return e.Err
+}
+
+// ItemError is returned by batch functions such as EncryptStrings when an item fails.
+// It wraps the error of the failed item.
+type ItemError struct {
+	Index int   // index of the failed item
+	Err   error // error returned for the item
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// MapItemError is returned by EncryptMap and DecryptMap when a value fails.
+// It wraps the error of the failed value.
+type MapItemError struct {
+	Key string // map key of the failed value
+	Err error  // error returned for the value
+}
+
+func (e *MapItemError) Error() string {
+	return fmt.Sprintf("map key '%s': %v", e.Key, e.Err)
+}
+
+func (e *MapItemError) Unwrap() error {
+	return e.Err
+}
+
+// CSVCellError is returned by EncryptCSV and DecryptCSV when a cell fails or a row lacks a selected column.
+// It wraps the error of the cell.
+type CSVCellError struct {
+	Row    int   // index of the row, starting at 0
+	Column int   // index of the column
+	Err    error // error returned for the cell
+}
+
+func (e *CSVCellError) Error() string {
+	return fmt.Sprintf("row %d, column %d: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *CSVCellError) Unwrap() error {
+	return e.Err
+}
+
+// JSONFieldError is returned by EncryptJSONFields and DecryptJSONFields when the value of a selected field fails.
+// It wraps the error of the value.
+type JSONFieldError struct {
+	Path string // dot-separated path of the value, with array indices
+	Err  error  // error returned for the value
+}
+
+func (e *JSONFieldError) Error() string {
+	return fmt.Sprintf("field '%s': %v", e.Path, e.Err)
+}
+
+func (e *JSONFieldError) Unwrap() error {
+	return e.Err
+}
+
+// errFileExists returns an error wrapping ErrFileExists for the operation 'op' on 'path'.
+func errFileExists(op, path string) error {
+	return fmt.Errorf("can't %s, %w: '%s'", op, ErrFileExists, path)
+}
+
+// errFileNotFound returns an error wrapping ErrFileNotFound for the operation 'op' on 'path'.
+func errFileNotFound(op, path string) error {
+	return fmt.Errorf("can't %s, %w: '%s'", op, ErrFileNotFound, path)
+}
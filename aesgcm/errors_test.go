@@ -0,0 +1,80 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func Test_errors(t *testing.T) {
+	e, _ := Encrypt("Hello World!", "myKey123")
+	raw, _ := EncryptRaw([]byte("Hello World!"), "myKey123")
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"too short", func() error { _, err := DecryptRaw([]byte{1, 2, 3}, "myKey123"); return err }, ErrCiphertextTooShort},
+		{"too short password", func() error { _, err := DecryptWithPassword("AAAA", "myKey123"); return err }, ErrCiphertextTooShort},
+		{"wrong key", func() error { _, err := Decrypt(e, "wrongKey"); return err }, ErrAuthenticationFailed},
+		{"tampered", func() error { _, err := DecryptRaw(tampered, "myKey123"); return err }, ErrAuthenticationFailed},
+		{"decryption failed", func() error { _, err := Decrypt(e, "wrongKey"); return err }, ErrDecryptionFailed},
+		{"decryption failed bytes", func() error { _, err := DecryptBytes(tampered, "myKey123"); return err }, ErrDecryptionFailed},
+		{"encrypt missing file", func() error { return EncryptFile("../test_data/does-not-exist", "myKey123") }, ErrFileNotFound},
+		{"decrypt missing file", func() error { return DecryptFile("../test_data/does-not-exist", "myKey123") }, ErrFileNotFound},
+		{"decrypt from missing file", func() error { _, err := DecryptFromFile("../test_data/does-not-exist", "myKey123"); return err }, ErrFileNotFound},
+		{"invalid encoding", func() error { _, err := Decrypt("not base64!", "myKey123"); return err }, ErrInvalidEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected error wrapping %v, got %v\n", tt.want, err)
+			}
+		})
+	}
+}
+
+func Test_malformedCiphertext(t *testing.T) {
+	e, _ := Encrypt("Hello World!", "myKey123")
+	random := make([]byte, 40)
+	_, _ = rand.Read(random)
+
+	tests := []struct {
+		name      string
+		text      string
+		key       string
+		malformed bool
+		offset    int64
+	}{
+		{"truncated base64", e[:len(e)-3], "myKey123", true, int64(len(e) - 4)},
+		{"invalid character", e[:5] + "*" + e[6:], "myKey123", true, 5},
+		{"random bytes", base64.StdEncoding.EncodeToString(random), "myKey123", false, 0},
+		{"wrong key", e, "wrongKey", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(tt.text, tt.key)
+			var malformed *MalformedCiphertextError
+			if tt.malformed {
+				if !errors.As(err, &malformed) || !errors.Is(err, ErrInvalidEncoding) {
+					t.Fatalf("expected MalformedCiphertextError, got %v\n", err)
+				}
+				if malformed.Offset != tt.offset {
+					t.Errorf("expected offset %d, got %d\n", tt.offset, malformed.Offset)
+				}
+				if errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("malformed ciphertext reported as authentication failure: %v\n", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrAuthenticationFailed) || errors.As(err, &malformed) {
+				t.Errorf("expected ErrAuthenticationFailed, got %v\n", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// expiryMagic flags ciphertexts written by EncryptWithTTL. It is followed by the expiry in milliseconds since
+// the Unix epoch (int64, big-endian), and both are authenticated as additional data.
+var expiryMagic = []byte("AGT\x01")
+
+// expiryHeaderSize is the length of the expiry flag and the expiry.
+const expiryHeaderSize = 4 + 8
+
+// Clock provides the current time, see WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// systemClock is the default Clock, which returns time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock used to compute the expiry of ciphertexts encrypted with EncryptWithTTL
+// and to check it on decryption. It defaults to the system clock and is meant for tests.
+func WithClock(clock Clock) Option {
+	return func(o *options) error {
+		if clock == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		o.clock = clock
+		return nil
+	}
+}
+
+// EncryptWithTTL encrypts the given plaintext using AES-GCM encryption with the provided key, like Encrypt, and
+// embeds an expiry 'ttl' from now. Decrypt and the other decryption functions authenticate the expiry along with
+// the ciphertext and return an *ExpiredError, which wraps ErrExpired, once it has passed. The expiry is stored
+// with millisecond precision and is visible without the key. It returns the encoded ciphertext and any error encountered.
+func EncryptWithTTL(plaintext, key string, ttl time.Duration, opts ...Option) (string, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.EncryptWithTTL(plaintext, ttl)
+}
+
+// EncryptWithTTL encrypts the given plaintext with an expiry 'ttl' from now and returns the encoded ciphertext.
+// See the package-level EncryptWithTTL for details.
+func (c *Cipher) EncryptWithTTL(plaintext string, ttl time.Duration) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	encrypted, err := c.EncryptBytesWithTTL(pt, ttl)
+	if err != nil {
+		return "", err
+	}
+	return c.opts.encoding.EncodeToString(encrypted), nil
+}
+
+// EncryptBytesWithTTL encrypts the given bytes with an expiry 'ttl' from now and returns the raw ciphertext bytes,
+// which are decrypted with DecryptBytes. See the package-level EncryptWithTTL for details.
+func (c *Cipher) EncryptBytesWithTTL(bytes []byte, ttl time.Duration) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid TTL %s, must be positive", ttl)
+	}
+	header := expiryHeader(c.opts.clock.Now().Add(ttl))
+	encrypted, err := c.expiringCipher(header).EncryptBytes(bytes)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted...), nil
+}
+
+// expiryHeader returns the expiry flag followed by 'expiry'.
+func expiryHeader(expiry time.Time) []byte {
+	return binary.BigEndian.AppendUint64(bytes.Clone(expiryMagic), uint64(expiry.UnixMilli()))
+}
+
+// expiringCipher returns a copy of the Cipher that authenticates the expiry header before the AAD set with WithAAD.
+func (c *Cipher) expiringCipher(header []byte) *Cipher {
+	return c.withAAD(append(bytes.Clone(header), c.opts.aad...))
+}
+
+// decryptExpiringBytes decrypts 'data', which starts with the expiry flag, and checks the expiry once it has been authenticated.
+func (c *Cipher) decryptExpiringBytes(data []byte) ([]byte, error) {
+	if len(data) < expiryHeaderSize {
+		return c.decryptUnexpiringBytes(data)
+	}
+	header := data[:expiryHeaderSize]
+	decrypted, err := c.expiringCipher(header).decryptUnexpiringBytes(data[expiryHeaderSize:])
+	if err != nil {
+		// a ciphertext without header whose nonce happens to start with the flag
+		if decrypted, legacyErr := c.decryptUnexpiringBytes(data); legacyErr == nil {
+			return decrypted, nil
+		}
+		return nil, err
+	}
+	expiry := time.UnixMilli(int64(binary.BigEndian.Uint64(header[len(expiryMagic):])))
+	if !c.opts.clock.Now().Before(expiry) {
+		return nil, &ExpiredError{Expiry: expiry}
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,102 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_EncryptWithTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+
+	e, err := EncryptWithTTL("reset-token", "myKey123", time.Hour, clock)
+	if err != nil {
+		t.Fatalf("EncryptWithTTL() error = %v\n", err)
+	}
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		expired bool
+	}{
+		{"fresh", 0, false},
+		{"almost expired", time.Hour - time.Millisecond, false},
+		{"expired", time.Hour, true},
+		{"long expired", 365 * 24 * time.Hour, true},
+		{"clock set back", -time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := WithClock(ClockFunc(func() time.Time { return now.Add(tt.elapsed) }))
+			d, err := Decrypt(e, "myKey123", at)
+			if !tt.expired {
+				if err != nil || d != "reset-token" {
+					t.Errorf("Decrypt() = %q, %v, want reset-token\n", d, err)
+				}
+				return
+			}
+			var expired *ExpiredError
+			if !errors.Is(err, ErrExpired) || !errors.As(err, &expired) || d != "" {
+				t.Fatalf("Decrypt() = %q, %v, want ErrExpired\n", d, err)
+			}
+			if !expired.Expiry.Equal(now.Add(time.Hour)) {
+				t.Errorf("ExpiredError.Expiry = %s, want %s\n", expired.Expiry, now.Add(time.Hour))
+			}
+		})
+	}
+
+	if _, err := Decrypt(e, "wrongKey", clock); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		if _, err := EncryptWithTTL("reset-token", "myKey123", ttl); err == nil {
+			t.Errorf("EncryptWithTTL() with TTL %s expected an error\n", ttl)
+		}
+	}
+	if _, err := New("myKey123", WithClock(nil)); err == nil {
+		t.Errorf("WithClock(nil) expected an error\n")
+	}
+}
+
+func Test_EncryptWithTTL_tampered(t *testing.T) {
+	now := time.Now()
+	c, _ := New("myKey123", WithClock(ClockFunc(func() time.Time { return now })), WithAAD([]byte("user-42")))
+	e, err := c.EncryptBytesWithTTL([]byte("reset-token"), time.Minute)
+	if err != nil {
+		t.Fatalf("EncryptBytesWithTTL() error = %v\n", err)
+	}
+	if !bytes.HasPrefix(e, expiryMagic) {
+		t.Errorf("expected the expiry flag\n")
+	}
+
+	// an extended expiry fails to authenticate
+	extended := append(expiryHeader(now.Add(24*time.Hour)), e[expiryHeaderSize:]...)
+	if _, err := c.DecryptBytes(extended); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptBytes() with an extended expiry error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	// as does a stripped one
+	if _, err := c.DecryptBytes(e[expiryHeaderSize:]); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptBytes() without the expiry error = %v, want ErrAuthenticationFailed\n", err)
+	}
+
+	// ciphertexts without expiry keep decrypting, whatever the time
+	plain, _ := c.EncryptBytes([]byte("forever"))
+	late, _ := New("myKey123", WithClock(ClockFunc(func() time.Time { return now.AddDate(100, 0, 0) })), WithAAD([]byte("user-42")))
+	if d, err := late.DecryptBytes(plain); err != nil || string(d) != "forever" {
+		t.Errorf("DecryptBytes() of a ciphertext without expiry = %q, %v\n", d, err)
+	}
+	if _, err := late.DecryptBytes(e); !errors.Is(err, ErrExpired) {
+		t.Errorf("DecryptBytes() error = %v, want ErrExpired\n", err)
+	}
+
+	// options that change the format apply to the ciphertext after the expiry
+	cc, _ := New("myKey123", WithCompression(Zstd), WithFingerprint(), WithKeySize(16))
+	ce, err := cc.EncryptWithTTL("reset-token", time.Minute)
+	if err != nil {
+		t.Fatalf("EncryptWithTTL() error = %v\n", err)
+	}
+	if d, err := Decrypt(ce, "myKey123", WithKeySize(16)); err != nil || d != "reset-token" {
+		t.Errorf("Decrypt() = %q, %v, want reset-token\n", d, err)
+	}
+}
@@ -0,0 +1,107 @@
+package aesgcm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithExtension makes EncryptFile and the other functions encrypting files in place write the result to the path
+// of the file followed by 'ext', such as ".enc", and remove the original, see WithKeepOriginal. DecryptFile and
+// the other functions decrypting files in place expect the suffix and write the result to the path without it.
+// Both fail with an error wrapping ErrFileExists if the result would replace an existing file, unless WithOverwrite
+// has been passed. Files are written atomically like in place and backups made with WithBackup are not made.
+//
+// Files whose name already ends with 'ext' are refused by the encrypting functions with an error wrapping
+// ErrAlreadyEncrypted unless WithForce has been passed, files without it, or whose name consists of nothing
+// but 'ext', are refused by the decrypting functions with an error wrapping ErrNotEncrypted.
+// EncryptDir and DecryptDir skip such files and apply the same rule to the files in the mirror set with WithDestDir.
+// EncryptFileTo and DecryptFileTo are not affected.
+func WithExtension(ext string) Option {
+	return func(o *options) error {
+		if strings.Trim(ext, ".") == "" {
+			return fmt.Errorf("extension must not be empty or consist of dots only, got '%s'", ext)
+		}
+		if strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("extension must not contain a path separator: '%s'", ext)
+		}
+		o.extension = ext
+		return nil
+	}
+}
+
+// WithKeepOriginal makes the functions writing to a new file because of WithExtension keep the original file.
+func WithKeepOriginal() Option {
+	return func(o *options) error {
+		o.keepOriginal = true
+		return nil
+	}
+}
+
+// hasExtension reports whether the name of the file located at 'path' ends with the extension set with WithExtension.
+func (c *Cipher) hasExtension(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), c.opts.extension)
+}
+
+// stripExtension returns 'path' without the extension set with WithExtension. It returns an error wrapping
+// ErrNotEncrypted if the name of the file doesn't end with the extension or consists of nothing else.
+func (c *Cipher) stripExtension(path string) (string, error) {
+	if !c.hasExtension(path) {
+		return "", fmt.Errorf("can't decrypt, %w, the name doesn't end with '%s': '%s'", ErrNotEncrypted, c.opts.extension, path)
+	}
+	if filepath.Base(path) == c.opts.extension {
+		return "", fmt.Errorf("can't decrypt, %w, the name would be empty without '%s': '%s'", ErrNotEncrypted, c.opts.extension, path)
+	}
+	return strings.TrimSuffix(path, c.opts.extension), nil
+}
+
+// encryptFileExt encrypts the file located at 'path', read through 'wrap' unless it is nil, to the path
+// followed by the extension set with WithExtension and removes it unless WithKeepOriginal is set.
+func (c *Cipher) encryptFileExt(ctx context.Context, path string, wrap readerWrapper) error {
+	path, err := c.symlinkTarget("encrypt", path)
+	if err != nil || path == "" {
+		return err
+	}
+	if c.hasExtension(path) && !c.opts.force {
+		return fmt.Errorf("can't encrypt, %w, the name ends with '%s': '%s'", ErrAlreadyEncrypted, c.opts.extension, path)
+	}
+	if err := c.encryptFileTo(ctx, path, path+c.opts.extension, wrap); err != nil {
+		return err
+	}
+	return c.removeOriginal(path, c.opts.shredSource)
+}
+
+// decryptFileExt decrypts the file located at 'path', read through 'wrap' unless it is nil, to the path
+// without the extension set with WithExtension and removes it unless WithKeepOriginal is set.
+func (c *Cipher) decryptFileExt(ctx context.Context, path string, wrap readerWrapper) error {
+	path, err := c.symlinkTarget("decrypt", path)
+	if err != nil || path == "" {
+		return err
+	}
+	dst, err := c.stripExtension(path)
+	if err != nil {
+		return err
+	}
+	cc, err := c.pathCipher(path)
+	if err != nil {
+		return err
+	}
+	if err := cc.transferFile(ctx, "decrypt", path, dst, wrap, cc.decryptFileTo); err != nil {
+		return err
+	}
+	return c.removeOriginal(path, false)
+}
+
+// removeOriginal removes the file located at 'path' once it has been processed into another file,
+// unless WithKeepOriginal is set or it has been 'shredded' already.
+func (c *Cipher) removeOriginal(path string, shredded bool) error {
+	if c.opts.keepOriginal || shredded {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("processed '%s', but can't remove it: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func Test_WithExtension(t *testing.T) {
+	for _, ext := range []string{"", ".", "..", "/enc", `.e\nc`} {
+		if _, err := New("myKey123", WithExtension(ext)); err == nil {
+			t.Errorf("WithExtension(%q) expected an error\n", ext)
+		}
+	}
+
+	root := dirTree(t, map[string]string{"a.txt": "Hello A!", "b.enc": "Hello B!", ".enc": "Hello Empty!"})
+	path := filepath.Join(root, "a.txt")
+	ext := WithExtension(".enc")
+
+	if err := EncryptFile(path, "myKey123", ext); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	if exists(path) {
+		t.Errorf("EncryptFile() kept the original\n")
+	}
+	if ok, _ := IsEncrypted(path + ".enc"); !ok {
+		t.Errorf("EncryptFile() didn't write %s.enc\n", path)
+	}
+	if err := DecryptFile(path+".enc", "myKey123", ext, WithKeepOriginal()); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != "Hello A!" || !exists(path+".enc") {
+		t.Errorf("DecryptFile() with WithKeepOriginal = %q, original kept %v\n", d, exists(path+".enc"))
+	}
+
+	// the target exists
+	if err := DecryptFile(path+".enc", "myKey123", ext); !errors.Is(err, ErrFileExists) {
+		t.Errorf("DecryptFile() onto an existing file error = %v, want ErrFileExists\n", err)
+	}
+	if err := DecryptFile(path+".enc", "myKey123", ext, WithOverwrite()); err != nil || exists(path+".enc") {
+		t.Errorf("DecryptFile() with WithOverwrite error = %v, original kept %v\n", err, exists(path+".enc"))
+	}
+
+	// names that already carry or lack the extension
+	if err := EncryptFile(filepath.Join(root, "b.enc"), "myKey123", ext); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Errorf("EncryptFile() of b.enc error = %v, want ErrAlreadyEncrypted\n", err)
+	}
+	if err := EncryptFile(filepath.Join(root, "b.enc"), "myKey123", ext, WithForce(), WithKeepOriginal()); err != nil || !exists(filepath.Join(root, "b.enc.enc")) {
+		t.Errorf("EncryptFile() of b.enc with WithForce error = %v\n", err)
+	}
+	if err := DecryptFile(path, "myKey123", ext); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("DecryptFile() without the extension error = %v, want ErrNotEncrypted\n", err)
+	}
+	if err := DecryptFile(filepath.Join(root, ".enc"), "myKey123", ext); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("DecryptFile() of .enc error = %v, want ErrNotEncrypted\n", err)
+	}
+}
+
+func Test_WithExtension_dir(t *testing.T) {
+	files := map[string]string{"a.txt": "Hello A!", "sub/b.txt": "Hello B!", "sub/c.enc": "Hello C!"}
+	root := dirTree(t, files)
+	c, _ := New("myKey123", WithExtension(".enc"))
+
+	if s, err := c.EncryptDir(root); err != nil || s != (DirSummary{Processed: 2, Skipped: 1}) {
+		t.Errorf("EncryptDir() = %+v, %v, want 2 processed and 1 skipped\n", s, err)
+	}
+	for _, name := range []string{"a.txt.enc", "sub/b.txt.enc", "sub/c.enc"} {
+		if !exists(filepath.Join(root, name)) {
+			t.Errorf("expected %s after EncryptDir()\n", name)
+		}
+	}
+	if exists(filepath.Join(root, "a.txt")) {
+		t.Errorf("EncryptDir() kept the original of a.txt\n")
+	}
+
+	// the mirror gets the same names
+	dest := filepath.Join(t.TempDir(), "mirror")
+	m, _ := New("myKey123", WithExtension(".enc"), WithDestDir(dest))
+	if s, err := m.DecryptDir(root); err == nil || s != (DirSummary{Processed: 2, Failed: 1}) {
+		t.Errorf("DecryptDir() into a mirror = %+v, %v, want 2 processed and 1 failed\n", s, err)
+	}
+	if d, _ := os.ReadFile(filepath.Join(dest, "sub/b.txt")); string(d) != "Hello B!" {
+		t.Errorf("DecryptDir() into a mirror: got %q\n", d)
+	}
+
+	// sub/c.enc isn't encrypted, it is the only file that fails
+	s, err := c.DecryptDir(root)
+	if !errors.Is(err, ErrNotEncrypted) || s != (DirSummary{Processed: 2, Failed: 1}) {
+		t.Errorf("DecryptDir() = %+v, %v, want 2 processed and 1 failed\n", s, err)
+	}
+	for name, text := range files {
+		if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+			t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+		}
+	}
+	if exists(filepath.Join(root, "a.txt.enc")) {
+		t.Errorf("DecryptDir() kept a.txt.enc\n")
+	}
+}
@@ -0,0 +1,343 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+	"github.com/toxyl/flo"
+)
+
+// maxPrefixSize is the largest combined size of the headers written by Cipher.prefix.
+const maxPrefixSize = keySizeHeaderSize + fingerprintHeaderSize + keyCheckHeaderSize
+
+// readerWrapper wraps the reader of a file of the given size, for example to report progress.
+type readerWrapper func(r io.Reader, size int64) io.Reader
+
+// chainWrappers returns a readerWrapper that applies the non-nil 'wrappers' in order, so the last one
+// wraps all others. It returns nil if all 'wrappers' are nil.
+func chainWrappers(wrappers ...readerWrapper) readerWrapper {
+	var chain readerWrapper
+	for _, w := range wrappers {
+		if w == nil {
+			continue
+		}
+		if prev := chain; prev != nil {
+			chain = func(r io.Reader, size int64) io.Reader {
+				return w(prev(r, size), size)
+			}
+		} else {
+			chain = w
+		}
+	}
+	return chain
+}
+
+// writeFileAtomic writes 'data' to a temporary file next to 'path' and renames it to 'path' once
+// the data has been flushed to disk. The original file is left intact if any step fails.
+// The mode of an existing file at 'path' is preserved.
+func writeFileAtomic(path string, data []byte) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFunc is like writeFileAtomic but lets 'write' stream the data into the temporary file.
+// The file is only renamed to 'path' if 'write' succeeds.
+func writeFileAtomicFunc(path string, write func(w io.Writer) error) error {
+	return writeFileFunc(path, atomicfile.Options{Overwrite: true}, write)
+}
+
+// writeOptions returns the options to write a file that takes over the attributes of the source file 'f'.
+func (c *Cipher) writeOptions(f *os.File, overwrite bool) (atomicfile.Options, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return atomicfile.Options{}, err
+	}
+	return atomicfile.Options{Like: fi, Ownership: c.opts.ownership, Overwrite: overwrite}, nil
+}
+
+// writeFileFunc lets 'write' stream data into a temporary file next to 'path', which is moved to 'path' once
+// the data has been flushed to disk, see atomicfile.WriteWith. Without 'wo.Like' the file gets the permissions
+// of an existing file at 'path', defaulting to 0644. Unless 'wo.Overwrite' is set, it fails with an error
+// wrapping ErrFileExists if 'path' exists when the file is moved.
+func writeFileFunc(path string, wo atomicfile.Options, write func(w io.Writer) error) error {
+	err := atomicfile.WriteWith(path, wo, write)
+	if !wo.Overwrite && errors.Is(err, fs.ErrExist) {
+		return errFileExists("write", path)
+	}
+	return err
+}
+
+// moveNoReplace moves the file at 'from' to 'to', failing with an error wrapping ErrFileExists if 'to' exists.
+func moveNoReplace(from, to string) error {
+	err := atomicfile.MoveNoReplace(from, to)
+	if errors.Is(err, fs.ErrExist) {
+		return errFileExists("write", to)
+	}
+	return err
+}
+
+// samePath reports whether 'a' and 'b' refer to the same file.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA == nil && errB == nil && absA == absB {
+		return true
+	}
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(fa, fb)
+}
+
+// EncryptFileTo encrypts the file located at 'src' into a new file at 'dst', leaving 'src' untouched.
+// Missing parent directories of 'dst' are created and 'dst' gets the permissions and modification time of 'src'.
+// It returns an error if 'src' doesn't exist, if 'src' and 'dst' are the same file, if any encryption
+// operation fails or, unless WithOverwrite has been passed, an error wrapping ErrFileExists if 'dst' exists.
+//
+// The result is written to a temporary file next to 'dst' which is only moved to 'dst' once it is complete,
+// so an interrupted run never leaves a partially written 'dst'. With WithShredSource, 'src' is shredded afterwards.
+func (c *Cipher) EncryptFileTo(src, dst string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.encryptFileTo(context.Background(), src, dst, nil)
+}
+
+// DecryptFileTo decrypts the file located at 'src' into a new file at 'dst', leaving 'src' untouched.
+// See EncryptFileTo for how 'dst' is written and which errors are returned.
+func (c *Cipher) DecryptFileTo(src, dst string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	cc, err := c.pathCipher(src)
+	if err != nil {
+		return err
+	}
+	return cc.transferFile(context.Background(), "decrypt", src, dst, nil, cc.decryptFileTo)
+}
+
+// transferFile applies 'fn' to the contents of 'src', read through 'wrap' unless it is nil, and writes the result
+// to 'dst' for the operation 'op'. Nothing is written to 'dst' if 'ctx' is done before the result is complete.
+func (c *Cipher) transferFile(ctx context.Context, op, src, dst string, wrap readerWrapper, fn func(w io.Writer, r io.Reader) error) error {
+	src, err := c.symlinkTarget(op, src)
+	if err != nil || src == "" {
+		return err
+	}
+	if samePath(src, dst) {
+		return fmt.Errorf("can't %s '%s' to itself, use %sFile to %s in place", op, src, strings.ToUpper(op[:1])+op[1:], op)
+	}
+	if _, err := os.Lstat(dst); err == nil && !c.opts.overwrite {
+		return errFileExists(op, dst)
+	}
+	f, r, err := openFile(op, src, chainWrappers(ctxWrapper(ctx), wrap, progressWrapper(c.opts.progress)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	wo, err := c.writeOptions(f, c.opts.overwrite)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err = writeFileFunc(dst, wo, func(w io.Writer) error {
+		if err := fn(w, r); err != nil {
+			return err
+		}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		return checkUnchanged(op, src, wo.Like)
+	})
+	if err == nil {
+		reportDone(r)
+	}
+	return err
+}
+
+// encryptTo encrypts the plaintext read from 'r' into the file format and writes it to 'w':
+// the headers selected by the options followed by a stream in the format of EncryptStream,
+// preceded by the compression flag with WithCompression.
+func (c *Cipher) encryptTo(w io.Writer, r io.Reader) error {
+	switch c.opts.compression {
+	case Zstd:
+		return c.encryptChunkedTo(w, r)
+	case Gzip:
+		return c.encryptGzipTo(w, r)
+	}
+	prefix, err := c.prefix()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	return c.encryptStream(w, r)
+}
+
+// decryptTo decrypts data in the file format read from 'r' and writes the plaintext to 'w'.
+// Data holding a single ciphertext, as produced by EncryptBytes, is read into memory and decrypted.
+func (c *Cipher) decryptTo(w io.Writer, r io.Reader) error {
+	br := bufio.NewReaderSize(r, DefaultChunkSize)
+	head, _ := br.Peek(maxPrefixSize + len(streamMagic))
+	if p, ok := c.streamPrefix(head); ok {
+		if _, err := br.Discard(p.size); err != nil {
+			return err
+		}
+		return c.decryptPrefixedStream(w, br, p)
+	}
+
+	limit := int64(0)
+	if c.opts.maxSize > 0 {
+		limit = maxCiphertextLen(c.opts.maxSize, DefaultChunkSize)
+	}
+	data, err := readAllLimited(br, limit)
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return errTooLarge(c.opts.maxSize)
+		}
+		return err
+	}
+	decrypted, err := c.decryptSingle(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(decrypted)
+	return err
+}
+
+// streamPrefix parses the headers at the start of 'head' and reports whether a stream follows them.
+func (c *Cipher) streamPrefix(head []byte) (parsedPrefix, bool) {
+	p, err := c.parsePrefix(head)
+	if err != nil || p.size > len(head) {
+		return p, false
+	}
+	return p, bytes.HasPrefix(head[p.size:], streamMagic)
+}
+
+// decryptPrefixedStream verifies the key check header of 'p', if any, and decrypts the stream read from 'r',
+// which must be positioned right after the headers, to 'w'. Authentication failures are reported as
+// *KeyMismatchError if the recorded fingerprint differs from the one of the Cipher.
+func (c *Cipher) decryptPrefixedStream(w io.Writer, r io.Reader, p parsedPrefix) error {
+	if p.keyCheck != nil {
+		if err := verifyKeyCheck(c.aead, p.keyCheck); err != nil {
+			return c.keyMismatch(p.fingerprint, err)
+		}
+	}
+	return c.keyMismatch(p.fingerprint, c.decryptStream(w, r))
+}
+
+// openFile opens the file located at 'path' for the operation 'op' and returns it with its reader,
+// wrapped by 'wrap' unless it is nil.
+func openFile(op, path string, wrap readerWrapper) (*os.File, io.Reader, error) {
+	if !flo.File(path).Exists() {
+		return nil, nil, errFileNotFound(op, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if wrap == nil {
+		return f, f, nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return f, wrap(f, fi.Size()), nil
+}
+
+// encryptFile encrypts the file located at 'path' in place, reading it through 'wrap'.
+// The file is only replaced if 'ctx' isn't done once encryption has finished.
+func (c *Cipher) encryptFile(ctx context.Context, path string, wrap readerWrapper) error {
+	if c.opts.extension != "" {
+		return c.encryptFileExt(ctx, path, wrap)
+	}
+	cc, err := c.pathCipher(path)
+	if err != nil {
+		return err
+	}
+	return cc.processFile(ctx, "encrypt", path, wrap, cc.refuseEncrypted(path, cc.encryptTo))
+}
+
+// decryptFile decrypts the file located at 'path' in place, reading it through 'wrap'.
+// The file is only replaced if 'ctx' isn't done once decryption has finished.
+func (c *Cipher) decryptFile(ctx context.Context, path string, wrap readerWrapper) error {
+	if c.opts.extension != "" {
+		return c.decryptFileExt(ctx, path, wrap)
+	}
+	cc, err := c.pathCipher(path)
+	if err != nil {
+		return err
+	}
+	return cc.processFile(ctx, "decrypt", path, wrap, cc.decryptFileTo)
+}
+
+// processFile applies 'fn' to the contents of the file located at 'path' for the operation 'op' and
+// replaces the file with the result, after backing it up if WithBackup is set. The file is left unchanged if 'ctx' is done or if the file has been
+// modified while 'fn' was running.
+func (c *Cipher) processFile(ctx context.Context, op, path string, wrap readerWrapper, fn func(w io.Writer, r io.Reader) error) error {
+	path, err := c.symlinkTarget(op, path)
+	if err != nil || path == "" {
+		return err
+	}
+	if err := c.refuseBackup(op, path); err != nil {
+		return err
+	}
+	wrap = chainWrappers(wrap, progressWrapper(c.opts.progress))
+	f, r, err := openFile(op, path, wrap)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	wo, err := c.writeOptions(f, true)
+	if err != nil {
+		return err
+	}
+	err = writeFileFunc(path, wo, func(w io.Writer) error {
+		if err := fn(w, r); err != nil {
+			return err
+		}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		if err := checkUnchanged(op, path, wo.Like); err != nil {
+			return err
+		}
+		if c.opts.backup == "" {
+			return nil
+		}
+		return c.backupFile(path, c.opts.backup)
+	})
+	if err == nil {
+		reportDone(r)
+	}
+	return err
+}
+
+// checkUnchanged returns an error wrapping ErrFileModified if the size or modification time of the file
+// located at 'path' differ from 'before', which means it has been written to during the operation 'op'.
+func checkUnchanged(op, path string, before os.FileInfo) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Size() != before.Size() || !fi.ModTime().Equal(before.ModTime()) {
+		return fmt.Errorf("can't %s, %w: '%s'", op, ErrFileModified, path)
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// chunkedFile encrypts 'data' with a chunk size of 100 bytes into a file below 't.TempDir()'
+// and returns its path and the encrypted contents.
+func chunkedFile(t *testing.T, data []byte, opts ...Option) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chunked.bin")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	c, err := New("myKey123", append([]Option{WithChunkSize(100)}, opts...)...)
+	if err != nil {
+		t.Fatalf("could not create cipher: %s\n", err)
+	}
+	if err := c.EncryptFile(path); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	e, _ := os.ReadFile(path)
+	return path, e
+}
+
+func Test_chunkedFile(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		opts []Option
+	}{
+		{"empty", 0, nil},
+		{"one chunk", 99, nil},
+		{"exact chunks", 300, nil},
+		{"many chunks", 1234, nil},
+		{"aad", 1234, []Option{WithAAD([]byte("user-42"))}},
+		{"all headers", 1234, []Option{WithKeySize(16), WithFingerprint(), WithKeyCheck()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+			path, e := chunkedFile(t, data, tt.opts...)
+			if !bytes.Contains(e[:min(len(e), maxPrefixSize+len(streamMagic))], streamMagic) {
+				t.Errorf("expected the chunked format\n")
+			}
+
+			c, _ := newDecryptCipher("myKey123", decryptOnly(tt.opts)...)
+			if d, err := c.DecryptBytes(e); err != nil || !bytes.Equal(d, data) {
+				t.Errorf("could not decrypt chunked bytes: %v\n", err)
+			}
+			if err := c.DecryptFile(path); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, data) {
+				t.Errorf("encrypt/decrypt chunked file failed: %v: plaintext mismatch\n", tt.name)
+			}
+		})
+	}
+}
+
+// decryptOnly returns the options of 'opts' that can be used for decryption.
+func decryptOnly(opts []Option) []Option {
+	var res []Option
+	for _, opt := range opts {
+		if _, err := newDecryptOptions(opt); err == nil {
+			res = append(res, opt)
+		}
+	}
+	return res
+}
+
+func Test_chunkedFile_tampering(t *testing.T) {
+	data := make([]byte, 550)
+	_, _ = rand.Read(data)
+	_, e := chunkedFile(t, data)
+
+	const sealedChunk = 100 + 16
+	start := streamHeaderSize + 12
+	chunk := func(i int) []byte { return e[start+i*sealedChunk : start+(i+1)*sealedChunk] }
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	header := e[:start]
+	last := e[start+5*sealedChunk:]
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"reordered chunks", join(header, chunk(1), chunk(0), chunk(2), chunk(3), chunk(4), last), ErrAuthenticationFailed},
+		{"duplicated chunk", join(header, chunk(0), chunk(0), chunk(2), chunk(3), chunk(4), last), ErrAuthenticationFailed},
+		{"deleted middle chunk", join(header, chunk(0), chunk(1), chunk(3), chunk(4), last), ErrAuthenticationFailed},
+		{"deleted final chunk", join(header, chunk(0), chunk(1), chunk(2), chunk(3), chunk(4)), ErrStreamTruncated},
+		{"truncated final chunk", e[:len(e)-1], ErrAuthenticationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tampered.bin")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := DecryptFile(path, "myKey123"); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v\n", tt.want, err)
+			}
+			if d, _ := os.ReadFile(path); !bytes.Equal(d, tt.data) {
+				t.Errorf("file was modified although decryption failed\n")
+			}
+		})
+	}
+}
+
+func Test_chunkedFile_legacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.bin")
+	e, _ := EncryptRaw([]byte("Hello World!"), "myKey123")
+	if err := os.WriteFile(path, e, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := DecryptFile(path, "myKey123"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted without WithLegacyFormat, got %v\n", err)
+	}
+	if err := DecryptFile(path, "myKey123", WithLegacyFormat()); err != nil {
+		t.Fatalf("could not decrypt single-shot file: %s\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != "Hello World!" {
+		t.Errorf("expected Hello World!, got %s\n", d)
+	}
+}
+
+func Test_EncryptToFile_chunked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.bin")
+	if err := EncryptToFile([]byte("Hello World!"), path, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt to file: %s\n", err)
+	}
+	if ok, err := IsEncrypted(path); err != nil || !ok {
+		t.Errorf("IsEncrypted() = %v, %v, want true\n", ok, err)
+	}
+	if err := DecryptFile(path, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt file without WithLegacyFormat: %s\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != "Hello World!" {
+		t.Errorf("expected Hello World!, got %s\n", d)
+	}
+}
+
+func Test_EncryptFileTo(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.txt")
+	data := []byte("secret contents")
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	existing := filepath.Join(dir, "existing.bin")
+	if err := os.WriteFile(existing, []byte("keep me"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	tests := []struct {
+		name    string
+		dst     string
+		opts    []Option
+		wantErr error
+	}{
+		{"other directory", filepath.Join(dir, "out", "nested", "plain.bin"), nil, nil},
+		{"same file", src, nil, errors.New("same file")},
+		{"same file relative", filepath.Join(dir, ".", "plain.txt"), []Option{WithOverwrite()}, errors.New("same file")},
+		{"existing", existing, nil, ErrFileExists},
+		{"existing overwrite", existing, []Option{WithOverwrite()}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := EncryptFileTo(src, tt.dst, "myKey123", tt.opts...)
+			if s, _ := os.ReadFile(src); !bytes.Equal(s, data) {
+				t.Fatalf("source has been modified\n")
+			}
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("EncryptFileTo() expected an error\n")
+				}
+				if errors.Is(tt.wantErr, ErrFileExists) {
+					if !errors.Is(err, ErrFileExists) || !errors.Is(err, os.ErrExist) {
+						t.Errorf("EncryptFileTo() error = %v, want ErrFileExists\n", err)
+					}
+					if e, _ := os.ReadFile(tt.dst); string(e) != "keep me" {
+						t.Errorf("existing destination has been modified\n")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncryptFileTo() error = %v\n", err)
+			}
+			if fi, err := os.Stat(tt.dst); err != nil || fi.Mode().Perm() != 0o600 {
+				t.Errorf("expected the destination to have the permissions of the source: %v\n", err)
+			}
+
+			out := tt.dst + ".dec"
+			if err := DecryptFileTo(tt.dst, out, "myKey123"); err != nil {
+				t.Fatalf("DecryptFileTo() error = %v\n", err)
+			}
+			if d, _ := os.ReadFile(out); !bytes.Equal(d, data) {
+				t.Errorf("DecryptFileTo() = %q, want %q\n", d, data)
+			}
+			if err := DecryptFileTo(tt.dst, out, "myKey123"); !errors.Is(err, ErrFileExists) {
+				t.Errorf("DecryptFileTo() error = %v, want ErrFileExists\n", err)
+			}
+			if err := DecryptFileTo(tt.dst, out, "wrongKey"); err == nil {
+				t.Errorf("DecryptFileTo() with the wrong key expected an error\n")
+			}
+		})
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("unexpected leftover temporary file %s\n", e.Name())
+		}
+	}
+}
+
+func Test_writeFileFunc_failures(t *testing.T) {
+	t.Run("stale temporary files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.txt")
+		if err := os.WriteFile(path, []byte("contents"), 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		stale := filepath.Join(dir, ".data.txt.tmp-123")
+		fresh := filepath.Join(dir, ".data.txt.tmp-456")
+		other := filepath.Join(dir, ".other.txt.tmp-789")
+		for _, p := range []string{stale, fresh, other} {
+			if err := os.WriteFile(p, []byte("partial"), 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+		}
+		old := time.Now().Add(-2 * atomicfile.StaleTempAge)
+		for _, p := range []string{stale, other} {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatalf("could not change file times: %s\n", err)
+			}
+		}
+
+		if err := EncryptDir(dir, "myKey123"); err != nil {
+			t.Fatalf("EncryptDir() error = %v\n", err)
+		}
+		if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the stale temporary file to be removed\n")
+		}
+		for _, p := range []string{fresh, other} {
+			if d, err := os.ReadFile(p); err != nil || string(d) != "partial" {
+				t.Errorf("expected %s to be left untouched\n", filepath.Base(p))
+			}
+		}
+	})
+
+	t.Run("directory not writable", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("permissions are not enforced for root")
+		}
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.txt")
+		if err := os.WriteFile(path, []byte("contents"), 0o600); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if err := os.Chmod(dir, 0o500); err != nil {
+			t.Fatalf("could not change permissions: %s\n", err)
+		}
+		defer os.Chmod(dir, 0o700)
+
+		err := EncryptFile(path, "myKey123")
+		if !errors.Is(err, os.ErrPermission) || !strings.Contains(err.Error(), "must be writable") {
+			t.Errorf("EncryptFile() error = %v, want a permission error\n", err)
+		}
+	})
+}
+
+// attrTime is the modification time set by attrFile.
+var attrTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// attrFile creates a file with the mode 0640 and the modification time attrTime.
+func attrFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secrets.txt")
+	if err := os.WriteFile(path, []byte("secret contents"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("could not change permissions: %s\n", err)
+	}
+	if err := os.Chtimes(path, attrTime, attrTime); err != nil {
+		t.Fatalf("could not change file times: %s\n", err)
+	}
+	return path
+}
+
+// checkAttrs reports an error if the file at 'path' lost the attributes set by attrFile.
+func checkAttrs(t *testing.T, step, path string) {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("%s: %s\n", step, err)
+	}
+	if fi.Mode().Perm() != 0o640 {
+		t.Errorf("%s: mode = %v, want %v\n", step, fi.Mode().Perm(), os.FileMode(0o640))
+	}
+	if !fi.ModTime().Equal(attrTime) {
+		t.Errorf("%s: modification time = %v, want %v\n", step, fi.ModTime(), attrTime)
+	}
+}
+
+func Test_fileAttributes(t *testing.T) {
+	t.Run("in place", func(t *testing.T) {
+		path := attrFile(t)
+		if err := EncryptFile(path, "myKey123"); err != nil {
+			t.Fatalf("EncryptFile() error = %v\n", err)
+		}
+		checkAttrs(t, "EncryptFile", path)
+		if err := RotateFileKey(path, "myKey123", "newKey456"); err != nil {
+			t.Fatalf("RotateFileKey() error = %v\n", err)
+		}
+		checkAttrs(t, "RotateFileKey", path)
+		if err := DecryptFile(path, "newKey456"); err != nil {
+			t.Fatalf("DecryptFile() error = %v\n", err)
+		}
+		checkAttrs(t, "DecryptFile", path)
+	})
+
+	t.Run("separate destination", func(t *testing.T) {
+		src := attrFile(t)
+		enc := filepath.Join(t.TempDir(), "secrets.bin")
+		dec := filepath.Join(t.TempDir(), "secrets.txt")
+		if err := EncryptFileTo(src, enc, "myKey123"); err != nil {
+			t.Fatalf("EncryptFileTo() error = %v\n", err)
+		}
+		checkAttrs(t, "EncryptFileTo", enc)
+		if err := DecryptFileTo(enc, dec, "myKey123"); err != nil {
+			t.Fatalf("DecryptFileTo() error = %v\n", err)
+		}
+		checkAttrs(t, "DecryptFileTo", dec)
+		if err := EncryptFileToPath(dec, dec, "myKey123"); err != nil {
+			t.Fatalf("EncryptFileToPath() error = %v\n", err)
+		}
+		checkAttrs(t, "EncryptFileToPath", dec)
+	})
+}
@@ -0,0 +1,87 @@
+package aesgcm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maxFilenameLength is the longest file name accepted by common file systems, in bytes.
+const maxFilenameLength = 255
+
+// EncryptFilename encrypts the file name 'name' using AES-GCM encryption with the provided key and returns it
+// encoded as unpadded URL-safe base64, which is a valid file name on all supported operating systems.
+// The result differs on every call since a random nonce is used.
+//
+// 'name' must be a base name without path separators. An error is returned if it is empty, "." or "..",
+// or too long for the encrypted name to fit into 255 bytes, which allows names of up to about 145 bytes.
+func EncryptFilename(name, key string) (string, error) {
+	if err := checkFilename(name); err != nil {
+		return "", fmt.Errorf("can't encrypt file name: %w", err)
+	}
+	encoded, err := EncryptWithEncoding(name, key, RawURL)
+	if err != nil {
+		return "", err
+	}
+	if len(encoded) > maxFilenameLength {
+		return "", fmt.Errorf("can't encrypt file name, the result exceeds %d bytes: '%s'", maxFilenameLength, name)
+	}
+	return encoded, nil
+}
+
+// DecryptFilename decrypts a file name produced by EncryptFilename using AES-GCM decryption with the provided key.
+// It returns an error if decryption fails or if the result isn't a valid base name, so a decrypted name
+// can never point outside of the directory it is used in.
+func DecryptFilename(encoded, key string) (string, error) {
+	name, err := DecryptWithEncoding(encoded, key, RawURL)
+	if err != nil {
+		return "", err
+	}
+	if err := checkFilename(name); err != nil {
+		return "", fmt.Errorf("can't decrypt file name: %w", err)
+	}
+	return name, nil
+}
+
+// RenameEncrypted renames the file or directory located at 'path' to its name encrypted with EncryptFilename,
+// in the same directory. Only the name is encrypted, not the contents. It returns the new path, or an error
+// wrapping ErrFileExists if a file with the new name already exists.
+func RenameEncrypted(path, key string) (newPath string, err error) {
+	return renameBase(path, func(name string) (string, error) {
+		return EncryptFilename(name, key)
+	})
+}
+
+// RenameDecrypted reverts RenameEncrypted: it renames the file or directory located at 'path' to its name
+// decrypted with DecryptFilename, in the same directory, and returns the new path.
+func RenameDecrypted(path, key string) (newPath string, err error) {
+	return renameBase(path, func(name string) (string, error) {
+		return DecryptFilename(name, key)
+	})
+}
+
+// renameBase renames the file located at 'path' to the name returned by 'fn' for its current name,
+// without replacing an existing file.
+func renameBase(path string, fn func(name string) (string, error)) (string, error) {
+	dir, name := filepath.Split(filepath.Clean(path))
+	newName, err := fn(name)
+	if err != nil {
+		return "", err
+	}
+	newPath := filepath.Join(dir, newName)
+	if err := moveNoReplace(path, newPath); err != nil {
+		return "", err
+	}
+	return newPath, nil
+}
+
+// checkFilename returns an error if 'name' isn't a valid base name.
+func checkFilename(name string) error {
+	switch {
+	case name == "", name == ".", name == "..":
+		return fmt.Errorf("invalid file name '%s'", name)
+	case strings.ContainsAny(name, "/\\\x00"):
+		return fmt.Errorf("file name must not contain a path separator: '%s'", name)
+	}
+	return nil
+}
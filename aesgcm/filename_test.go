@@ -0,0 +1,96 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_EncryptFilename(t *testing.T) {
+	valid := regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"simple", "report.pdf", false},
+		{"spaces and unicode", "Übersicht 2024 (final).xlsx", false},
+		{"dotfile", ".bashrc", false},
+		{"longest", strings.Repeat("a", 145), false},
+		{"too long", strings.Repeat("a", 200), true},
+		{"empty", "", true},
+		{"dot", ".", true},
+		{"dot dot", "..", true},
+		{"separator", "dir/file.txt", true},
+		{"backslash", `dir\file.txt`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := EncryptFilename(tt.input, "myKey123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncryptFilename() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !valid.MatchString(encrypted) || len(encrypted) > 255 {
+				t.Errorf("EncryptFilename() = %q, not a valid file name\n", encrypted)
+			}
+			if strings.Contains(encrypted, tt.input) {
+				t.Errorf("EncryptFilename() = %q contains the plaintext name\n", encrypted)
+			}
+			decrypted, err := DecryptFilename(encrypted, "myKey123")
+			if err != nil || decrypted != tt.input {
+				t.Errorf("DecryptFilename() = %q, %v, want %q\n", decrypted, err, tt.input)
+			}
+			if _, err := DecryptFilename(encrypted, "wrongKey"); err == nil {
+				t.Errorf("DecryptFilename() with the wrong key expected an error\n")
+			}
+		})
+	}
+
+	// a name decrypting to a path is rejected
+	encrypted, _ := EncryptURL("../etc/passwd", "myKey123")
+	if _, err := DecryptFilename(encrypted, "myKey123"); err == nil {
+		t.Errorf("DecryptFilename() of a path expected an error\n")
+	}
+}
+
+func Test_RenameEncrypted(t *testing.T) {
+	root := dirTree(t, map[string]string{"secret report.txt": "Hello World!"})
+	path := filepath.Join(root, "secret report.txt")
+
+	newPath, err := RenameEncrypted(path, "myKey123")
+	if err != nil {
+		t.Fatalf("RenameEncrypted() error = %v\n", err)
+	}
+	if filepath.Dir(newPath) != root {
+		t.Errorf("RenameEncrypted() moved the file to %s\n", newPath)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("RenameEncrypted() left the original name\n")
+	}
+	if d, _ := os.ReadFile(newPath); string(d) != "Hello World!" {
+		t.Errorf("RenameEncrypted() changed the contents: %q\n", d)
+	}
+
+	// the original name is taken again
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if _, err := RenameDecrypted(newPath, "myKey123"); !errors.Is(err, ErrFileExists) {
+		t.Errorf("RenameDecrypted() error = %v, want ErrFileExists\n", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("could not remove file: %s\n", err)
+	}
+	if got, err := RenameDecrypted(newPath, "myKey123"); err != nil || got != path {
+		t.Errorf("RenameDecrypted() = %q, %v, want %q\n", got, err, path)
+	}
+	if _, err := RenameEncrypted(filepath.Join(root, "missing.txt"), "myKey123"); err == nil {
+		t.Errorf("RenameEncrypted() of a missing file expected an error\n")
+	}
+}
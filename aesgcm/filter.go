@@ -0,0 +1,118 @@
+package aesgcm
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithInclude restricts EncryptDir and DecryptDir to files matching at least one of the glob 'patterns'.
+// Patterns are matched against the slash-separated path relative to the root of the walk using path.Match
+// syntax, extended by "**" as a path segment, which matches any number of directories. A pattern without
+// a slash matches the file name at any depth, so "*.yml" is the same as "**/*.yml".
+// WithExclude takes precedence over WithInclude.
+func WithInclude(patterns ...string) Option {
+	return func(o *options) error {
+		if err := validatePatterns(patterns); err != nil {
+			return err
+		}
+		o.include = append(o.include, patterns...)
+		return nil
+	}
+}
+
+// WithExclude makes EncryptDir and DecryptDir leave out files and directories matching any of the glob 'patterns',
+// written as for WithInclude. Excluded directories are not walked at all, so excluding ".git" or "node_modules"
+// also avoids reading their contents. Exclusion takes precedence over WithInclude.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) error {
+		if err := validatePatterns(patterns); err != nil {
+			return err
+		}
+		o.exclude = append(o.exclude, patterns...)
+		return nil
+	}
+}
+
+// validatePatterns returns an error if any of the 'patterns' is malformed.
+func validatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if p == "" {
+			return fmt.Errorf("glob pattern must not be empty")
+		}
+		for _, seg := range strings.Split(p, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern '%s': %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the slash-separated relative path 'name' matches 'pattern'.
+// See WithInclude for the syntax.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches the path segments 'names' against the pattern segments 'patterns'.
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// matchAny reports whether 'name' matches any of the 'patterns'.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirFilter returns the filter applied to the entries below 'root' by directory operations,
+// combining the patterns set with WithInclude and WithExclude with the optional 'include' callback.
+// It returns nil if nothing is filtered.
+func (c *Cipher) dirFilter(root string, include func(path string) bool) func(path string, isDir bool) bool {
+	if include == nil && len(c.opts.include) == 0 && len(c.opts.exclude) == 0 {
+		return nil
+	}
+	return func(p string, isDir bool) bool {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return true
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(c.opts.exclude, rel) {
+			return false
+		}
+		if isDir {
+			return true
+		}
+		if len(c.opts.include) > 0 && !matchAny(c.opts.include, rel) {
+			return false
+		}
+		return include == nil || include(p)
+	}
+}
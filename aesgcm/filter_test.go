@@ -0,0 +1,148 @@
+package aesgcm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.log", "app.log", true},
+		{"*.log", "logs/deep/app.log", true},
+		{"*.log", "app.log.txt", false},
+		{".git", ".git", true},
+		{".git", "sub/.git", true},
+		{"node_modules", "web/node_modules", true},
+		{"config/*.yml", "config/app.yml", true},
+		{"config/*.yml", "sub/config/app.yml", false},
+		{"config/*.yml", "config/sub/app.yml", false},
+		{"**/secrets/*.bak", "secrets/a.bak", true},
+		{"**/secrets/*.bak", "x/y/secrets/a.bak", true},
+		{"**/secrets/*.bak", "x/secrets/y/a.bak", false},
+		{"**/secrets/*.bak", "x/secrets/a.txt", false},
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**/*.md", "docs/b.md", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"docs/**/*.md", "other/docs/c.md", false},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/x/y/c", false},
+		{"file[0-9].txt", "dir/file7.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v\n", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WithInclude_invalid(t *testing.T) {
+	for _, opt := range []Option{WithInclude("[a-"), WithExclude("ok", "sub/[")} {
+		if _, err := New("myKey123", opt); err == nil {
+			t.Errorf("expected an error for an invalid pattern\n")
+		}
+	}
+	if _, err := New("myKey123", WithExclude("")); err == nil {
+		t.Errorf("expected an error for an empty pattern\n")
+	}
+}
+
+func Test_dirFilters(t *testing.T) {
+	files := map[string]string{
+		"main.go":                       "package main",
+		"app.log":                       "log line",
+		"config/app.yml":                "key: value",
+		"config/secrets/db.bak":         "backup",
+		"config/secrets/db.txt":         "password",
+		"deep/a/secrets/old.bak":        "backup",
+		"deep/a/secrets/keep/new.bak":   "backup",
+		".git/HEAD":                     "ref: refs/heads/main",
+		"web/node_modules/pkg/index.js": "module.exports = {}",
+		"web/index.js":                  "console.log(1)",
+	}
+	tests := []struct {
+		name     string
+		opts     []Option
+		want     []string
+		filtered int
+	}{
+		{
+			"exclude",
+			[]Option{WithExclude(".git", "node_modules", "*.log", "**/secrets/*.bak")},
+			[]string{"config/app.yml", "config/secrets/db.txt", "deep/a/secrets/keep/new.bak", "main.go", "web/index.js"},
+			5, // .git, node_modules, app.log and two backups
+		},
+		{
+			"include",
+			[]Option{WithInclude("*.js", "config/**")},
+			[]string{"config/app.yml", "config/secrets/db.bak", "config/secrets/db.txt", "web/index.js", "web/node_modules/pkg/index.js"},
+			5,
+		},
+		{
+			"exclude wins",
+			[]Option{WithInclude("*.bak", "*.js"), WithExclude("deep/**/keep", "node_modules")},
+			[]string{"config/secrets/db.bak", "deep/a/secrets/old.bak", "web/index.js"},
+			7, // keep, node_modules and five other files
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := dirTree(t, files)
+			c, err := New("myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not create cipher: %s\n", err)
+			}
+			s, err := c.EncryptDir(root)
+			if err != nil {
+				t.Fatalf("EncryptDir() error = %v\n", err)
+			}
+			var got []string
+			for name, text := range files {
+				if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+					got = append(got, name)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("encrypted %v, want %v\n", got, tt.want)
+			}
+			if s.Processed != len(tt.want) || s.Filtered != tt.filtered {
+				t.Errorf("EncryptDir() = %+v, want %d processed and %d filtered\n", s, len(tt.want), tt.filtered)
+			}
+
+			s, err = c.DecryptDir(root)
+			if err != nil || s.Processed != len(tt.want) {
+				t.Errorf("DecryptDir() = %+v, %v, want %d processed\n", s, err, len(tt.want))
+			}
+			for name, text := range files {
+				if d, _ := os.ReadFile(filepath.Join(root, name)); string(d) != text {
+					t.Errorf("encrypt/decrypt dir with filters failed: %s\n", name)
+				}
+			}
+		})
+	}
+
+	t.Run("filtered callback", func(t *testing.T) {
+		root := dirTree(t, files)
+		err := EncryptDirFiltered(root, "myKey123", func(path string) bool { return strings.HasSuffix(path, ".go") })
+		if err != nil {
+			t.Fatalf("EncryptDirFiltered() error = %v\n", err)
+		}
+		if d, _ := os.ReadFile(filepath.Join(root, "main.go")); string(d) == files["main.go"] {
+			t.Errorf("expected main.go to be encrypted\n")
+		}
+		if d, _ := os.ReadFile(filepath.Join(root, "web/index.js")); string(d) != files["web/index.js"] {
+			t.Errorf("expected web/index.js to be left alone\n")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// fingerprintSize is the number of bytes of a key fingerprint.
+const fingerprintSize = 8
+
+// fingerprintHeaderSize is the length of the fingerprint header: magic + fingerprint.
+const fingerprintHeaderSize = 4 + fingerprintSize
+
+// fingerprintMagic identifies the fingerprint header.
+var fingerprintMagic = []byte("AGKF")
+
+// fingerprintLabel separates fingerprints from other uses of SHA-256 over the scrambled key.
+var fingerprintLabel = []byte("aesgcm key fingerprint v1")
+
+// fingerprint returns the fingerprint of the scrambled key 'key'.
+func fingerprint(key []byte) []byte {
+	h := sha256.New()
+	h.Write(fingerprintLabel)
+	h.Write(key)
+	return h.Sum(nil)[:fingerprintSize]
+}
+
+// KeyFingerprint returns a short, stable identifier of 'key': the first 8 bytes of SHA-256 over the
+// scrambled key, hex-encoded. It returns an empty string if the key is weak or can't be scrambled.
+//
+// The fingerprint can't be reversed to the key and is safe to log and store alongside ciphertexts.
+// Note that it allows checking guesses of the key just like a ciphertext does, so it doesn't protect
+// weak passwords any better than the ciphertext itself.
+func KeyFingerprint(key string) string {
+	kc, err := newKeyCipher(key)
+	if err != nil {
+		return ""
+	}
+	defer kc.wipe()
+	return hex.EncodeToString(fingerprint(kc.key))
+}
+
+// WithFingerprint prefixes ciphertexts with the fingerprint of the key, see KeyFingerprint.
+// Decrypting such a ciphertext with a different key fails with a *KeyMismatchError
+// that reports both fingerprints. It can only be used for encryption.
+func WithFingerprint() Option {
+	return func(o *options) error {
+		o.fingerprint = true
+		o.encryptOnly = append(o.encryptOnly, "WithFingerprint")
+		return nil
+	}
+}
+
+// fingerprintHeader returns the fingerprint header for the fingerprint 'fp'.
+func fingerprintHeader(fp []byte) []byte {
+	return append(append(make([]byte, 0, fingerprintHeaderSize), fingerprintMagic...), fp...)
+}
+
+// parseFingerprintHeader returns the fingerprint recorded in the header of 'data' and whether a header was found.
+func parseFingerprintHeader(data []byte) ([]byte, bool) {
+	if len(data) < fingerprintHeaderSize || !bytes.HasPrefix(data, fingerprintMagic) {
+		return nil, false
+	}
+	return data[len(fingerprintMagic):fingerprintHeaderSize], true
+}
@@ -0,0 +1,90 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_KeyFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"key 1", "myKey123"},
+		{"key 2", "myKey124"},
+		{"key 3", "12345678"},
+		{"key 4", "11111111"},
+	}
+	seen := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := KeyFingerprint(tt.key)
+			if len(fp) != 2*fingerprintSize {
+				t.Errorf("expected %d hex characters, got %q\n", 2*fingerprintSize, fp)
+			}
+			if again := KeyFingerprint(tt.key); again != fp {
+				t.Errorf("fingerprint not stable: %v: %s != %s\n", tt.name, fp, again)
+			}
+			if other, ok := seen[fp]; ok {
+				t.Errorf("keys %q and %q have the same fingerprint %s\n", other, tt.key, fp)
+			}
+			seen[fp] = tt.key
+		})
+	}
+	if fp := KeyFingerprint(""); fp != "" {
+		t.Errorf("expected empty fingerprint for a weak key, got %s\n", fp)
+	}
+}
+
+func Test_WithFingerprint(t *testing.T) {
+	key, wrongKey := "myKey123", "wrongKey"
+	e, err := Encrypt("Hello World!", key, WithFingerprint())
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	if d, err := Decrypt(e, key); err != nil || d != "Hello World!" {
+		t.Errorf("decrypt failed: %v, %v\n", d, err)
+	}
+
+	_, err = Decrypt(e, wrongKey)
+	var mismatch *KeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected *KeyMismatchError, got %v\n", err)
+	}
+	if mismatch.Expected != KeyFingerprint(key) || mismatch.Actual != KeyFingerprint(wrongKey) {
+		t.Errorf("expected fingerprints %s and %s, got %s and %s\n", KeyFingerprint(key), KeyFingerprint(wrongKey), mismatch.Expected, mismatch.Actual)
+	}
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected error to wrap ErrAuthenticationFailed: %v\n", err)
+	}
+
+	combined, err := Encrypt("Hello World!", key, WithFingerprint(), WithKeyCheck(), WithKeySize(16))
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	if d, err := Decrypt(combined, key, WithKeySize(16)); err != nil || d != "Hello World!" {
+		t.Errorf("decrypt with fingerprint, key check and key size failed: %v, %v\n", d, err)
+	}
+	if ok, err := VerifyKeyCiphertext(combined, key, WithKeySize(16)); !ok || err != nil {
+		t.Errorf("verify with fingerprint and key check failed: %v, %v\n", ok, err)
+	}
+	if _, err := Decrypt(combined, wrongKey, WithKeySize(16)); !errors.As(err, &mismatch) {
+		t.Errorf("expected *KeyMismatchError, got %v\n", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "fingerprint.txt")
+	if err := os.WriteFile(file, []byte("Hello World!"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(file, key, WithFingerprint()); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	if err := DecryptFile(file, wrongKey); !errors.As(err, &mismatch) {
+		t.Errorf("expected *KeyMismatchError when decrypting file, got %v\n", err)
+	}
+	if err := DecryptFile(file, key); err != nil {
+		t.Errorf("could not decrypt file: %s\n", err)
+	}
+}
@@ -0,0 +1,35 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// EncryptGob serializes 'value' with encoding/gob and encrypts the result like Encrypt with the provided key
+// and 'opts'. It returns the encoded ciphertext and any error encountered while serializing or encrypting.
+//
+// Gob is more compact than JSON for numbers and large numeric slices. Like JSON, gob only serializes
+// exported struct fields, types with unexported state must implement gob.GobEncoder and gob.GobDecoder.
+// Interface values inside 'value' require their concrete types to be registered with gob.Register.
+func EncryptGob(value any, key string, opts ...Option) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return "", fmt.Errorf("can't encode value: %w", err)
+	}
+	return Encrypt(buf.String(), key, opts...)
+}
+
+// DecryptGob decrypts 'ciphertext' like Decrypt with the provided key and 'opts' and deserializes the
+// result into 'dst', which must be a non-nil pointer to a type compatible with the encrypted value.
+// It returns an error if decryption or deserialization fails.
+func DecryptGob(ciphertext, key string, dst any, opts ...Option) error {
+	data, err := Decrypt(ciphertext, key, opts...)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewDecoder(bytes.NewReader([]byte(data))).Decode(dst); err != nil {
+		return fmt.Errorf("can't decode value: %w", err)
+	}
+	return nil
+}
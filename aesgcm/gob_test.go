@@ -0,0 +1,85 @@
+package aesgcm
+
+import (
+	"reflect"
+	"testing"
+)
+
+type gobRecord struct {
+	Name    string
+	Samples []float64
+	Counts  map[string]int
+	hidden  string
+}
+
+func Test_Gob(t *testing.T) {
+	samples := make([]float64, 1000)
+	for i := range samples {
+		samples[i] = float64(i) * 1.5
+	}
+	record := gobRecord{Name: "sensor", Samples: samples, Counts: map[string]int{"a": 1, "b": 2}, hidden: "not encoded"}
+
+	t.Run("struct", func(t *testing.T) {
+		e, err := EncryptGob(record, "myKey123")
+		if err != nil {
+			t.Fatalf("EncryptGob() error = %v\n", err)
+		}
+		var d gobRecord
+		if err := DecryptGob(e, "myKey123", &d); err != nil {
+			t.Fatalf("DecryptGob() error = %v\n", err)
+		}
+		want := record
+		want.hidden = ""
+		if !reflect.DeepEqual(d, want) {
+			t.Errorf("DecryptGob() = %+v, want %+v\n", d, want)
+		}
+		if err := DecryptGob(e, "wrongKey", &d); err == nil {
+			t.Errorf("DecryptGob() with the wrong key expected an error\n")
+		}
+	})
+
+	t.Run("pointer and options", func(t *testing.T) {
+		opts := []Option{WithAAD([]byte("record-1")), WithEncoding(Hex)}
+		e, err := EncryptGob(&record, "myKey123", opts...)
+		if err != nil {
+			t.Fatalf("EncryptGob() error = %v\n", err)
+		}
+		var d *gobRecord
+		if err := DecryptGob(e, "myKey123", &d, opts...); err != nil || d == nil || d.Name != record.Name {
+			t.Errorf("DecryptGob() = %+v, %v\n", d, err)
+		}
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		e, err := EncryptGob(samples, "myKey123")
+		if err != nil {
+			t.Fatalf("EncryptGob() error = %v\n", err)
+		}
+		var d []float64
+		if err := DecryptGob(e, "myKey123", &d); err != nil || !reflect.DeepEqual(d, samples) {
+			t.Errorf("DecryptGob() = %v, want the original samples\n", err)
+		}
+		j, _ := EncryptJSON(samples, "myKey123")
+		if len(e) >= len(j) {
+			t.Errorf("expected gob to be more compact than JSON: %d >= %d bytes\n", len(e), len(j))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := EncryptGob(nil, "myKey123"); err == nil {
+			t.Errorf("EncryptGob() of nil expected an error\n")
+		}
+		if _, err := EncryptGob(func() {}, "myKey123"); err == nil {
+			t.Errorf("EncryptGob() of a function expected an error\n")
+		}
+		e, _ := EncryptGob(record, "myKey123")
+		var d gobRecord
+		if err := DecryptGob(e, "myKey123", d); err == nil {
+			t.Errorf("DecryptGob() into a non-pointer expected an error\n")
+		}
+		var s string
+		if err := DecryptGob(e, "myKey123", &s); err == nil {
+			t.Errorf("DecryptGob() into an incompatible type expected an error\n")
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// headerVersion is the version of the ciphertext header written by EncryptBytes.
+// Version 0 is the headerless nonce||ciphertext format of earlier versions, which is still decrypted,
+// and version 1 the header without creation time.
+const headerVersion = 2
+
+// headerMagic identifies ciphertexts starting with a versioned header.
+var headerMagic = []byte("AGH")
+
+// Algorithm IDs recorded in the ciphertext header.
+const (
+	algAES128GCM = 1
+	algAES192GCM = 2
+	algAES256GCM = 3
+)
+
+// KDF IDs recorded in the ciphertext header.
+const (
+	kdfWeakKeyScrambler = 1 // keys.WeakKeyScrambler, no parameters
+	kdfCustom           = 2 // a function set with WithKDF, whose parameters are unknown to the package
+	kdfArgon2id         = 3 // EncryptWithPassword, the parameters are the Argon2 parameters and the salt
+	kdfPBKDF2           = 4 // EncryptWithPBKDF2, the parameters are the iteration count (uint32) and the salt
+	kdfScrypt           = 5 // EncryptWithScrypt, the parameters are log2(N), r and p (1 byte each) and the salt
+)
+
+// versionedHeader is a parsed ciphertext header.
+//
+// The format is stable, lengths are single bytes:
+//
+//	"AGH" (3 bytes) | version (1 byte) | algorithm ID (1 byte) | KDF ID (1 byte) | KDF parameter length P | KDF parameters (P bytes) | nonce length N | nonce (N bytes) | creation time (8 bytes)
+//
+// The creation time is recorded in milliseconds since the Unix epoch (int64, big-endian) from version 2 on.
+// The whole header is authenticated as additional data, followed by the AAD set with WithAAD, if any.
+type versionedHeader struct {
+	raw       []byte // the encoded header
+	version   byte
+	algorithm byte
+	kdf       byte
+	kdfParams []byte
+	nonce     []byte
+	created   time.Time // zero before version 2
+}
+
+// algorithmID returns the algorithm ID of the Cipher, which is determined by its key size.
+func (c *Cipher) algorithmID() byte {
+	switch c.opts.keySize {
+	case 16:
+		return algAES128GCM
+	case 24:
+		return algAES192GCM
+	}
+	return algAES256GCM
+}
+
+// kdfID returns the KDF ID of the Cipher.
+func (c *Cipher) kdfID() byte {
+	if c.opts.kdf != nil {
+		return kdfCustom
+	}
+	return kdfWeakKeyScrambler
+}
+
+// newHeader encodes the ciphertext header of the Cipher for 'nonce'.
+// The KDFs of a Cipher have no parameters that could be recorded.
+func (c *Cipher) newHeader(nonce []byte) []byte {
+	return appendHeader(nil, c.algorithmID(), c.kdfID(), nil, nonce, c.opts.clock.Now())
+}
+
+// appendHeader appends a ciphertext header of the current version to 'dst'.
+// 'kdfParams' and 'nonce' must not be longer than 255 bytes.
+func appendHeader(dst []byte, algorithm, kdf byte, kdfParams, nonce []byte, created time.Time) []byte {
+	dst = append(dst, headerMagic...)
+	dst = append(dst, headerVersion, algorithm, kdf, byte(len(kdfParams)))
+	dst = append(dst, kdfParams...)
+	dst = append(dst, byte(len(nonce)))
+	dst = append(dst, nonce...)
+	return binary.BigEndian.AppendUint64(dst, uint64(created.UnixMilli()))
+}
+
+// parseHeader parses the ciphertext header at the start of 'data' and reports whether there is one.
+// It returns an error wrapping ErrUnsupportedVersion for headers of newer versions
+// and an error wrapping ErrCorruptHeader if the header is truncated.
+func parseHeader(data []byte) (versionedHeader, bool, error) {
+	var h versionedHeader
+	if len(data) <= len(headerMagic) || !bytes.HasPrefix(data, headerMagic) || data[len(headerMagic)] == 0 {
+		return h, false, nil
+	}
+	h.version = data[len(headerMagic)]
+	if h.version > headerVersion {
+		return h, true, fmt.Errorf("%w: version %d, at most %d is supported", ErrUnsupportedVersion, h.version, headerVersion)
+	}
+	i := len(headerMagic) + 1
+	if len(data) < i+3 {
+		return h, true, fmt.Errorf("%w: ciphertext header truncated", ErrCorruptHeader)
+	}
+	h.algorithm, h.kdf = data[i], data[i+1]
+	i += 2
+	if n := int(data[i]); len(data) > i+n+1 {
+		h.kdfParams = data[i+1 : i+1+n]
+		i += 1 + n
+	} else {
+		return h, true, fmt.Errorf("%w: ciphertext header truncated", ErrCorruptHeader)
+	}
+	if n := int(data[i]); len(data) >= i+n+1 {
+		h.nonce = data[i+1 : i+1+n]
+		i += 1 + n
+	} else {
+		return h, true, fmt.Errorf("%w: ciphertext header truncated", ErrCorruptHeader)
+	}
+	if h.version >= 2 {
+		if len(data) < i+8 {
+			return h, true, fmt.Errorf("%w: ciphertext header truncated", ErrCorruptHeader)
+		}
+		h.created = time.UnixMilli(int64(binary.BigEndian.Uint64(data[i:])))
+		i += 8
+	}
+	h.raw = data[:i]
+	return h, true, nil
+}
+
+// checkHeader returns an error if the ciphertext described by 'h' can't have been produced by the Cipher.
+func (c *Cipher) checkHeader(h versionedHeader) error {
+	if h.algorithm != c.algorithmID() {
+		switch h.algorithm {
+		case algAES128GCM, algAES192GCM, algAES256GCM:
+			return fmt.Errorf("%w: ciphertext was encrypted with a %d-byte key", ErrKeySizeMismatch, 8+8*int(h.algorithm))
+		}
+		return fmt.Errorf("%w: unknown algorithm %d", ErrUnsupportedVersion, h.algorithm)
+	}
+	if h.kdf != c.kdfID() {
+		switch h.kdf {
+		case kdfWeakKeyScrambler:
+			return fmt.Errorf("%w: ciphertext was encrypted without WithKDF", ErrKeyDerivation)
+		case kdfCustom:
+			return fmt.Errorf("%w: ciphertext was encrypted with WithKDF", ErrKeyDerivation)
+		case kdfArgon2id:
+			return fmt.Errorf("%w: ciphertext was encrypted with a password, see DecryptWithPassword", ErrKeyDerivation)
+		case kdfPBKDF2:
+			return fmt.Errorf("%w: ciphertext was encrypted with a password, see DecryptWithPBKDF2", ErrKeyDerivation)
+		case kdfScrypt:
+			return fmt.Errorf("%w: ciphertext was encrypted with a password, see DecryptWithScrypt", ErrKeyDerivation)
+		}
+		return fmt.Errorf("%w: unknown key derivation %d", ErrUnsupportedVersion, h.kdf)
+	}
+	if len(h.nonce) != c.aead.NonceSize() {
+		return fmt.Errorf("%w: invalid nonce length %d", ErrCorruptHeader, len(h.nonce))
+	}
+	return nil
+}
+
+// seal encrypts 'plaintext' with a random nonce and returns header||ciphertext.
+func (c *Cipher) seal(plaintext []byte) ([]byte, error) {
+	nonce, err := randomNonce(c.aead, c.opts.rand, c.fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.padding > 0 {
+		plaintext = pad(plaintext, c.opts.padding)
+	}
+	header := c.newHeader(nonce)
+	return c.aead.Seal(header, nonce, plaintext, append(bytes.Clone(header), c.opts.aad...)), nil
+}
+
+// openVersioned decrypts header||ciphertext as written by seal, or nonce||ciphertext if there is no header,
+// and strips the padding set with WithPadding, if any.
+func (c *Cipher) openVersioned(data []byte) ([]byte, error) {
+	decrypted, err := c.openUnpadded(data)
+	if err != nil || c.opts.padding == 0 {
+		return decrypted, err
+	}
+	return unpad(decrypted, c.opts.padding)
+}
+
+// openUnpadded is openVersioned without stripping the padding.
+// Should the nonce of a ciphertext without header happen to start with the header magic, it is still decrypted.
+func (c *Cipher) openUnpadded(data []byte) ([]byte, error) {
+	h, ok, err := parseHeader(data)
+	if !ok {
+		return open(c.aead, data, c.opts.aad)
+	}
+	if err == nil {
+		err = c.checkHeader(h)
+	}
+	if err == nil {
+		var decrypted []byte
+		decrypted, err = c.aead.Open(nil, h.nonce, data[len(h.raw):], append(bytes.Clone(h.raw), c.opts.aad...))
+		if err == nil {
+			if c.created != nil {
+				*c.created = h.created
+			}
+			return decrypted, nil
+		}
+		err = fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+	if decrypted, legacyErr := open(c.aead, data, c.opts.aad); legacyErr == nil {
+		return decrypted, nil
+	}
+	return nil, err
+}
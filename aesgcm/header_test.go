@@ -0,0 +1,175 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/toxyl/keys"
+)
+
+func Test_versionedHeader(t *testing.T) {
+	nonce := []byte("0123456789ab")
+	kdf := func(key string) ([]byte, error) {
+		k, err := keys.WeakKeyScrambler(key + "!")
+		return []byte(k), err
+	}
+	tests := []struct {
+		name   string
+		opts   []Option
+		header string
+	}{
+		{"default", nil, "AGH\x02\x03\x01\x00\x0c"},
+		{"AES-128", []Option{WithKeySize(16)}, "AGK\x10AGH\x02\x01\x01\x00\x0c"},
+		{"AES-192", []Option{WithKeySize(24)}, "AGK\x18AGH\x02\x02\x01\x00\x0c"},
+		{"custom KDF", []Option{WithKDF(kdf)}, "AGH\x02\x03\x02\x00\x0c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("myKey123", append(tt.opts, WithRand(bytes.NewReader(nonce)))...)
+			if err != nil {
+				t.Fatalf("could not create cipher: %s\n", err)
+			}
+			e, err := c.EncryptBytes([]byte("Hello World!"))
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if want := append([]byte(tt.header), nonce...); !bytes.HasPrefix(e, want) {
+				t.Errorf("expected the header %q, got %q\n", want, e[:min(len(e), len(want))])
+			}
+			d, _ := New("myKey123", tt.opts...)
+			if p, err := d.DecryptBytes(e); err != nil || string(p) != "Hello World!" {
+				t.Errorf("DecryptBytes() = %q, %v\n", p, err)
+			}
+		})
+	}
+}
+
+func Test_versionedHeader_errors(t *testing.T) {
+	c, _ := New("myKey123")
+	e, _ := c.EncryptBytes([]byte("Hello World!"))
+	modified := func(i int, b byte) []byte {
+		m := bytes.Clone(e)
+		m[i] = b
+		return m
+	}
+	kdfCipher, _ := New("myKey123", WithKDF(func(key string) ([]byte, error) {
+		k, err := keys.WeakKeyScrambler(key)
+		return []byte(k), err
+	}))
+	withKDF, _ := kdfCipher.EncryptBytes([]byte("Hello World!"))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"newer version", modified(3, 3), ErrUnsupportedVersion},
+		{"unknown algorithm", modified(4, 9), ErrUnsupportedVersion},
+		{"other key size", modified(4, algAES128GCM), ErrKeySizeMismatch},
+		{"unknown KDF", modified(5, 9), ErrUnsupportedVersion},
+		{"other KDF", withKDF, ErrKeyDerivation},
+		{"nonce length", modified(7, 8), ErrCorruptHeader},
+		{"truncated", e[:6], ErrCorruptHeader},
+		{"truncated creation time", e[:24], ErrCorruptHeader},
+		{"tampered nonce", modified(8, e[8]^1), ErrAuthenticationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.DecryptBytes(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v\n", tt.want, err)
+			}
+		})
+	}
+}
+
+func Test_versionedHeader_legacy(t *testing.T) {
+	c, _ := New("myKey123")
+	random := make([]byte, 12)
+	_, _ = rand.Read(random)
+	// version 0 ciphertexts have no header, their nonce may start with the header magic by chance
+	for _, nonce := range [][]byte{random, []byte("AGH\x01\x03\x01\x00\x0c0123"), []byte("AGH\x07abcdefgh")} {
+		e, err := seal(c.aead, bytes.NewReader(nonce), c.fingerprint, []byte("Hello World!"), nil)
+		if err != nil {
+			t.Fatalf("could not seal: %s\n", err)
+		}
+		if p, err := c.DecryptBytes(e); err != nil || string(p) != "Hello World!" {
+			t.Errorf("DecryptBytes() of a version 0 ciphertext with nonce %q = %q, %v\n", nonce, p, err)
+		}
+	}
+}
+
+func Test_appendHeader(t *testing.T) {
+	h := appendHeader(nil, algAES256GCM, kdfScrypt, []byte{14, 8, 1}, []byte("0123456789ab"), time.UnixMilli(0x0102030405060708))
+	if want := "AGH\x02\x03\x05\x03\x0e\x08\x01\x0c0123456789ab\x01\x02\x03\x04\x05\x06\x07\x08"; string(h) != want {
+		t.Errorf("appendHeader() = %q, want %q\n", h, want)
+	}
+	p, ok, err := parseHeader(h)
+	if !ok || err != nil || p.kdf != kdfScrypt || !bytes.Equal(p.kdfParams, []byte{14, 8, 1}) || string(p.nonce) != "0123456789ab" {
+		t.Errorf("parseHeader() = %+v, %v, %v\n", p, ok, err)
+	}
+}
+
+func Test_passwordHeader(t *testing.T) {
+	defer func(p Argon2Params) { DefaultArgon2Params = p }(DefaultArgon2Params)
+	DefaultArgon2Params = Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1}
+	salt := bytes.Repeat([]byte{1}, saltSize)
+	legacy := func(params []byte, kc *keyCipher) string {
+		e, _ := kc.encrypt([]byte("Hello World!"), nil)
+		return base64.StdEncoding.EncodeToString(append(params, e...))
+	}
+	scryptCipher, _ := newScryptKeyCipher("myKey123", salt, 1<<14, 8, 1)
+
+	tests := []struct {
+		name    string
+		encrypt func() (string, error)
+		decrypt func(string) (string, error)
+		header  string // up to the salt
+		legacy  string
+	}{
+		{
+			"argon2id",
+			func() (string, error) { return EncryptWithPassword("Hello World!", "myKey123") },
+			func(e string) (string, error) { return DecryptWithPassword(e, "myKey123") },
+			"AGH\x02\x03\x03\x19\x00\x00\x00\x01\x00\x00\x20\x00\x01",
+			legacy(salt, newPasswordKeyCipher("myKey123", salt, DefaultArgon2Params)),
+		},
+		{
+			"pbkdf2",
+			func() (string, error) { return EncryptWithPBKDF2("Hello World!", "myKey123", MinPBKDF2Iterations) },
+			func(e string) (string, error) { return DecryptWithPBKDF2(e, "myKey123") },
+			"AGH\x02\x03\x04\x14\x00\x01\x86\xa0",
+			legacy(encodePBKDF2Params(MinPBKDF2Iterations, salt), newPBKDF2KeyCipher("myKey123", salt, MinPBKDF2Iterations)),
+		},
+		{
+			"scrypt",
+			func() (string, error) { return EncryptWithScrypt("Hello World!", "myKey123", 1<<14, 8, 1) },
+			func(e string) (string, error) { return DecryptWithScrypt(e, "myKey123") },
+			"AGH\x02\x03\x05\x13\x0e\x08\x01",
+			legacy(encodeScryptParams(1<<14, 8, 1, salt), scryptCipher),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := tt.encrypt()
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			raw, _ := base64.StdEncoding.DecodeString(e)
+			if !bytes.HasPrefix(raw, []byte(tt.header)) {
+				t.Errorf("expected the header %q, got %q\n", tt.header, raw[:min(len(raw), len(tt.header))])
+			} else if raw[len(tt.header)+saltSize] != 12 {
+				t.Errorf("expected the nonce length after the salt, got %d\n", raw[len(tt.header)+saltSize])
+			}
+			if d, err := tt.decrypt(e); err != nil || d != "Hello World!" {
+				t.Errorf("decrypt() = %q, %v\n", d, err)
+			}
+			if d, err := tt.decrypt(tt.legacy); err != nil || d != "Hello World!" {
+				t.Errorf("decrypt() of a legacy ciphertext = %q, %v\n", d, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// Metadata describes a ciphertext, as returned by Inspect.
+type Metadata struct {
+	Version     int       // version of the ciphertext header, 0 for headerless ciphertexts of earlier versions and streams
+	KeySize     int       // key size in bytes, 0 if unknown
+	Fingerprint string    // hex-encoded fingerprint of the key recorded with WithFingerprint, empty if none
+	KeyCheck    bool      // whether a key check value has been recorded with WithKeyCheck
+	Compressed  bool      // whether the plaintext has been compressed with WithCompression
+	Stream      bool      // whether the ciphertext is in the chunked format of EncryptStream and EncryptFile
+	Created     time.Time // creation time recorded in the header, zero for ciphertexts without one
+	Expiry      time.Time // expiry set with EncryptWithTTL, zero if none
+}
+
+// Inspect returns the metadata recorded in the given encoded encrypted text without decrypting it, so no key is needed.
+// Only WithEncoding is taken into account from 'opts'. The metadata isn't authenticated until the ciphertext has been
+// decrypted, so it must not be relied on for anything but diagnostics, see DecryptWithMaxAge to enforce the creation time.
+// It returns an error if the text can't be decoded or the header is corrupt or of a newer version.
+func Inspect(ciphertext string, opts ...Option) (Metadata, error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return Metadata{}, err
+	}
+	data, err := decodeCiphertext(ciphertext, o.encoding)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return InspectBytes(data)
+}
+
+// InspectBytes returns the metadata recorded in the given raw ciphertext bytes, as produced by EncryptBytes.
+// See Inspect for details.
+func InspectBytes(data []byte) (Metadata, error) {
+	var m Metadata
+	if bytes.HasPrefix(data, expiryMagic) && len(data) >= expiryHeaderSize {
+		m.Expiry = time.UnixMilli(int64(binary.BigEndian.Uint64(data[len(expiryMagic):])))
+		data = data[expiryHeaderSize:]
+	}
+	if magic := compressionMagic(data); magic != nil {
+		m.Compressed = !bytes.Equal(magic, storedMagic)
+		data = data[len(magic):]
+	}
+	if size, ok := parseKeySizeHeader(data); ok {
+		m.KeySize = size
+		data = data[keySizeHeaderSize:]
+	}
+	if fp, ok := parseFingerprintHeader(data); ok {
+		m.Fingerprint = hex.EncodeToString(fp)
+		data = data[fingerprintHeaderSize:]
+	}
+	if hasKeyCheckHeader(data) {
+		m.KeyCheck = true
+		data = data[min(len(data), keyCheckHeaderSize):]
+	}
+	if bytes.HasPrefix(data, streamMagic) {
+		// the key size of streams is only known for files with a non-default key size
+		m.Stream = true
+		return m, nil
+	}
+	h, ok, err := parseHeader(data)
+	if err != nil || !ok {
+		if m.KeySize == 0 {
+			m.KeySize = DefaultKeySize
+		}
+		return m, err
+	}
+	m.Version = int(h.version)
+	m.KeySize = 8 + 8*int(h.algorithm)
+	m.Created = h.created
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Inspect(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	file := func(opts ...Option) string {
+		c, _ := New("myKey123", opts...)
+		var buf bytes.Buffer
+		_ = c.encryptTo(&buf, bytes.NewReader([]byte("Hello World!")))
+		return StdBase64.EncodeToString(buf.Bytes())
+	}
+	var stream bytes.Buffer
+	_ = EncryptStream(&stream, bytes.NewReader([]byte("Hello World!")), "myKey123")
+	encrypt := func(opts ...Option) string {
+		e, err := Encrypt("Hello World!", "myKey123", append(opts, clock)...)
+		if err != nil {
+			t.Fatalf("could not encrypt: %s\n", err)
+		}
+		return e
+	}
+	ttl, _ := EncryptWithTTL("Hello World!", "myKey123", time.Hour, clock, WithCompression(Gzip))
+
+	tests := []struct {
+		name string
+		text string
+		want Metadata
+	}{
+		{"default", encrypt(), Metadata{Version: 2, KeySize: 32, Created: now}},
+		{"headers", encrypt(WithKeySize(16), WithFingerprint(), WithKeyCheck()), Metadata{Version: 2, KeySize: 16, Fingerprint: KeyFingerprint("myKey123"), KeyCheck: true, Created: now}},
+		{"compressed", encrypt(WithCompression(Zstd)), Metadata{Version: 2, KeySize: 32, Created: now}},
+		{"expiry", ttl, Metadata{Version: 2, KeySize: 32, Created: now, Expiry: now.Add(time.Hour)}},
+		{"file", file(WithKeySize(24)), Metadata{KeySize: 24, Stream: true}},
+		{"stream", StdBase64.EncodeToString(stream.Bytes()), Metadata{Stream: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect(tt.text)
+			if err != nil {
+				t.Fatalf("Inspect() error = %v\n", err)
+			}
+			// short plaintexts are stored uncompressed
+			if got.Version != tt.want.Version || got.KeySize != tt.want.KeySize || got.Fingerprint != tt.want.Fingerprint ||
+				got.KeyCheck != tt.want.KeyCheck || got.Stream != tt.want.Stream || !got.Created.Equal(tt.want.Created) || !got.Expiry.Equal(tt.want.Expiry) {
+				t.Errorf("Inspect() = %+v, want %+v\n", got, tt.want)
+			}
+		})
+	}
+
+	compressed, _ := Encrypt(string(bytes.Repeat([]byte("Hello World!"), 100)), "myKey123", WithCompression(Zstd))
+	if m, _ := Inspect(compressed); !m.Compressed {
+		t.Errorf("Inspect() = %+v, want Compressed\n", m)
+	}
+	raw, _ := Encrypt("Hello World!", "myKey123", WithEncoding(RawURL))
+	if m, err := Inspect(raw, WithEncoding(RawURL)); err != nil || m.Version != headerVersion {
+		t.Errorf("Inspect() with WithEncoding = %+v, %v\n", m, err)
+	}
+	if _, err := Inspect("not base64!"); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Inspect() of an invalid encoding error = %v, want ErrInvalidEncoding\n", err)
+	}
+	if _, err := InspectBytes([]byte("AGH\x09")); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("InspectBytes() of a newer version error = %v, want ErrUnsupportedVersion\n", err)
+	}
+}
@@ -0,0 +1,33 @@
+package aesgcm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EncryptJSON marshals 'value' to JSON and encrypts the result like Encrypt with the provided key and 'opts'.
+// It returns the encoded ciphertext and any error encountered while marshalling or encrypting.
+// A nil pointer, slice or map is marshalled to "null".
+func EncryptJSON[T any](value T, key string, opts ...Option) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal value: %w", err)
+	}
+	return Encrypt(string(data), key, opts...)
+}
+
+// DecryptJSON decrypts 'ciphertext' like Decrypt with the provided key and 'opts' and unmarshals the
+// resulting JSON into a value of type T. It returns the zero value of T and an error if decryption
+// or unmarshalling fails. A ciphertext of "null" yields the zero value of T, e.g. a nil pointer.
+func DecryptJSON[T any](ciphertext, key string, opts ...Option) (T, error) {
+	var value T
+	data, err := Decrypt(ciphertext, key, opts...)
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		var zero T
+		return zero, fmt.Errorf("can't unmarshal value: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,81 @@
+package aesgcm
+
+import (
+	"reflect"
+	"testing"
+)
+
+type jsonRecord struct {
+	Name  string            `json:"name"`
+	Port  int               `json:"port"`
+	Tags  []string          `json:"tags"`
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+func Test_JSON(t *testing.T) {
+	record := jsonRecord{Name: "db", Port: 5432, Tags: []string{"prod", "eu"}, Extra: map[string]string{"tls": "on"}}
+
+	t.Run("struct", func(t *testing.T) {
+		e, err := EncryptJSON(record, "myKey123")
+		if err != nil {
+			t.Fatalf("EncryptJSON() error = %v\n", err)
+		}
+		d, err := DecryptJSON[jsonRecord](e, "myKey123")
+		if err != nil || !reflect.DeepEqual(d, record) {
+			t.Errorf("DecryptJSON() = %+v, %v, want %+v\n", d, err, record)
+		}
+		if _, err := DecryptJSON[jsonRecord](e, "wrongKey"); err == nil {
+			t.Errorf("DecryptJSON() with the wrong key expected an error\n")
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		e, err := EncryptJSON(&record, "myKey123", WithAAD([]byte("record-1")))
+		if err != nil {
+			t.Fatalf("EncryptJSON() error = %v\n", err)
+		}
+		d, err := DecryptJSON[*jsonRecord](e, "myKey123", WithAAD([]byte("record-1")))
+		if err != nil || d == nil || !reflect.DeepEqual(*d, record) {
+			t.Errorf("DecryptJSON() = %+v, %v, want %+v\n", d, err, record)
+		}
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		var p *jsonRecord
+		e, err := EncryptJSON(p, "myKey123")
+		if err != nil {
+			t.Fatalf("EncryptJSON() error = %v\n", err)
+		}
+		if d, _ := Decrypt(e, "myKey123"); d != "null" {
+			t.Errorf("expected a nil pointer to marshal to null, got %q\n", d)
+		}
+		d, err := DecryptJSON[*jsonRecord](e, "myKey123")
+		if err != nil || d != nil {
+			t.Errorf("DecryptJSON() = %+v, %v, want nil\n", d, err)
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		var v any
+		e, err := EncryptJSON(v, "myKey123")
+		if err != nil {
+			t.Fatalf("EncryptJSON() error = %v\n", err)
+		}
+		if d, err := DecryptJSON[any](e, "myKey123"); err != nil || d != nil {
+			t.Errorf("DecryptJSON() = %v, %v, want nil\n", d, err)
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		e, _ := EncryptJSON([]int{1, 2, 3}, "myKey123")
+		if d, err := DecryptJSON[jsonRecord](e, "myKey123"); err == nil || !reflect.DeepEqual(d, jsonRecord{}) {
+			t.Errorf("DecryptJSON() = %+v, %v, want the zero value and an error\n", d, err)
+		}
+	})
+
+	t.Run("unsupported value", func(t *testing.T) {
+		if _, err := EncryptJSON(make(chan int), "myKey123"); err == nil {
+			t.Errorf("EncryptJSON() of a channel expected an error\n")
+		}
+	})
+}
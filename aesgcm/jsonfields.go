@@ -0,0 +1,237 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EncryptJSONFields encrypts the values of the given 'fields' of the JSON document 'data' like Encrypt with the
+// provided key and returns the document with the encoded ciphertexts as strings in place of the values. Everything
+// else, including the formatting and the order of the keys, is copied unchanged, so partially encrypted documents
+// can still be stored and queried by their other fields.
+//
+// A field is a dot-separated path of object keys, like "user.address.street". A segment selects an array element
+// by its index, like "items.0.price", or all elements with "#", like "items.#.price". Dots and backslashes that are
+// part of a key are escaped with a backslash. A value is encrypted as a whole, whether it is a string, a number,
+// an object or any other JSON value. Fields that don't exist in the document are ignored.
+//
+// It returns an error if 'data' isn't valid JSON or a field path is empty, and a *JSONFieldError wrapping
+// the error of the value if a value can't be encrypted. Every value is encrypted on its own and isn't bound to
+// its path, so values can be moved within the document without failing to decrypt.
+func EncryptJSONFields(data []byte, key string, fields []string, opts ...Option) ([]byte, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.EncryptJSONFields(data, fields)
+}
+
+// DecryptJSONFields decrypts the values of the given 'fields' of the JSON document 'data', as written by
+// EncryptJSONFields, like Decrypt with the provided key and returns the document with the original values
+// in place of the ciphertexts. See EncryptJSONFields for how fields are selected. A selected value that isn't
+// a string fails with a *JSONFieldError, as does a ciphertext that fails to decrypt.
+func DecryptJSONFields(data []byte, key string, fields []string, opts ...Option) ([]byte, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.DecryptJSONFields(data, fields)
+}
+
+// EncryptJSONFields encrypts the values of the given fields of the JSON document 'data'.
+// See the package-level EncryptJSONFields for details.
+func (c *Cipher) EncryptJSONFields(data []byte, fields []string) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return mapJSONFields(data, fields, func(raw []byte) ([]byte, error) {
+		e, err := c.Encrypt(string(raw))
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(e)
+	})
+}
+
+// DecryptJSONFields decrypts the values of the given fields of the JSON document 'data'.
+// See the package-level DecryptJSONFields for details.
+func (c *Cipher) DecryptJSONFields(data []byte, fields []string) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return mapJSONFields(data, fields, func(raw []byte) ([]byte, error) {
+		var e string
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, errors.New("value is not an encrypted string")
+		}
+		d, err := c.Decrypt(e)
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(d)) {
+			return nil, errors.New("decrypted value is not valid JSON")
+		}
+		return []byte(d), nil
+	})
+}
+
+// jsonStep is a segment of the path of a JSON value: an object key or, if 'index' isn't negative, an array index.
+type jsonStep struct {
+	key   string
+	index int
+}
+
+// jsonValue is the position of a selected value in a JSON document.
+type jsonValue struct {
+	start, end int
+	path       string
+}
+
+// mapJSONFields replaces the values of 'fields' in the JSON document 'data' with the result of 'fn' for their raw JSON.
+func mapJSONFields(data []byte, fields []string, fn func(raw []byte) ([]byte, error)) ([]byte, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON document")
+	}
+	paths := make([][]string, 0, len(fields))
+	for _, f := range fields {
+		if f == "" {
+			return nil, fmt.Errorf("field path must not be empty")
+		}
+		paths = append(paths, splitFieldPath(f))
+	}
+
+	w := &jsonWalker{dec: json.NewDecoder(bytes.NewReader(data)), fields: paths}
+	if err := w.walk(nil); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	last := 0
+	for _, v := range w.found {
+		replaced, err := fn(data[v.start:v.end])
+		if err != nil {
+			return nil, &JSONFieldError{Path: v.path, Err: err}
+		}
+		out = append(out, data[last:v.start]...)
+		out = append(out, replaced...)
+		last = v.end
+	}
+	return append(out, data[last:]...), nil
+}
+
+// splitFieldPath splits the field path 'field' at the dots that aren't escaped with a backslash.
+func splitFieldPath(field string) []string {
+	var segments []string
+	var seg strings.Builder
+	for i := 0; i < len(field); i++ {
+		switch {
+		case field[i] == '\\' && i+1 < len(field):
+			i++
+			seg.WriteByte(field[i])
+		case field[i] == '.':
+			segments = append(segments, seg.String())
+			seg.Reset()
+		default:
+			seg.WriteByte(field[i])
+		}
+	}
+	return append(segments, seg.String())
+}
+
+// jsonWalker finds the positions of the values selected by 'fields' in the document read by 'dec'.
+type jsonWalker struct {
+	dec    *json.Decoder
+	fields [][]string
+	found  []jsonValue
+}
+
+// match reports whether 'path' is one of the fields and whether it is the prefix of a longer one.
+func (w *jsonWalker) match(path []jsonStep) (full, prefix bool) {
+	for _, f := range w.fields {
+		if len(f) < len(path) {
+			continue
+		}
+		ok := true
+		for i, step := range path {
+			if step.index < 0 {
+				ok = f[i] == step.key
+			} else {
+				ok = f[i] == "#" || f[i] == strconv.Itoa(step.index)
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			full = full || len(f) == len(path)
+			prefix = prefix || len(f) > len(path)
+		}
+	}
+	return full, prefix
+}
+
+// walk reads the next value of the document, found at 'path', and records it or the selected values inside of it.
+func (w *jsonWalker) walk(path []jsonStep) error {
+	full, prefix := false, true
+	if len(path) > 0 {
+		full, prefix = w.match(path)
+	}
+	if full || !prefix {
+		var raw json.RawMessage
+		if err := w.dec.Decode(&raw); err != nil {
+			return err
+		}
+		if full {
+			end := int(w.dec.InputOffset())
+			w.found = append(w.found, jsonValue{start: end - len(raw), end: end, path: formatFieldPath(path)})
+		}
+		return nil
+	}
+
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for w.dec.More() {
+			key, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := w.walk(append(path[:len(path):len(path)], jsonStep{key: key.(string), index: -1})); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; w.dec.More(); i++ {
+			if err := w.walk(append(path[:len(path):len(path)], jsonStep{index: i})); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	_, err = w.dec.Token()
+	return err
+}
+
+// formatFieldPath returns 'path' as a dot-separated field path.
+func formatFieldPath(path []jsonStep) string {
+	segments := make([]string, len(path))
+	for i, step := range path {
+		if step.index < 0 {
+			segments[i] = strings.NewReplacer(`\`, `\\`, ".", `\.`).Replace(step.key)
+		} else {
+			segments[i] = strconv.Itoa(step.index)
+		}
+	}
+	return strings.Join(segments, ".")
+}
@@ -0,0 +1,93 @@
+package aesgcm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_EncryptJSONFields(t *testing.T) {
+	doc := `{
+  "id": 42,
+  "user": {"name": "Alice", "email": "alice@example.com", "address": {"street": "Main St. 1", "zip": 12345}},
+  "items": [{"sku": "a", "price": 9.99}, {"sku": "b", "price": 1e3}],
+  "a.b": [1, 2, 3],
+  "tags": null
+}`
+	fields := []string{"user.email", "user.address", "items.#.price", `a\.b`, "tags", "missing.field", "id.nested"}
+
+	encrypted, err := EncryptJSONFields([]byte(doc), "myKey123", fields)
+	if err != nil {
+		t.Fatalf("EncryptJSONFields() error = %v\n", err)
+	}
+	var got struct {
+		ID    int
+		User  map[string]any
+		Items []map[string]any
+		AB    any `json:"a.b"`
+		Tags  any
+	}
+	if err := json.Unmarshal(encrypted, &got); err != nil {
+		t.Fatalf("EncryptJSONFields() returned invalid JSON: %v\n%s\n", err, encrypted)
+	}
+	if got.ID != 42 || got.User["name"] != "Alice" || got.Items[1]["sku"] != "b" {
+		t.Errorf("expected the other fields to be unchanged: %s\n", encrypted)
+	}
+	for _, v := range []any{got.User["email"], got.User["address"], got.Items[0]["price"], got.Items[1]["price"], got.AB, got.Tags} {
+		if s, ok := v.(string); !ok || strings.Contains(doc, s) {
+			t.Errorf("expected %v to be encrypted\n", v)
+		}
+	}
+	if !strings.HasPrefix(string(encrypted), "{\n  \"id\": 42,\n  \"user\": {\"name\": \"Alice\", \"email\": \"") {
+		t.Errorf("expected the formatting to be kept: %s\n", encrypted)
+	}
+
+	decrypted, err := DecryptJSONFields(encrypted, "myKey123", fields)
+	if err != nil {
+		t.Fatalf("DecryptJSONFields() error = %v\n", err)
+	}
+	if string(decrypted) != doc {
+		t.Errorf("DecryptJSONFields() = %s, want %s\n", decrypted, doc)
+	}
+
+	// a single array element
+	encrypted, _ = EncryptJSONFields([]byte(doc), "myKey123", []string{"items.1.sku"})
+	if err := json.Unmarshal(encrypted, &got); err != nil || got.Items[0]["sku"] != "a" || got.Items[1]["sku"] == "b" {
+		t.Errorf("EncryptJSONFields() of an array element = %s, %v\n", encrypted, err)
+	}
+
+	var fieldErr *JSONFieldError
+	_, err = DecryptJSONFields(encrypted, "wrongKey", []string{"items.1.sku"})
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "items.1.sku" || !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptJSONFields() with the wrong key error = %v, want a *JSONFieldError for items.1.sku\n", err)
+	}
+	_, err = DecryptJSONFields(encrypted, "myKey123", []string{"id"})
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "id" {
+		t.Errorf("DecryptJSONFields() of an unencrypted field error = %v, want a *JSONFieldError for id\n", err)
+	}
+}
+
+func Test_EncryptJSONFields_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		fields []string
+	}{
+		{"invalid JSON", `{"a": 1`, []string{"a"}},
+		{"trailing data", `{"a": 1} {}`, []string{"a"}},
+		{"empty path", `{"a": 1}`, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncryptJSONFields([]byte(tt.data), "myKey123", tt.fields); err == nil {
+				t.Errorf("EncryptJSONFields() expected an error\n")
+			}
+		})
+	}
+
+	// without fields, the document is copied
+	if out, err := EncryptJSONFields([]byte(` [1, {"a": "b"}] `), "myKey123", nil); err != nil || string(out) != ` [1, {"a": "b"}] ` {
+		t.Errorf("EncryptJSONFields() without fields = %q, %v\n", out, err)
+	}
+}
@@ -0,0 +1,135 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// keyCheckHeaderSize is the length of the key check header: magic + nonce + tag + checksum.
+const keyCheckHeaderSize = 4 + 12 + 16 + 4
+
+// keyCheckMagic identifies the key check header.
+var keyCheckMagic = []byte("AGKV")
+
+// WithKeyCheck prefixes ciphertexts with a key check value, which allows VerifyKey and VerifyKeyCiphertext
+// to tell whether a key is correct without decrypting the payload.
+//
+// The check value is a GCM authentication tag over a fixed constant under the derived key with its own
+// random nonce, so it reveals nothing about the key beyond whether a candidate key matches.
+// Decryption detects the header automatically. It can only be used for encryption.
+func WithKeyCheck() Option {
+	return func(o *options) error {
+		o.keyCheck = true
+		o.encryptOnly = append(o.encryptOnly, "WithKeyCheck")
+		return nil
+	}
+}
+
+// keyCheckHeader creates the key check header for the key of 'aesGCM'.
+func keyCheckHeader(aesGCM cipher.AEAD, random io.Reader, keyHash []byte) ([]byte, error) {
+	check, err := seal(aesGCM, random, keyHash, nil, keyCheckMagic)
+	if err != nil {
+		return nil, err
+	}
+	header := append(append(make([]byte, 0, keyCheckHeaderSize), keyCheckMagic...), check...)
+	return binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(header)), nil
+}
+
+// hasKeyCheckHeader returns whether 'data' starts with the key check magic.
+func hasKeyCheckHeader(data []byte) bool {
+	return bytes.HasPrefix(data, keyCheckMagic)
+}
+
+// verifyKeyCheck verifies the key check header at the start of 'data' against the key of 'aesGCM'.
+// It returns an error wrapping ErrCorruptHeader if the header is truncated or damaged
+// and an error wrapping ErrAuthenticationFailed if the key is wrong.
+func verifyKeyCheck(aesGCM cipher.AEAD, data []byte) error {
+	if len(data) < keyCheckHeaderSize {
+		return fmt.Errorf("%w: key check header truncated", ErrCorruptHeader)
+	}
+	header := data[:keyCheckHeaderSize-4]
+	if crc32.ChecksumIEEE(header) != binary.BigEndian.Uint32(data[keyCheckHeaderSize-4:]) {
+		return fmt.Errorf("%w: key check header checksum mismatch", ErrCorruptHeader)
+	}
+	if _, err := open(aesGCM, header[len(keyCheckMagic):], keyCheckMagic); err != nil {
+		return fmt.Errorf("wrong key: %w", err)
+	}
+	return nil
+}
+
+// verifyKey checks the key of the Cipher against the data read from 'r', which may start with a key check header
+// or hold a stream. Only the headers and, for streams without key check header, the first chunk are read.
+// It returns false and an error wrapping ErrNoKeyCheck if the data holds neither.
+func (c *Cipher) verifyKey(r io.Reader) (bool, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(maxPrefixSize + len(streamMagic))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	p, err := c.parsePrefix(head)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case p.keyCheck != nil:
+		err = verifyKeyCheck(c.aead, p.keyCheck)
+	case bytes.HasPrefix(head[p.size:], streamMagic):
+		_, _ = br.Discard(p.size)
+		err = verifyStreamKey(c.aead, br, c.opts.aad)
+	default:
+		return false, ErrNoKeyCheck
+	}
+	return err == nil, c.keyMismatch(p.fingerprint, err)
+}
+
+// VerifyKeyCiphertext checks whether 'key' is the key the encoded ciphertext was encrypted with,
+// without decrypting the payload. The ciphertext must have been encrypted with WithKeyCheck.
+//
+// It returns true if the key matches. Otherwise it returns false and an error wrapping
+// ErrAuthenticationFailed for a wrong key, ErrCorruptHeader for a damaged key check value or
+// ErrNoKeyCheck for ciphertexts without a key check value, for which the result is unknown.
+func VerifyKeyCiphertext(ciphertext, key string, opts ...Option) (bool, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+	data, err := c.decodeCiphertext(ciphertext)
+	if err != nil {
+		return false, err
+	}
+	return c.verifyKey(bytes.NewReader(data))
+}
+
+// VerifyKey checks whether 'key' is the key the file located at 'path' was encrypted with.
+// Only the start of the file is read: the key check value of files encrypted with WithKeyCheck
+// or the first chunk of files in the chunked format written by EncryptFile and EncryptStream,
+// so the check is cheap even for very large files.
+//
+// It returns true if the key matches. Otherwise it returns false and an error wrapping
+// ErrAuthenticationFailed for a wrong key, ErrCorruptHeader for a damaged key check value,
+// ErrNoKeyCheck for files without a key check value, for which the result is unknown,
+// or ErrFileNotFound if the file doesn't exist.
+func VerifyKey(path, key string, opts ...Option) (bool, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, errFileNotFound("verify", path)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	return c.verifyKey(f)
+}
@@ -0,0 +1,140 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_VerifyKeyCiphertext(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+		opts []Option
+	}{
+		{"default", "Hello World!", "myKey123", nil},
+		{"empty", "", "12345678", nil},
+		{"key size 16", "Hello World!", "11111111", []Option{WithKeySize(16)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt(tt.text, tt.key, append([]Option{WithKeyCheck()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if ok, err := VerifyKeyCiphertext(e, tt.key, tt.opts...); !ok || err != nil {
+				t.Errorf("verify with correct key failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := VerifyKeyCiphertext(e, tt.key+"!", tt.opts...); ok || !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("verify with wrong key: %v: expected false and ErrAuthenticationFailed, got %v, %v\n", tt.name, ok, err)
+			}
+			d, err := Decrypt(e, tt.key, tt.opts...)
+			if err != nil {
+				t.Fatalf("could not decrypt: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("decrypt failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := Decrypt(e, tt.key+"!", tt.opts...); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("decrypt with wrong key: %v: expected ErrAuthenticationFailed, got %v\n", tt.name, err)
+			}
+		})
+	}
+}
+
+func Test_VerifyKeyCiphertext_errors(t *testing.T) {
+	key := "myKey123"
+	legacy, err := Encrypt("Hello World!", key)
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	if ok, err := VerifyKeyCiphertext(legacy, key); ok || !errors.Is(err, ErrNoKeyCheck) {
+		t.Errorf("verify without key check: expected false and ErrNoKeyCheck, got %v, %v\n", ok, err)
+	}
+
+	e, err := Encrypt("Hello World!", key, WithKeyCheck())
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(e)
+	for _, i := range []int{5, 20, keyCheckHeaderSize - 1} {
+		corrupt := bytes.Clone(raw)
+		corrupt[i] ^= 0xff
+		if ok, err := VerifyKeyCiphertext(base64.StdEncoding.EncodeToString(corrupt), key); ok || !errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("verify with corrupt byte %d: expected false and ErrCorruptHeader, got %v, %v\n", i, ok, err)
+		}
+	}
+	truncated := base64.StdEncoding.EncodeToString(raw[:keyCheckHeaderSize-1])
+	if ok, err := VerifyKeyCiphertext(truncated, key); ok || !errors.Is(err, ErrCorruptHeader) {
+		t.Errorf("verify truncated header: expected false and ErrCorruptHeader, got %v, %v\n", ok, err)
+	}
+	if _, err := Decrypt(e, key, WithKeyCheck()); err == nil {
+		t.Errorf("decrypt with encrypt-only option WithKeyCheck succeeded\n")
+	}
+}
+
+func Test_VerifyKey(t *testing.T) {
+	dir := t.TempDir()
+	key := "myKey123"
+	plain := bytes.Repeat([]byte("Hello World!"), 10000)
+
+	checked := filepath.Join(dir, "checked.txt")
+	if err := os.WriteFile(checked, plain, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(checked, key, WithKeyCheck()); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+
+	legacy := filepath.Join(dir, "legacy.txt")
+	e, _ := EncryptRaw(plain, key)
+	if err := os.WriteFile(legacy, e, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	chunked := filepath.Join(dir, "chunked.txt")
+	if err := os.WriteFile(chunked, plain, 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(chunked, key); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncryptStream(&buf, bytes.NewReader(plain), key); err != nil {
+		t.Fatalf("could not encrypt stream: %s\n", err)
+	}
+	stream := filepath.Join(dir, "stream.bin")
+	if err := os.WriteFile(stream, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		key     string
+		want    bool
+		wantErr error
+	}{
+		{"key check, correct key", checked, key, true, nil},
+		{"key check, wrong key", checked, "wrongKey", false, ErrAuthenticationFailed},
+		{"stream, correct key", stream, key, true, nil},
+		{"stream, wrong key", stream, "wrongKey", false, ErrAuthenticationFailed},
+		{"chunked, correct key", chunked, key, true, nil},
+		{"chunked, wrong key", chunked, "wrongKey", false, ErrAuthenticationFailed},
+		{"legacy", legacy, key, false, ErrNoKeyCheck},
+		{"missing", filepath.Join(dir, "missing.txt"), key, false, ErrFileNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyKey(tt.path, tt.key)
+			if ok != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyKey() = %v, %v, expected %v, %v\n", ok, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"unicode/utf8"
+)
+
+// MinGeneratedKeyBytes is the minimum amount of entropy, in bytes, GenerateKey and GenerateKeyFromAlphabet accept.
+const MinGeneratedKeyBytes = 16
+
+// GenerateKey returns a key made from 'length' bytes read from crypto/rand, base64-encoded,
+// which can be passed straight into Encrypt and the other functions of this package.
+// It returns an error wrapping ErrWeakKey if 'length' is below MinGeneratedKeyBytes.
+//
+// Keys are passed through keys.WeakKeyScrambler, which derives the AES key from them without
+// stretching. This is fine for random keys, but also means entropy beyond the 32 bytes of the
+// derived AES key doesn't add any strength, so lengths between 16 and 32 are the sensible range.
+func GenerateKey(length int) (string, error) {
+	if length < MinGeneratedKeyBytes {
+		return "", fmt.Errorf("%w: %d bytes of entropy requested, at least %d are required", ErrWeakKey, length, MinGeneratedKeyBytes)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// MustGenerateKey is like GenerateKey but panics if the key can't be generated.
+// It is meant for init-time use.
+func MustGenerateKey(length int) string {
+	key, err := GenerateKey(length)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// GenerateKeyFromAlphabet returns a key of 'length' characters chosen uniformly at random from 'alphabet'
+// using crypto/rand. It returns an error wrapping ErrWeakKey if the key would hold less than
+// MinGeneratedKeyBytes bytes of entropy, and an error if the alphabet has fewer than two distinct characters.
+// See GenerateKey for how the key interacts with keys.WeakKeyScrambler.
+func GenerateKeyFromAlphabet(length int, alphabet string) (string, error) {
+	symbols := []rune{}
+	seen := map[rune]bool{}
+	for _, r := range alphabet {
+		if !seen[r] {
+			seen[r] = true
+			symbols = append(symbols, r)
+		}
+	}
+	if len(symbols) < 2 || !utf8.ValidString(alphabet) {
+		return "", fmt.Errorf("invalid alphabet, at least two distinct characters are required")
+	}
+	if bits := float64(length) * math.Log2(float64(len(symbols))); bits < 8*MinGeneratedKeyBytes {
+		return "", fmt.Errorf("%w: %d characters of a %d-character alphabet hold %.0f bits of entropy, at least %d are required", ErrWeakKey, length, len(symbols), bits, 8*MinGeneratedKeyBytes)
+	}
+	size := big.NewInt(int64(len(symbols)))
+	key := make([]rune, length)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		key[i] = symbols[n.Int64()]
+	}
+	return string(key), nil
+}
@@ -0,0 +1,125 @@
+package aesgcm
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_GenerateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		wantErr bool
+	}{
+		{"too short", MinGeneratedKeyBytes - 1, true},
+		{"negative", -1, true},
+		{"minimum", MinGeneratedKeyBytes, false},
+		{"32 bytes", 32, false},
+		{"64 bytes", 64, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateKey(tt.length)
+			if tt.wantErr {
+				if !errors.Is(err, ErrWeakKey) {
+					t.Errorf("expected ErrWeakKey, got %v\n", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not generate key: %s\n", err)
+			}
+			b, err := base64.StdEncoding.DecodeString(key)
+			if err != nil || len(b) != tt.length {
+				t.Errorf("expected %d base64-encoded bytes, got %d (%v)\n", tt.length, len(b), err)
+			}
+			e, err := Encrypt("Hello World!", key)
+			if err != nil {
+				t.Fatalf("could not encrypt with generated key: %s\n", err)
+			}
+			if d, err := Decrypt(e, key); err != nil || d != "Hello World!" {
+				t.Errorf("encrypt/decrypt with generated key failed: %v, %v\n", d, err)
+			}
+		})
+	}
+}
+
+func Test_GenerateKey_unique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 10000; i++ {
+		key := MustGenerateKey(MinGeneratedKeyBytes)
+		if seen[key] {
+			t.Fatalf("duplicate key after %d keys: %s\n", i, key)
+		}
+		seen[key] = true
+	}
+}
+
+func Test_MustGenerateKey_panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected MustGenerateKey to panic for a short length\n")
+		}
+	}()
+	MustGenerateKey(1)
+}
+
+func Test_GenerateKeyFromAlphabet(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		alphabet string
+		wantErr  bool
+	}{
+		{"hex", 32, "0123456789abcdef", false},
+		{"hex too short", 31, "0123456789abcdef", true},
+		{"binary", 128, "01", false},
+		{"unicode", 50, "äöüß€µ", false},
+		{"single character", 1000, "aaaa", true},
+		{"empty alphabet", 32, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateKeyFromAlphabet(tt.length, tt.alphabet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateKeyFromAlphabet() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if n := utf8.RuneCountInString(key); n != tt.length {
+				t.Errorf("expected %d characters, got %d\n", tt.length, n)
+			}
+			for _, r := range key {
+				if !strings.ContainsRune(tt.alphabet, r) {
+					t.Errorf("character %q not in alphabet %q\n", r, tt.alphabet)
+				}
+			}
+		})
+	}
+
+	seen := map[string]bool{}
+	counts := map[rune]int{}
+	for i := 0; i < 1000; i++ {
+		key, err := GenerateKeyFromAlphabet(32, "0123456789abcdef")
+		if err != nil {
+			t.Fatalf("could not generate key: %s\n", err)
+		}
+		if seen[key] {
+			t.Fatalf("duplicate key after %d keys: %s\n", i, key)
+		}
+		seen[key] = true
+		for _, r := range key {
+			counts[r]++
+		}
+	}
+	// 32000 characters over 16 symbols, 2000 expected per symbol
+	for r, n := range counts {
+		if n < 1700 || n > 2300 {
+			t.Errorf("character %q occurred %d times, expected about 2000\n", r, n)
+		}
+	}
+}
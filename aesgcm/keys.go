@@ -0,0 +1,108 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DecryptWithKeys decrypts an encoded ciphertext produced by Encrypt with the first of 'keys' that fits and returns
+// the plaintext along with the index of that key, for example while data is encrypted with either the old or the new
+// key during a key rotation. Keys are tried in order, each being derived only when it is tried.
+//
+// If the ciphertext has been encrypted with WithFingerprint, only keys with a matching fingerprint are tried.
+// If no key fits, the index is -1 and the error wraps ErrAuthenticationFailed and reports the number of keys tried,
+// but never the keys. Errors that don't depend on the key, such as an invalid encoding, are returned right away.
+func DecryptWithKeys(ciphertext string, keys ...string) (string, int, error) {
+	data, err := decodeCiphertext(ciphertext, StdBase64)
+	if err != nil {
+		return "", -1, err
+	}
+	fp, _ := parseFingerprintHeader(data)
+	var decrypted []byte
+	i, err := tryKeys("decrypt", keys, fp, func(c *Cipher) error {
+		d, err := c.DecryptBytes(data)
+		decrypted = d
+		return err
+	})
+	defer clear(decrypted)
+	return string(decrypted), i, err
+}
+
+// DecryptFileWithKeys decrypts the file located at 'path' in place like DecryptFile, using the first of 'keys'
+// that fits, and returns the index of that key. See DecryptWithKeys for the order in which the keys are tried.
+//
+// For files with a key check value or in the chunked format, a wrong key is detected by reading only the start
+// of the file. The file is left untouched if no key fits.
+func DecryptFileWithKeys(path string, keys ...string) (int, error) {
+	head, err := readHead(path, fingerprintHeaderSize)
+	if err != nil {
+		return -1, err
+	}
+	fp, _ := parseFingerprintHeader(head)
+	return tryKeys("decrypt", keys, fp, func(c *Cipher) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = c.verifyKey(f)
+		_ = f.Close()
+		if err != nil && !errors.Is(err, ErrNoKeyCheck) {
+			return err
+		}
+		return c.DecryptFile(path)
+	})
+}
+
+// tryKeys calls 'fn' with a Cipher for each of 'keys' in order until it doesn't fail with ErrAuthenticationFailed,
+// skipping keys whose fingerprint differs from 'fp' unless it is nil. It returns the index of that key, or -1
+// and an error reporting how many keys have been tried for the operation 'op'.
+func tryKeys(op string, keys []string, fp []byte, fn func(c *Cipher) error) (int, error) {
+	if len(keys) == 0 {
+		return -1, fmt.Errorf("can't %s, no keys given", op)
+	}
+	tried := 0
+	for i, key := range keys {
+		c, err := newDecryptCipher(key)
+		if err != nil {
+			return -1, fmt.Errorf("can't %s with key %d: %w", op, i, err)
+		}
+		if fp != nil && !bytes.Equal(fp, c.fingerprint) {
+			c.Close()
+			continue
+		}
+		tried++
+		err = fn(c)
+		c.Close()
+		if err == nil {
+			return i, nil
+		}
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			return -1, err
+		}
+	}
+	if fp != nil && tried == 0 {
+		return -1, fmt.Errorf("can't %s, the fingerprint matches none of the %d keys: %w", op, len(keys), ErrAuthenticationFailed)
+	}
+	return -1, fmt.Errorf("can't %s with any of the %d keys tried: %w", op, tried, ErrAuthenticationFailed)
+}
+
+// readHead returns up to 'n' bytes from the start of the file located at 'path'.
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errFileNotFound("decrypt", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, n)
+	m, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:m], nil
+}
@@ -0,0 +1,100 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DecryptWithKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		keys  []string
+		index int
+	}{
+		{"first", nil, []string{"oldKey123", "newKey123"}, 0},
+		{"second", nil, []string{"newKey123", "oldKey123"}, 1},
+		{"fingerprint", []Option{WithFingerprint()}, []string{"newKey123", "otherKey", "oldKey123"}, 2},
+		{"key check", []Option{WithKeyCheck()}, []string{"newKey123", "oldKey123"}, 1},
+		{"none", nil, []string{"newKey123", "otherKey"}, -1},
+		{"none with fingerprint", []Option{WithFingerprint()}, []string{"newKey123", "otherKey"}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt("Hello World!", "oldKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			d, i, err := DecryptWithKeys(e, tt.keys...)
+			if i != tt.index {
+				t.Errorf("DecryptWithKeys() index = %d, want %d\n", i, tt.index)
+			}
+			if tt.index < 0 {
+				if !errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("expected ErrAuthenticationFailed, got %v\n", err)
+				}
+				for _, key := range tt.keys {
+					if err != nil && strings.Contains(err.Error(), key) {
+						t.Errorf("error leaks a key: %v\n", err)
+					}
+				}
+				return
+			}
+			if err != nil || d != "Hello World!" {
+				t.Errorf("DecryptWithKeys() = %q, %v, want %q\n", d, err, "Hello World!")
+			}
+
+			path := filepath.Join(t.TempDir(), "secret.txt")
+			if err := os.WriteFile(path, []byte("Hello World!"), 0644); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFile(path, "oldKey123", tt.opts...); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			if i, err := DecryptFileWithKeys(path, tt.keys...); err != nil || i != tt.index {
+				t.Errorf("DecryptFileWithKeys() = %d, %v, want %d\n", i, err, tt.index)
+			}
+			if d, err := os.ReadFile(path); err != nil || string(d) != "Hello World!" {
+				t.Errorf("expected the decrypted file, got %q (%v)\n", d, err)
+			}
+		})
+	}
+}
+
+func Test_DecryptWithKeys_errors(t *testing.T) {
+	e, _ := Encrypt("Hello World!", "oldKey123")
+	if _, i, err := DecryptWithKeys(e); err == nil || i != -1 {
+		t.Errorf("expected error without keys, got %d, %v\n", i, err)
+	}
+	if _, _, err := DecryptWithKeys(e, "newKey123", ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+	if _, _, err := DecryptWithKeys("not base64!", "oldKey123"); err == nil || errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected an encoding error, got %v\n", err)
+	}
+	_, _, err := DecryptWithKeys(e, "a-key123", "b-key123", "c-key123")
+	if err == nil || !strings.Contains(err.Error(), "3 keys") {
+		t.Errorf("expected the number of keys tried in the error, got %v\n", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	if _, err := DecryptFileWithKeys(path, "oldKey123"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v\n", err)
+	}
+	if err := os.WriteFile(path, []byte("Hello World!"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(path, "oldKey123"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	before, _ := os.ReadFile(path)
+	if _, err := DecryptFileWithKeys(path, "newKey123", "otherKey"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed, got %v\n", err)
+	}
+	if after, _ := os.ReadFile(path); string(before) != string(after) {
+		t.Errorf("file has been modified although no key fits\n")
+	}
+}
@@ -0,0 +1,156 @@
+package aesgcm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxSize is the plaintext limit applied by decryption unless another limit is set with WithMaxSize.
+// The default of 0 means unlimited. It is read when a Cipher is created, so it should be set during initialization.
+var DefaultMaxSize int64 = 0
+
+// WithMaxSize limits the plaintext a decryption may produce to 'n' bytes, guarding against memory and disk
+// exhaustion by hostile inputs. Exceeding the limit results in an error wrapping ErrTooLarge.
+// 0 lifts the limit, negative values are rejected.
+//
+// Decrypt and DecryptBytes reject ciphertexts too large to hold at most 'n' bytes of plaintext before decoding
+// and decrypting them. DecryptFile, DecryptStream and the streaming readers and writers stop as soon as more
+// than 'n' bytes of plaintext would be emitted. Decompressed plaintext is subject to the limit as well.
+func WithMaxSize(n int64) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("max size must not be negative, got %d", n)
+		}
+		o.maxSize = n
+		return nil
+	}
+}
+
+// errTooLarge returns an error wrapping ErrTooLarge for the limit 'limit'.
+func errTooLarge(limit int64) error {
+	return fmt.Errorf("%w of %d bytes", ErrTooLarge, limit)
+}
+
+// chunkOverhead is the most a chunk of a stream or file adds to its plaintext besides the expansion
+// of compression: the length of a chunk of the chunked file format, a zstd frame and the tag.
+const chunkOverhead = 4 + 64 + 16
+
+// maxCiphertextLen returns the length of the largest ciphertext accepted for the plaintext limit 'limit'.
+// It leaves room for the headers, for incompressible data growing by compression and for the overhead
+// of the chunks of a stream with the chunk size 'chunkSize'.
+func maxCiphertextLen(limit int64, chunkSize int) int64 {
+	return limit + limit>>8 + (limit/int64(chunkSize)+1)*chunkOverhead + 1024
+}
+
+// recordedChunkSize returns the chunk size recorded in the stream header of 'data', which may be preceded
+// by a compression flag and the headers written by prefix, or DefaultChunkSize if 'data' doesn't hold one.
+func (c *Cipher) recordedChunkSize(data []byte) int {
+	if magic := compressionMagic(data); magic != nil {
+		data = data[len(magic):]
+	}
+	p, ok := c.streamPrefix(data)
+	if !ok || len(data) < p.size+streamHeaderSize {
+		return DefaultChunkSize
+	}
+	n := int(binary.BigEndian.Uint32(data[p.size+len(streamMagic)+1:]))
+	if n <= 0 || n > maxChunkSize {
+		return DefaultChunkSize
+	}
+	return n
+}
+
+// headTextLen is the length of the start of a text decoded to find the chunk size of a stream.
+// It is a multiple of 4, so the start of base64 and hex encoded texts can be decoded on its own.
+var headTextLen = (2*(magicPeekSize+streamHeaderSize) + 3) &^ 3
+
+// decodedLen returns the maximum length of the data decoded from an 'n' byte text by 'enc'.
+// Encodings that don't report it are assumed not to expand their input.
+func decodedLen(enc Encoding, n int) int64 {
+	if e, ok := enc.(interface{ DecodedLen(n int) int }); ok {
+		return int64(e.DecodedLen(n))
+	}
+	return int64(n)
+}
+
+// checkCiphertextLen returns an error wrapping ErrTooLarge if a ciphertext of 'n' bytes exceeds the limit set with WithMaxSize.
+// Streams written with a chunk size smaller than the default have more room for their tags. 'head' returns the start
+// of the ciphertext to find the recorded chunk size, it is only called if 'n' exceeds the limit for the default.
+func (c *Cipher) checkCiphertextLen(n int64, head func() []byte) error {
+	if c.opts.maxSize <= 0 {
+		return nil
+	}
+	padding := int64(c.opts.padding)
+	if n <= maxCiphertextLen(c.opts.maxSize, DefaultChunkSize)+padding {
+		return nil
+	}
+	if chunkSize := c.recordedChunkSize(head()); n <= maxCiphertextLen(c.opts.maxSize, chunkSize)+padding {
+		return nil
+	}
+	return errTooLarge(c.opts.maxSize)
+}
+
+// decodeCiphertext decodes 'text' with the encoding of the Cipher, rejecting texts that would decode
+// to a ciphertext exceeding the limit set with WithMaxSize before decoding them.
+func (c *Cipher) decodeCiphertext(text string) ([]byte, error) {
+	head := func() []byte {
+		data, _ := c.opts.encoding.DecodeString(text[:min(len(text), headTextLen)])
+		return data
+	}
+	if err := c.checkCiphertextLen(decodedLen(c.opts.encoding, len(text)), head); err != nil {
+		return nil, err
+	}
+	return decodeCiphertext(text, c.opts.encoding)
+}
+
+// readAllLimited reads 'r' until EOF like io.ReadAll, but returns an error wrapping ErrTooLarge
+// as soon as more than 'limit' bytes have been read. A limit of 0 means unlimited.
+func readAllLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge(limit)
+	}
+	return data, nil
+}
+
+// limitWriter passes writes on to 'w' until more than 'limit' bytes would have been written in total.
+type limitWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+}
+
+// limitOutput wraps 'w' to fail with an error wrapping ErrTooLarge once more plaintext than allowed
+// by WithMaxSize is written to it. 'w' is returned unchanged if there is no limit.
+func (c *Cipher) limitOutput(w io.Writer) io.Writer {
+	if c.opts.maxSize <= 0 {
+		return w
+	}
+	return &limitWriter{w: w, limit: c.opts.maxSize}
+}
+
+// Write writes 'p' to the underlying writer, or nothing if that would exceed the limit.
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.written+int64(len(p)) > lw.limit {
+		return 0, errTooLarge(lw.limit)
+	}
+	n, err := lw.w.Write(p)
+	lw.written += int64(n)
+	return n, err
+}
+
+// countPlaintext adds 'n' bytes to the plaintext counted in 'total' and returns an error wrapping ErrTooLarge
+// if the result exceeds 'limit'. A limit of 0 means unlimited.
+func countPlaintext(total *int64, n int, limit int64) error {
+	if limit > 0 && *total+int64(n) > limit {
+		return errTooLarge(limit)
+	}
+	*total += int64(n)
+	return nil
+}
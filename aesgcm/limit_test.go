@@ -0,0 +1,231 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_WithMaxSize(t *testing.T) {
+	if _, err := New("myKey123", WithMaxSize(-1)); err == nil {
+		t.Errorf("WithMaxSize(-1) expected an error\n")
+	}
+	const limit = 1000
+	tests := []struct {
+		name    string
+		size    int
+		opts    []Option
+		wantErr bool
+	}{
+		{"at limit", limit, nil, false},
+		{"over limit", limit + 1, nil, true},
+		{"hex over limit", limit + 1, []Option{WithEncoding(Hex)}, true},
+		{"raw url at limit", limit, []Option{WithEncoding(RawURL)}, false},
+		{"key check over limit", limit + 1, []Option{WithKeyCheck()}, true},
+		{"unlimited", 10 * limit, []Option{WithMaxSize(0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := strings.Repeat("a", tt.size)
+			ciphertext, err := Encrypt(plaintext, "myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v\n", err)
+			}
+			var decOpts []Option
+			for _, opt := range append([]Option{WithMaxSize(limit)}, tt.opts...) {
+				if _, err := newDecryptOptions(opt); err == nil {
+					decOpts = append(decOpts, opt)
+				}
+			}
+			got, err := Decrypt(ciphertext, "myKey123", decOpts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrTooLarge) {
+				t.Errorf("Decrypt() error = %v, want ErrTooLarge\n", err)
+			}
+			if !tt.wantErr && got != plaintext {
+				t.Errorf("Decrypt() returned %d bytes, want %d\n", len(got), len(plaintext))
+			}
+		})
+	}
+}
+
+func Test_WithMaxSize_noAllocation(t *testing.T) {
+	const size = 64 * 1024 * 1024
+	text := strings.Repeat("A", size)
+	data := make([]byte, size)
+	c, err := New("myKey123", WithMaxSize(1024))
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	_, decErr := c.Decrypt(text)
+	_, bytesErr := c.DecryptBytes(data)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(decErr, ErrTooLarge) {
+		t.Errorf("Decrypt() error = %v, want ErrTooLarge\n", decErr)
+	}
+	if !errors.Is(bytesErr, ErrTooLarge) {
+		t.Errorf("DecryptBytes() error = %v, want ErrTooLarge\n", bytesErr)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1024*1024 {
+		t.Errorf("rejecting %d bytes allocated %d bytes\n", size, alloc)
+	}
+}
+
+func Test_WithMaxSize_stream(t *testing.T) {
+	const limit = 3 * DefaultChunkSize
+	var stream bytes.Buffer
+	if err := EncryptStream(&stream, io.LimitReader(zeroReader{}, limit+1), "myKey123"); err != nil {
+		t.Fatalf("EncryptStream() error = %v\n", err)
+	}
+	c, err := newDecryptCipher("myKey123", WithMaxSize(limit))
+	if err != nil {
+		t.Fatalf("newDecryptCipher() error = %v\n", err)
+	}
+
+	counter := &countingWriter{}
+	if err := c.DecryptStream(counter, bytes.NewReader(stream.Bytes())); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("DecryptStream() error = %v, want ErrTooLarge\n", err)
+	}
+	if counter.n > limit {
+		t.Errorf("DecryptStream() emitted %d bytes, limit is %d\n", counter.n, limit)
+	}
+
+	n, err := io.Copy(io.Discard, c.DecryptReader(bytes.NewReader(stream.Bytes())))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("DecryptReader() error = %v, want ErrTooLarge\n", err)
+	}
+	if n > limit {
+		t.Errorf("DecryptReader() emitted %d bytes, limit is %d\n", n, limit)
+	}
+
+	counter = &countingWriter{}
+	dw := c.DecryptWriter(counter)
+	_, err = dw.Write(stream.Bytes())
+	if err == nil {
+		err = dw.Close()
+	}
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("DecryptWriter() error = %v, want ErrTooLarge\n", err)
+	}
+	if counter.n > limit {
+		t.Errorf("DecryptWriter() emitted %d bytes, limit is %d\n", counter.n, limit)
+	}
+
+	// the package-level reader uses DefaultMaxSize
+	defer func(old int64) { DefaultMaxSize = old }(DefaultMaxSize)
+	DefaultMaxSize = limit
+	r, err := NewDecryptReader(bytes.NewReader(stream.Bytes()), "myKey123")
+	if err != nil {
+		t.Fatalf("NewDecryptReader() error = %v\n", err)
+	}
+	if _, err := io.Copy(io.Discard, r); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("NewDecryptReader() error = %v, want ErrTooLarge\n", err)
+	}
+}
+
+func Test_WithMaxSize_file(t *testing.T) {
+	const limit = 100 * 1024
+	tests := []struct {
+		name       string
+		size       int
+		encrypt    func(path string) error
+		compressed bool
+		wantErr    bool
+	}{
+		{"at limit", limit, func(path string) error { return EncryptFile(path, "myKey123") }, false, false},
+		{"over limit", limit + 1, func(path string) error { return EncryptFile(path, "myKey123") }, false, true},
+		{"single ciphertext over limit", limit + 1, func(path string) error {
+			data, _ := os.ReadFile(path)
+			encrypted, err := EncryptBytes(data, "myKey123")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(path, encrypted, 0644)
+		}, false, true},
+		{"compressed over limit", limit + 1, func(path string) error { return EncryptFileCompressed(path, "myKey123") }, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, encrypted := encryptedFile(t, tt.size, tt.encrypt)
+			decrypt := DecryptFile
+			if tt.compressed {
+				decrypt = DecryptFileCompressed
+			}
+			err := decrypt(path, "myKey123", WithMaxSize(limit), WithLegacyFormat())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decrypt error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				return
+			}
+			if !errors.Is(err, ErrTooLarge) {
+				t.Errorf("decrypt error = %v, want ErrTooLarge\n", err)
+			}
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, encrypted) {
+				t.Errorf("file changed after a failed decryption\n")
+			}
+		})
+	}
+}
+
+func Test_WithMaxSize_decompressionBomb(t *testing.T) {
+	const size = 16 * 1024 * 1024
+	ciphertext, err := EncryptCompressed(strings.Repeat("\x00", size), "myKey123")
+	if err != nil {
+		t.Fatalf("EncryptCompressed() error = %v\n", err)
+	}
+	if _, err := DecryptCompressed(ciphertext, "myKey123", WithMaxSize(1024*1024)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("DecryptCompressed() error = %v, want ErrTooLarge\n", err)
+	}
+	if got, err := DecryptCompressed(ciphertext, "myKey123", WithMaxSize(size)); err != nil || len(got) != size {
+		t.Errorf("DecryptCompressed() = %d bytes, %v, want %d bytes\n", len(got), err, size)
+	}
+}
+
+func Test_WithMaxSize_smallChunks(t *testing.T) {
+	const limit = 1000
+	c, err := New("myKey123", WithChunkSize(4))
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+	d, err := New("myKey123", WithMaxSize(limit))
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+	for _, tt := range []struct {
+		size    int
+		wantErr bool
+	}{
+		{limit, false},
+		{limit + 1, true},
+	} {
+		var stream bytes.Buffer
+		if err := c.EncryptStream(&stream, io.LimitReader(zeroReader{}, int64(tt.size))); err != nil {
+			t.Fatalf("EncryptStream() error = %v\n", err)
+		}
+		if stream.Len() <= int(maxCiphertextLen(limit, DefaultChunkSize)) {
+			t.Fatalf("expected a stream exceeding the limit for the default chunk size, got %d bytes\n", stream.Len())
+		}
+		got, err := d.DecryptBytes(stream.Bytes())
+		if (err != nil) != tt.wantErr || tt.wantErr && !errors.Is(err, ErrTooLarge) {
+			t.Errorf("DecryptBytes() of %d bytes with 4-byte chunks error = %v, wantErr %v\n", tt.size, err, tt.wantErr)
+		}
+		if !tt.wantErr && len(got) != tt.size {
+			t.Errorf("DecryptBytes() returned %d bytes, want %d\n", len(got), tt.size)
+		}
+		if _, err := d.Decrypt(StdBase64.EncodeToString(stream.Bytes())); (err != nil) != tt.wantErr {
+			t.Errorf("Decrypt() of %d bytes with 4-byte chunks error = %v, wantErr %v\n", tt.size, err, tt.wantErr)
+		}
+	}
+}
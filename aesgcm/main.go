@@ -3,15 +3,19 @@
 package aesgcm
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"encoding/base64"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/toxyl/errors"
-	"github.com/toxyl/flo"
 	"github.com/toxyl/keys"
 )
 
@@ -20,59 +24,157 @@ type keyCipher struct {
 	key []byte
 }
 
+// MinKeyLength is the minimum number of characters a key or password must have, 8 by default.
+// Callers can raise it to enforce stronger keys.
+var MinKeyLength = 8
+
+// validateKey checks that the key has at least MinKeyLength characters and doesn't consist only of
+// whitespace or control characters. It returns an error wrapping ErrKeyTooShort or ErrWeakKey otherwise.
+func validateKey(key string) error {
+	if n := utf8.RuneCountInString(key); n < MinKeyLength {
+		return fmt.Errorf("%w: key has %d characters, at least %d are required", ErrKeyTooShort, n, MinKeyLength)
+	}
+	if strings.TrimFunc(key, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) == "" {
+		return fmt.Errorf("%w: key is empty or consists only of whitespace or control characters", ErrWeakKey)
+	}
+	return nil
+}
+
 // newKeyCipher creates a new keyCipher instance initialized with a scrambled key.
-// It returns an error if key scrambling fails.
+// It returns an error if the key is weak or if key scrambling fails.
 func newKeyCipher(key string) (*keyCipher, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
 	k, err := keys.WeakKeyScrambler(key)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrKeyDerivation, err)
 	}
 	return &keyCipher{key: []byte(k)}, nil
 }
 
-// encrypt encrypts the provided data using AES-GCM encryption.
-// It returns the encrypted ciphertext along with any error encountered.
-func (c *keyCipher) encrypt(data []byte) ([]byte, error) {
+// aead creates the AES-GCM AEAD instance for the key.
+// It returns an error if the key has an invalid length.
+func (c *keyCipher) aead() (cipher.AEAD, error) {
 	block, err := aes.NewCipher(c.key)
 	if err != nil {
 		return nil, err
 	}
+	return cipher.NewGCM(block)
+}
 
-	aesGCM, err := cipher.NewGCM(block)
+// encrypt encrypts the provided data using AES-GCM encryption, authenticating the optional additional data.
+// It returns the encrypted ciphertext along with any error encountered.
+func (c *keyCipher) encrypt(data, additionalData []byte) ([]byte, error) {
+	aesGCM, err := c.aead()
 	if err != nil {
 		return nil, err
 	}
+	return seal(aesGCM, rand.Reader, fingerprint(c.key), data, additionalData)
+}
 
-	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+// decrypt decrypts the provided AES-GCM encrypted data, verifying the optional additional data.
+// It returns the decrypted plaintext along with any error encountered.
+func (c *keyCipher) decrypt(data, additionalData []byte) ([]byte, error) {
+	aesGCM, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	return open(aesGCM, data, additionalData)
+}
+
+// seal encrypts the provided data with a nonce read from 'random', authenticating the optional additional data.
+// The nonce is tracked for the key hash 'keyHash' if nonce tracking is enabled.
+// It returns the nonce followed by the sealed data along with any error encountered.
+func seal(aesGCM cipher.AEAD, random io.Reader, keyHash, data, additionalData []byte) ([]byte, error) {
+	nonce, err := randomNonce(aesGCM, random, keyHash)
+	if err != nil {
 		return nil, err
 	}
 
-	return aesGCM.Seal(nonce, nonce, data, nil), nil
+	return aesGCM.Seal(nonce, nonce, data, additionalData), nil
 }
 
-// decrypt decrypts the provided AES-GCM encrypted data.
-// It returns the decrypted plaintext along with any error encountered.
-func (c *keyCipher) decrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(c.key)
+// randomNonce reads a nonce for 'aesGCM' from 'random' and tracks it for the key hash 'keyHash',
+// see EnableNonceTracking.
+func randomNonce(aesGCM cipher.AEAD, random io.Reader, keyHash []byte) ([]byte, error) {
+	nonce := make([]byte, aesGCM.NonceSize())
+	if n, err := io.ReadFull(random, nonce); err != nil {
+		return nil, fmt.Errorf("can't generate nonce, random source returned %d of %d bytes: %w", n, len(nonce), err)
+	}
+	if err := trackNonce(keyHash, nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// sealWithHeader encrypts 'data' like encrypt, but returns header||ciphertext with a ciphertext header recording
+// the KDF 'kdf' and its parameters 'kdfParams', which is authenticated as additional data.
+func (c *keyCipher) sealWithHeader(data []byte, kdf byte, kdfParams []byte) ([]byte, error) {
+	aesGCM, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomNonce(aesGCM, rand.Reader, fingerprint(c.key))
 	if err != nil {
 		return nil, err
 	}
+	header := appendHeader(nil, algAES256GCM, kdf, kdfParams, nonce, time.Now())
+	return aesGCM.Seal(header, nonce, data, header), nil
+}
 
-	aesGCM, err := cipher.NewGCM(block)
+// openWithHeader decrypts 'data' as written by sealWithHeader, whose header has been parsed into 'h'.
+func (c *keyCipher) openWithHeader(h versionedHeader, data []byte) ([]byte, error) {
+	aesGCM, err := c.aead()
 	if err != nil {
 		return nil, err
 	}
+	if h.algorithm != algAES256GCM {
+		return nil, fmt.Errorf("%w: unknown algorithm %d", ErrUnsupportedVersion, h.algorithm)
+	}
+	if len(h.nonce) != aesGCM.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce length %d", ErrCorruptHeader, len(h.nonce))
+	}
+	plaintext, err := aesGCM.Open(nil, h.nonce, data[len(h.raw):], h.raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+	return plaintext, nil
+}
 
+// openPasswordCiphertext decrypts 'data' as written by one of the password-based functions using the KDF 'kdf':
+// header||ciphertext, with the header recording the parameters of the KDF and the salt, from which 'derive'
+// derives the key. Data without such a header is decrypted by 'legacy', which handles the formats of earlier versions.
+func openPasswordCiphertext(data []byte, kdf byte, derive func(params []byte) (*keyCipher, error), legacy func(data []byte) ([]byte, error)) ([]byte, error) {
+	h, ok, err := parseHeader(data)
+	if ok && err == nil && h.kdf == kdf {
+		kc, err := derive(h.kdfParams)
+		if err != nil {
+			return nil, err
+		}
+		defer kc.wipe()
+		return kc.openWithHeader(h, data)
+	}
+	// a header that fails to parse may be the start of a salt of earlier versions
+	decrypted, legacyErr := legacy(data)
+	if legacyErr != nil && err != nil {
+		return nil, err
+	}
+	return decrypted, legacyErr
+}
+
+// open decrypts the provided nonce-prefixed data, verifying the optional additional data.
+// It returns the decrypted plaintext along with any error encountered.
+func open(aesGCM cipher.AEAD, data, additionalData []byte) ([]byte, error) {
 	nonceSize := aesGCM.NonceSize()
 	if len(data) < nonceSize {
-		return nil, fmt.Errorf("data too short")
+		return nil, ErrCiphertextTooShort
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
 	}
 
 	return plaintext, nil
@@ -80,6 +182,7 @@ func (c *keyCipher) decrypt(data []byte) ([]byte, error) {
 
 // Encrypt encrypts the given plaintext using AES-GCM encryption with the provided key.
 // It returns the base64-encoded encrypted ciphertext and any error encountered.
+// The encoding, additional authenticated data and other settings can be changed with 'opts'.
 //
 // The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
 // which is the maximum allowed length for AES-GCM encryption. This process enhances security by converting
@@ -88,20 +191,32 @@ func (c *keyCipher) decrypt(data []byte) ([]byte, error) {
 //
 // Note: The input key is not directly usable with other AES-GCM implementations or tools,
 // as it undergoes specific scrambling tailored for this package's usage.
-func Encrypt(plaintext, key string) (string, error) {
-	cipher, err := newKeyCipher(key)
+func Encrypt(plaintext, key string, opts ...Option) (string, error) {
+	c, err := New(key, opts...)
 	if err != nil {
 		return "", err
 	}
-	encrypted, err := cipher.encrypt([]byte(plaintext))
-	if err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(encrypted), nil
+	defer c.Close()
+	return c.Encrypt(plaintext)
+}
+
+// EncryptHex encrypts the given plaintext using AES-GCM encryption with the provided key.
+// It returns the lowercase hex-encoded encrypted ciphertext and any error encountered.
+// The ciphertext is identical to the one produced by Encrypt, only the encoding differs.
+func EncryptHex(plaintext, key string) (string, error) {
+	return EncryptWithEncoding(plaintext, key, Hex)
+}
+
+// EncryptURL encrypts the given plaintext using AES-GCM encryption with the provided key.
+// It returns the encrypted ciphertext encoded as unpadded URL-safe base64 and any error encountered,
+// so the result can be used in URLs, cookies and file names without further escaping.
+func EncryptURL(plaintext, key string) (string, error) {
+	return EncryptWithEncoding(plaintext, key, RawURL)
 }
 
 // EncryptBytes encrypts the given bytes using AES-GCM encryption with the provided key.
-// It returns the encrypted bytes and any error encountered.
+// It returns the raw encrypted bytes (header including the nonce, followed by the sealed data) without any encoding
+// and any error encountered. A nil or empty input yields a valid ciphertext of an empty plaintext.
 //
 // The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
 // which is the maximum allowed length for AES-GCM encryption. This process enhances security by converting
@@ -111,51 +226,72 @@ func Encrypt(plaintext, key string) (string, error) {
 // Note: The input key is not directly usable with other AES-GCM implementations or tools,
 // as it undergoes specific scrambling tailored for this package's usage.
 func EncryptBytes(bytes []byte, key string) ([]byte, error) {
-	cipher, err := newKeyCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	encrypted, err := cipher.encrypt(bytes)
+	return EncryptRaw(bytes, key)
+}
+
+// EncryptRaw encrypts the given plaintext using AES-GCM encryption with the provided key.
+// It returns the header||ciphertext bytes without any encoding and any error encountered.
+//
+// The raw form is a single ciphertext, unlike the chunked format written by EncryptFile and EncryptToFile.
+// When the output of EncryptRaw is stored in a file, DecryptFile only decrypts it with WithLegacyFormat,
+// and IsEncrypted reports it as not encrypted. Encrypt is the base64-encoded equivalent of EncryptRaw.
+func EncryptRaw(plaintext []byte, key string) ([]byte, error) {
+	c, err := New(key)
 	if err != nil {
 		return nil, err
 	}
-	return encrypted, nil
+	defer c.Close()
+	return c.EncryptBytes(plaintext)
 }
 
 // Decrypt decrypts the given base64-encoded encrypted text using AES-GCM decryption with the provided key.
-// It returns the decrypted plaintext and any error encountered.
-func Decrypt(text, key string) (string, error) {
-	cipher, err := newKeyCipher(key)
-	if err != nil {
-		return "", err
-	}
-	encryptedData, err := base64.StdEncoding.DecodeString(text)
+// It returns the decrypted plaintext and any error encountered. The 'opts' must match the ones used
+// for encryption, passing an encryption-only option such as WithRand returns an error.
+func Decrypt(text, key string, opts ...Option) (string, error) {
+	c, err := newDecryptCipher(key, opts...)
 	if err != nil {
 		return "", err
 	}
-	decrypted, err := cipher.decrypt(encryptedData)
-	if err != nil {
-		return "", err
-	}
-	return string(decrypted), nil
+	defer c.Close()
+	return c.Decrypt(text)
 }
 
-// DecryptBytes decrypts the given encrypted bytes using AES-GCM decryption with the provided key.
-// It returns the decrypted bytes and any error encountered.
+// DecryptHex decrypts the given hex-encoded encrypted text using AES-GCM decryption with the provided key.
+// It returns the decrypted plaintext and any error encountered.
+// Input of odd length or with non-hex characters is rejected before decryption is attempted.
+func DecryptHex(text, key string) (string, error) {
+	return DecryptWithEncoding(text, key, Hex)
+}
+
+// DecryptURL decrypts the given URL-safe base64-encoded encrypted text using AES-GCM decryption with the provided key.
+// It returns the decrypted plaintext and any error encountered. Padded input is accepted for backward compatibility.
+func DecryptURL(text, key string) (string, error) {
+	return DecryptWithEncoding(text, key, RawURL)
+}
+
+// DecryptBytes decrypts the given raw encrypted bytes (as produced by EncryptBytes) using AES-GCM decryption
+// with the provided key. It returns the decrypted bytes and any error encountered.
 func DecryptBytes(bytes []byte, key string) ([]byte, error) {
-	cipher, err := newKeyCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	decrypted, err := cipher.decrypt(bytes)
+	return DecryptRaw(bytes, key)
+}
+
+// DecryptRaw decrypts the given raw ciphertext bytes using AES-GCM decryption with the provided key.
+// It returns the decrypted plaintext and any error encountered.
+//
+// The input is expected in the raw form produced by EncryptRaw. The contents of files written by EncryptFile
+// and EncryptToFile are accepted as well, as long as they fit into memory.
+func DecryptRaw(ciphertext []byte, key string) ([]byte, error) {
+	c, err := New(key)
 	if err != nil {
 		return nil, err
 	}
-	return decrypted, nil
+	defer c.Close()
+	return c.DecryptBytes(ciphertext)
 }
 
 // EncryptFile encrypts the file located at 'path' using AES-GCM encryption with the provided key.
-// It returns an error if the file doesn't exist or if any encryption operation fails.
+// It returns an error if the file doesn't exist or if any encryption operation fails, and an error wrapping
+// ErrAlreadyEncrypted if the file is already encrypted, unless WithForce has been passed. The additional authenticated data and other settings can be changed with 'opts'.
 //
 // The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
 // which is the maximum allowed length for AES-GCM encryption. This process enhances security by converting
@@ -164,27 +300,18 @@ func DecryptBytes(bytes []byte, key string) ([]byte, error) {
 //
 // Note: The input key is not directly usable with other AES-GCM implementations or tools,
 // as it undergoes specific scrambling tailored for this package's usage.
-func EncryptFile(path, key string) error {
-	f := flo.File(path)
-	if !f.Exists() {
-		return errors.Newf("can't encrypt, file '%s' does not exist", f.Path())
-	}
-	cipher, err := newKeyCipher(key)
+func EncryptFile(path, key string, opts ...Option) error {
+	c, err := New(key, opts...)
 	if err != nil {
 		return err
 	}
-	encrypted, err := cipher.encrypt(f.AsBytes())
-	if err != nil {
-		return err
-	}
-	if err := f.StoreBytes(encrypted); err != nil {
-		return err
-	}
-	return nil
+	defer c.Close()
+	return c.EncryptFile(path)
 }
 
-// EncryptToFile encrypts the given `bytes` using AES-GCM encryption with the provided key and writes the result to 'path'.
-// It returns an error if the file doesn't exist or if any encryption operation fails.
+// EncryptToFile encrypts the given `data` using AES-GCM encryption with the provided key and writes the result to 'path'
+// in the chunked format of EncryptFile, so it can be decrypted in place with DecryptFile and is reported by IsEncrypted.
+// The file is replaced atomically. It returns an error if the file can't be written or if any encryption operation fails.
 //
 // The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
 // which is the maximum allowed length for AES-GCM encryption. This process enhances security by converting
@@ -193,52 +320,153 @@ func EncryptFile(path, key string) error {
 //
 // Note: The input key is not directly usable with other AES-GCM implementations or tools,
 // as it undergoes specific scrambling tailored for this package's usage.
-func EncryptToFile(bytes []byte, path, key string) error {
-	cipher, err := newKeyCipher(key)
+func EncryptToFile(data []byte, path, key string) error {
+	c, err := New(key)
 	if err != nil {
 		return err
 	}
-	encrypted, err := cipher.encrypt(bytes)
+	defer c.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		return c.encryptTo(w, bytes.NewReader(data))
+	})
+}
+
+// DecryptFile decrypts the file located at 'path' using AES-GCM decryption with the provided key.
+// It returns an error if the file doesn't exist or if any decryption operation fails.
+// The 'opts' must match the ones used for encryption, passing an encryption-only option returns an error.
+func DecryptFile(path, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptFile(path)
+}
+
+// DecryptFromFile decrypts the file located at 'path' using AES-GCM decryption with the provided key.
+// It returns the decrypted file bytes or nil and an error if the file doesn't exist or if any decryption operation fails.
+func DecryptFromFile(path, key string) ([]byte, error) {
+	c, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	f, r, err := openFile("decrypt", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := c.decryptTo(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncryptStringToFile encrypts the given plaintext using AES-GCM encryption with the provided key and writes the
+// ciphertext bytes to 'path', avoiding the overhead of base64-encoding the result. It is the string counterpart
+// of EncryptToFile, writes the same format and returns an error if any encryption operation fails.
+func EncryptStringToFile(plaintext, path, key string) error {
+	pt := []byte(plaintext)
+	defer clear(pt)
+	return EncryptToFile(pt, path, key)
+}
+
+// DecryptStringFromFile decrypts the file located at 'path', as written by EncryptStringToFile, using AES-GCM
+// decryption with the provided key. It is the string counterpart of DecryptFromFile and returns the decrypted
+// plaintext or an error if the file doesn't exist or if any decryption operation fails.
+func DecryptStringFromFile(path, key string) (string, error) {
+	decrypted, err := DecryptFromFile(path, key)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
+
+// EncryptFileTo encrypts the file located at 'src' using AES-GCM encryption with the provided key
+// and writes the result to the new file 'dst', leaving 'src' untouched.
+// Unless WithOverwrite is passed, it returns an error wrapping ErrFileExists if 'dst' exists.
+// See Cipher.EncryptFileTo for details.
+func EncryptFileTo(src, dst, key string, opts ...Option) error {
+	c, err := New(key, opts...)
 	if err != nil {
 		return err
 	}
-	if err := flo.File(path).StoreBytes(encrypted); err != nil {
+	defer c.Close()
+	return c.EncryptFileTo(src, dst)
+}
+
+// DecryptFileTo decrypts the file located at 'src' using AES-GCM decryption with the provided key
+// and writes the result to the new file 'dst', leaving 'src' untouched.
+// Unless WithOverwrite is passed, it returns an error wrapping ErrFileExists if 'dst' exists.
+// See Cipher.EncryptFileTo for details.
+func DecryptFileTo(src, dst, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer c.Close()
+	return c.DecryptFileTo(src, dst)
 }
 
-// DecryptFile decrypts the file located at 'path' using AES-GCM decryption with the provided key.
-// It returns an error if the file doesn't exist or if any decryption operation fails.
-func DecryptFile(path, key string) error {
-	f := flo.File(path)
-	if !f.Exists() {
-		return errors.Newf("can't decrypt, file '%s' does not exist", f.Path())
+// EncryptFileToPath encrypts the file located at 'src' using AES-GCM encryption with the provided key
+// and writes the result to 'dst', leaving 'src' untouched. Missing parent directories of 'dst' are created.
+// It returns an error if 'src' doesn't exist or if any encryption operation fails.
+//
+// The result is written to a temporary file which is then renamed to 'dst', so passing the same path
+// for 'src' and 'dst' encrypts the file in place without risking a partially written file.
+func EncryptFileToPath(src, dst, key string) error {
+	c, err := New(key)
+	if err != nil {
+		return err
 	}
-	cipher, err := newKeyCipher(key)
+	defer c.Close()
+	f, r, err := openFile("encrypt", src, nil)
 	if err != nil {
 		return err
 	}
-	decrypted, err := cipher.decrypt(f.AsBytes())
+	defer f.Close()
+	wo, err := c.writeOptions(f, true)
 	if err != nil {
 		return err
 	}
-	if err := f.StoreBytes(decrypted); err != nil {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return err
 	}
-	return nil
+	return writeFileFunc(dst, wo, func(w io.Writer) error {
+		return c.encryptTo(w, r)
+	})
 }
 
-// DecryptFromFile decrypts the file located at 'path' using AES-GCM decryption with the provided key.
-// It returns the decrypted file bytes or nil and an error if the file doesn't exist or if any decryption operation fails.
-func DecryptFromFile(path, key string) ([]byte, error) {
-	f := flo.File(path)
-	if !f.Exists() {
-		return nil, errors.Newf("can't decrypt, file '%s' does not exist", f.Path())
+// DecryptFileToPath decrypts the file located at 'src' using AES-GCM decryption with the provided key
+// and writes the result to 'dst', leaving 'src' untouched. Missing parent directories of 'dst' are created.
+// It returns an error if 'src' doesn't exist or if any decryption operation fails.
+//
+// The result is written to a temporary file which is then renamed to 'dst', so passing the same path
+// for 'src' and 'dst' decrypts the file in place without risking a partially written file.
+func DecryptFileToPath(src, dst, key string) error {
+	c, err := New(key)
+	if err != nil {
+		return err
 	}
-	cipher, err := newKeyCipher(key)
+	defer c.Close()
+	f, r, err := openFile("decrypt", src, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer f.Close()
+	wo, err := c.writeOptions(f, true)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
 	}
-	return cipher.decrypt(f.AsBytes())
+	return writeFileFunc(dst, wo, func(w io.Writer) error {
+		return c.decryptTo(w, r)
+	})
 }
@@ -1,6 +1,13 @@
 package aesgcm
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/toxyl/flo"
@@ -16,8 +23,8 @@ func Test_test(t *testing.T) {
 		{"test 1", "../test_data/test1.txt", "Hello World!", "myKey123"},
 		{"test 2", "../test_data/test2.txt", "Hello World!", "12345678"},
 		{"test 3", "../test_data/test3.txt", "Hello World!", "1234567890"},
-		{"test 4", "../test_data/test4.txt", "Hello World!", "1111"},
-		{"test 5", "../test_data/test.bin", "Hello World!", "1234"},
+		{"test 4", "../test_data/test4.txt", "Hello World!", "11111111"},
+		{"test 5", "../test_data/test.bin", "Hello World!", "12345678"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -47,3 +54,296 @@ func Test_test(t *testing.T) {
 		})
 	}
 }
+
+func Test_bytes(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data []byte
+		key  string
+	}{
+		{"nul bytes", "../test_data/bytes1.bin", []byte{0x00, 'a', 0x00, 0x00, 'b', 0x00}, "myKey123"},
+		{"invalid utf-8", "../test_data/bytes2.bin", []byte{0xff, 0xfe, 0xfd, 0xc3, 0x28, 0xa0, 0xa1}, "12345678"},
+		{"mixed", "../test_data/bytes3.bin", []byte{'H', 'i', 0x00, 0xe2, 0x82, 0x00, 0xf0, 0x28, 0x8c, 0xbc}, "11111111"},
+		{"empty", "../test_data/bytes4.bin", []byte{}, "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptBytes(tt.data, tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt bytes: %s\n", err)
+			}
+			d, err := DecryptBytes(e, tt.key)
+			if err != nil {
+				t.Fatalf("could not decrypt bytes: %s\n", err)
+			}
+			if !bytes.Equal(tt.data, d) {
+				t.Errorf("encrypt/decrypt bytes failed: %v: expected %x, got %x!\n", tt.name, tt.data, d)
+			}
+
+			if err := flo.File(tt.file).StoreBytes(e); err != nil {
+				t.Fatalf("could not store encrypted bytes: %s\n", err)
+			}
+			decrypted, err := DecryptFromFile(tt.file, tt.key)
+			_ = flo.File(tt.file).Remove()
+			if err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if !bytes.Equal(tt.data, decrypted) {
+				t.Errorf("decrypt file failed: %v: expected %x, got %x!\n", tt.name, tt.data, decrypted)
+			}
+		})
+	}
+}
+
+func Test_nilBytes(t *testing.T) {
+	e, err := EncryptBytes(nil, "myKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt nil bytes: %s\n", err)
+	}
+	d, err := DecryptBytes(e, "myKey123")
+	if err != nil {
+		t.Fatalf("could not decrypt nil bytes: %s\n", err)
+	}
+	if len(d) != 0 {
+		t.Errorf("encrypt/decrypt nil bytes failed: expected empty result, got %x!\n", d)
+	}
+}
+
+func Test_raw(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+		key  string
+	}{
+		{"raw 1", "../test_data/raw1.bin", "Hello World!", "myKey123"},
+		{"raw 2", "../test_data/raw2.bin", "", "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptRaw([]byte(tt.text), tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt raw: %s\n", err)
+			}
+			if err := flo.File(tt.file).StoreBytes(e); err != nil {
+				t.Fatalf("could not store raw ciphertext: %s\n", err)
+			}
+			if err := DecryptFile(tt.file, tt.key, WithLegacyFormat()); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if d := flo.File(tt.file).AsString(); d != tt.text {
+				t.Errorf("decrypt file failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+
+			if err := EncryptFile(tt.file, tt.key); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			d, err := DecryptRaw(flo.File(tt.file).AsBytes(), tt.key)
+			_ = flo.File(tt.file).Remove()
+			if err != nil {
+				t.Fatalf("could not decrypt raw: %s\n", err)
+			}
+			if string(d) != tt.text {
+				t.Errorf("decrypt raw failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+		})
+	}
+}
+
+func Test_hex(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"hex 1", "Hello World!", "myKey123"},
+		{"hex 2", "Hello World!", "12345678"},
+		{"hex 3", "Hello World!", "1234567890"},
+		{"hex 4", "Hello World!", "11111111"},
+		{"hex 5", "Hello World!", "12345678"},
+		{"hex 6", "", "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := EncryptHex(tt.text, tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt hex: %s\n", err)
+			}
+			if h != strings.ToLower(h) {
+				t.Errorf("expected lowercase hex, got %s\n", h)
+			}
+			dh, err := DecryptHex(h, tt.key)
+			if err != nil {
+				t.Fatalf("could not decrypt hex: %s\n", err)
+			}
+			raw, _ := hex.DecodeString(h)
+			db, err := Decrypt(base64.StdEncoding.EncodeToString(raw), tt.key)
+			if err != nil {
+				t.Fatalf("could not decrypt re-encoded hex ciphertext: %s\n", err)
+			}
+			if dh != tt.text || db != tt.text {
+				t.Errorf("hex/base64 round-trip failed: %v: expected %v, got %v (hex) and %v (base64)!\n", tt.name, tt.text, dh, db)
+			}
+		})
+	}
+
+	h, _ := EncryptHex("Hello World!", "myKey123")
+	for _, bad := range []string{h[:len(h)-1], "zz" + h[2:]} {
+		if _, err := DecryptHex(bad, "myKey123"); err == nil || !strings.Contains(err.Error(), "invalid hex") {
+			t.Errorf("expected invalid hex error for %s, got %v\n", bad, err)
+		}
+	}
+}
+
+func Test_url(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"url 1", "Hello World!", "myKey123"},
+		{"url 2", "Hello World!!", "12345678"},
+		{"url 3", "Hello World!!!", "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptURL(tt.text, tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt url: %s\n", err)
+			}
+			if strings.ContainsAny(e, "+/=") {
+				t.Errorf("expected url-safe unpadded output, got %s\n", e)
+			}
+
+			v := url.Values{}
+			v.Set("c", e)
+			q, err := url.ParseQuery(v.Encode())
+			if err != nil {
+				t.Fatalf("could not parse query: %s\n", err)
+			}
+			if q.Get("c") != e || v.Encode() != "c="+e {
+				t.Errorf("url round-trip changed ciphertext: expected %s, got %s (%s)\n", e, q.Get("c"), v.Encode())
+			}
+
+			d, err := DecryptURL(q.Get("c"), tt.key)
+			if err != nil {
+				t.Fatalf("could not decrypt url: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt url failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+
+			raw, _ := base64.RawURLEncoding.DecodeString(e)
+			d, err = DecryptURL(base64.URLEncoding.EncodeToString(raw), tt.key)
+			if err != nil || d != tt.text {
+				t.Errorf("decrypt padded url failed: %v: expected %v, got %v (%v)!\n", tt.name, tt.text, d, err)
+			}
+		})
+	}
+}
+
+func Test_fileToPath(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.txt")
+	enc := filepath.Join(dir, "out/nested/encrypted.bin")
+	dec := filepath.Join(dir, "decrypted/plain.txt")
+	if err := flo.File(src).StoreBytes([]byte("Hello World!")); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	if err := EncryptFileToPath(src, enc, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt to path: %s\n", err)
+	}
+	if s := flo.File(src).AsString(); s != "Hello World!" {
+		t.Errorf("source was modified: got %v\n", s)
+	}
+	if err := DecryptFileToPath(enc, dec, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt to path: %s\n", err)
+	}
+	if s := flo.File(dec).AsString(); s != "Hello World!" {
+		t.Errorf("encrypt/decrypt to path failed: expected Hello World!, got %v\n", s)
+	}
+
+	if err := EncryptFileToPath(src, src, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt in place: %s\n", err)
+	}
+	if err := DecryptFileToPath(src, src, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt in place: %s\n", err)
+	}
+	if s := flo.File(src).AsString(); s != "Hello World!" {
+		t.Errorf("encrypt/decrypt in place failed: expected Hello World!, got %v\n", s)
+	}
+}
+
+func Test_weakKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "weak.txt")
+	if err := flo.File(file).StoreBytes([]byte("Hello World!")); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	for _, key := range []string{"", "    ", "\t\n \r", "\x00\x01\x02\x7f", "abc"} {
+		if _, err := Encrypt("Hello World!", key); !errors.Is(err, ErrWeakKey) {
+			t.Errorf("expected ErrWeakKey for %q, got %v\n", key, err)
+		}
+		if _, err := EncryptWithPassword("Hello World!", key); !errors.Is(err, ErrWeakKey) {
+			t.Errorf("expected ErrWeakKey from EncryptWithPassword for %q, got %v\n", key, err)
+		}
+		if err := EncryptFile(file, key); !errors.Is(err, ErrWeakKey) {
+			t.Errorf("expected ErrWeakKey from EncryptFile for %q, got %v\n", key, err)
+		}
+		if err := DecryptFile(file, key); !errors.Is(err, ErrWeakKey) {
+			t.Errorf("expected ErrWeakKey from DecryptFile for %q, got %v\n", key, err)
+		}
+		if s := flo.File(file).AsString(); s != "Hello World!" {
+			t.Errorf("file was modified with weak key %q\n", key)
+		}
+	}
+
+	for _, key := range []string{"", "abc", "1234567"} {
+		if _, err := Encrypt("Hello World!", key); !errors.Is(err, ErrKeyTooShort) {
+			t.Errorf("expected ErrKeyTooShort for %q, got %v\n", key, err)
+		}
+	}
+	if _, err := Encrypt("Hello World!", "\t\t\t\t    "); !errors.Is(err, ErrWeakKey) || errors.Is(err, ErrKeyTooShort) {
+		t.Errorf("expected ErrWeakKey but not ErrKeyTooShort for a long whitespace key, got %v\n", err)
+	}
+
+	defer func(n int) { MinKeyLength = n }(MinKeyLength)
+	MinKeyLength = 12
+	if _, err := Encrypt("Hello World!", "myKey123"); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey with raised MinKeyLength, got %v\n", err)
+	}
+	if _, err := Encrypt("Hello World!", "myLongerKey123"); err != nil {
+		t.Errorf("unexpected error with long key: %v\n", err)
+	}
+}
+
+func Test_stringToFile(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"string 1", "Hello World!", "myKey123"},
+		{"string 2", "", "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "secret.bin")
+			if err := EncryptStringToFile(tt.text, file, tt.key); err != nil {
+				t.Fatalf("could not encrypt string to file: %s\n", err)
+			}
+			if d, err := DecryptRaw(flo.File(file).AsBytes(), tt.key); err != nil || string(d) != tt.text {
+				t.Errorf("file does not hold the raw ciphertext: got %v (%v)\n", d, err)
+			}
+			d, err := DecryptStringFromFile(file, tt.key)
+			if err != nil {
+				t.Fatalf("could not decrypt string from file: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt string file failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// nameMagic identifies the header written by EncryptDir with WithOpaqueNames, which records the encrypted
+// relative path of a file. It is followed by the length of the sealed path as uint16 and the sealed path.
+var nameMagic = []byte("AGN\x01")
+
+// nameAAD separates the sealed paths from other ciphertexts of the same key.
+var nameAAD = []byte("aesgcm opaque name")
+
+// opaqueEncoding is lowercase base32 without padding, so opaque names are valid on case-insensitive file systems.
+var opaqueEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// WithOpaqueNames makes EncryptDir store every file directly in the directory set with WithDestDir, which is
+// required, under an opaque name that is derived from its relative path with HMAC-SHA256 and the key: 52 lowercase
+// letters and digits. Neither file names nor the directory structure are revealed, but encrypting the same
+// tree again yields the same names, so unchanged files map to the same file of the mirror, see WithOverwrite.
+// The relative path is encrypted into a header in front of the file, and authenticated along with the contents.
+//
+// DecryptDir with WithOpaqueNames restores the original paths below the directory set with WithDestDir from these
+// headers. Paths that would point outside of it, have a name longer than 255 bytes or only differ in case from
+// another restored path, which would clash on case-insensitive file systems, fail, as do files whose opaque name
+// doesn't match their recorded path. ListDir lists the original paths without decrypting the contents.
+func WithOpaqueNames() Option {
+	return func(o *options) error {
+		o.opaqueNames = true
+		return nil
+	}
+}
+
+// nameKey derives the key of the HMAC which derives opaque names from the AES key 'key'.
+func nameKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(nameAAD)
+	return mac.Sum(nil)
+}
+
+// opaqueName returns the opaque name of the file at the path 'rel', relative to the root of the directory.
+func (c *Cipher) opaqueName(rel string) string {
+	mac := hmac.New(sha256.New, c.nameKey)
+	mac.Write([]byte(filepath.ToSlash(rel)))
+	return opaqueEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// nameHeader returns the header recording the relative path 'rel'.
+func (c *Cipher) nameHeader(rel string) ([]byte, error) {
+	sealed, err := c.withAAD(nameAAD).seal([]byte(filepath.ToSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) > 0xffff {
+		return nil, fmt.Errorf("path too long to record: '%s'", rel)
+	}
+	header := binary.BigEndian.AppendUint16(bytes.Clone(nameMagic), uint16(len(sealed)))
+	return append(header, sealed...), nil
+}
+
+// readNameHeader reads the header written by nameHeader from 'r' and returns it along with the recorded relative path,
+// which is validated to stay inside the directory. It returns an error wrapping ErrNotEncrypted if there is no header.
+func (c *Cipher) readNameHeader(r io.Reader) ([]byte, string, error) {
+	header := make([]byte, len(nameMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, nameMagic) {
+		return nil, "", fmt.Errorf("%w, no opaque name header", ErrNotEncrypted)
+	}
+	sealed := make([]byte, binary.BigEndian.Uint16(header[len(nameMagic):]))
+	if _, err := io.ReadFull(r, sealed); err != nil {
+		return nil, "", fmt.Errorf("%w: opaque name header truncated", ErrCorruptHeader)
+	}
+	name, err := c.withAAD(nameAAD).openVersioned(sealed)
+	if err != nil {
+		return nil, "", fmt.Errorf("can't decrypt the name: %w", err)
+	}
+	rel := filepath.FromSlash(string(name))
+	if !filepath.IsLocal(rel) {
+		return nil, "", fmt.Errorf("%w: recorded path leaves the directory: '%s'", ErrCorruptHeader, name)
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if len(part) > maxFilenameLength {
+			return nil, "", fmt.Errorf("recorded name exceeds %d bytes: '%s'", maxFilenameLength, part)
+		}
+	}
+	return append(header, sealed...), rel, nil
+}
+
+// namedCipher returns a copy of the Cipher that authenticates the name header 'header' before the AAD set with WithAAD.
+func (c *Cipher) namedCipher(header []byte) *Cipher {
+	return c.withAAD(append(bytes.Clone(header), c.opts.aad...))
+}
+
+// opaqueDir returns the callbacks of processDir for WithOpaqueNames: 'dir' for the directories and 'file'
+// for the files below 'root', which are encrypted or decrypted into the directory 'dest'.
+func (c *Cipher) opaqueDir(ctx context.Context, root, dest string, encrypt bool) (dir, file func(path string) error, err error) {
+	if dest == "" {
+		return nil, nil, fmt.Errorf("WithOpaqueNames requires WithDestDir")
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, nil, err
+	}
+	dir = func(path string) error {
+		if path != root && samePath(path, dest) {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	// names used by this run, to detect collisions and paths that only differ in case
+	var mu sync.Mutex
+	used := map[string]string{}
+	claim := func(name, path string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.ToLower(name)
+		if other, ok := used[key]; ok {
+			return fmt.Errorf("%w: '%s' clashes with '%s'", ErrFileExists, path, other)
+		}
+		used[key] = path
+		return nil
+	}
+
+	if encrypt {
+		file = func(path string) error {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := c.opaqueName(rel)
+			if err := claim(name, path); err != nil {
+				return err
+			}
+			header, err := c.nameHeader(rel)
+			if err != nil {
+				return err
+			}
+			return c.encryptFileToFunc(ctx, path, filepath.Join(dest, name), nil, func(w io.Writer, r io.Reader) error {
+				if err := writeFull(w, header); err != nil {
+					return err
+				}
+				return c.namedCipher(header).encryptTo(w, r)
+			})
+		}
+		return dir, file, nil
+	}
+	file = func(path string) error {
+		rel, err := c.readName(path)
+		if err != nil {
+			return err
+		}
+		// the name header isn't authenticated yet, a header copied from another file must not claim its path
+		if filepath.Base(path) != c.opaqueName(rel) {
+			return fmt.Errorf("%w: name header doesn't match the file name: '%s'", ErrCorruptHeader, path)
+		}
+		t := filepath.Join(dest, rel)
+		if err := claim(t, path); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(t), 0755); err != nil {
+			return err
+		}
+		return c.transferFile(ctx, "decrypt", path, t, nil, func(w io.Writer, r io.Reader) error {
+			br := bufio.NewReaderSize(r, DefaultChunkSize)
+			header, _, err := c.readNameHeader(br)
+			if err != nil {
+				return err
+			}
+			return c.namedCipher(header).decryptFileTo(w, br)
+		})
+	}
+	return dir, file, nil
+}
+
+// readName returns the relative path recorded in the name header of the file located at 'path'.
+func (c *Cipher) readName(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, rel, err := c.readNameHeader(bufio.NewReader(f))
+	return rel, err
+}
+
+// ListDir returns the original relative paths of the files written by EncryptDir with WithOpaqueNames into
+// the directory 'root', keyed by the paths of the encrypted files, using AES-GCM decryption with the provided key.
+// Only the name headers are decrypted, not the contents. Files whose name can't be decrypted are left out
+// and their errors are returned together.
+func ListDir(root, key string, opts ...Option) (map[string]string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.ListDir(root)
+}
+
+// ListDir returns the original relative paths of the files below 'root'. See the package-level ListDir for details.
+func (c *Cipher) ListDir(root string) (map[string]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	var mu sync.Mutex
+	names := map[string]string{}
+	_, err := walkFiles(context.Background(), root, c.dirWorkers(), c.dirSymlinks(), c.dirFilter(root, nil), nil, func(path string) error {
+		rel, err := c.readName(path)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		names[path] = rel
+		return nil
+	})
+	return names, err
+}
@@ -0,0 +1,144 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_WithOpaqueNames(t *testing.T) {
+	files := map[string]string{
+		"a.txt":                                "Hello A!",
+		"sub/b.txt":                            "Hello B!",
+		"customers/ACME Corp/invoice-0042.pdf": "Invoice 42",
+	}
+	root := dirTree(t, files)
+	enc := filepath.Join(t.TempDir(), "enc")
+	opaque := regexp.MustCompile(`^[a-z2-7]{52}$`)
+
+	if err := EncryptDir(root, "myKey123", WithOpaqueNames()); err == nil {
+		t.Errorf("EncryptDir() with WithOpaqueNames but without WithDestDir expected an error\n")
+	}
+	if err := EncryptDir(root, "myKey123", WithOpaqueNames(), WithDestDir(enc)); err != nil {
+		t.Fatalf("EncryptDir() error = %v\n", err)
+	}
+	entries, _ := os.ReadDir(enc)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+		if e.IsDir() || !opaque.MatchString(e.Name()) {
+			t.Errorf("expected an opaque file name, got %s\n", e.Name())
+		}
+		if data, _ := os.ReadFile(filepath.Join(enc, e.Name())); bytes.Contains(data, []byte("invoice")) || bytes.Contains(data, []byte("Invoice")) {
+			t.Errorf("%s contains a plaintext name or contents\n", e.Name())
+		}
+	}
+	if len(names) != len(files) {
+		t.Fatalf("expected %d files, got %d\n", len(files), len(names))
+	}
+
+	// the names are deterministic
+	if err := EncryptDir(root, "myKey123", WithOpaqueNames(), WithDestDir(enc), WithOverwrite()); err != nil {
+		t.Fatalf("EncryptDir() again error = %v\n", err)
+	}
+	entries, _ = os.ReadDir(enc)
+	if len(entries) != len(names) {
+		t.Errorf("encrypting again changed the names\n")
+	}
+
+	listed, err := ListDir(enc, "myKey123")
+	if err != nil {
+		t.Fatalf("ListDir() error = %v\n", err)
+	}
+	var got, want []string
+	for _, rel := range listed {
+		got = append(got, filepath.ToSlash(rel))
+	}
+	for name := range files {
+		want = append(want, name)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDir() = %v, want %v\n", got, want)
+	}
+	if _, err := ListDir(enc, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("ListDir() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+	}
+
+	dec := filepath.Join(t.TempDir(), "dec")
+	if err := DecryptDir(enc, "myKey123", WithOpaqueNames(), WithDestDir(dec)); err != nil {
+		t.Fatalf("DecryptDir() error = %v\n", err)
+	}
+	for name, text := range files {
+		if d, _ := os.ReadFile(filepath.Join(dec, name)); string(d) != text {
+			t.Errorf("encrypt/decrypt dir failed: %s: got %q\n", name, d)
+		}
+	}
+}
+
+func Test_WithOpaqueNames_tampered(t *testing.T) {
+	root := dirTree(t, map[string]string{"a.txt": "Hello A!", "b.txt": "Hello B!"})
+	enc := filepath.Join(t.TempDir(), "enc")
+	if err := EncryptDir(root, "myKey123", WithOpaqueNames(), WithDestDir(enc)); err != nil {
+		t.Fatalf("EncryptDir() error = %v\n", err)
+	}
+	c, _ := New("myKey123", WithOpaqueNames())
+	pathA := filepath.Join(enc, c.opaqueName("a.txt"))
+	pathB := filepath.Join(enc, c.opaqueName("b.txt"))
+
+	// the contents of b.txt with the name header of a.txt
+	a, _ := os.ReadFile(pathA)
+	b, _ := os.ReadFile(pathB)
+	headerSize := func(data []byte) int {
+		return len(nameMagic) + 2 + int(binary.BigEndian.Uint16(data[len(nameMagic):]))
+	}
+	swapped := append(bytes.Clone(a[:headerSize(a)]), b[headerSize(b):]...)
+	if err := os.WriteFile(pathB, swapped, 0644); err != nil {
+		t.Fatalf("could not write file: %s\n", err)
+	}
+	dec := filepath.Join(t.TempDir(), "dec")
+	d, _ := New("myKey123", WithOpaqueNames(), WithDestDir(dec))
+	s, err := d.DecryptDir(enc)
+	if err == nil || s.Failed != 1 {
+		t.Errorf("DecryptDir() with a swapped name header = %+v, %v, want 1 failed\n", s, err)
+	}
+	if d, _ := os.ReadFile(filepath.Join(dec, "a.txt")); string(d) != "Hello A!" {
+		t.Errorf("DecryptDir() with a swapped name header restored a.txt as %q, want %q\n", d, "Hello A!")
+	}
+
+	// recorded paths must stay inside the directory
+	header, _ := c.nameHeader("../evil.txt")
+	if _, _, err := c.readNameHeader(bytes.NewReader(header)); !errors.Is(err, ErrCorruptHeader) {
+		t.Errorf("readNameHeader() of a path leaving the directory error = %v, want ErrCorruptHeader\n", err)
+	}
+	header, _ = c.nameHeader(strings.Repeat("a", 300))
+	if _, _, err := c.readNameHeader(bytes.NewReader(header)); err == nil {
+		t.Errorf("readNameHeader() of a name exceeding 255 bytes expected an error\n")
+	}
+}
+
+func Test_WithOpaqueNames_caseClash(t *testing.T) {
+	root := dirTree(t, map[string]string{"README.txt": "upper"})
+	if err := os.WriteFile(filepath.Join(root, "readme.txt"), []byte("lower"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 2 {
+		t.Skip("the file system is case-insensitive")
+	}
+	enc := filepath.Join(t.TempDir(), "enc")
+	if err := EncryptDir(root, "myKey123", WithOpaqueNames(), WithDestDir(enc)); err != nil {
+		t.Fatalf("EncryptDir() error = %v\n", err)
+	}
+	err := DecryptDir(enc, "myKey123", WithOpaqueNames(), WithDestDir(filepath.Join(t.TempDir(), "dec")))
+	if !errors.Is(err, ErrFileExists) {
+		t.Errorf("DecryptDir() of names only differing in case error = %v, want ErrFileExists\n", err)
+	}
+}
@@ -0,0 +1,77 @@
+package aesgcm
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// NonceRegistry records the nonces used with every key to detect nonce reuse, which breaks the confidentiality
+// and authenticity of AES-GCM. With random 96-bit nonces a collision is astronomically unlikely, so a detected
+// reuse points to a broken or deterministic random source, such as one set with WithRand, or a cloned process state.
+// It is safe for concurrent use by multiple goroutines.
+//
+// Only exact matches are detected. Streams and files record just the base nonce N of their chunks, while chunk i
+// is sealed with N + i, so a nonce that falls into the range of another stream's chunk nonces goes unnoticed,
+// as does overlap between the chunk nonces of two streams. With a broken random source, such reuse can happen
+// without the registry reporting it.
+//
+// Note: the registry keeps every recorded nonce for its lifetime, about 12 bytes per encryption.
+type NonceRegistry struct {
+	mu    sync.Mutex
+	seen  map[string]map[string]struct{} // nonces by key hash
+	count int
+}
+
+// NewNonceRegistry creates an empty NonceRegistry.
+func NewNonceRegistry() *NonceRegistry {
+	return &NonceRegistry{seen: map[string]map[string]struct{}{}}
+}
+
+// CheckAndRecord records 'nonce' as used with the key identified by 'keyHash'. It returns an error wrapping
+// ErrNonceReused if the nonce has already been recorded for that key, without recording it again.
+func (r *NonceRegistry) CheckAndRecord(keyHash, nonce []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nonces, ok := r.seen[string(keyHash)]
+	if !ok {
+		nonces = map[string]struct{}{}
+		r.seen[string(keyHash)] = nonces
+	}
+	if _, ok := nonces[string(nonce)]; ok {
+		return fmt.Errorf("%w: nonce %x has already been used with this key", ErrNonceReused, nonce)
+	}
+	nonces[string(nonce)] = struct{}{}
+	r.count++
+	return nil
+}
+
+// Len returns the number of nonces recorded across all keys.
+func (r *NonceRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// nonceRegistry is the registry set with EnableNonceTracking, nil if tracking is disabled.
+var nonceRegistry atomic.Pointer[NonceRegistry]
+
+// EnableNonceTracking makes every encryption in the process record its random nonces in 'r', identifying keys
+// by their fingerprint, and fail with an error wrapping ErrNonceReused instead of reusing a nonce with the same key.
+// Passing nil disables tracking, which is the default, as the registry takes a lock and grows with every encryption.
+// EncryptDeterministic is never tracked, since it reuses nonces for identical plaintexts by design.
+//
+// Tracking is incomplete for streams and files: only the base nonce of their chunks is recorded, not the nonces
+// derived from it for the following chunks, so reuse between those and other nonces isn't detected.
+// See NonceRegistry for details.
+func EnableNonceTracking(r *NonceRegistry) {
+	nonceRegistry.Store(r)
+}
+
+// trackNonce records 'nonce' for 'keyHash' in the registry set with EnableNonceTracking, if any.
+func trackNonce(keyHash, nonce []byte) error {
+	if r := nonceRegistry.Load(); r != nil {
+		return r.CheckAndRecord(keyHash, nonce)
+	}
+	return nil
+}
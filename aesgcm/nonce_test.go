@@ -0,0 +1,103 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func Test_NonceRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyHash string
+		nonce   string
+		wantErr bool
+	}{
+		{"first", "key 1", "nonce 1", false},
+		{"other nonce", "key 1", "nonce 2", false},
+		{"other key", "key 2", "nonce 1", false},
+		{"reused", "key 1", "nonce 1", true},
+		{"reused again", "key 1", "nonce 1", true},
+		{"reused other key", "key 2", "nonce 1", true},
+	}
+	r := NewNonceRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.CheckAndRecord([]byte(tt.keyHash), []byte(tt.nonce))
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrNonceReused)) {
+				t.Errorf("CheckAndRecord() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+		})
+	}
+	if n := r.Len(); n != 3 {
+		t.Errorf("expected 3 recorded nonces, got %d\n", n)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = r.CheckAndRecord([]byte("key 3"), []byte(fmt.Sprintf("%d-%d", i, j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := r.Len(); n != 3+8*100 {
+		t.Errorf("expected %d recorded nonces, got %d\n", 3+8*100, n)
+	}
+}
+
+func Test_EnableNonceTracking(t *testing.T) {
+	r := NewNonceRegistry()
+	EnableNonceTracking(r)
+	t.Cleanup(func() { EnableNonceTracking(nil) })
+
+	nonce := []byte("0123456789ab")
+	if _, err := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce))); err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	if _, err := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce))); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("expected ErrNonceReused for a reused nonce, got %v\n", err)
+	}
+	if _, err := Encrypt("Hello World!", "otherKey", WithRand(bytes.NewReader(nonce))); err != nil {
+		t.Errorf("expected the nonce to be accepted for another key, got %v\n", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := EncryptStream(io.Discard, bytes.NewReader(nil), "myKey123")
+		if err != nil {
+			t.Fatalf("could not encrypt stream: %s\n", err)
+		}
+	}
+	c, _ := New("streamKey", WithRand(bytes.NewReader(bytes.Repeat(nonce, 2))))
+	if err := c.EncryptStream(io.Discard, bytes.NewReader(nil)); err != nil {
+		t.Fatalf("could not encrypt stream: %s\n", err)
+	}
+	if err := c.EncryptStream(io.Discard, bytes.NewReader(nil)); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("expected ErrNonceReused for a reused stream nonce, got %v\n", err)
+	}
+
+	before := r.Len()
+	if err := EncryptToFile([]byte("Hello World!"), filepath.Join(t.TempDir(), "secret.txt"), "myKey123"); err != nil {
+		t.Fatalf("could not encrypt to file: %s\n", err)
+	}
+	if r.Len() != before+1 {
+		t.Errorf("expected EncryptToFile to record its nonce\n")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := EncryptDeterministic("Hello World!", "myKey123"); err != nil {
+			t.Errorf("EncryptDeterministic() must not be tracked, got %v\n", err)
+		}
+	}
+
+	EnableNonceTracking(nil)
+	if _, err := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce))); err != nil {
+		t.Errorf("expected no tracking once disabled, got %v\n", err)
+	}
+}
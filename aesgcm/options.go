@@ -0,0 +1,207 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultKeySize is the AES key size in bytes used unless another size is requested with WithKeySize.
+// It selects AES-256.
+const DefaultKeySize = 32
+
+// keySizeHeaderSize is the length of the header that records a non-default key size.
+const keySizeHeaderSize = 4
+
+// keySizeMagic identifies the key size header.
+var keySizeMagic = []byte("AGK")
+
+// keySizeHeader returns the header recording the key size 'size'.
+func keySizeHeader(size int) []byte {
+	return append(append([]byte{}, keySizeMagic...), byte(size))
+}
+
+// parseKeySizeHeader returns the key size recorded in the header of 'data' and whether a valid header was found.
+func parseKeySizeHeader(data []byte) (int, bool) {
+	if len(data) < keySizeHeaderSize || string(data[:len(keySizeMagic)]) != string(keySizeMagic) {
+		return 0, false
+	}
+	switch size := int(data[len(keySizeMagic)]); size {
+	case 16, 24, 32:
+		return size, true
+	}
+	return 0, false
+}
+
+// options holds the settings that can be changed with an Option.
+type options struct {
+	keySize      int
+	aad          []byte
+	encoding     Encoding
+	rand         io.Reader
+	keyCheck     bool
+	fingerprint  bool
+	kdf          KDFFunc
+	chunkSize    int
+	overwrite    bool
+	ownership    bool
+	progress     ProgressFunc
+	destDir      string
+	include      []string
+	exclude      []string
+	concurrency  int
+	legacyFormat bool
+	force        bool
+	shredSource  bool
+	backup       string // suffix of the backups made by WithBackup, empty for none
+	backupPolicy BackupPolicy
+	maxSize      int64 // plaintext limit for decryption, 0 for none
+	symlinks     SymlinkPolicy
+	extension    string // suffix set with WithExtension, empty for none
+	keepOriginal bool
+	opaqueNames  bool
+	pathBinding  bool
+	pathRoot     string // root set with WithPathRoot, empty for absolute paths
+	originalPath string // path set with WithOriginalPath, empty for the actual path
+	padding      int    // block size set with WithPadding, 0 for none
+	compression  Compression
+	clock        Clock
+	clockSkew    time.Duration // tolerance of DecryptWithMaxAge
+	encryptOnly  []string      // names of the applied options that only apply to encryption
+}
+
+// Option configures how data is encrypted and decrypted.
+type Option func(*options) error
+
+// newOptions returns the default options with 'opts' applied.
+// Without any options, the result matches the behavior of Encrypt and Decrypt without options.
+func newOptions(opts ...Option) (*options, error) {
+	o := &options{
+		keySize:   DefaultKeySize,
+		encoding:  StdBase64,
+		rand:      rand.Reader,
+		chunkSize: DefaultChunkSize,
+		maxSize:   DefaultMaxSize,
+		clock:     systemClock{},
+		clockSkew: DefaultClockSkew,
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// newDecryptOptions is like newOptions but returns an error if an encryption-only option has been passed.
+func newDecryptOptions(opts ...Option) (*options, error) {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.encryptOnly) > 0 {
+		return nil, fmt.Errorf("%s can only be used for encryption", strings.Join(o.encryptOnly, ", "))
+	}
+	return o, nil
+}
+
+// WithKeySize selects the AES key size in bytes: 16 for AES-128, 24 for AES-192 or 32 for AES-256 (the default).
+// The scrambled key is truncated to the requested length.
+//
+// Ciphertexts produced with a non-default key size are prefixed with a header recording the key size,
+// so decrypting them with a different setting fails with ErrKeySizeMismatch instead of an authentication error.
+func WithKeySize(size int) Option {
+	return func(o *options) error {
+		switch size {
+		case 16, 24, 32:
+			o.keySize = size
+			return nil
+		}
+		return fmt.Errorf("invalid key size %d, must be 16, 24 or 32", size)
+	}
+}
+
+// WithAAD sets the additional authenticated data (AAD) the ciphertext is bound to.
+// The AAD is not stored inside the ciphertext, the same value must be passed for decryption.
+func WithAAD(aad []byte) Option {
+	return func(o *options) error {
+		o.aad = aad
+		return nil
+	}
+}
+
+// WithEncoding sets the Encoding of textual ciphertexts, which defaults to StdBase64.
+// It has no effect on functions that work with raw bytes or files.
+func WithEncoding(enc Encoding) Option {
+	return func(o *options) error {
+		if enc == nil {
+			return fmt.Errorf("encoding must not be nil")
+		}
+		o.encoding = enc
+		return nil
+	}
+}
+
+// WithRand sets the source of randomness used to generate nonces, which defaults to crypto/rand.Reader.
+// It is meant for reproducible tests and must not be used with predictable sources in production.
+// It can only be used for encryption.
+func WithRand(r io.Reader) Option {
+	return func(o *options) error {
+		if r == nil {
+			return fmt.Errorf("rand must not be nil")
+		}
+		o.rand = r
+		o.encryptOnly = append(o.encryptOnly, "WithRand")
+		return nil
+	}
+}
+
+// KDFFunc derives the raw AES key material from a key or password.
+// The result must hold at least as many bytes as the selected key size, excess bytes are ignored.
+// It is wiped once the Cipher has been set up, so the KDF must return a new slice on every call.
+type KDFFunc func(key string) ([]byte, error)
+
+// WithKDF replaces keys.WeakKeyScrambler as the function that derives the AES key from the provided key.
+// Keys are still checked with the same rules as without a KDF, see MinKeyLength.
+// Ciphertexts can only be decrypted with the KDF they were encrypted with.
+func WithKDF(kdf KDFFunc) Option {
+	return func(o *options) error {
+		if kdf == nil {
+			return fmt.Errorf("kdf must not be nil")
+		}
+		o.kdf = kdf
+		return nil
+	}
+}
+
+// WithChunkSize sets the amount of plaintext sealed per chunk by the stream functions, which defaults to
+// DefaultChunkSize. Decryption reads the chunk size from the stream, so it has no effect there.
+func WithChunkSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 || n > maxChunkSize {
+			return fmt.Errorf("invalid chunk size %d, must be between 1 and %d", n, maxChunkSize)
+		}
+		o.chunkSize = n
+		return nil
+	}
+}
+
+// WithOverwrite allows EncryptFileTo and DecryptFileTo to replace an existing destination file.
+func WithOverwrite() Option {
+	return func(o *options) error {
+		o.overwrite = true
+		return nil
+	}
+}
+
+// WithOwnership makes file operations also restore the owner and group of the original file on the output file.
+// Changing the owner requires root privileges, the option has no effect for other users or on Windows.
+// The permissions and modification time are always restored.
+func WithOwnership() Option {
+	return func(o *options) error {
+		o.ownership = true
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_keySize(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"aes-128", 16},
+		{"aes-192", 24},
+		{"aes-256", 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("myKey123", WithKeySize(tt.size))
+			if err != nil {
+				t.Fatalf("could not create cipher: %s\n", err)
+			}
+			e, err := c.Encrypt("Hello World!")
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if d, err := c.Decrypt(e); err != nil || d != "Hello World!" {
+				t.Errorf("encrypt/decrypt with key size %d failed: got %v (%v)\n", tt.size, d, err)
+			}
+
+			for _, other := range []int{16, 24, 32} {
+				if other == tt.size {
+					continue
+				}
+				oc, _ := New("myKey123", WithKeySize(other))
+				if _, err := oc.Decrypt(e); !errors.Is(err, ErrKeySizeMismatch) {
+					t.Errorf("expected ErrKeySizeMismatch decrypting %d-byte ciphertext with %d-byte key, got %v\n", tt.size, other, err)
+				}
+			}
+			if tt.size != DefaultKeySize {
+				if _, err := Decrypt(e, "myKey123"); !errors.Is(err, ErrKeySizeMismatch) {
+					t.Errorf("expected ErrKeySizeMismatch from package Decrypt, got %v\n", err)
+				}
+			}
+		})
+	}
+
+	e, _ := Encrypt("Hello World!", "myKey123")
+	c, _ := New("myKey123", WithKeySize(DefaultKeySize))
+	if d, err := c.Decrypt(e); err != nil || d != "Hello World!" {
+		t.Errorf("default key size is not compatible with Encrypt: got %v (%v)\n", d, err)
+	}
+
+	if _, err := New("myKey123", WithKeySize(20)); err == nil {
+		t.Errorf("expected error for invalid key size\n")
+	}
+}
+
+func Test_options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"no options", nil},
+		{"aad", []Option{WithAAD([]byte("user-42"))}},
+		{"hex", []Option{WithEncoding(Hex)}},
+		{"aad + hex", []Option{WithAAD([]byte("user-42")), WithEncoding(Hex)}},
+		{"aad + url + aes-128", []Option{WithAAD([]byte("tenant")), WithEncoding(RawURL), WithKeySize(16)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt("Hello World!", "myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			d, err := Decrypt(e, "myKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not decrypt: %s\n", err)
+			}
+			if d != "Hello World!" {
+				t.Errorf("encrypt/decrypt with options failed: %v: got %v\n", tt.name, d)
+			}
+			if len(tt.opts) > 0 {
+				if _, err := Decrypt(e, "myKey123"); err == nil {
+					t.Errorf("decrypt without options succeeded: %v\n", tt.name)
+				}
+			}
+		})
+	}
+
+	e, _ := Encrypt("Hello World!", "myKey123", WithAAD([]byte("user-42")), WithEncoding(Hex))
+	if d, err := DecryptHex(e, "myKey123"); err == nil {
+		t.Errorf("decrypt hex without aad succeeded: got %v\n", d)
+	}
+	if d, err := DecryptWithAAD(e, "myKey123", []byte("user-42")); err == nil {
+		t.Errorf("decrypt base64 of hex ciphertext succeeded: got %v\n", d)
+	}
+
+	if _, err := Decrypt(e, "myKey123", WithRand(bytes.NewReader(make([]byte, 64)))); err == nil {
+		t.Errorf("expected error when passing WithRand to Decrypt\n")
+	}
+	if _, err := Encrypt("Hello World!", "myKey123", WithEncoding(nil)); err == nil {
+		t.Errorf("expected error for nil encoding\n")
+	}
+}
+
+func Test_withRand(t *testing.T) {
+	nonce := []byte("0123456789ab")
+	clock := WithClock(ClockFunc(func() time.Time { return time.UnixMilli(0x0102030405) }))
+	e1, err := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce)), clock)
+	if err != nil {
+		t.Fatalf("could not encrypt with fixed rand: %s\n", err)
+	}
+	e2, _ := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce)), clock)
+	if e1 != e2 {
+		t.Errorf("expected identical output for identical rand, got %s and %s\n", e1, e2)
+	}
+	if !strings.HasPrefix(e1, "QUdIAgMBAAwwMTIzNDU2Nzg5YWIAAAABAgME") {
+		t.Errorf("expected ciphertext to start with the base64 of the header, got %s\n", e1)
+	}
+
+	// regression vector for the format: base64(header || AES-256-GCM(key).Seal(plaintext)), authenticating
+	// the header as additional data. The key is fixed with WithKDF, so the vector doesn't depend on keys.WeakKeyScrambler.
+	kdf := WithKDF(func(string) ([]byte, error) { return []byte("0123456789abcdef0123456789abcdef"), nil })
+	e3, err := Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce)), clock, kdf)
+	if err != nil {
+		t.Fatalf("could not encrypt with fixed rand and key: %s\n", err)
+	}
+	if want := "QUdIAgMCAAwwMTIzNDU2Nzg5YWIAAAABAgMEBdHMi1Ys6s90h2lHnC6BbOXfIFi8lfeAXIvL+yY="; e3 != want {
+		t.Errorf("unexpected ciphertext format: expected %s, got %s\n", want, e3)
+	}
+
+	// version 1 headers without creation time are still decrypted
+	legacy := "QUdIAQMCAAwwMTIzNDU2Nzg5YWLRzItWLOrPdIdpR5wDopgtZftLtrYD8XgkE4mm"
+	if d, err := Decrypt(legacy, "myKey123", kdf); err != nil || d != "Hello World!" {
+		t.Errorf("Decrypt() of a version 1 ciphertext = %q, %v\n", d, err)
+	}
+
+	_, err = Encrypt("Hello World!", "myKey123", WithRand(bytes.NewReader(nonce[:5])))
+	if err == nil || !strings.Contains(err.Error(), "5 of 12 bytes") {
+		t.Errorf("expected descriptive error for short rand, got %v\n", err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package aesgcm
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_WithOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner requires root")
+	}
+	owner := func(path string) (uint32, uint32) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("could not stat file: %s\n", err)
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		return st.Uid, st.Gid
+	}
+	path := attrFile(t)
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Skipf("could not change owner: %s\n", err)
+	}
+	if err := EncryptFile(path, "myKey123", WithOwnership()); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	if uid, gid := owner(path); uid != 1234 || gid != 5678 {
+		t.Errorf("owner = %d:%d, want 1234:5678\n", uid, gid)
+	}
+	checkAttrs(t, "EncryptFile", path)
+	if err := DecryptFile(path, "myKey123"); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if uid, _ := owner(path); uid == 1234 {
+		t.Errorf("expected the owner to change without WithOwnership\n")
+	}
+	checkAttrs(t, "DecryptFile", path)
+}
@@ -0,0 +1,236 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// paddingMagic precedes the block size in the additional data of ciphertexts padded with WithPadding,
+// so they can't be decrypted with a different setting, or without it, and yield the padded plaintext.
+// pkcs7Magic precedes the additional data of ciphertexts written by EncryptPadded for the same reason.
+var (
+	paddingMagic = []byte("AGP\x01")
+	pkcs7Magic   = []byte("AGP\x02")
+)
+
+// maxPaddingBlockSize limits the block size of WithPadding, as up to a block is held back while decrypting a stream.
+const maxPaddingBlockSize = maxChunkSize
+
+// WithPadding pads the plaintext to the next multiple of 'blockSize' bytes before encryption, so ciphertexts
+// only reveal the length of the plaintext rounded up to the block size. The padding is a single 0x80 byte
+// followed by zero bytes, which is always appended, so any plaintext round-trips. It applies to strings
+// and bytes as well as to the final chunk of streams and files.
+//
+// The same option must be passed for decryption, which strips the padding. The block size is authenticated
+// as additional data, so decrypting with a different setting fails with ErrAuthenticationFailed.
+// Malformed padding is rejected with an error wrapping ErrInvalidPadding.
+func WithPadding(blockSize int) Option {
+	return func(o *options) error {
+		if blockSize < 1 || blockSize > maxPaddingBlockSize {
+			return fmt.Errorf("invalid padding block size %d, must be between 1 and %d", blockSize, maxPaddingBlockSize)
+		}
+		o.padding = blockSize
+		return nil
+	}
+}
+
+// paddingAAD returns the additional data 'aad' preceded by the padding magic and 'blockSize'.
+func paddingAAD(blockSize int, aad []byte) []byte {
+	header := binary.BigEndian.AppendUint32(bytes.Clone(paddingMagic), uint32(blockSize))
+	return append(header, aad...)
+}
+
+// padLen returns the length of the padding appended to 'n' bytes of plaintext, between 1 and 'blockSize'.
+func padLen(n int64, blockSize int) int {
+	return blockSize - int(n%int64(blockSize))
+}
+
+// padding returns the padding appended to 'n' bytes of plaintext.
+func padding(n int64, blockSize int) []byte {
+	p := make([]byte, padLen(n, blockSize))
+	p[0] = 0x80
+	return p
+}
+
+// pad returns a copy of 'p' with the padding appended.
+func pad(p []byte, blockSize int) []byte {
+	return append(bytes.Clone(p), padding(int64(len(p)), blockSize)...)
+}
+
+// unpad returns 'p' without the padding appended by pad.
+func unpad(p []byte, blockSize int) ([]byte, error) {
+	if len(p)%blockSize != 0 {
+		return nil, fmt.Errorf("%w: length %d isn't a multiple of %d", ErrInvalidPadding, len(p), blockSize)
+	}
+	return trimPadding(p, blockSize)
+}
+
+// trimPadding returns 'p' without the padding at its end, which must be at most 'blockSize' bytes long.
+func trimPadding(p []byte, blockSize int) ([]byte, error) {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-blockSize; i-- {
+		if p[i] == 0x80 {
+			return p[:i], nil
+		}
+		if p[i] != 0 {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%w: no padding marker", ErrInvalidPadding)
+}
+
+// padReader appends the padding to the plaintext read from 'r' once it is exhausted.
+type padReader struct {
+	r         io.Reader
+	blockSize int
+	n         int64  // plaintext read so far
+	pad       []byte // padding not yet returned
+	done      bool   // whether 'r' is exhausted
+}
+
+// padReader wraps the plaintext reader 'r' to append the padding set with WithPadding, if any.
+func (c *Cipher) padReader(r io.Reader) io.Reader {
+	if c.opts.padding == 0 {
+		return r
+	}
+	return &padReader{r: r, blockSize: c.opts.padding}
+}
+
+// Read returns plaintext read from 'r', followed by the padding.
+func (pr *padReader) Read(p []byte) (int, error) {
+	if !pr.done {
+		n, err := pr.r.Read(p)
+		pr.n += int64(n)
+		if err != io.EOF {
+			return n, err
+		}
+		pr.done = true
+		pr.pad = padding(pr.n, pr.blockSize)
+		if n > 0 {
+			return n, nil
+		}
+	}
+	if len(pr.pad) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, pr.pad)
+	pr.pad = pr.pad[n:]
+	return n, nil
+}
+
+// unpadder strips the padding from the plaintext of a stream chunk by chunk. As the padding may span
+// the final two chunks, the last block of every chunk is held back until the next one has been decrypted.
+type unpadder struct {
+	blockSize int
+	held      []byte // plaintext held back from the previous chunk
+	out       []byte // plaintext returned for the previous chunk, wiped by the next call
+	n         int64  // padded plaintext seen so far
+}
+
+// newUnpadder returns an unpadder for the padding set with WithPadding, or nil if there is none.
+func (c *Cipher) newUnpadder() *unpadder {
+	if c.opts.padding == 0 {
+		return nil
+	}
+	return &unpadder{blockSize: c.opts.padding}
+}
+
+// next takes the decrypted plaintext of the next chunk, which is the final one if 'last' is set,
+// and returns the plaintext that can be released.
+func (u *unpadder) next(plain []byte, last bool) ([]byte, error) {
+	u.n += int64(len(plain))
+	data := make([]byte, 0, len(u.held)+len(plain))
+	data = append(append(data, u.held...), plain...)
+	u.wipe()
+	u.out = data
+	if last {
+		if u.n%int64(u.blockSize) != 0 {
+			return nil, fmt.Errorf("%w: length %d isn't a multiple of %d", ErrInvalidPadding, u.n, u.blockSize)
+		}
+		return trimPadding(data, u.blockSize)
+	}
+	keep := min(len(data), u.blockSize)
+	u.held = bytes.Clone(data[len(data)-keep:])
+	return data[:len(data)-keep], nil
+}
+
+// wipe clears the plaintext held by the unpadder. It does nothing if 'u' is nil.
+func (u *unpadder) wipe() {
+	if u == nil {
+		return
+	}
+	clear(u.held)
+	clear(u.out)
+	u.held, u.out = nil, nil
+}
+
+// EncryptPadded pads the given plaintext to the next multiple of 'blockSize' bytes with PKCS#7 padding and encrypts
+// the result using AES-GCM encryption with the provided key. It returns the encoded ciphertext and any error encountered.
+//
+// 'blockSize' must be between 1 and 255. Larger block sizes hide the plaintext length better, as more plaintexts
+// share the same ciphertext length, but increase the ciphertext size by up to 'blockSize' bytes; smaller ones cost
+// less but reveal more. A full block of padding is added to plaintexts that already are a multiple of the block size.
+// Use DecryptPadded to decrypt, Decrypt fails with ErrAuthenticationFailed. See WithPadding for larger block sizes.
+func EncryptPadded(plaintext, key string, blockSize int, opts ...Option) (string, error) {
+	c, err := New(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.EncryptPadded(plaintext, blockSize)
+}
+
+// DecryptPadded decrypts a ciphertext produced by EncryptPadded using AES-GCM decryption with the provided key
+// and strips the padding, whose length is recorded in the padding itself. It returns the plaintext and any
+// error encountered, which wraps ErrInvalidPadding if the padding is malformed.
+func DecryptPadded(ciphertext, key string, opts ...Option) (string, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.DecryptPadded(ciphertext)
+}
+
+// EncryptPadded pads the given plaintext to the next multiple of 'blockSize' bytes, encrypts it and returns
+// the encoded ciphertext. See the package-level EncryptPadded for details.
+func (c *Cipher) EncryptPadded(plaintext string, blockSize int) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	if blockSize < 1 || blockSize > 255 {
+		return "", fmt.Errorf("invalid block size %d, must be between 1 and 255", blockSize)
+	}
+	n := padLen(int64(len(plaintext)), blockSize)
+	padded := append([]byte(plaintext), bytes.Repeat([]byte{byte(n)}, n)...)
+	defer clear(padded)
+	return c.pkcs7Cipher().Encrypt(string(padded))
+}
+
+// DecryptPadded decrypts a ciphertext produced by EncryptPadded and strips the padding.
+// See the package-level DecryptPadded for details.
+func (c *Cipher) DecryptPadded(ciphertext string) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	padded, err := c.pkcs7Cipher().Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(padded) == 0 {
+		return "", fmt.Errorf("%w: no padding", ErrInvalidPadding)
+	}
+	n := int(padded[len(padded)-1])
+	if n == 0 || n > len(padded) || bytes.Count([]byte(padded[len(padded)-n:]), []byte{byte(n)}) != n {
+		return "", fmt.Errorf("%w: malformed PKCS#7 padding", ErrInvalidPadding)
+	}
+	return padded[:len(padded)-n], nil
+}
+
+// pkcs7Cipher returns a copy of the Cipher that authenticates the PKCS#7 padding flag before the AAD set with WithAAD.
+func (c *Cipher) pkcs7Cipher() *Cipher {
+	return c.withAAD(append(bytes.Clone(pkcs7Magic), c.opts.aad...))
+}
@@ -0,0 +1,226 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_WithPadding(t *testing.T) {
+	for _, size := range []int{0, -1, maxPaddingBlockSize + 1} {
+		if _, err := New("myKey123", WithPadding(size)); err == nil {
+			t.Errorf("WithPadding(%d) expected an error\n", size)
+		}
+	}
+
+	c, _ := New("myKey123", WithPadding(32))
+	tests := []struct {
+		name      string
+		plaintext string
+		blocks    int
+	}{
+		{"empty", "", 1},
+		{"short", "secret", 1},
+		{"one byte less than a block", strings.Repeat("a", 31), 1},
+		{"a full block", strings.Repeat("a", 32), 2},
+		{"ends like padding", "data\x80", 1},
+		{"ends like padding with zeros", "data\x80\x00\x00", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := c.EncryptBytes([]byte(tt.plaintext))
+			if err != nil {
+				t.Fatalf("EncryptBytes() error = %v\n", err)
+			}
+			plain, _ := EncryptBytes([]byte(tt.plaintext), "myKey123")
+			if got, want := len(encrypted)-len(plain), tt.blocks*32-len(tt.plaintext); got != want {
+				t.Errorf("padding added %d bytes, want %d\n", got, want)
+			}
+			decrypted, err := c.DecryptBytes(encrypted)
+			if err != nil || string(decrypted) != tt.plaintext {
+				t.Errorf("DecryptBytes() = %q, %v, want %q\n", decrypted, err, tt.plaintext)
+			}
+		})
+	}
+
+	// same bucket, same length
+	a, _ := c.Encrypt("short")
+	b, _ := c.Encrypt("a little longer")
+	if len(a) != len(b) {
+		t.Errorf("plaintexts in the same bucket have different lengths: %d and %d\n", len(a), len(b))
+	}
+
+	// the setting must match
+	if _, err := DecryptBytes(mustEncryptBytes(t, c, "secret"), "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptBytes() without WithPadding error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	other, _ := New("myKey123", WithPadding(16))
+	if _, err := other.DecryptBytes(mustEncryptBytes(t, c, "secret")); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptBytes() with a different block size error = %v, want ErrAuthenticationFailed\n", err)
+	}
+}
+
+func mustEncryptBytes(t *testing.T, c *Cipher, plaintext string) []byte {
+	t.Helper()
+	encrypted, err := c.EncryptBytes([]byte(plaintext))
+	if err != nil {
+		t.Fatalf("EncryptBytes() error = %v\n", err)
+	}
+	return encrypted
+}
+
+func Test_unpad(t *testing.T) {
+	tests := []struct {
+		name   string
+		padded string
+		want   string
+		err    bool
+	}{
+		{"valid", "ab\x80\x00", "ab", false},
+		{"only padding", "\x80\x00\x00\x00", "", false},
+		{"marker last", "abc\x80", "abc", false},
+		{"no marker", "ab\x00\x00", "", true},
+		{"wrong marker", "ab\x01\x00", "", true},
+		{"not a multiple", "ab\x80", "", true},
+		{"too long", "\x80\x00\x00\x00\x00\x00\x00\x00", "", true},
+		{"empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unpad([]byte(tt.padded), 4)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidPadding) || !errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("unpad() error = %v, want ErrInvalidPadding\n", err)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("unpad() = %q, %v, want %q\n", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WithPadding_stream(t *testing.T) {
+	c, _ := New("myKey123", WithPadding(100), WithChunkSize(64))
+	for _, size := range []int{0, 1, 63, 64, 99, 100, 128, 1000} {
+		plaintext := bytes.Repeat([]byte{0x80, 0}, size)[:size]
+
+		var enc bytes.Buffer
+		if err := c.EncryptStream(&enc, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("EncryptStream() error = %v\n", err)
+		}
+		var dec bytes.Buffer
+		if err := c.DecryptStream(&dec, bytes.NewReader(enc.Bytes())); err != nil || !bytes.Equal(dec.Bytes(), plaintext) {
+			t.Errorf("size %d: DecryptStream() = %d bytes, %v\n", size, dec.Len(), err)
+		}
+
+		// readers and writers produce and accept the same format
+		got, err := io.ReadAll(c.DecryptReader(c.EncryptReader(bytes.NewReader(plaintext))))
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("size %d: EncryptReader() and DecryptReader() = %d bytes, %v\n", size, len(got), err)
+		}
+		var out bytes.Buffer
+		ew, _ := c.EncryptWriter(&out)
+		_, _ = ew.Write(plaintext)
+		if err := ew.Close(); err != nil {
+			t.Fatalf("Close() error = %v\n", err)
+		}
+		dec.Reset()
+		dw := c.DecryptWriter(&dec)
+		_, _ = dw.Write(out.Bytes())
+		if err := dw.Close(); err != nil || !bytes.Equal(dec.Bytes(), plaintext) {
+			t.Errorf("size %d: EncryptWriter() and DecryptWriter() = %d bytes, %v\n", size, dec.Len(), err)
+		}
+	}
+
+	// streams without padding fail to authenticate
+	var enc bytes.Buffer
+	if err := EncryptStream(&enc, strings.NewReader("Hello World!"), "myKey123"); err != nil {
+		t.Fatalf("EncryptStream() error = %v\n", err)
+	}
+	if err := c.DecryptStream(io.Discard, &enc); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptStream() of an unpadded stream error = %v, want ErrAuthenticationFailed\n", err)
+	}
+}
+
+func Test_WithPadding_file(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[string]int{"a": 1, "b": 900}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if err := EncryptFile(filepath.Join(dir, name), "myKey123", WithPadding(1024)); err != nil {
+			t.Fatalf("EncryptFile() error = %v\n", err)
+		}
+	}
+	a, _ := os.Stat(filepath.Join(dir, "a"))
+	b, _ := os.Stat(filepath.Join(dir, "b"))
+	if a.Size() != b.Size() {
+		t.Errorf("files in the same bucket have different sizes: %d and %d\n", a.Size(), b.Size())
+	}
+	for name, size := range sizes {
+		if err := DecryptFile(filepath.Join(dir, name), "myKey123", WithPadding(1024)); err != nil {
+			t.Fatalf("DecryptFile() error = %v\n", err)
+		}
+		if d, _ := os.ReadFile(filepath.Join(dir, name)); len(d) != size {
+			t.Errorf("DecryptFile() = %d bytes, want %d\n", len(d), size)
+		}
+	}
+}
+
+func Test_EncryptPadded(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		blockSize int
+		wantErr   bool
+	}{
+		{"empty", "", 16, false},
+		{"short", "secret", 16, false},
+		{"a full block", strings.Repeat("a", 16), 16, false},
+		{"ends like padding", "data\x01", 8, false},
+		{"ends like full padding", "\x08\x08\x08\x08\x08\x08\x08\x08", 8, false},
+		{"block size 1", "secret", 1, false},
+		{"block size 255", "secret", 255, false},
+		{"block size 0", "secret", 0, true},
+		{"block size 256", "secret", 256, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptPadded(tt.plaintext, "myKey123", tt.blockSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncryptPadded() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if d, err := DecryptPadded(e, "myKey123"); err != nil || d != tt.plaintext {
+				t.Errorf("DecryptPadded() = %q, %v, want %q\n", d, err, tt.plaintext)
+			}
+			if _, err := Decrypt(e, "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Decrypt() of a padded ciphertext error = %v, want ErrAuthenticationFailed\n", err)
+			}
+		})
+	}
+
+	a, _ := EncryptPadded("yes", "myKey123", 32)
+	b, _ := EncryptPadded("no, certainly not", "myKey123", 32)
+	if len(a) != len(b) {
+		t.Errorf("plaintexts in the same block have different lengths: %d and %d\n", len(a), len(b))
+	}
+
+	// padding is checked after authentication
+	c, _ := New("myKey123")
+	for _, padded := range []string{"", "data\x00", "data\x02\x03", "\x09", strings.Repeat("\x81", 129) + "\x82"} {
+		e, _ := c.pkcs7Cipher().Encrypt(padded)
+		if _, err := c.DecryptPadded(e); !errors.Is(err, ErrInvalidPadding) {
+			t.Errorf("DecryptPadded() of %q error = %v, want ErrInvalidPadding\n", padded, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// saltSize is the length of the random salt of password-based ciphertexts.
+const saltSize = 16
+
+const (
+	// argon2ParamsSize is the length of the encoded Argon2 parameters: time + memory + threads.
+	argon2ParamsSize = 4 + 4 + 1
+
+	// maxArgon2Time, maxArgon2Memory and maxArgon2Work limit the parameters read from a ciphertext header
+	// to keep hostile ciphertexts from stalling decryption or exhausting memory. maxArgon2Work bounds the
+	// product of time and memory, e.g. 4 passes over 1 GiB or 64 passes over 64 MiB.
+	maxArgon2Time   = 64
+	maxArgon2Memory = 1 << 20 // 1 GiB
+	maxArgon2Work   = 4 << 20 // 4 GiB
+)
+
+// Argon2Params holds the Argon2id tuning parameters used to derive keys from passwords.
+type Argon2Params struct {
+	Time    uint32 // number of passes over the memory
+	Memory  uint32 // memory usage in KiB
+	Threads uint8  // degree of parallelism
+}
+
+// DefaultArgon2Params are the parameters used by EncryptWithPassword.
+// They follow the recommendations of RFC 9106 for interactive use.
+//
+// The parameters are recorded in the ciphertext header, so they can be changed without affecting the decryption
+// of existing ciphertexts. Only ciphertexts of earlier versions, which lack the header, are decrypted with the
+// current values. Time must be between 1 and 64, Memory between 8*Threads KiB and 1 GiB, Time*Memory at most
+// 4 GiB and Threads at least 1.
+var DefaultArgon2Params = Argon2Params{
+	Time:    1,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// validate returns an error if the parameters are out of range.
+func (p Argon2Params) validate() error {
+	if p.Time < 1 || p.Time > maxArgon2Time || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+		return fmt.Errorf("invalid Argon2 parameters: time=%d, memory=%d KiB, threads=%d", p.Time, p.Memory, p.Threads)
+	}
+	if uint64(p.Time)*uint64(p.Memory) > maxArgon2Work {
+		return fmt.Errorf("invalid Argon2 parameters: time*memory must be at most %d KiB, got time=%d, memory=%d KiB", maxArgon2Work, p.Time, p.Memory)
+	}
+	return nil
+}
+
+// encodeArgon2Params returns the parameters 'p' followed by 'salt' as recorded in the ciphertext header.
+func encodeArgon2Params(p Argon2Params, salt []byte) []byte {
+	b := binary.BigEndian.AppendUint32(make([]byte, 0, argon2ParamsSize+len(salt)), p.Time)
+	b = binary.BigEndian.AppendUint32(b, p.Memory)
+	return append(append(b, p.Threads), salt...)
+}
+
+// decodeArgon2Params returns the parameters and the salt recorded in the ciphertext header.
+func decodeArgon2Params(b []byte) (Argon2Params, []byte, error) {
+	if len(b) != argon2ParamsSize+saltSize {
+		return Argon2Params{}, nil, fmt.Errorf("%w: invalid Argon2 parameter length %d", ErrCorruptHeader, len(b))
+	}
+	p := Argon2Params{Time: binary.BigEndian.Uint32(b), Memory: binary.BigEndian.Uint32(b[4:]), Threads: b[8]}
+	if err := p.validate(); err != nil {
+		return Argon2Params{}, nil, fmt.Errorf("%w: %w", ErrCorruptHeader, err)
+	}
+	return p, b[argon2ParamsSize:], nil
+}
+
+// newPasswordKeyCipher creates a new keyCipher instance with a 32-byte key derived
+// from the password and salt using Argon2id with the parameters 'p'.
+func newPasswordKeyCipher(password string, salt []byte, p Argon2Params) *keyCipher {
+	pw := []byte(password)
+	defer clear(pw)
+	return &keyCipher{key: argon2.IDKey(pw, salt, p.Time, p.Memory, p.Threads, 32)}
+}
+
+// EncryptWithPassword encrypts the given plaintext using AES-GCM encryption with a key derived
+// from the password via Argon2id with DefaultArgon2Params. It returns the base64-encoded encrypted
+// ciphertext and any error encountered.
+//
+// A random 16-byte salt is generated for every call. It is recorded in the ciphertext header along with
+// the Argon2 parameters, so DecryptWithPassword can derive the same key again.
+func EncryptWithPassword(plaintext, password string) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	p := DefaultArgon2Params
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	kc := newPasswordKeyCipher(password, salt, p)
+	defer kc.wipe()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	encrypted, err := kc.sealWithHeader(pt, kdfArgon2id, encodeArgon2Params(p, salt))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// DecryptWithPassword decrypts the given base64-encoded encrypted text produced by EncryptWithPassword.
+// It returns the decrypted plaintext and any error encountered. Ciphertexts of earlier versions, which
+// consist of the salt followed by the nonce and the sealed data, are decrypted with DefaultArgon2Params.
+func DecryptWithPassword(text, password string) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	encryptedData, err := decodeCiphertext(text, StdBase64)
+	if err != nil {
+		return "", err
+	}
+	derive := func(params []byte) (*keyCipher, error) {
+		p, salt, err := decodeArgon2Params(params)
+		if err != nil {
+			return nil, err
+		}
+		return newPasswordKeyCipher(password, salt, p), nil
+	}
+	legacy := func(data []byte) ([]byte, error) {
+		if len(data) < saltSize {
+			return nil, ErrCiphertextTooShort
+		}
+		p := DefaultArgon2Params
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		kc := newPasswordKeyCipher(password, data[:saltSize], p)
+		defer kc.wipe()
+		return kc.decrypt(data[saltSize:], nil)
+	}
+	decrypted, err := openPasswordCiphertext(encryptedData, kdfArgon2id, derive, legacy)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
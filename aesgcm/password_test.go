@@ -0,0 +1,88 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func Test_password(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		password string
+	}{
+		{"password 1", "Hello World!", "myKey123"},
+		{"password 2", "Hello World!", "correct horse battery staple"},
+		{"password 3", "", "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptWithPassword(tt.text, tt.password)
+			if err != nil {
+				t.Fatalf("could not encrypt with password: %s\n", err)
+			}
+			d, err := DecryptWithPassword(e, tt.password)
+			if err != nil {
+				t.Fatalf("could not decrypt with password: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt with password failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := DecryptWithPassword(e, tt.password+"x"); err == nil {
+				t.Errorf("decrypt with wrong password succeeded: %v\n", tt.name)
+			}
+			e2, _ := EncryptWithPassword(tt.text, tt.password)
+			if e == e2 {
+				t.Errorf("expected different ciphertexts due to random salt: %v\n", tt.name)
+			}
+		})
+	}
+}
+
+func Test_password_params(t *testing.T) {
+	defer func(p Argon2Params) { DefaultArgon2Params = p }(DefaultArgon2Params)
+	DefaultArgon2Params = Argon2Params{Time: 2, Memory: 8 * 1024, Threads: 2}
+	e, err := EncryptWithPassword("Hello World!", "myKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt with password: %s\n", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(e)
+	want := append([]byte("AGH\x02\x03\x03\x19"), encodeArgon2Params(DefaultArgon2Params, nil)...)
+	if !bytes.HasPrefix(raw, want) {
+		t.Errorf("expected the header to record the Argon2 parameters, got %x\n", raw[:len(want)])
+	}
+
+	// the recorded parameters are used, not the current ones
+	DefaultArgon2Params = Argon2Params{Time: 1, Memory: 16 * 1024, Threads: 1}
+	if d, err := DecryptWithPassword(e, "myKey123"); err != nil || d != "Hello World!" {
+		t.Errorf("DecryptWithPassword() after changing DefaultArgon2Params = %q, %v\n", d, err)
+	}
+
+	// ciphertexts of earlier versions are decrypted with the current parameters
+	salt := bytes.Repeat([]byte{1}, saltSize)
+	kc := newPasswordKeyCipher("myKey123", salt, DefaultArgon2Params)
+	legacy, _ := kc.encrypt([]byte("Hello World!"), nil)
+	if d, err := DecryptWithPassword(base64.StdEncoding.EncodeToString(append(salt, legacy...)), "myKey123"); err != nil || d != "Hello World!" {
+		t.Errorf("DecryptWithPassword() of a legacy ciphertext = %q, %v\n", d, err)
+	}
+
+	// hostile parameters are rejected before deriving the key
+	for _, p := range []Argon2Params{
+		{Time: 1, Memory: 1 << 31, Threads: 1},
+		{Time: 1, Memory: 2 << 20, Threads: 1},
+		{Time: 8, Memory: 1 << 20, Threads: 1},
+		{Time: 65, Memory: 8 * 1024, Threads: 1},
+	} {
+		hostile := append([]byte("AGH\x02\x03\x03\x19"), encodeArgon2Params(p, salt)...)
+		hostile = append(append(hostile, 12), make([]byte, 12+8+16)...)
+		if _, err := DecryptWithPassword(base64.StdEncoding.EncodeToString(hostile), "myKey123"); !errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("DecryptWithPassword() with hostile parameters %+v error = %v, want ErrCorruptHeader\n", p, err)
+		}
+	}
+	DefaultArgon2Params = Argon2Params{Time: 1, Memory: 1024, Threads: 0}
+	if _, err := EncryptWithPassword("Hello World!", "myKey123"); err == nil {
+		t.Errorf("EncryptWithPassword() with invalid parameters expected an error\n")
+	}
+}
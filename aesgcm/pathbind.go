@@ -0,0 +1,117 @@
+package aesgcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"path/filepath"
+)
+
+// pathMagic separates the path bound with WithPathBinding from the AAD set with WithAAD. It is followed by the length
+// of the path as uint16 and the path. Nothing of it is stored in the file.
+var pathMagic = []byte("AGB\x01")
+
+// WithPathBinding makes EncryptFile, EncryptFileTo and the other file functions authenticate the path of the
+// encrypted file along with its contents, like AAD that isn't stored in the file. Decrypting or verifying it
+// under another path fails with ErrAuthenticationFailed, so an attacker with write access to the storage can't
+// swap files or move them to another place. The same option must be passed for decryption.
+//
+// The path is the absolute path of the encrypted file, or its path relative to the root set with WithPathRoot,
+// with forward slashes on all operating systems. A file that has been renamed or moved legitimately can still be
+// decrypted with WithOriginalPath. EncryptDir and DecryptDir always use paths relative to the root of the
+// encrypted tree, so the tree can be relocated as a whole, and VerifyDir does the same.
+//
+// Files encrypted by EncryptDir with WithOpaqueNames aren't bound to their path, as their original relative path
+// is encrypted and authenticated in their name header instead, which is what DecryptDir restores them from.
+// Names encrypted with EncryptFilename are bound to nothing: when a file is renamed to its encrypted name after its
+// contents have been encrypted with WithPathBinding, it must be decrypted under its original name with WithOriginalPath,
+// or the contents must be encrypted after renaming it.
+func WithPathBinding() Option {
+	return func(o *options) error {
+		o.pathBinding = true
+		return nil
+	}
+}
+
+// WithPathRoot sets the directory the paths bound with WithPathBinding are relative to, so the files can be
+// relocated together with 'root'. Files outside of 'root' fail to encrypt and decrypt.
+func WithPathRoot(root string) Option {
+	return func(o *options) error {
+		if root == "" {
+			return fmt.Errorf("path root must not be empty")
+		}
+		o.pathRoot = root
+		return nil
+	}
+}
+
+// WithOriginalPath makes the file functions authenticate 'path' instead of the actual path of the file
+// with WithPathBinding, so a file that has been renamed or moved since it was encrypted can be decrypted.
+// 'path' is resolved like the actual path, relative to the root set with WithPathRoot if any. It can't be
+// used with the directory functions, which process many files.
+func WithOriginalPath(path string) Option {
+	return func(o *options) error {
+		if path == "" {
+			return fmt.Errorf("original path must not be empty")
+		}
+		o.originalPath = path
+		return nil
+	}
+}
+
+// boundPath returns the path of the file located at 'path' as bound with WithPathBinding.
+func (c *Cipher) boundPath(path string) (string, error) {
+	if c.opts.originalPath != "" {
+		path = c.opts.originalPath
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if c.opts.pathRoot == "" {
+		return filepath.ToSlash(abs), nil
+	}
+	root, err := filepath.Abs(c.opts.pathRoot)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("'%s' is outside of the path root '%s'", path, c.opts.pathRoot)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// pathCipher returns a copy of the Cipher that authenticates the path of the file located at 'path' before the
+// AAD set with WithAAD if WithPathBinding is set, and the Cipher itself otherwise.
+func (c *Cipher) pathCipher(path string) (*Cipher, error) {
+	if !c.opts.pathBinding {
+		return c, nil
+	}
+	p, err := c.boundPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) > math.MaxUint16 {
+		return nil, fmt.Errorf("path is too long to be bound: %d bytes", len(p))
+	}
+	aad := bytes.Clone(pathMagic)
+	aad = binary.BigEndian.AppendUint16(aad, uint16(len(p)))
+	aad = append(aad, p...)
+	return c.withAAD(append(aad, c.opts.aad...)), nil
+}
+
+// dirPathCipher returns a copy of the Cipher that binds the paths of the files of a directory tree
+// relative to 'root' if WithPathBinding is set, and the Cipher itself otherwise.
+func (c *Cipher) dirPathCipher(root string) (*Cipher, error) {
+	if !c.opts.pathBinding {
+		return c, nil
+	}
+	if c.opts.originalPath != "" {
+		return nil, fmt.Errorf("WithOriginalPath can't be used with directories")
+	}
+	cc := *c
+	cc.opts.pathRoot = root
+	return &cc, nil
+}
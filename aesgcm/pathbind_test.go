@@ -0,0 +1,132 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_WithPathBinding(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a.txt")
+	b := filepath.Join(root, "b.txt")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("contents of "+filepath.Base(p)), 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if err := EncryptFile(p, "myKey123", WithPathBinding(), WithAAD([]byte("user-42"))); err != nil {
+			t.Fatalf("EncryptFile() error = %v\n", err)
+		}
+	}
+	opts := []Option{WithPathBinding(), WithAAD([]byte("user-42"))}
+
+	// swap the files
+	tmp := filepath.Join(root, "tmp")
+	_ = os.Rename(a, tmp)
+	_ = os.Rename(b, a)
+	_ = os.Rename(tmp, b)
+	for _, p := range []string{a, b} {
+		if err := DecryptFile(p, "myKey123", opts...); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptFile() of a swapped file error = %v, want ErrAuthenticationFailed\n", err)
+		}
+		if err := VerifyFile(p, "myKey123", opts...); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("VerifyFile() of a swapped file error = %v, want ErrAuthenticationFailed\n", err)
+		}
+	}
+	if err := DecryptFile(a, "myKey123", WithAAD([]byte("user-42"))); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptFile() without WithPathBinding error = %v, want ErrAuthenticationFailed\n", err)
+	}
+
+	// the escape hatch for legitimate renames
+	if err := VerifyFile(a, "myKey123", append(opts, WithOriginalPath(b))...); err != nil {
+		t.Errorf("VerifyFile() with WithOriginalPath error = %v\n", err)
+	}
+	if err := DecryptFile(a, "myKey123", append(opts, WithOriginalPath(b))...); err != nil {
+		t.Fatalf("DecryptFile() with WithOriginalPath error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "contents of b.txt" {
+		t.Errorf("DecryptFile() = %q, want the contents of b.txt\n", data)
+	}
+
+	// relative to a root
+	if err := EncryptFileTo(a, filepath.Join(root, "sub", "a.enc"), "myKey123", WithPathBinding(), WithPathRoot(root)); err != nil {
+		t.Fatalf("EncryptFileTo() error = %v\n", err)
+	}
+	moved := t.TempDir()
+	if err := os.Rename(filepath.Join(root, "sub"), filepath.Join(moved, "sub")); err != nil {
+		t.Fatalf("could not move the directory: %s\n", err)
+	}
+	out := filepath.Join(moved, "a.txt")
+	if err := DecryptFileTo(filepath.Join(moved, "sub", "a.enc"), out, "myKey123", WithPathBinding(), WithPathRoot(moved)); err != nil {
+		t.Errorf("DecryptFileTo() relative to the new root error = %v\n", err)
+	}
+	if err := DecryptFileTo(filepath.Join(moved, "sub", "a.enc"), out, "myKey123", WithPathBinding(), WithPathRoot(filepath.Join(moved, "sub"))); err == nil {
+		t.Errorf("DecryptFileTo() relative to another root expected an error\n")
+	}
+	if err := EncryptFile(a, "myKey123", WithPathBinding(), WithPathRoot(moved)); err == nil {
+		t.Errorf("EncryptFile() outside of the path root expected an error\n")
+	}
+}
+
+func Test_WithPathBinding_dir(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "tree")
+	files := map[string]string{"a.txt": "A", "sub/b.txt": "B", "sub/c.txt": "C"}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+	}
+	if err := EncryptDir(root, "myKey123", WithPathBinding(), WithExtension(".enc")); err != nil {
+		t.Fatalf("EncryptDir() error = %v\n", err)
+	}
+
+	// the tree can be relocated as a unit
+	moved := filepath.Join(base, "moved")
+	if err := os.Rename(root, moved); err != nil {
+		t.Fatalf("could not move the tree: %s\n", err)
+	}
+	results, err := VerifyDir(moved, "myKey123", WithPathBinding())
+	if err != nil || len(results) != len(files) {
+		t.Fatalf("VerifyDir() = %v, %v\n", results, err)
+	}
+	for p, err := range results {
+		if err != nil {
+			t.Errorf("VerifyDir() of %s error = %v\n", p, err)
+		}
+	}
+
+	// but files can't be moved within it
+	b, c := filepath.Join(moved, "sub", "b.txt.enc"), filepath.Join(moved, "sub", "c.txt.enc")
+	_ = os.Rename(b, b+".tmp")
+	_ = os.Rename(c, b)
+	_ = os.Rename(b+".tmp", c)
+	if err := DecryptDir(moved, "myKey123", WithPathBinding(), WithExtension(".enc")); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptDir() of swapped files error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(moved, "a.txt")); string(data) != "A" {
+		t.Errorf("DecryptDir() = %q, want the other files to be decrypted\n", data)
+	}
+	if err := DecryptDir(moved, "myKey123", WithPathBinding(), WithOriginalPath(b)); err == nil {
+		t.Errorf("DecryptDir() with WithOriginalPath expected an error\n")
+	}
+
+	// into a mirror, the paths are relative to the mirror
+	src := filepath.Join(base, "src")
+	_ = os.MkdirAll(src, 0755)
+	_ = os.WriteFile(filepath.Join(src, "d.txt"), []byte("D"), 0644)
+	dest := filepath.Join(base, "dest")
+	if err := EncryptDir(src, "myKey123", WithPathBinding(), WithDestDir(dest)); err != nil {
+		t.Fatalf("EncryptDir() with WithDestDir error = %v\n", err)
+	}
+	out := filepath.Join(base, "out")
+	if err := DecryptDir(dest, "myKey123", WithPathBinding(), WithDestDir(out)); err != nil {
+		t.Fatalf("DecryptDir() with WithDestDir error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "d.txt")); string(data) != "D" {
+		t.Errorf("DecryptDir() with WithDestDir = %q, want %q\n", data, "D")
+	}
+}
@@ -0,0 +1,121 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// MinPBKDF2Iterations is the lowest iteration count accepted by EncryptWithPBKDF2.
+	MinPBKDF2Iterations = 100000
+
+	// maxPBKDF2Iterations limits the iteration count to keep hostile ciphertexts from stalling decryption.
+	maxPBKDF2Iterations = 1 << 24
+
+	pbkdf2ParamsSize = 4 + saltSize // iteration count + salt
+)
+
+// newPBKDF2KeyCipher creates a new keyCipher instance with a 32-byte key derived
+// from the password and salt using PBKDF2 with SHA-256.
+func newPBKDF2KeyCipher(password string, salt []byte, iter int) *keyCipher {
+	pw := []byte(password)
+	defer clear(pw)
+	return &keyCipher{key: pbkdf2.Key(pw, salt, iter, 32, sha256.New)}
+}
+
+// encodePBKDF2Params encodes the iteration count and the salt as KDF parameters of the ciphertext header.
+func encodePBKDF2Params(iter int, salt []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(iter)), salt...)
+}
+
+// decodePBKDF2Params decodes and validates the KDF parameters written by encodePBKDF2Params.
+func decodePBKDF2Params(b []byte) (iter int, salt []byte, err error) {
+	if len(b) != pbkdf2ParamsSize {
+		return 0, nil, fmt.Errorf("%w: invalid PBKDF2 parameter length %d", ErrCorruptHeader, len(b))
+	}
+	iter = int(binary.BigEndian.Uint32(b))
+	if err := validatePBKDF2Iterations(iter); err != nil {
+		return 0, nil, err
+	}
+	return iter, b[4:], nil
+}
+
+// validatePBKDF2Iterations returns an error wrapping ErrWeakKDFParams if 'iter' is out of range.
+func validatePBKDF2Iterations(iter int) error {
+	if iter < MinPBKDF2Iterations || iter > maxPBKDF2Iterations {
+		return fmt.Errorf("%w: PBKDF2 iteration count %d is not within [%d, %d]", ErrWeakKDFParams, iter, MinPBKDF2Iterations, maxPBKDF2Iterations)
+	}
+	return nil
+}
+
+// EncryptWithPBKDF2 encrypts the given plaintext using AES-GCM encryption with a key derived from the password
+// via PBKDF2-SHA256 with 'iter' iterations. It returns the base64-encoded encrypted ciphertext and any error encountered.
+//
+// PBKDF2 is an alternative to EncryptWithPassword for deployments that can't afford Argon2's memory requirements.
+// The iteration count must be at least MinPBKDF2Iterations. It is recorded as KDF parameters in the versioned
+// ciphertext header together with a random 16-byte salt, so DecryptWithPBKDF2 is self-contained.
+func EncryptWithPBKDF2(plaintext, password string, iter int) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	if err := validatePBKDF2Iterations(iter); err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	kc := newPBKDF2KeyCipher(password, salt, iter)
+	defer kc.wipe()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	encrypted, err := kc.sealWithHeader(pt, kdfPBKDF2, encodePBKDF2Params(iter, salt))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// DecryptWithPBKDF2 decrypts the given base64-encoded encrypted text produced by EncryptWithPBKDF2.
+// It returns the decrypted plaintext and any error encountered. Ciphertexts of earlier versions, which
+// consist of the iteration count and the salt followed by the nonce and the sealed data, are decrypted as well.
+func DecryptWithPBKDF2(text, password string) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	encryptedData, err := decodeCiphertext(text, StdBase64)
+	if err != nil {
+		return "", err
+	}
+	derive := func(params []byte) (*keyCipher, error) {
+		iter, salt, err := decodePBKDF2Params(params)
+		if err != nil {
+			return nil, err
+		}
+		return newPBKDF2KeyCipher(password, salt, iter), nil
+	}
+	legacy := func(data []byte) ([]byte, error) {
+		if len(data) < pbkdf2ParamsSize {
+			return nil, ErrCiphertextTooShort
+		}
+		iter, salt, err := decodePBKDF2Params(data[:pbkdf2ParamsSize])
+		if err != nil {
+			return nil, err
+		}
+		kc := newPBKDF2KeyCipher(password, salt, iter)
+		defer kc.wipe()
+		return kc.decrypt(data[pbkdf2ParamsSize:], nil)
+	}
+	decrypted, err := openPasswordCiphertext(encryptedData, kdfPBKDF2, derive, legacy)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
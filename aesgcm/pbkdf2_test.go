@@ -0,0 +1,40 @@
+package aesgcm
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_pbkdf2(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		password string
+		iter     int
+	}{
+		{"pbkdf2 1", "Hello World!", "myKey123", MinPBKDF2Iterations},
+		{"pbkdf2 2", "", "correct horse battery staple", 2 * MinPBKDF2Iterations},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptWithPBKDF2(tt.text, tt.password, tt.iter)
+			if err != nil {
+				t.Fatalf("could not encrypt with pbkdf2: %s\n", err)
+			}
+			d, err := DecryptWithPBKDF2(e, tt.password)
+			if err != nil {
+				t.Fatalf("could not decrypt with pbkdf2: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt with pbkdf2 failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := DecryptWithPBKDF2(e, tt.password+"x"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed for wrong password, got %v\n", err)
+			}
+		})
+	}
+
+	if _, err := EncryptWithPBKDF2("Hello World!", "myKey123", MinPBKDF2Iterations-1); !errors.Is(err, ErrWeakKDFParams) {
+		t.Errorf("expected ErrWeakKDFParams for low iteration count, got %v\n", err)
+	}
+}
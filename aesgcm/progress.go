@@ -0,0 +1,99 @@
+package aesgcm
+
+import (
+	"context"
+	"io"
+)
+
+// ProgressFunc is called with the number of bytes processed so far and the total number of bytes.
+// The total is -1 while it is unknown, for example when reading from a stream.
+type ProgressFunc func(bytesProcessed, total int64)
+
+// WithProgress reports the progress of file and stream operations to 'cb' after each chunk of up to
+// DefaultChunkSize bytes has been read. The processed bytes never decrease and, on success, the last call
+// has 'bytesProcessed' equal to 'total'. Operations on strings and byte slices don't report progress.
+//
+// The callback is invoked synchronously from the goroutine running the operation, never concurrently
+// for a single operation, so it doesn't need any locking unless it shares state with other goroutines.
+func WithProgress(cb ProgressFunc) Option {
+	return func(o *options) error {
+		o.progress = cb
+		return nil
+	}
+}
+
+// progressReader is an io.Reader that reports the accumulated number of bytes read after each chunk.
+type progressReader struct {
+	r     io.Reader
+	cb    ProgressFunc
+	done  int64
+	total int64
+}
+
+// finish reports the completion of the operation unless the last report already did.
+// A total that is unknown or has changed while reading is replaced by the number of bytes read.
+func (r *progressReader) finish() {
+	if r.total == r.done && r.done > 0 {
+		return
+	}
+	r.total = r.done
+	r.cb(r.done, r.total)
+}
+
+// reportDone sends the final progress report if 'r' reports progress.
+func reportDone(r io.Reader) {
+	if pr, ok := r.(*progressReader); ok {
+		pr.finish()
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if len(p) > DefaultChunkSize {
+		p = p[:DefaultChunkSize]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.done += int64(n)
+		r.cb(r.done, r.total)
+	}
+	return n, err
+}
+
+// progressWrapper returns a readerWrapper that reports the progress to 'cb' after each chunk.
+// A nil 'cb' disables reporting.
+func progressWrapper(cb ProgressFunc) readerWrapper {
+	if cb == nil {
+		return nil
+	}
+	return func(r io.Reader, size int64) io.Reader {
+		return &progressReader{r: r, cb: cb, total: size}
+	}
+}
+
+// EncryptFileWithProgress is like EncryptFile with WithProgress, it reports the progress to 'cb' after each chunk
+// of DefaultChunkSize bytes has been read. Passing a nil 'cb' behaves exactly like EncryptFile.
+//
+// The callback is invoked synchronously from the calling goroutine, so it doesn't need any locking
+// unless it shares state with other goroutines.
+func EncryptFileWithProgress(path, key string, cb ProgressFunc) error {
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.encryptFile(context.Background(), path, progressWrapper(cb))
+}
+
+// DecryptFileWithProgress is like DecryptFile with WithProgress, it reports the progress to 'cb' after each chunk
+// of DefaultChunkSize bytes has been read. Passing a nil 'cb' behaves exactly like DecryptFile.
+//
+// The callback is invoked synchronously from the calling goroutine, so it doesn't need any locking
+// unless it shares state with other goroutines.
+func DecryptFileWithProgress(path, key string, cb ProgressFunc) error {
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.decryptFile(context.Background(), path, progressWrapper(cb))
+}
@@ -0,0 +1,173 @@
+package aesgcm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_progress(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		size int
+		key  string
+	}{
+		{"progress 1", "../test_data/progress1.bin", 3*DefaultChunkSize + 5, "myKey123"},
+		{"progress 2", "../test_data/progress2.bin", 10, "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bytes.Repeat([]byte{0x42}, tt.size)
+			f := flo.File(tt.file)
+			defer func() { _ = f.Remove() }()
+			if err := f.StoreBytes(data); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+
+			calls, last, total := 0, int64(0), int64(0)
+			cb := func(done, tot int64) {
+				if done <= last {
+					t.Errorf("progress did not advance: %d after %d\n", done, last)
+				}
+				calls, last, total = calls+1, done, tot
+			}
+			if err := EncryptFileWithProgress(tt.file, tt.key, cb); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			if last != int64(tt.size) || total != int64(tt.size) {
+				t.Errorf("expected final progress %d/%d, got %d/%d\n", tt.size, tt.size, last, total)
+			}
+			if want := (tt.size + DefaultChunkSize - 1) / DefaultChunkSize; calls != want {
+				t.Errorf("expected %d progress calls, got %d\n", want, calls)
+			}
+
+			if err := DecryptFileWithProgress(tt.file, tt.key, nil); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			if !bytes.Equal(f.AsBytes(), data) {
+				t.Errorf("encrypt/decrypt file with progress failed: %v\n", tt.name)
+			}
+		})
+	}
+}
+
+// slowReader returns at most 'n' bytes per read and yields the processor between reads.
+type slowReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	runtime.Gosched()
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	return r.r.Read(p)
+}
+
+// progressRecorder records the reports of a ProgressFunc and checks that they are monotonic
+// and never concurrent.
+type progressRecorder struct {
+	t       *testing.T
+	active  atomic.Bool
+	calls   int
+	last    int64
+	total   int64
+	unknown bool // whether any report before the last had an unknown total
+}
+
+func (p *progressRecorder) report(done, total int64) {
+	if !p.active.CompareAndSwap(false, true) {
+		p.t.Errorf("progress reported concurrently\n")
+	}
+	defer p.active.Store(false)
+	if done < p.last || (p.calls > 0 && done == p.last && total == p.total) {
+		p.t.Errorf("progress did not advance: %d/%d after %d/%d\n", done, total, p.last, p.total)
+	}
+	if p.calls > 0 && p.total == -1 {
+		p.unknown = true
+	}
+	p.calls, p.last, p.total = p.calls+1, done, total
+}
+
+// check reports an error unless the final report was 'want'/'want'.
+func (p *progressRecorder) check(want int64) {
+	p.t.Helper()
+	if p.calls == 0 || p.last != want || p.total != want {
+		p.t.Errorf("expected final progress %d/%d, got %d/%d after %d calls\n", want, want, p.last, p.total, p.calls)
+	}
+}
+
+func Test_WithProgress(t *testing.T) {
+	for _, size := range []int{0, 10, 3*DefaultChunkSize + 5} {
+		t.Run(fmt.Sprintf("file %d", size), func(t *testing.T) {
+			data := bytes.Repeat([]byte{0x42}, size)
+			path := filepath.Join(t.TempDir(), "progress.bin")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+
+			p := &progressRecorder{t: t}
+			if err := EncryptFile(path, "myKey123", WithProgress(p.report)); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+			p.check(int64(size))
+
+			fi, _ := os.Stat(path)
+			p = &progressRecorder{t: t}
+			dst := filepath.Join(t.TempDir(), "progress.txt")
+			if err := DecryptFileTo(path, dst, "myKey123", WithProgress(p.report)); err != nil {
+				t.Fatalf("could not decrypt file: %s\n", err)
+			}
+			p.check(fi.Size())
+			if d, _ := os.ReadFile(dst); !bytes.Equal(d, data) {
+				t.Errorf("encrypt/decrypt file with progress failed\n")
+			}
+		})
+
+		t.Run(fmt.Sprintf("stream %d", size), func(t *testing.T) {
+			data := bytes.Repeat([]byte{0x42}, size)
+			p := &progressRecorder{t: t}
+			c, _ := New("myKey123", WithProgress(p.report))
+			var e bytes.Buffer
+			if err := c.EncryptStream(&e, &slowReader{r: bytes.NewReader(data), n: 1000}); err != nil {
+				t.Fatalf("could not encrypt stream: %s\n", err)
+			}
+			p.check(int64(size))
+			if size > 1000 && (!p.unknown || p.calls <= size/1000) {
+				t.Errorf("expected more than %d reports with an unknown total, got %d\n", size/1000, p.calls)
+			}
+
+			p = &progressRecorder{t: t}
+			c, _ = New("myKey123", WithProgress(p.report))
+			n := int64(e.Len())
+			var d bytes.Buffer
+			if err := c.DecryptStream(&d, &slowReader{r: &e, n: 1000}); err != nil {
+				t.Fatalf("could not decrypt stream: %s\n", err)
+			}
+			p.check(n)
+			if !bytes.Equal(d.Bytes(), data) {
+				t.Errorf("encrypt/decrypt stream with progress failed\n")
+			}
+		})
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		p := &progressRecorder{t: t}
+		c, _ := New("myKey123", WithProgress(p.report))
+		if err := c.DecryptStream(io.Discard, bytes.NewReader(make([]byte, 100))); err == nil {
+			t.Fatalf("expected decrypting garbage to fail\n")
+		}
+		if p.calls > 0 && p.last == p.total {
+			t.Errorf("expected no completion report on failure, got %d/%d\n", p.last, p.total)
+		}
+	})
+}
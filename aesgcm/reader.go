@@ -0,0 +1,263 @@
+package aesgcm
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+// decryptReader decrypts a stream in the format of EncryptStream chunk by chunk.
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	extra  []byte // additional data set with WithAAD
+	buf    []byte // ciphertext of the current chunk, decrypted in place
+	plain  []byte // authenticated plaintext of the current chunk not yet returned
+	chunk  uint64
+	last   bool      // whether the final chunk has been read
+	err    error     // sticky error
+	limit  int64     // plaintext limit set with WithMaxSize, 0 for none
+	total  int64     // plaintext authenticated so far
+	unpad  *unpadder // strips the padding set with WithPadding, nil without it
+}
+
+// NewDecryptReader returns an io.Reader that reads a stream produced by EncryptStream or NewEncryptWriter from 'r'
+// and returns the plaintext, decrypted using AES-GCM decryption with the provided key.
+// The stream header is read immediately, so an error is returned if it is malformed or if the key is weak.
+//
+// Every chunk is authenticated before any of its plaintext is returned. If 'r' ends before the final chunk,
+// Read returns an error wrapping ErrStreamTruncated instead of io.EOF, a chunk that fails authentication
+// results in an error wrapping ErrAuthenticationFailed. Plaintext of earlier chunks may already have been
+// returned at that point, so callers must discard it if an error other than io.EOF is returned.
+func NewDecryptReader(r io.Reader, key string) (io.Reader, error) {
+	kc, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	defer kc.wipe()
+	aesGCM, err := kc.aead()
+	if err != nil {
+		return nil, err
+	}
+	return newDecryptReader(aesGCM, r, nil, DefaultMaxSize)
+}
+
+// DecryptReader returns an io.Reader that lazily decrypts a stream produced by EncryptStream, EncryptReader
+// or NewEncryptWriter as it is read from 'r', chunk by chunk, using AES-GCM decryption with the provided key.
+// Unlike NewDecryptReader, nothing is read from 'r' until the first call to Read, so an error is only returned
+// if the key is weak. A malformed header is reported by Read, see NewDecryptReader for how chunk errors are reported.
+func DecryptReader(r io.Reader, key string) (io.Reader, error) {
+	c, err := newDecryptCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.DecryptReader(r), nil
+}
+
+// DecryptReader returns an io.Reader that lazily decrypts a stream read from 'r'.
+// The additional data set with WithAAD is authenticated with every chunk. See the package-level DecryptReader for details.
+func (c *Cipher) DecryptReader(r io.Reader) io.Reader {
+	if err := c.acquire(); err != nil {
+		return &decryptReader{err: err}
+	}
+	defer c.release()
+	return &decryptReader{r: r, aead: c.aead, extra: c.opts.aad, limit: c.opts.maxSize, unpad: c.newUnpadder()}
+}
+
+// newDecryptReader reads the stream header from 'r' and returns a decryptReader for the chunks that follow,
+// which are authenticated with the additional data 'extra' and may hold at most 'limit' bytes of plaintext.
+func newDecryptReader(aesGCM cipher.AEAD, r io.Reader, extra []byte, limit int64) (*decryptReader, error) {
+	dr := &decryptReader{r: r, aead: aesGCM, extra: extra, limit: limit}
+	if err := dr.readHeader(); err != nil {
+		return nil, err
+	}
+	return dr, nil
+}
+
+// readHeader reads the stream header and allocates the buffer for its chunk size.
+func (dr *decryptReader) readHeader() error {
+	header, chunkSize, err := readStreamHeader(dr.aead, dr.r)
+	if err != nil {
+		return err
+	}
+	dr.header = header
+	dr.buf = make([]byte, chunkSize+dr.aead.Overhead())
+	return nil
+}
+
+// Read returns plaintext of the current chunk, reading and authenticating the next chunk once it is exhausted.
+// The stream header is read first if that hasn't happened yet.
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	if err := dr.fill(); err != nil {
+		return 0, err
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+// WriteTo writes the plaintext to 'w' chunk by chunk, so io.Copy doesn't hold plaintext in a buffer of its own.
+func (dr *decryptReader) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for {
+		if err := dr.fill(); err == io.EOF {
+			return written, nil
+		} else if err != nil {
+			return written, err
+		}
+		if err := writeFull(w, dr.plain); err != nil {
+			return written, err
+		}
+		written += int64(len(dr.plain))
+		dr.plain = nil
+	}
+}
+
+// fill reads and authenticates chunks until plaintext is available. It returns io.EOF after the final chunk
+// or the sticky error, and wipes the plaintext left in the buffers once the stream has ended or failed.
+func (dr *decryptReader) fill() error {
+	for len(dr.plain) == 0 {
+		if dr.err == nil && dr.last {
+			dr.err = io.EOF
+		}
+		if dr.err != nil {
+			clear(dr.buf)
+			dr.unpad.wipe()
+			return dr.err
+		}
+		if dr.header == nil {
+			dr.err = dr.readHeader()
+		} else {
+			dr.err = dr.next()
+		}
+	}
+	return nil
+}
+
+// next reads and authenticates the next chunk.
+func (dr *decryptReader) next() error {
+	i := dr.chunk
+	n, last, err := stream.ReadChunk(dr.aead, dr.r, dr.buf, i)
+	if err != nil {
+		return err
+	}
+	plain, err := openChunk(dr.aead, dr.header, i, dr.buf[:n], last, dr.extra)
+	if err != nil {
+		return err
+	}
+	if dr.unpad != nil {
+		if plain, err = dr.unpad.next(plain, last); err != nil {
+			return err
+		}
+	}
+	if err := countPlaintext(&dr.total, len(plain), dr.limit); err != nil {
+		return err
+	}
+	dr.plain = plain
+	dr.chunk++
+	dr.last = last
+	return nil
+}
+
+// openChunk authenticates and decrypts the sealed chunk 'i' of the stream with the header 'header' in place.
+func openChunk(aesGCM cipher.AEAD, header []byte, i uint64, sealed []byte, last bool, extra []byte) ([]byte, error) {
+	plain, err := aesGCM.Open(sealed[:0], stream.ChunkNonce(header[streamHeaderSize:], i), sealed, stream.ChunkAAD(header, last, extra))
+	if err != nil {
+		return nil, fmt.Errorf("can't decrypt chunk %d, stream truncated or corrupted: %w: %w", i, ErrAuthenticationFailed, err)
+	}
+	return plain, nil
+}
+
+// encryptReader encrypts the plaintext read from 'r' into the format of EncryptStream as it is read.
+type encryptReader struct {
+	r         io.Reader
+	aead      cipher.AEAD
+	rand      io.Reader
+	keyHash   []byte // fingerprint of the key for nonce tracking
+	extra     []byte // additional data set with WithAAD
+	chunkSize int
+	header    []byte
+	buf       []byte // plaintext of the current chunk
+	sealed    []byte // sealed current chunk
+	out       []byte // encrypted data not yet returned
+	chunk     uint64
+	last      bool  // whether the final chunk has been sealed
+	err       error // sticky error
+}
+
+// EncryptReader returns an io.Reader that lazily encrypts the plaintext read from 'r' using AES-GCM encryption
+// with the provided key, in the same format as EncryptStream. This allows io.Copy(dst, r) without holding
+// the whole plaintext in memory: nothing is read from 'r' until the first call to Read, and only one chunk
+// of DefaultChunkSize bytes is buffered at a time.
+// An error is only returned if the key is weak, errors reading from 'r' are returned by Read.
+func EncryptReader(r io.Reader, key string) (io.Reader, error) {
+	c, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.EncryptReader(r), nil
+}
+
+// EncryptReader returns an io.Reader that lazily encrypts the plaintext read from 'r', using the chunk size
+// set with WithChunkSize. The additional data set with WithAAD is authenticated with every chunk.
+// See the package-level EncryptReader for details.
+func (c *Cipher) EncryptReader(r io.Reader) io.Reader {
+	if err := c.acquire(); err != nil {
+		return &encryptReader{err: err}
+	}
+	defer c.release()
+	return &encryptReader{r: c.padReader(r), aead: c.aead, rand: c.opts.rand, keyHash: c.fingerprint, extra: c.opts.aad, chunkSize: c.opts.chunkSize}
+}
+
+// Read returns encrypted data, starting with the stream header, and seals the next chunk once it is exhausted.
+func (er *encryptReader) Read(p []byte) (int, error) {
+	for len(er.out) == 0 {
+		if er.err != nil {
+			return 0, er.err
+		}
+		if er.last {
+			return 0, io.EOF
+		}
+		if er.header == nil {
+			er.err = er.writeHeader()
+		} else {
+			er.err = er.next()
+		}
+	}
+	n := copy(p, er.out)
+	er.out = er.out[n:]
+	return n, nil
+}
+
+// writeHeader creates the stream header and queues it for output.
+func (er *encryptReader) writeHeader() error {
+	header, err := newStreamHeader(er.aead, er.rand, er.keyHash, er.chunkSize)
+	if err != nil {
+		return err
+	}
+	er.header = header
+	er.buf = make([]byte, er.chunkSize)
+	er.out = header
+	return nil
+}
+
+// next reads and seals the next chunk. Like with EncryptStream, a full chunk at the end of the plaintext
+// is followed by an empty final chunk.
+func (er *encryptReader) next() error {
+	n, err := io.ReadFull(er.r, er.buf)
+	last := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !last {
+		clear(er.buf[:n])
+		return err
+	}
+	nonce := stream.ChunkNonce(er.header[streamHeaderSize:], er.chunk)
+	er.sealed = er.aead.Seal(er.sealed[:0], nonce, er.buf[:n], stream.ChunkAAD(er.header, last, er.extra))
+	clear(er.buf[:n])
+	er.out = er.sealed
+	er.chunk++
+	er.last = last
+	return nil
+}
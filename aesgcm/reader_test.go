@@ -0,0 +1,239 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+// randomChunks writes 'data' to 'w' in pieces of random size.
+func randomChunks(w io.Writer, data []byte, rnd *mathrand.Rand) error {
+	for len(data) > 0 {
+		n := min(1+rnd.Intn(2*DefaultChunkSize), len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func Test_NewDecryptReader(t *testing.T) {
+	rnd := mathrand.New(mathrand.NewSource(1))
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"small", 12},
+		{"chunk", DefaultChunkSize},
+		{"chunk + 1", DefaultChunkSize + 1},
+		{"random 1", rnd.Intn(5 * DefaultChunkSize)},
+		{"random 2", rnd.Intn(5 * DefaultChunkSize)},
+		{"random 3", rnd.Intn(5 * DefaultChunkSize)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			var encrypted bytes.Buffer
+			w, err := NewEncryptWriter(&encrypted, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create encrypt writer: %s\n", err)
+			}
+			if err := randomChunks(w, data, rnd); err != nil {
+				t.Fatalf("could not write: %s\n", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("could not close: %s\n", err)
+			}
+			e := encrypted.Bytes()
+
+			readers := map[string]func(io.Reader) io.Reader{
+				"plain":    func(r io.Reader) io.Reader { return r },
+				"one byte": iotest.OneByteReader,
+				"half":     iotest.HalfReader,
+			}
+			for name, wrap := range readers {
+				r, err := NewDecryptReader(wrap(bytes.NewReader(e)), "myKey123")
+				if err != nil {
+					t.Fatalf("could not create decrypt reader: %s\n", err)
+				}
+				d, err := io.ReadAll(iotest.OneByteReader(r))
+				if err != nil {
+					t.Fatalf("could not read (%s): %s\n", name, err)
+				}
+				if !bytes.Equal(data, d) {
+					t.Errorf("encrypt writer/decrypt reader failed (%s): %v: plaintext mismatch\n", name, tt.name)
+				}
+			}
+
+			for _, cut := range []int{1, 17, tt.size%DefaultChunkSize + 16} {
+				if len(e)-cut < streamHeaderSize+12 {
+					continue
+				}
+				r, err := NewDecryptReader(bytes.NewReader(e[:len(e)-cut]), "myKey123")
+				if err != nil {
+					t.Fatalf("could not create decrypt reader: %s\n", err)
+				}
+				if _, err := io.ReadAll(r); !errors.Is(err, ErrStreamTruncated) && !errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("expected truncation error when cutting %d bytes: %v: got %v\n", cut, tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_NewDecryptReader_errors(t *testing.T) {
+	data := make([]byte, 2*DefaultChunkSize)
+	var encrypted bytes.Buffer
+	w, _ := NewEncryptWriter(&encrypted, "myKey123")
+	_, _ = w.Write(data)
+	_ = w.Close()
+	e := encrypted.Bytes()
+
+	// dropping the final (empty) chunk leaves only full chunks
+	r, _ := NewDecryptReader(bytes.NewReader(e[:len(e)-16]), "myKey123")
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for missing final chunk, got %v\n", err)
+	}
+
+	// a tampered second chunk must not release any of its plaintext
+	tampered := bytes.Clone(e)
+	tampered[len(tampered)-17] ^= 0xff
+	r, _ = NewDecryptReader(bytes.NewReader(tampered), "myKey123")
+	d, err := io.ReadAll(r)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed for tampered chunk, got %v\n", err)
+	}
+	if len(d) != DefaultChunkSize {
+		t.Errorf("expected only the first chunk to be returned, got %d bytes\n", len(d))
+	}
+
+	r, _ = NewDecryptReader(bytes.NewReader(e), "wrongKey")
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed for wrong key, got %v\n", err)
+	}
+	if _, err := NewDecryptReader(bytes.NewReader(e[:5]), "myKey123"); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for truncated header, got %v\n", err)
+	}
+	if _, err := NewDecryptReader(bytes.NewReader(e), ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+}
+
+// countingReader counts the calls to Read.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	cr.reads++
+	return cr.r.Read(p)
+}
+
+func Test_EncryptReader(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"small", 12},
+		{"chunk", DefaultChunkSize},
+		{"chunk + 1", DefaultChunkSize + 1},
+		{"chunks", 3*DefaultChunkSize + 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			src := &countingReader{r: bytes.NewReader(data)}
+			er, err := EncryptReader(src, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create encrypt reader: %s\n", err)
+			}
+			if src.reads != 0 {
+				t.Errorf("EncryptReader() read from the source before Read was called\n")
+			}
+			var encrypted bytes.Buffer
+			if _, err := io.Copy(&encrypted, iotest.HalfReader(er)); err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if want := streamHeaderSize + 12 + tt.size + (tt.size/DefaultChunkSize+1)*16; encrypted.Len() != want {
+				t.Errorf("expected %d encrypted bytes, got %d\n", want, encrypted.Len())
+			}
+
+			var d bytes.Buffer
+			if err := DecryptStream(&d, bytes.NewReader(encrypted.Bytes()), "myKey123"); err != nil || !bytes.Equal(data, d.Bytes()) {
+				t.Errorf("encrypt reader output doesn't decrypt with DecryptStream: %v\n", err)
+			}
+
+			enc := &countingReader{r: bytes.NewReader(encrypted.Bytes())}
+			dr, err := DecryptReader(enc, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create decrypt reader: %s\n", err)
+			}
+			if enc.reads != 0 {
+				t.Errorf("DecryptReader() read from the source before Read was called\n")
+			}
+			if got, err := io.ReadAll(iotest.OneByteReader(dr)); err != nil || !bytes.Equal(data, got) {
+				t.Errorf("encrypt reader/decrypt reader failed: %v\n", err)
+			}
+		})
+	}
+}
+
+func Test_EncryptReader_options(t *testing.T) {
+	c, err := New("myKey123", WithChunkSize(1024), WithAAD([]byte("user-42")))
+	if err != nil {
+		t.Fatalf("could not create cipher: %s\n", err)
+	}
+	data := bytes.Repeat([]byte("a"), 5000)
+	encrypted, err := io.ReadAll(c.EncryptReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	if d, err := io.ReadAll(c.DecryptReader(bytes.NewReader(encrypted))); err != nil || !bytes.Equal(data, d) {
+		t.Errorf("cipher encrypt reader/decrypt reader failed: %v\n", err)
+	}
+	r, err := DecryptReader(bytes.NewReader(encrypted), "myKey123")
+	if err != nil {
+		t.Fatalf("could not create decrypt reader: %s\n", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed without the AAD, got %v\n", err)
+	}
+}
+
+func Test_EncryptReader_errors(t *testing.T) {
+	if _, err := EncryptReader(bytes.NewReader(nil), ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+	if _, err := DecryptReader(bytes.NewReader(nil), ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+
+	readErr := errors.New("read failed")
+	er, err := EncryptReader(iotest.ErrReader(readErr), "myKey123")
+	if err != nil {
+		t.Fatalf("could not create encrypt reader: %s\n", err)
+	}
+	if _, err := io.ReadAll(er); !errors.Is(err, readErr) {
+		t.Errorf("expected the source error from Read, got %v\n", err)
+	}
+
+	dr, err := DecryptReader(bytes.NewReader([]byte("garbage")), "myKey123")
+	if err != nil {
+		t.Fatalf("DecryptReader() error = %v, header errors must be returned by Read\n", err)
+	}
+	if _, err := io.ReadAll(dr); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for a truncated header, got %v\n", err)
+	}
+}
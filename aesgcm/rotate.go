@@ -0,0 +1,212 @@
+package aesgcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rotateFile re-encrypts the file located at 'path' from 'oldCipher' to 'newCipher'.
+// The plaintext is piped from decryption to encryption, so memory usage stays constant for files
+// in the chunked format. The result is written to a temporary file which is then renamed over the original.
+func rotateFile(path string, oldCipher, newCipher *Cipher) error {
+	f, r, err := openFile("rotate", path, nil)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	wo, err := newCipher.writeOptions(f, true)
+	if err != nil {
+		return err
+	}
+	return writeFileFunc(path, wo, func(w io.Writer) error {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(oldCipher.decryptTo(pw, r))
+		}()
+		err := newCipher.encryptTo(w, pr)
+		pr.CloseWithError(err)
+		return err
+	})
+}
+
+// RotateFileKey re-encrypts the file located at 'path', which must have been encrypted with 'oldKey',
+// so that it is encrypted with 'newKey' afterwards. It returns an error if the file doesn't exist
+// or if any decryption or encryption operation fails.
+//
+// The re-encrypted data is written to a temporary file which is then renamed over the original,
+// so the file is never left in a partially written state.
+func RotateFileKey(path, oldKey, newKey string) error {
+	oldCipher, err := New(oldKey)
+	if err != nil {
+		return err
+	}
+	defer oldCipher.Close()
+	newCipher, err := New(newKey)
+	if err != nil {
+		return err
+	}
+	defer newCipher.Close()
+	return rotateFile(path, oldCipher, newCipher)
+}
+
+// RotateDirKey applies RotateFileKey to every regular file in the directory tree below 'root'.
+// Symlinks are skipped. If a file fails to rotate, the remaining files are still processed
+// and all errors are returned together.
+func RotateDirKey(root, oldKey, newKey string) error {
+	oldCipher, err := New(oldKey)
+	if err != nil {
+		return err
+	}
+	defer oldCipher.Close()
+	newCipher, err := New(newKey)
+	if err != nil {
+		return err
+	}
+	defer newCipher.Close()
+	_, err = walkFiles(context.Background(), root, 1, SkipSymlinks, nil, nil, func(path string) error {
+		return rotateFile(path, oldCipher, newCipher)
+	})
+	return err
+}
+
+// RekeyFile re-encrypts the file located at 'path' from 'oldKey' to 'newKey' like RotateFileKey, but checks
+// 'oldKey' before re-encrypting anything and refuses to re-encrypt with the same key unless WithForce has been
+// passed. The 'opts' apply to both keys.
+//
+// The plaintext is never written to disk: it is piped from decryption to encryption into a temporary file
+// which then replaces the original, so the file is either fully encrypted with 'oldKey' or fully encrypted
+// with 'newKey', even if the process dies midway. It returns an error wrapping ErrAuthenticationFailed
+// if 'oldKey' is wrong and ErrFileNotFound if the file doesn't exist. For files with a key check value
+// or in the chunked format, a wrong 'oldKey' is detected by reading only the start of the file.
+func RekeyFile(path, oldKey, newKey string, opts ...Option) error {
+	oldCipher, newCipher, err := rekeyCiphers(oldKey, newKey, opts)
+	if err != nil {
+		return err
+	}
+	defer oldCipher.Close()
+	defer newCipher.Close()
+	return rekeyFile(path, oldCipher, newCipher)
+}
+
+// RekeyDir applies RekeyFile to every regular file in the directory tree below 'root'. Symlinks are skipped
+// unless set otherwise with WithSymlinks, files can be selected with WithInclude and WithExclude and are
+// processed concurrently, see WithConcurrency.
+// If a file fails to rekey, it is left encrypted with 'oldKey', the remaining files are still processed
+// and all errors are returned together.
+func RekeyDir(root, oldKey, newKey string, opts ...Option) error {
+	oldCipher, newCipher, err := rekeyCiphers(oldKey, newKey, opts)
+	if err != nil {
+		return err
+	}
+	defer oldCipher.Close()
+	defer newCipher.Close()
+	_, err = walkFiles(context.Background(), root, newCipher.dirWorkers(), newCipher.dirSymlinks(), newCipher.dirFilter(root, nil), nil, func(path string) error {
+		return rekeyFile(path, oldCipher, newCipher)
+	})
+	return err
+}
+
+// rekeyCiphers creates the Ciphers for 'oldKey' and 'newKey' with 'opts'.
+// It returns an error if the keys are the same, unless WithForce is among 'opts'.
+func rekeyCiphers(oldKey, newKey string, opts []Option) (*Cipher, *Cipher, error) {
+	newCipher, err := New(newKey, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if oldKey == newKey && !newCipher.opts.force {
+		return nil, nil, fmt.Errorf("can't rekey, the old and the new key are the same")
+	}
+	oldCipher, err := New(oldKey, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldCipher, newCipher, nil
+}
+
+// rekeyFile verifies the key of 'oldCipher' against the file located at 'path' and re-encrypts it with 'newCipher'.
+func rekeyFile(path string, oldCipher, newCipher *Cipher) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return errFileNotFound("rekey", path)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = oldCipher.verifyKey(f)
+	_ = f.Close()
+	switch {
+	case errors.Is(err, ErrAuthenticationFailed):
+		return fmt.Errorf("can't rekey '%s', the old key doesn't match: %w", path, err)
+	case err != nil && !errors.Is(err, ErrNoKeyCheck):
+		return fmt.Errorf("can't rekey '%s': %w", path, err)
+	}
+	return rotateFile(path, oldCipher, newCipher)
+}
+
+// Rekey decrypts an encoded ciphertext produced by Encrypt with 'oldKey' and re-encrypts it with 'newKey' in one call,
+// so the plaintext is never handed to the caller. The 'opts' apply to both keys and the same key is refused unless
+// WithForce has been passed. It returns an error wrapping ErrAuthenticationFailed if 'oldKey' is wrong.
+// To rekey many values, use RekeyStrings or the Rekey method of Ciphers created once, which derive the keys only once.
+func Rekey(ciphertext, oldKey, newKey string, opts ...Option) (string, error) {
+	oldCipher, newCipher, err := rekeyCiphers(oldKey, newKey, opts)
+	if err != nil {
+		return "", err
+	}
+	defer oldCipher.Close()
+	defer newCipher.Close()
+	return oldCipher.Rekey(ciphertext, newCipher)
+}
+
+// RekeyStrings applies Rekey to every encoded ciphertext and returns the new ciphertexts in the same order.
+// Both keys are derived only once and large batches are processed concurrently like with EncryptStrings.
+// If an item fails, an *ItemError reporting its index is returned.
+func RekeyStrings(items []string, oldKey, newKey string, opts ...Option) ([]string, error) {
+	oldCipher, newCipher, err := rekeyCiphers(oldKey, newKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer oldCipher.Close()
+	defer newCipher.Close()
+	return oldCipher.RekeyStrings(items, newCipher)
+}
+
+// Rekey decrypts an encoded ciphertext with the Cipher and re-encrypts it with 'newCipher'.
+// See the package-level Rekey for details.
+func (c *Cipher) Rekey(ciphertext string, newCipher *Cipher) (string, error) {
+	if err := c.acquire(); err != nil {
+		return "", err
+	}
+	defer c.release()
+	data, err := c.decodeCiphertext(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := c.DecryptBytes(data)
+	if errors.Is(err, ErrAuthenticationFailed) {
+		return "", fmt.Errorf("can't rekey, the old key doesn't match: %w", err)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	encrypted, err := newCipher.EncryptBytes(decrypted)
+	if err != nil {
+		return "", err
+	}
+	return newCipher.opts.encoding.EncodeToString(encrypted), nil
+}
+
+// RekeyStrings decrypts every encoded ciphertext with the Cipher and re-encrypts it with 'newCipher'.
+// See the package-level RekeyStrings for details.
+func (c *Cipher) RekeyStrings(items []string, newCipher *Cipher) ([]string, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return newCipher.mapStrings(items, func(ciphertext string) (string, error) {
+		return c.Rekey(ciphertext, newCipher)
+	})
+}
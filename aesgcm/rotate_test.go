@@ -0,0 +1,213 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_rotateFileKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rotate.txt")
+	if err := EncryptToFile([]byte("Hello World!"), file, "oldKey123"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	if err := RotateFileKey(file, "wrongKey", "newKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed for wrong old key, got %v\n", err)
+	}
+	if d, err := DecryptFromFile(file, "oldKey123"); err != nil || string(d) != "Hello World!" {
+		t.Errorf("failed rotation modified the file: got %s (%v)\n", d, err)
+	}
+
+	if err := RotateFileKey(file, "oldKey123", "newKey123"); err != nil {
+		t.Fatalf("could not rotate key: %s\n", err)
+	}
+	if _, err := DecryptFromFile(file, "oldKey123"); err == nil {
+		t.Errorf("file still decrypts with the old key\n")
+	}
+	if d, err := DecryptFromFile(file, "newKey123"); err != nil || string(d) != "Hello World!" {
+		t.Errorf("rotated file does not decrypt with the new key: got %s (%v)\n", d, err)
+	}
+}
+
+func Test_rotateDirKey(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":     "Hello World!",
+		"sub/b.txt": "Hello Sub!",
+	}
+	for name, text := range files {
+		if err := EncryptToFile([]byte(text), filepath.Join(root, name), "oldKey123"); err != nil {
+			t.Fatalf("could not encrypt file: %s\n", err)
+		}
+	}
+	plain := filepath.Join(root, "plain.txt")
+	if err := flo.File(plain).StoreBytes([]byte("not encrypted")); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	if err := RotateDirKey(root, "oldKey123", "newKey123"); err == nil {
+		t.Errorf("expected error for unencrypted file\n")
+	}
+	for name, text := range files {
+		if d, err := DecryptFromFile(filepath.Join(root, name), "newKey123"); err != nil || string(d) != text {
+			t.Errorf("rotate dir failed: %s: expected %v, got %s (%v)\n", name, text, d, err)
+		}
+	}
+}
+
+func Test_RekeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rekey.txt")
+	if err := os.WriteFile(path, []byte("Hello World!"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(path, "oldKey123"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	encrypted, _ := os.ReadFile(path)
+
+	err := RekeyFile(path, "wrongKey", "newKey123")
+	if !errors.Is(err, ErrAuthenticationFailed) || !strings.Contains(err.Error(), "old key") {
+		t.Errorf("expected ErrAuthenticationFailed for the wrong old key, got %v\n", err)
+	}
+	if err := RekeyFile(path, "oldKey123", "oldKey123"); err == nil {
+		t.Errorf("expected an error for the same key\n")
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, encrypted) {
+		t.Errorf("failed rekey modified the file\n")
+	}
+	if err := RekeyFile(path, "oldKey123", "oldKey123", WithForce()); err != nil {
+		t.Errorf("could not rekey with the same key and WithForce: %s\n", err)
+	}
+	if d, _ := os.ReadFile(path); bytes.Equal(d, encrypted) {
+		t.Errorf("expected the file to be re-encrypted with WithForce\n")
+	}
+
+	if err := RekeyFile(path, "oldKey123", "newKey123"); err != nil {
+		t.Fatalf("could not rekey file: %s\n", err)
+	}
+	if _, err := VerifyKey(path, "oldKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("file still matches the old key: %v\n", err)
+	}
+	if err := DecryptFile(path, "newKey123"); err != nil {
+		t.Fatalf("rekeyed file does not decrypt with the new key: %s\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != "Hello World!" {
+		t.Errorf("expected Hello World!, got %s\n", d)
+	}
+
+	if err := RekeyFile(filepath.Join(t.TempDir(), "missing"), "oldKey123", "newKey123"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v\n", err)
+	}
+}
+
+func Test_RekeyDir(t *testing.T) {
+	files := map[string]string{
+		"a.txt":     "Hello World!",
+		"sub/b.txt": "Hello Sub!",
+		"sub/c.md":  "# Title",
+	}
+	root := dirTree(t, files)
+	if err := EncryptDir(root, "oldKey123"); err != nil {
+		t.Fatalf("could not encrypt dir: %s\n", err)
+	}
+	other := filepath.Join(root, "other.txt")
+	if err := os.WriteFile(other, []byte("Other!"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFile(other, "otherKey"); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+
+	err := RekeyDir(root, "oldKey123", "newKey123", WithExclude("*.md"))
+	if !errors.Is(err, ErrAuthenticationFailed) || !strings.Contains(err.Error(), "other.txt") {
+		t.Errorf("expected ErrAuthenticationFailed for other.txt, got %v\n", err)
+	}
+	// every file is fully encrypted with exactly one key
+	want := map[string]string{"a.txt": "newKey123", "sub/b.txt": "newKey123", "sub/c.md": "oldKey123", "other.txt": "otherKey"}
+	for name, key := range want {
+		if ok, err := VerifyKey(filepath.Join(root, name), key); !ok {
+			t.Errorf("%s: expected the key %s, got %v\n", name, key, err)
+		}
+	}
+	if err := RekeyDir(root, "newKey123", "newKey123"); err == nil {
+		t.Errorf("expected an error for the same key\n")
+	}
+}
+
+func Test_Rekey(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		decOpts []Option
+	}{
+		{"default", nil, nil},
+		{"key check", []Option{WithKeyCheck()}, nil},
+		{"hex", []Option{WithEncoding(Hex)}, []Option{WithEncoding(Hex)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt("Hello World!", "oldKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			r, err := Rekey(e, "oldKey123", "newKey123", tt.opts...)
+			if err != nil {
+				t.Fatalf("Rekey() error = %v\n", err)
+			}
+			if _, err := Decrypt(r, "oldKey123", tt.decOpts...); err == nil {
+				t.Errorf("rekeyed ciphertext still decrypts with the old key\n")
+			}
+			if d, err := Decrypt(r, "newKey123", tt.decOpts...); err != nil || d != "Hello World!" {
+				t.Errorf("rekeyed ciphertext doesn't decrypt with the new key: got %s (%v)\n", d, err)
+			}
+			if _, err := Rekey(e, "wrongKey", "newKey123", tt.opts...); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed for wrong old key, got %v\n", err)
+			}
+		})
+	}
+	if _, err := Rekey("", "myKey123", "myKey123"); err == nil {
+		t.Errorf("expected error for the same key\n")
+	}
+}
+
+func Test_RekeyStrings(t *testing.T) {
+	items := make([]string, 4*minItemsPerWorker)
+	for i := range items {
+		items[i] = fmt.Sprintf("row %d", i)
+	}
+	e, err := EncryptStrings(items, "oldKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt strings: %s\n", err)
+	}
+	r, err := RekeyStrings(e, "oldKey123", "newKey123")
+	if err != nil {
+		t.Fatalf("RekeyStrings() error = %v\n", err)
+	}
+	d, err := DecryptStrings(r, "newKey123")
+	if err != nil {
+		t.Fatalf("could not decrypt strings: %s\n", err)
+	}
+	for i := range items {
+		if d[i] != items[i] {
+			t.Fatalf("rekey strings failed at index %d: expected %v, got %v!\n", i, items[i], d[i])
+		}
+	}
+
+	bad := len(e) / 2
+	mixed := append([]string{}, e...)
+	mixed[bad] = r[bad]
+	_, err = RekeyStrings(mixed, "oldKey123", "newKey123")
+	var itemErr *ItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != bad {
+		t.Fatalf("expected *ItemError for index %d, got %v\n", bad, err)
+	}
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed, got %v\n", err)
+	}
+}
@@ -0,0 +1,164 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// minScryptN is the lowest CPU/memory cost parameter accepted for scrypt.
+	minScryptN = 1 << 14
+
+	// maxScryptLogN, maxScryptMemory and maxScryptWork limit the parameters read from a ciphertext header
+	// to keep hostile ciphertexts from exhausting memory or stalling decryption. Deriving a key needs
+	// 128*N*r bytes of memory, which may not exceed what ScryptSensitiveParams need, and N*r*p is bounded
+	// to four times their work.
+	maxScryptLogN   = 20
+	maxScryptMemory = 1 << 30 // 1 GiB
+	maxScryptWork   = 1 << 25
+
+	scryptParamsSize = 3 + saltSize // log2(N) + r + p + salt
+)
+
+// ScryptParams holds the scrypt tuning parameters used to derive keys from passwords.
+type ScryptParams struct {
+	N int // CPU/memory cost, a power of two of at least 16384
+	R int // block size, between 1 and 255
+	P int // parallelization, between 1 and 255
+}
+
+var (
+	// ScryptInteractiveParams are suitable for interactive logins, modelled on libsodium's interactive preset.
+	// Deriving a key needs about 16 MiB of memory.
+	ScryptInteractiveParams = ScryptParams{N: 1 << 14, R: 8, P: 1}
+
+	// ScryptSensitiveParams are suitable for highly sensitive data, modelled on libsodium's sensitive preset.
+	// Deriving a key needs about 1 GiB of memory.
+	ScryptSensitiveParams = ScryptParams{N: 1 << 20, R: 8, P: 1}
+)
+
+// validateScryptParams returns an error wrapping ErrWeakScryptParams if N is too low,
+// or another error if the parameters can't be encoded in the ciphertext header.
+func validateScryptParams(n, r, p int) error {
+	if n < minScryptN {
+		return fmt.Errorf("%w: N must be at least %d, got %d", ErrWeakScryptParams, minScryptN, n)
+	}
+	if n&(n-1) != 0 || bits.TrailingZeros(uint(n)) > maxScryptLogN {
+		return fmt.Errorf("invalid scrypt parameters: N must be a power of two of at most 2^%d, got %d", maxScryptLogN, n)
+	}
+	if r < 1 || r > 255 || p < 1 || p > 255 {
+		return fmt.Errorf("invalid scrypt parameters: r and p must be between 1 and 255, got r=%d, p=%d", r, p)
+	}
+	if 128*n*r > maxScryptMemory {
+		return fmt.Errorf("invalid scrypt parameters: 128*N*r must be at most %d bytes, got N=%d, r=%d", maxScryptMemory, n, r)
+	}
+	if n*r*p > maxScryptWork {
+		return fmt.Errorf("invalid scrypt parameters: N*r*p must be at most %d, got N=%d, r=%d, p=%d", maxScryptWork, n, r, p)
+	}
+	return nil
+}
+
+// encodeScryptParams encodes the scrypt parameters and the salt as KDF parameters of the ciphertext header.
+func encodeScryptParams(n, r, p int, salt []byte) []byte {
+	return append([]byte{byte(bits.TrailingZeros(uint(n))), byte(r), byte(p)}, salt...)
+}
+
+// decodeScryptParams decodes and validates the KDF parameters written by encodeScryptParams.
+func decodeScryptParams(b []byte) (n, r, p int, salt []byte, err error) {
+	if len(b) != scryptParamsSize {
+		return 0, 0, 0, nil, fmt.Errorf("%w: invalid scrypt parameter length %d", ErrCorruptHeader, len(b))
+	}
+	if b[0] > maxScryptLogN {
+		return 0, 0, 0, nil, fmt.Errorf("%w: scrypt N must be at most 2^%d, got 2^%d", ErrCorruptHeader, maxScryptLogN, b[0])
+	}
+	n, r, p = 1<<b[0], int(b[1]), int(b[2])
+	if err := validateScryptParams(n, r, p); err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("%w: %w", ErrCorruptHeader, err)
+	}
+	return n, r, p, b[3:], nil
+}
+
+// newScryptKeyCipher creates a new keyCipher instance with a 32-byte key derived
+// from the password and salt using scrypt.
+func newScryptKeyCipher(password string, salt []byte, n, r, p int) (*keyCipher, error) {
+	pw := []byte(password)
+	defer clear(pw)
+	k, err := scrypt.Key(pw, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyDerivation, err)
+	}
+	return &keyCipher{key: k}, nil
+}
+
+// EncryptWithScrypt encrypts the given plaintext using AES-GCM encryption with a key derived from the password
+// via scrypt with the parameters N, r and p. It returns the base64-encoded encrypted ciphertext and any error encountered.
+//
+// The parameters and a random 16-byte salt are recorded as KDF parameters in the versioned ciphertext header,
+// so DecryptWithScrypt can derive the same key again. ScryptInteractiveParams and ScryptSensitiveParams
+// provide sensible presets. An N below 16384 is rejected with ErrWeakScryptParams, and parameters needing
+// more than 1 GiB of memory (128*N*r bytes) or with N*r*p above 2^25 are rejected as well.
+func EncryptWithScrypt(plaintext, password string, N, r, p int) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	if err := validateScryptParams(N, r, p); err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	cipher, err := newScryptKeyCipher(password, salt, N, r, p)
+	if err != nil {
+		return "", err
+	}
+	defer cipher.wipe()
+	pt := []byte(plaintext)
+	defer clear(pt)
+	encrypted, err := cipher.sealWithHeader(pt, kdfScrypt, encodeScryptParams(N, r, p, salt))
+	if err != nil {
+		return "", err
+	}
+	return StdBase64.EncodeToString(encrypted), nil
+}
+
+// DecryptWithScrypt decrypts the given base64-encoded encrypted text produced by EncryptWithScrypt.
+// It returns the decrypted plaintext and any error encountered. Ciphertexts of earlier versions, which
+// consist of the parameters and the salt followed by the nonce and the sealed data, are decrypted as well.
+func DecryptWithScrypt(text, password string) (string, error) {
+	if err := validateKey(password); err != nil {
+		return "", err
+	}
+	encryptedData, err := decodeCiphertext(text, StdBase64)
+	if err != nil {
+		return "", err
+	}
+	derive := func(params []byte) (*keyCipher, error) {
+		n, r, p, salt, err := decodeScryptParams(params)
+		if err != nil {
+			return nil, err
+		}
+		return newScryptKeyCipher(password, salt, n, r, p)
+	}
+	legacy := func(data []byte) ([]byte, error) {
+		if len(data) < scryptParamsSize {
+			return nil, ErrCiphertextTooShort
+		}
+		cipher, err := derive(data[:scryptParamsSize])
+		if err != nil {
+			return nil, err
+		}
+		defer cipher.wipe()
+		return cipher.decrypt(data[scryptParamsSize:], nil)
+	}
+	decrypted, err := openPasswordCiphertext(encryptedData, kdfScrypt, derive, legacy)
+	if err != nil {
+		return "", err
+	}
+	defer clear(decrypted)
+	return string(decrypted), nil
+}
@@ -0,0 +1,74 @@
+package aesgcm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func Test_scrypt(t *testing.T) {
+	p := ScryptInteractiveParams
+	tests := []struct {
+		name     string
+		text     string
+		password string
+	}{
+		{"scrypt 1", "Hello World!", "myKey123"},
+		{"scrypt 2", "", "correct horse battery staple"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptWithScrypt(tt.text, tt.password, p.N, p.R, p.P)
+			if err != nil {
+				t.Fatalf("could not encrypt with scrypt: %s\n", err)
+			}
+			d, err := DecryptWithScrypt(e, tt.password)
+			if err != nil {
+				t.Fatalf("could not decrypt with scrypt: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt with scrypt failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := DecryptWithScrypt(e, tt.password+"x"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed for wrong password, got %v\n", err)
+			}
+		})
+	}
+
+	_, err := EncryptWithScrypt("Hello World!", "myKey123", 1<<13, 8, 1)
+	if !errors.Is(err, ErrWeakScryptParams) || !errors.Is(err, ErrWeakKDFParams) {
+		t.Errorf("expected ErrWeakScryptParams for low N, got %v\n", err)
+	}
+	if _, err := EncryptWithScrypt("Hello World!", "myKey123", 20000, 8, 1); err == nil {
+		t.Errorf("expected error for N that is not a power of two\n")
+	}
+}
+
+func Test_scrypt_hostileHeader(t *testing.T) {
+	e, err := EncryptWithScrypt("Hello World!", "myKey123", 1<<14, 8, 1)
+	if err != nil {
+		t.Fatalf("could not encrypt with scrypt: %s\n", err)
+	}
+	raw, _ := StdBase64.DecodeString(e)
+	tests := []struct {
+		name string
+		logN byte
+		r, p byte
+	}{
+		{"N too high", 21, 8, 1},
+		{"memory too high", 20, 255, 1},
+		{"work too high", 17, 8, 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forged := bytes.Clone(raw)
+			forged[7], forged[8], forged[9] = tt.logN, tt.r, tt.p
+			if _, err := DecryptWithScrypt(StdBase64.EncodeToString(forged), "myKey123"); !errors.Is(err, ErrCorruptHeader) {
+				t.Errorf("DecryptWithScrypt() with forged parameters error = %v, want ErrCorruptHeader\n", err)
+			}
+		})
+	}
+	if _, err := EncryptWithScrypt("Hello World!", "myKey123", 1<<20, 255, 1); err == nil {
+		t.Errorf("expected error for parameters needing more than 1 GiB of memory\n")
+	}
+}
@@ -0,0 +1,129 @@
+package aesgcm
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultShredPasses is the number of passes of random data used by WithShredSource.
+var DefaultShredPasses = 1
+
+// ShredFile overwrites the contents of the file located at 'path' with random data 'passes' times, truncates it
+// and removes it, syncing the file to disk after every step. It returns an error if 'passes' is less than 1,
+// wrapping ErrFileNotFound if the file doesn't exist, and refuses directories, symlinks and other special files.
+// A file that shrinks while it is shredded is overwritten up to its current size in every pass; a file that is
+// removed or replaced by another process meanwhile is shredded through the open handle and the new file is kept.
+//
+// Note: shredding is best effort. On SSDs, with wear leveling, and on copy-on-write or journaling file systems
+// such as btrfs, ZFS or APFS, the overwritten data may be written to new blocks while the old ones survive, and
+// snapshots or backups keep their own copies. Encrypt sensitive data before it is written to disk where possible.
+func ShredFile(path string, passes int) error {
+	if passes < 1 {
+		return fmt.Errorf("can't shred '%s', passes must be at least 1, got %d", path, passes)
+	}
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return errFileNotFound("shred", path)
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("can't shred '%s', not a regular file: %s", path, fi.Mode().Type())
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if opened, err := f.Stat(); err != nil {
+		return err
+	} else if !os.SameFile(fi, opened) {
+		return fmt.Errorf("can't shred '%s', the file has been replaced", path)
+	}
+
+	for i := 0; i < passes; i++ {
+		if err := overwriteRandom(f); err != nil {
+			return fmt.Errorf("can't shred '%s', pass %d failed: %w", path, i+1, err)
+		}
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if current, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !os.SameFile(fi, current) {
+		return fmt.Errorf("shredded '%s', but it has been replaced by another file, which is kept", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// overwriteRandom overwrites the current contents of 'f' with random data and syncs it to disk.
+func overwriteRandom(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, rand.Reader, fi.Size()); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// WithShredSource makes EncryptFileTo, and EncryptDir with WithDestDir, shred the plaintext source file
+// with ShredFile and DefaultShredPasses once the encrypted copy has been completely written and synced to disk.
+// The source is kept if encryption fails. See ShredFile for the limits of shredding.
+// It can only be used for encryption.
+func WithShredSource() Option {
+	return func(o *options) error {
+		o.shredSource = true
+		o.encryptOnly = append(o.encryptOnly, "WithShredSource")
+		return nil
+	}
+}
+
+// encryptFileTo encrypts the file located at 'src', read through 'wrap' unless it is nil, into 'dst'
+// and shreds 'src' afterwards if WithShredSource is set. With WithPathBinding, 'dst' is bound.
+func (c *Cipher) encryptFileTo(ctx context.Context, src, dst string, wrap readerWrapper) error {
+	cc, err := c.pathCipher(dst)
+	if err != nil {
+		return err
+	}
+	return cc.encryptFileToFunc(ctx, src, dst, wrap, cc.encryptTo)
+}
+
+// encryptFileToFunc is encryptFileTo with the encryption done by 'encrypt'.
+func (c *Cipher) encryptFileToFunc(ctx context.Context, src, dst string, wrap readerWrapper, encrypt func(w io.Writer, r io.Reader) error) error {
+	src, err := c.symlinkTarget("encrypt", src)
+	if err != nil || src == "" {
+		return err
+	}
+	if err := c.transferFile(ctx, "encrypt", src, dst, wrap, c.refuseEncrypted(src, encrypt)); err != nil {
+		return err
+	}
+	if !c.opts.shredSource {
+		return nil
+	}
+	if err := ShredFile(src, DefaultShredPasses); err != nil {
+		return fmt.Errorf("encrypted '%s' to '%s', but can't shred the source: %w", src, dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ShredFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		passes int
+	}{
+		{"empty", 0, 1},
+		{"small", 12, 1},
+		{"large", 3*DefaultChunkSize + 17, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret.txt")
+			if err := os.WriteFile(path, make([]byte, tt.size), 0644); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := ShredFile(path, tt.passes); err != nil {
+				t.Fatalf("ShredFile() error = %v\n", err)
+			}
+			if _, err := os.Lstat(path); !os.IsNotExist(err) {
+				t.Errorf("expected the file to be removed, got %v\n", err)
+			}
+		})
+	}
+}
+
+func Test_ShredFile_errors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.txt")
+	if err := ShredFile(path, 1); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v\n", err)
+	}
+	if err := os.WriteFile(path, []byte("Hello World!"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := ShredFile(path, 0); err == nil {
+		t.Errorf("expected error for 0 passes\n")
+	}
+	if err := ShredFile(dir, 1); err == nil {
+		t.Errorf("expected error for a directory\n")
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(path, link); err == nil {
+		if err := ShredFile(link, 1); err == nil {
+			t.Errorf("expected error for a symlink\n")
+		}
+	}
+	if d, err := os.ReadFile(path); err != nil || string(d) != "Hello World!" {
+		t.Errorf("refused shredding modified the file: got %q (%v)\n", d, err)
+	}
+}
+
+func Test_WithShredSource(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "secret.txt")
+	dst := filepath.Join(dir, "secret.txt.enc")
+	if err := os.WriteFile(src, []byte("Hello World!"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := os.WriteFile(dst, nil, 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFileTo(src, dst, "myKey123", WithShredSource()); !errors.Is(err, ErrFileExists) {
+		t.Errorf("expected ErrFileExists, got %v\n", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source has been shredded although encryption failed: %v\n", err)
+	}
+
+	if err := EncryptFileTo(src, dst, "myKey123", WithShredSource(), WithOverwrite()); err != nil {
+		t.Fatalf("EncryptFileTo() error = %v\n", err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("expected the source to be shredded, got %v\n", err)
+	}
+	out := filepath.Join(dir, "secret.out")
+	if err := DecryptFileTo(dst, out, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt: %s\n", err)
+	}
+	if d, err := os.ReadFile(out); err != nil || string(d) != "Hello World!" {
+		t.Errorf("expected the plaintext, got %q (%v)\n", d, err)
+	}
+	if err := DecryptFileTo(dst, filepath.Join(dir, "x"), "myKey123", WithShredSource()); err == nil {
+		t.Errorf("expected error for WithShredSource on decryption\n")
+	}
+}
@@ -0,0 +1,168 @@
+package aesgcm
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+const (
+	// DefaultChunkSize is the amount of plaintext sealed per chunk by EncryptStream.
+	DefaultChunkSize = 64 * 1024
+
+	// maxChunkSize limits the chunk size accepted from a stream header to keep memory usage bounded.
+	maxChunkSize = stream.MaxChunkSize
+
+	streamVersion    = 1
+	streamHeaderSize = stream.HeaderSize // magic + version + chunk size, followed by the base nonce
+)
+
+// streamMagic identifies streams produced by EncryptStream.
+var streamMagic = []byte("AGCS")
+
+// EncryptStream reads plaintext from 'src', encrypts it using AES-GCM encryption with the provided key
+// and writes the result to 'dst'. It returns an error if reading, encrypting or writing fails.
+//
+// The plaintext is processed in chunks of DefaultChunkSize bytes, so memory usage stays constant
+// regardless of the input size. Every chunk is sealed individually with a nonce derived by incrementing
+// the base nonce. The last chunk is always shorter than the chunk size (it may be empty),
+// which allows DecryptStream to detect truncated streams.
+//
+// The format is stable, all integers are big-endian:
+//
+//	header:  "AGCS" (4 bytes) | version 1 (1 byte) | chunk size C (uint32) | base nonce N (12 bytes)
+//	chunk i: AES-GCM ciphertext of up to C plaintext bytes followed by its 16-byte tag
+//
+// Chunk i is sealed with the nonce N + i, where i is added to the last 8 bytes of N, and with the header
+// followed by a single byte as additional data: 1 for the last chunk, 0 for all others. With WithAAD,
+// the additional data of every chunk ends with the given AAD.
+// Every chunk but the last holds exactly C plaintext bytes, the last one holds fewer.
+func EncryptStream(dst io.Writer, src io.Reader, key string) error {
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.EncryptStream(dst, src)
+}
+
+// EncryptStream reads plaintext from 'src', encrypts it and writes the result to 'dst' in the format
+// of the package-level EncryptStream, using the chunk size set with WithChunkSize.
+// The additional data set with WithAAD is authenticated with every chunk.
+// With WithProgress, the plaintext bytes read are reported with an unknown total.
+func (c *Cipher) EncryptStream(dst io.Writer, src io.Reader) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	src = c.streamProgress(src)
+	if err := c.encryptStream(dst, src); err != nil {
+		return err
+	}
+	reportDone(src)
+	return nil
+}
+
+// encryptStream is EncryptStream without progress reporting.
+func (c *Cipher) encryptStream(w io.Writer, r io.Reader) error {
+	header, err := newStreamHeader(c.aead, c.opts.rand, c.fingerprint, c.opts.chunkSize)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	return stream.EncryptChunks(c.aead, header, c.opts.aad, c.padReader(r), w, c.opts.chunkSize)
+}
+
+// streamProgress wraps 'r' to report the progress set with WithProgress, if any, with an unknown total.
+func (c *Cipher) streamProgress(r io.Reader) io.Reader {
+	if wrap := progressWrapper(c.opts.progress); wrap != nil {
+		return wrap(r, -1)
+	}
+	return r
+}
+
+// newStreamHeader creates a stream header for 'chunkSize' with a base nonce read from 'random',
+// which is tracked for the key hash 'keyHash' if nonce tracking is enabled.
+func newStreamHeader(aesGCM cipher.AEAD, random io.Reader, keyHash []byte, chunkSize int) ([]byte, error) {
+	header, err := stream.NewHeader(aesGCM, streamMagic, streamVersion, random, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := trackNonce(keyHash, header[streamHeaderSize:]); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// DecryptStream reads a stream produced by EncryptStream from 'src', decrypts it using AES-GCM decryption
+// with the provided key and writes the plaintext to 'dst'. It returns an error if the stream is malformed,
+// truncated or has been tampered with.
+//
+// Note: Plaintext of already authenticated chunks is written to 'dst' before the whole stream has been verified,
+// callers must discard the output if an error is returned.
+func DecryptStream(dst io.Writer, src io.Reader, key string) error {
+	c, err := newDecryptCipher(key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecryptStream(dst, src)
+}
+
+// DecryptStream reads a stream produced by EncryptStream from 'src', decrypts it and writes the plaintext to 'dst'.
+// See the package-level DecryptStream for how errors are reported.
+// With WithProgress, the encrypted bytes read are reported with an unknown total.
+func (c *Cipher) DecryptStream(dst io.Writer, src io.Reader) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	src = c.streamProgress(src)
+	if err := c.decryptStream(dst, src); err != nil {
+		return err
+	}
+	reportDone(src)
+	return nil
+}
+
+// decryptStream is DecryptStream without progress reporting.
+func (c *Cipher) decryptStream(w io.Writer, r io.Reader) error {
+	dr, err := newDecryptReader(c.aead, r, c.opts.aad, c.opts.maxSize)
+	if err != nil {
+		return err
+	}
+	dr.unpad = c.newUnpadder()
+	_, err = io.Copy(w, dr)
+	return err
+}
+
+// readStreamHeader reads and validates the header of a stream produced by EncryptStream.
+// It returns the header, including the base nonce, and the chunk size.
+func readStreamHeader(aesGCM cipher.AEAD, r io.Reader) ([]byte, int, error) {
+	return stream.ReadHeader(aesGCM, r, streamMagic, streamVersion)
+}
+
+// verifyStreamKey checks whether the key of 'aesGCM' matches the stream read from 'r' by authenticating
+// only its first chunk with the additional data 'extra'. It returns nil if the key matches.
+func verifyStreamKey(aesGCM cipher.AEAD, r io.Reader, extra []byte) error {
+	header, chunkSize, err := readStreamHeader(aesGCM, r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptHeader, err)
+	}
+	buf := make([]byte, chunkSize+aesGCM.Overhead())
+	defer clear(buf)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w in chunk 0: %w", ErrStreamTruncated, err)
+	}
+	nonce := stream.ChunkNonce(header[streamHeaderSize:], 0)
+	if _, err := aesGCM.Open(buf[:0], nonce, buf[:n], stream.ChunkAAD(header, n < len(buf), extra)); err != nil {
+		return fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_stream(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		key  string
+	}{
+		{"empty", 0, "myKey123"},
+		{"small", 12, "12345678"},
+		{"chunk - 1", DefaultChunkSize - 1, "1234567890"},
+		{"chunk", DefaultChunkSize, "11111111"},
+		{"chunk + 1", DefaultChunkSize + 1, "12345678"},
+		{"multiple chunks", 3*DefaultChunkSize + 17, "myKey123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			var encrypted bytes.Buffer
+			if err := EncryptStream(&encrypted, bytes.NewReader(data), tt.key); err != nil {
+				t.Fatalf("could not encrypt stream: %s\n", err)
+			}
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes()), tt.key); err != nil {
+				t.Fatalf("could not decrypt stream: %s\n", err)
+			}
+			if !bytes.Equal(data, decrypted.Bytes()) {
+				t.Errorf("encrypt/decrypt stream failed: %v: plaintext mismatch\n", tt.name)
+			}
+
+			if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), tt.key+"x"); err == nil {
+				t.Errorf("decrypt stream with wrong key succeeded: %v\n", tt.name)
+			}
+
+			e := encrypted.Bytes()
+			lastChunk := tt.size%DefaultChunkSize + 16
+			for _, cut := range []int{1, 16, 17, lastChunk, len(e) / 2} {
+				if cut >= len(e) {
+					continue
+				}
+				err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e[:len(e)-cut]), tt.key)
+				if err == nil || !strings.Contains(err.Error(), "truncated") {
+					t.Errorf("expected truncation error when cutting %d bytes: %v: got %v\n", cut, tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_streamHeader(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, strings.NewReader("Hello World!"), "myKey123"); err != nil {
+		t.Fatalf("could not encrypt stream: %s\n", err)
+	}
+	e := encrypted.Bytes()
+
+	tampered := append([]byte{}, e...)
+	tampered[0] = 'X'
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(tampered), "myKey123"); err == nil {
+		t.Errorf("decrypt stream with bad magic succeeded\n")
+	}
+
+	tampered = append([]byte{}, e...)
+	tampered[len(tampered)-1] ^= 0xff
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(tampered), "myKey123"); err == nil {
+		t.Errorf("decrypt tampered stream succeeded\n")
+	}
+
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e[:5]), "myKey123"); err == nil {
+		t.Errorf("decrypt stream with truncated header succeeded\n")
+	}
+}
+
+func Test_streamMemory(t *testing.T) {
+	const size = 64 * 1024 * 1024
+	src := io.LimitReader(zeroReader{}, size)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(EncryptStream(pw, src, "myKey123"))
+	}()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	counter := &countingWriter{}
+	if err := DecryptStream(counter, pr, "myKey123"); err != nil {
+		t.Fatalf("could not decrypt stream: %s\n", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if counter.n != size {
+		t.Errorf("expected %d bytes, got %d\n", size, counter.n)
+	}
+	// both sides allocate per chunk, but never anywhere near the size of the stream
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/4 {
+		t.Errorf("streaming %d bytes allocated %d bytes\n", size, alloc)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
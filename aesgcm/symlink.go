@@ -0,0 +1,80 @@
+package aesgcm
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkPolicy decides how symlinks are handled by the functions processing files and directories.
+type SymlinkPolicy int
+
+const (
+	// symlinksDefault selects FollowSymlinks for single files and SkipSymlinks for directories.
+	symlinksDefault SymlinkPolicy = iota
+	// FollowSymlinks processes the target of a symlink in place, the symlink itself is left as it is.
+	// Directory functions only follow symlinks to regular files, symlinks to directories are skipped,
+	// and process a target reached through several paths only once. It is the default for single files.
+	FollowSymlinks
+	// SkipSymlinks leaves symlinks and their targets alone. The file functions return without doing anything.
+	// It is the default for directories.
+	SkipSymlinks
+	// ErrorOnSymlinks fails with an error wrapping ErrSymlink for every symlink.
+	// Directory functions still process the remaining files.
+	ErrorOnSymlinks
+)
+
+// WithSymlinks sets how symlinks are handled by EncryptFile, DecryptFile, EncryptDir, DecryptDir and the other
+// functions processing files or walking directories. Without it, the file functions follow symlinks and
+// the directory functions skip them.
+//
+// Dangling symlinks, whose target doesn't exist or can't be resolved, such as link loops, never make a directory
+// function fail, regardless of the policy: they are left alone and counted in DirSummary.Dangling.
+func WithSymlinks(p SymlinkPolicy) Option {
+	return func(o *options) error {
+		if p != FollowSymlinks && p != SkipSymlinks && p != ErrorOnSymlinks {
+			return fmt.Errorf("invalid symlink policy %d", p)
+		}
+		o.symlinks = p
+		return nil
+	}
+}
+
+// fileSymlinks returns the symlink policy of the file functions.
+func (c *Cipher) fileSymlinks() SymlinkPolicy {
+	if c.opts.symlinks == symlinksDefault {
+		return FollowSymlinks
+	}
+	return c.opts.symlinks
+}
+
+// dirSymlinks returns the symlink policy of the directory functions.
+func (c *Cipher) dirSymlinks() SymlinkPolicy {
+	if c.opts.symlinks == symlinksDefault {
+		return SkipSymlinks
+	}
+	return c.opts.symlinks
+}
+
+// symlinkTarget applies the symlink policy of the file functions to 'path' for the operation 'op'.
+// It returns 'path' unless it is a symlink, the target of a followed symlink, or "" if the symlink is skipped.
+func (c *Cipher) symlinkTarget(op, path string) (string, error) {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+		return path, nil
+	}
+	switch c.fileSymlinks() {
+	case SkipSymlinks:
+		return "", nil
+	case ErrorOnSymlinks:
+		return "", fmt.Errorf("can't %s, %w: '%s'", op, ErrSymlink, path)
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return "", errFileNotFound(op, path)
+	} else if err != nil {
+		return "", fmt.Errorf("can't %s, can't resolve symlink '%s': %w", op, path, err)
+	}
+	return target, nil
+}
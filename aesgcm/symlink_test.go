@@ -0,0 +1,128 @@
+package aesgcm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// symlinkTree creates a tree with two files, a symlink to a file in the tree, a symlink to a file outside
+// of the tree, a symlink to the root, a link loop and a dangling symlink. It returns the root and the path
+// of the file outside of the tree.
+func symlinkTree(t *testing.T) (string, string) {
+	t.Helper()
+	root := dirTree(t, map[string]string{"a.txt": "Hello A!", "sub/b.txt": "Hello B!"})
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("Hello Outside!"), 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	links := map[string]string{
+		"in.txt":       filepath.Join(root, "a.txt"),
+		"out.txt":      outside,
+		"sub/root":     root,
+		"loop1":        filepath.Join(root, "loop2"),
+		"loop2":        filepath.Join(root, "loop1"),
+		"dangling.txt": filepath.Join(root, "missing.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatalf("could not create symlink: %s\n", err)
+		}
+	}
+	return root, outside
+}
+
+func Test_WithSymlinks_dir(t *testing.T) {
+	if _, err := New("myKey123", WithSymlinks(SymlinkPolicy(42))); err == nil {
+		t.Errorf("WithSymlinks() with an invalid policy expected an error\n")
+	}
+	tests := []struct {
+		name        string
+		opts        []Option
+		want        DirSummary
+		wantErr     error
+		wantOutside bool // whether the file outside of the tree is encrypted
+	}{
+		{"default", nil, DirSummary{Processed: 2, Skipped: 3, Dangling: 3}, nil, false},
+		{"skip", []Option{WithSymlinks(SkipSymlinks)}, DirSummary{Processed: 2, Skipped: 3, Dangling: 3}, nil, false},
+		{"error", []Option{WithSymlinks(ErrorOnSymlinks)}, DirSummary{Processed: 2, Failed: 3, Dangling: 3}, ErrSymlink, false},
+		// in.txt is skipped since a.txt has been encrypted already, sub/root since it links to a directory
+		{"follow", []Option{WithSymlinks(FollowSymlinks)}, DirSummary{Processed: 3, Skipped: 2, Dangling: 3}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, outside := symlinkTree(t)
+			c, _ := New("myKey123", tt.opts...)
+			s, err := c.EncryptDir(root)
+			if s != tt.want {
+				t.Errorf("EncryptDir() = %+v, want %+v\n", s, tt.want)
+			}
+			if (err != nil) != (tt.wantErr != nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("EncryptDir() error = %v, want %v\n", err, tt.wantErr)
+			}
+			if ok, _ := IsEncrypted(outside); ok != tt.wantOutside {
+				t.Errorf("file outside of the tree encrypted = %v, want %v\n", ok, tt.wantOutside)
+			}
+			for _, name := range []string{"in.txt", "out.txt", "sub/root", "loop1", "dangling.txt"} {
+				if fi, err := os.Lstat(filepath.Join(root, name)); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+					t.Errorf("symlink has been replaced: %s\n", name)
+				}
+			}
+
+			if _, err := c.DecryptDir(root); err != nil && tt.wantErr == nil {
+				t.Fatalf("DecryptDir() error = %v\n", err)
+			}
+			if ok, _ := IsEncrypted(outside); ok {
+				t.Errorf("file outside of the tree is still encrypted\n")
+			}
+			if d, _ := os.ReadFile(filepath.Join(root, "in.txt")); string(d) != "Hello A!" {
+				t.Errorf("encrypt/decrypt dir failed: got %q\n", d)
+			}
+		})
+	}
+}
+
+func Test_WithSymlinks_file(t *testing.T) {
+	root, outside := symlinkTree(t)
+	link := filepath.Join(root, "out.txt")
+
+	if err := EncryptFile(link, "myKey123", WithSymlinks(SkipSymlinks)); err != nil {
+		t.Errorf("EncryptFile() with SkipSymlinks error = %v\n", err)
+	}
+	if ok, _ := IsEncrypted(outside); ok {
+		t.Errorf("EncryptFile() with SkipSymlinks encrypted the target\n")
+	}
+	if err := EncryptFile(link, "myKey123", WithSymlinks(ErrorOnSymlinks)); !errors.Is(err, ErrSymlink) {
+		t.Errorf("EncryptFile() with ErrorOnSymlinks error = %v, want ErrSymlink\n", err)
+	}
+	if err := EncryptFileTo(link, filepath.Join(root, "copy.txt"), "myKey123", WithSymlinks(ErrorOnSymlinks)); !errors.Is(err, ErrSymlink) {
+		t.Errorf("EncryptFileTo() with ErrorOnSymlinks error = %v, want ErrSymlink\n", err)
+	}
+	if err := EncryptFile(filepath.Join(root, "dangling.txt"), "myKey123"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("EncryptFile() of a dangling symlink error = %v, want ErrFileNotFound\n", err)
+	}
+	if err := EncryptFile(filepath.Join(root, "loop1"), "myKey123"); err == nil {
+		t.Errorf("EncryptFile() of a link loop expected an error\n")
+	}
+
+	// by default, the target is processed and the symlink is kept
+	if err := EncryptFile(link, "myKey123"); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	if ok, _ := IsEncrypted(outside); !ok {
+		t.Errorf("EncryptFile() didn't encrypt the target\n")
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("EncryptFile() replaced the symlink\n")
+	}
+	if err := DecryptFile(link, "myKey123", WithSymlinks(SkipSymlinks)); err != nil {
+		t.Errorf("DecryptFile() with SkipSymlinks error = %v\n", err)
+	}
+	if err := DecryptFile(link, "myKey123"); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(outside); string(d) != "Hello Outside!" {
+		t.Errorf("encrypt/decrypt through a symlink failed: got %q\n", d)
+	}
+}
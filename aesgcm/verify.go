@@ -0,0 +1,163 @@
+package aesgcm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// VerifyFile checks that the file located at 'path' decrypts successfully with the provided key, without writing
+// anything: it streams through the file and authenticates every chunk, so memory usage stays constant regardless
+// of the file size. It returns nil only if DecryptFile with the same options, or DecryptFileCompressed
+// for compressed files, would succeed.
+//
+// The error tells the causes apart:
+//   - ErrNotEncrypted if the file isn't encrypted at all, see WithLegacyFormat for files of earlier versions,
+//   - an error wrapping ErrAuthenticationFailed, or a *KeyMismatchError if the file records a key fingerprint,
+//     if the key is wrong,
+//   - a *CorruptChunkError reporting the chunk and its offset if the file is damaged or truncated.
+//
+// A wrong key is detected by the key check value of files encrypted with WithKeyCheck, other files are assumed to
+// have been encrypted with a different key if their first chunk fails authentication, since the key and a damaged
+// first chunk can't be told apart there. Compressed files are verified without decompressing them.
+func VerifyFile(path, key string, opts ...Option) error {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.VerifyFile(path)
+}
+
+// VerifyDir applies VerifyFile to every regular file in the directory tree below 'root' and returns the result
+// of every file by its path, nil for files that verify. Symlinks are skipped unless set otherwise with WithSymlinks,
+// files can be selected with WithInclude and WithExclude and are verified concurrently, see WithConcurrency. The error only reports
+// directories that can't be read; failed files are reported in the result.
+func VerifyDir(root, key string, opts ...Option) (map[string]error, error) {
+	c, err := newDecryptCipher(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.VerifyDir(root)
+}
+
+// VerifyFile checks that the file located at 'path' decrypts successfully without writing anything.
+// See the package-level VerifyFile for details.
+func (c *Cipher) VerifyFile(path string) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	cc, err := c.pathCipher(path)
+	if err != nil {
+		return err
+	}
+	f, r, err := openFile("verify", path, nil)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := cc.verifyTo(r); err != nil {
+		return fmt.Errorf("can't verify '%s': %w", path, err)
+	}
+	return nil
+}
+
+// VerifyDir applies VerifyFile to every regular file below 'root'. See the package-level VerifyDir for details.
+func (c *Cipher) VerifyDir(root string) (map[string]error, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	c, err := c.dirPathCipher(root)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	results := map[string]error{}
+	_, err = walkFiles(context.Background(), root, c.dirWorkers(), c.dirSymlinks(), c.dirFilter(root, nil), nil, func(path string) error {
+		err := c.VerifyFile(path)
+		mu.Lock()
+		defer mu.Unlock()
+		results[path] = err
+		return nil
+	})
+	return results, err
+}
+
+// verifyTo authenticates the data in the file format read from 'r', which may have been compressed,
+// without decrypting it into memory.
+func (c *Cipher) verifyTo(r io.Reader) error {
+	br := bufio.NewReaderSize(r, DefaultChunkSize)
+	head, err := br.Peek(len(compressedMagic) + maxPrefixSize + len(streamMagic) + 1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if bytes.HasPrefix(head, chunkedMagic) {
+		_, _ = br.Discard(len(chunkedMagic))
+		return c.decryptChunkedTo(io.Discard, br)
+	}
+	offset := int64(0)
+	for _, magic := range [][]byte{compressedMagic, gzipMagic} {
+		if bytes.HasPrefix(head, magic) {
+			c = c.compressedCipher(magic)
+			head = head[len(magic):]
+			offset = int64(len(magic))
+		}
+	}
+	if !IsEncryptedData(head) {
+		if c.opts.legacyFormat {
+			_, _ = br.Discard(int(offset))
+			return c.decryptTo(io.Discard, br)
+		}
+		return ErrNotEncrypted
+	}
+	p, err := c.parsePrefix(head)
+	if err != nil {
+		return err
+	}
+	if p.keyCheck != nil {
+		if err := verifyKeyCheck(c.aead, p.keyCheck); err != nil {
+			return c.keyMismatch(p.fingerprint, err)
+		}
+	}
+	offset += int64(p.size)
+	if _, err := br.Discard(int(offset)); err != nil {
+		return err
+	}
+	header, chunkSize, err := readStreamHeader(c.aead, br)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptHeader, err)
+	}
+	offset += int64(len(header))
+
+	buf := make([]byte, chunkSize+c.aead.Overhead())
+	defer clear(buf)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(br, buf)
+		if err == io.EOF {
+			return &CorruptChunkError{Chunk: i, Offset: offset, Err: fmt.Errorf("%w, missing final chunk", ErrStreamTruncated)}
+		}
+		last := err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if n < c.aead.Overhead() {
+			return &CorruptChunkError{Chunk: i, Offset: offset, Err: ErrStreamTruncated}
+		}
+		if _, err := openChunk(c.aead, header, i, buf[:n], last, c.opts.aad); err != nil {
+			if i == 0 && p.keyCheck == nil {
+				return c.keyMismatch(p.fingerprint, err)
+			}
+			return &CorruptChunkError{Chunk: i, Offset: offset, Err: err}
+		}
+		if last {
+			return nil
+		}
+		offset += int64(n)
+	}
+}
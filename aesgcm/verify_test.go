@@ -0,0 +1,140 @@
+package aesgcm
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encryptedFile writes 'size' random bytes to a new file and encrypts it with 'encrypt'.
+func encryptedFile(t *testing.T, size int, encrypt func(path string) error) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	plain := make([]byte, size)
+	_, _ = rand.Read(plain)
+	if err := os.WriteFile(path, plain, 0644); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := encrypt(path); err != nil {
+		t.Fatalf("could not encrypt file: %s\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	return path, data
+}
+
+func Test_VerifyFile(t *testing.T) {
+	size := 3*DefaultChunkSize + 17
+	tests := []struct {
+		name    string
+		encrypt func(path string) error
+		opts    []Option
+	}{
+		{"default", func(p string) error { return EncryptFile(p, "myKey123") }, nil},
+		{"key check", func(p string) error { return EncryptFile(p, "myKey123", WithKeyCheck()) }, nil},
+		{"aad", func(p string) error { return EncryptFile(p, "myKey123", WithAAD([]byte("user-42"))) }, []Option{WithAAD([]byte("user-42"))}},
+		{"compressed", func(p string) error { return EncryptFileCompressed(p, "myKey123") }, nil},
+		{"gzip", func(p string) error { return EncryptFileGzip(p, "myKey123") }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, data := encryptedFile(t, size, tt.encrypt)
+			if err := VerifyFile(path, "myKey123", tt.opts...); err != nil {
+				t.Errorf("VerifyFile() error = %v\n", err)
+			}
+			if after, _ := os.ReadFile(path); string(after) != string(data) {
+				t.Errorf("VerifyFile() modified the file\n")
+			}
+			var corrupt *CorruptChunkError
+			err := VerifyFile(path, "wrongKey", tt.opts...)
+			if !errors.Is(err, ErrAuthenticationFailed) || errors.As(err, &corrupt) {
+				t.Errorf("expected a wrong key error, got %v\n", err)
+			}
+
+			truncated := data[:len(data)-20]
+			if err := os.WriteFile(path, truncated, 0644); err != nil {
+				t.Fatalf("could not write file: %s\n", err)
+			}
+			if err := VerifyFile(path, "myKey123", tt.opts...); !errors.As(err, &corrupt) {
+				t.Errorf("expected *CorruptChunkError for a truncated file, got %v\n", err)
+			}
+		})
+	}
+}
+
+func Test_VerifyFile_corrupted(t *testing.T) {
+	path, data := encryptedFile(t, 3*DefaultChunkSize+17, func(p string) error { return EncryptFile(p, "myKey123") })
+	chunk := int64(DefaultChunkSize + 16)
+	offset := int64(streamHeaderSize+12) + 2*chunk
+	tampered := append([]byte{}, data...)
+	tampered[offset+100] ^= 0xff
+	if err := os.WriteFile(path, tampered, 0644); err != nil {
+		t.Fatalf("could not write file: %s\n", err)
+	}
+	var corrupt *CorruptChunkError
+	err := VerifyFile(path, "myKey123")
+	if !errors.As(err, &corrupt) {
+		t.Fatalf("expected *CorruptChunkError, got %v\n", err)
+	}
+	if corrupt.Chunk != 2 || corrupt.Offset != offset {
+		t.Errorf("expected chunk 2 at offset %d, got chunk %d at offset %d\n", offset, corrupt.Chunk, corrupt.Offset)
+	}
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected the chunk error to wrap ErrAuthenticationFailed, got %v\n", err)
+	}
+
+	// with a key check value, a damaged first chunk is told apart from a wrong key
+	path, data = encryptedFile(t, 100, func(p string) error { return EncryptFile(p, "myKey123", WithKeyCheck()) })
+	data[len(data)-1] ^= 0xff
+	_ = os.WriteFile(path, data, 0644)
+	if err := VerifyFile(path, "myKey123"); !errors.As(err, &corrupt) || corrupt.Chunk != 0 {
+		t.Errorf("expected *CorruptChunkError for chunk 0, got %v\n", err)
+	}
+
+	path, _ = encryptedFile(t, 100, func(p string) error { return EncryptFile(p, "myKey123", WithFingerprint()) })
+	var mismatch *KeyMismatchError
+	if err := VerifyFile(path, "wrongKey"); !errors.As(err, &mismatch) {
+		t.Errorf("expected *KeyMismatchError, got %v\n", err)
+	}
+
+	plain := filepath.Join(t.TempDir(), "plain.txt")
+	_ = os.WriteFile(plain, []byte("Hello World!"), 0644)
+	if err := VerifyFile(plain, "myKey123"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v\n", err)
+	}
+	if err := VerifyFile(filepath.Join(t.TempDir(), "missing"), "myKey123"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v\n", err)
+	}
+}
+
+func Test_VerifyDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "sub/b.txt", "plain.txt"} {
+		p := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte("Hello World!"), 0644); err != nil {
+			t.Fatalf("could not create file: %s\n", err)
+		}
+		if name != "plain.txt" {
+			if err := EncryptFile(p, "myKey123"); err != nil {
+				t.Fatalf("could not encrypt file: %s\n", err)
+			}
+		}
+	}
+	results, err := VerifyDir(root, "myKey123", WithConcurrency(2))
+	if err != nil {
+		t.Fatalf("VerifyDir() error = %v\n", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected 3 results, got %d\n", len(results))
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err, ok := results[filepath.Join(root, name)]; !ok || err != nil {
+			t.Errorf("expected %s to verify, got %v (%v)\n", name, err, ok)
+		}
+	}
+	if err := results[filepath.Join(root, "plain.txt")]; !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted for plain.txt, got %v\n", err)
+	}
+}
@@ -0,0 +1,68 @@
+package aesgcm
+
+import "sync"
+
+// cipherState is the state of a Cipher, which is shared with the copies its methods make of it.
+type cipherState struct {
+	mu      sync.Mutex
+	closed  bool
+	running int    // number of methods that have acquired the Cipher
+	nameKey []byte // key of the opaque names, wiped once the Cipher is closed and no method is running
+}
+
+// acquire returns ErrClosed if the Cipher has been closed. Otherwise it keeps Close from wiping the key
+// material until release is called.
+func (c *Cipher) acquire() error {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.closed {
+		return ErrClosed
+	}
+	c.state.running++
+	return nil
+}
+
+// release ends the use of the Cipher started by acquire and wipes the key material if it has been closed meanwhile.
+func (c *Cipher) release() {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.running--
+	c.state.wipe()
+}
+
+// wipe clears the key material once the Cipher is closed and no method is running. The caller must hold 'mu'.
+func (s *cipherState) wipe() {
+	if s.closed && s.running == 0 {
+		clear(s.nameKey)
+	}
+}
+
+// Close wipes the key material held by the Cipher, so the key doesn't linger in memory, and core dumps, until
+// it is garbage collected. Afterwards, every method of the Cipher returns ErrClosed, including the methods of
+// its copies; EncryptReader, DecryptReader and DecryptWriter return a reader or writer that fails with it.
+// Close is safe for concurrent use: methods that are still running complete, or fail with ErrClosed, and the
+// key material is wiped once the last of them returns. It always returns nil and can be called more than once.
+//
+// The scrambled key itself is already wiped by New once the AES-GCM instance has been set up, and the package-level
+// functions close the Cipher they create before returning. What can't be wiped are the key string passed by the
+// caller, the key string returned by keys.WeakKeyScrambler and the expanded key schedule held inside crypto/aes,
+// which is only dropped for the garbage collector along with the Cipher. Readers and writers created before Close
+// hold the AES-GCM instance themselves and keep working.
+//
+// Temporary buffers the package allocates for plaintext, such as the chunk buffers of streams, files, readers
+// and writers and the byte slices converted from and to the strings of Encrypt and Decrypt, are wiped by the
+// methods themselves once they are no longer needed, regardless of Close. Readers and writers wipe theirs once
+// the stream has ended or failed. Plaintext passed to or returned by the methods belongs to the caller and isn't
+// wiped, nor are the strings holding it, which can't be cleared in Go.
+func (c *Cipher) Close() error {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.closed = true
+	c.state.wipe()
+	return nil
+}
+
+// wipe overwrites the key of the keyCipher with zeros once it is no longer needed.
+func (c *keyCipher) wipe() {
+	clear(c.key)
+}
@@ -0,0 +1,156 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_wipe(t *testing.T) {
+	var derived [][]byte
+	kdf := WithKDF(func(key string) ([]byte, error) {
+		sum := sha512.Sum512([]byte(key))
+		derived = append(derived, sum[:])
+		return sum[:], nil
+	})
+	zero := make([]byte, sha512.Size)
+
+	e, err := Encrypt("Hello World!", "myKey123", kdf)
+	if err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	c, err := New("myKey123", kdf, WithOpaqueNames())
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+	for i, k := range derived {
+		if !bytes.Equal(k, zero) {
+			t.Errorf("key %d: expected the derived key and its excess bytes to be wiped: %x\n", i, k)
+		}
+	}
+	if d, err := c.Decrypt(e); err != nil || d != "Hello World!" {
+		t.Fatalf("Decrypt() after wiping the derived key = %q, %v\n", d, err)
+	}
+
+	nameKey := c.nameKey
+	if bytes.Equal(nameKey, zero[:len(nameKey)]) {
+		t.Fatalf("expected a name key\n")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v\n", err)
+	}
+	if !bytes.Equal(nameKey, zero[:len(nameKey)]) {
+		t.Errorf("Close() expected the name key to be wiped: %x\n", nameKey)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() of a closed Cipher error = %v\n", err)
+	}
+	for name, use := range map[string]func() error{
+		"Encrypt":       func() error { _, err := c.Encrypt("Hello World!"); return err },
+		"Decrypt":       func() error { _, err := c.Decrypt(e); return err },
+		"EncryptStream": func() error { return c.EncryptStream(io.Discard, strings.NewReader("Hello World!")) },
+		"EncryptReader": func() error { _, err := io.ReadAll(c.EncryptReader(strings.NewReader("Hello World!"))); return err },
+		"DecryptWriter": func() error { return c.DecryptWriter(io.Discard).Close() },
+		"EncryptDir":    func() error { _, err := c.EncryptDir(t.TempDir()); return err },
+	} {
+		if err := use(); !errors.Is(err, ErrClosed) {
+			t.Errorf("%s() after Close error = %v, want ErrClosed\n", name, err)
+		}
+	}
+
+	kc, _ := newKeyCipher("myKey123")
+	key := kc.key
+	kc.wipe()
+	if !bytes.Equal(key, zero[:len(key)]) {
+		t.Errorf("wipe() = %x, want zeros\n", key)
+	}
+}
+
+func Test_Close_concurrent(t *testing.T) {
+	c, err := New("myKey123", WithOpaqueNames())
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+	nameKey := c.nameKey
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e, err := c.Encrypt("Hello World!")
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Encrypt() error = %v\n", err)
+					return
+				}
+				if d, err := c.Decrypt(e); err != nil && !errors.Is(err, ErrClosed) || err == nil && d != "Hello World!" {
+					t.Errorf("Decrypt() = %q, %v\n", d, err)
+					return
+				}
+			}
+		}()
+	}
+	_ = c.Close()
+	wg.Wait()
+	if !bytes.Equal(nameKey, make([]byte, len(nameKey))) {
+		t.Errorf("expected the name key to be wiped once all methods returned: %x\n", nameKey)
+	}
+}
+
+func Test_wipe_buffers(t *testing.T) {
+	c, err := New("myKey123", WithChunkSize(16), WithPadding(8))
+	if err != nil {
+		t.Fatalf("New() error = %v\n", err)
+	}
+	defer c.Close()
+	plaintext := strings.Repeat("Hello World! ", 5)
+	isZero := func(b []byte) bool { return bytes.Count(b, []byte{0}) == len(b) }
+
+	var stream bytes.Buffer
+	w, _ := c.EncryptWriter(&stream)
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		t.Fatalf("Write() error = %v\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v\n", err)
+	}
+	if ew := w.(*encryptWriter); !isZero(ew.buf[:cap(ew.buf)]) {
+		t.Errorf("encryptWriter expected the plaintext buffer to be wiped: %q\n", ew.buf[:cap(ew.buf)])
+	}
+
+	er := c.EncryptReader(strings.NewReader(plaintext)).(*encryptReader)
+	if _, err := io.ReadAll(er); err != nil {
+		t.Fatalf("ReadAll() error = %v\n", err)
+	}
+	if !isZero(er.buf) {
+		t.Errorf("encryptReader expected the plaintext buffer to be wiped: %q\n", er.buf)
+	}
+
+	dr := c.DecryptReader(bytes.NewReader(stream.Bytes())).(*decryptReader)
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, dr); err != nil || out.String() != plaintext {
+		t.Fatalf("DecryptReader() = %q, %v\n", out.String(), err)
+	}
+	if !isZero(dr.buf) || dr.unpad.held != nil || dr.unpad.out != nil {
+		t.Errorf("decryptReader expected the plaintext buffers to be wiped: %q\n", dr.buf)
+	}
+
+	out.Reset()
+	dw := c.DecryptWriter(&out).(*decryptWriter)
+	if _, err := dw.Write(stream.Bytes()); err != nil {
+		t.Fatalf("Write() error = %v\n", err)
+	}
+	if err := dw.Close(); err != nil || out.String() != plaintext {
+		t.Fatalf("DecryptWriter() = %q, %v\n", out.String(), err)
+	}
+	if !isZero(dw.buf[:cap(dw.buf)]) || dw.unpad.held != nil || dw.unpad.out != nil {
+		t.Errorf("decryptWriter expected the plaintext buffers to be wiped: %q\n", dw.buf[:cap(dw.buf)])
+	}
+}
@@ -0,0 +1,283 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+)
+
+// encryptWriter encrypts everything written to it into the format of EncryptStream.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	extra  []byte // additional data set with WithAAD
+	buf    []byte // plaintext of the current chunk, at most chunkSize bytes
+	sealed []byte
+	chunk  uint64
+	err    error // sticky error, ErrClosed after Close
+	pad    int   // block size set with WithPadding, 0 for none
+	n      int64 // plaintext written so far
+}
+
+// NewEncryptWriter returns an io.WriteCloser that encrypts everything written to it using AES-GCM encryption
+// with the provided key and writes the result to 'w', in the same format as EncryptStream.
+// It returns an error if the key is weak or if the stream header can't be written.
+//
+// Plaintext is buffered and sealed in chunks of DefaultChunkSize bytes. Close must be called to seal the
+// final chunk, which marks the end of the stream; it doesn't close 'w'. If Close is never called,
+// DecryptStream fails with a truncation error instead of returning the partial plaintext.
+// Once a write to 'w' fails, including short writes, every further call returns that error.
+// Write and Close after Close return ErrClosed.
+func NewEncryptWriter(w io.Writer, key string) (io.WriteCloser, error) {
+	return EncryptWriter(w, key)
+}
+
+// EncryptWriter returns an io.WriteCloser that encrypts everything written to it using AES-GCM encryption
+// with the provided key and forwards the sealed chunks to 'w', so the ciphertext is never held in memory
+// as a whole, for example when writing to a network connection or an archive/zip entry.
+// It behaves exactly like NewEncryptWriter, see there for details.
+func EncryptWriter(w io.Writer, key string) (io.WriteCloser, error) {
+	c, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.EncryptWriter(w)
+}
+
+// EncryptWriter returns an io.WriteCloser that encrypts everything written to it and writes the result to 'w',
+// using the chunk size set with WithChunkSize. The additional data set with WithAAD is authenticated with every chunk.
+// See NewEncryptWriter for details.
+func (c *Cipher) EncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	header, err := newStreamHeader(c.aead, c.opts.rand, c.fingerprint, c.opts.chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	ew := &encryptWriter{
+		w:      w,
+		aead:   c.aead,
+		header: header,
+		extra:  c.opts.aad,
+		buf:    make([]byte, 0, c.opts.chunkSize),
+		sealed: make([]byte, 0, c.opts.chunkSize+c.aead.Overhead()),
+		pad:    c.opts.padding,
+	}
+	if err := writeFull(w, header); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+// writeFull writes 'p' to 'w', turning short writes into io.ErrShortWrite.
+func writeFull(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// seal seals the buffered plaintext as the next chunk and writes it.
+func (ew *encryptWriter) seal(last bool) error {
+	nonce := stream.ChunkNonce(ew.header[streamHeaderSize:], ew.chunk)
+	ew.sealed = ew.aead.Seal(ew.sealed[:0], nonce, ew.buf, stream.ChunkAAD(ew.header, last, ew.extra))
+	clear(ew.buf)
+	ew.buf = ew.buf[:0]
+	ew.chunk++
+	if err := writeFull(ew.w, ew.sealed); err != nil {
+		ew.err = err
+		return err
+	}
+	return nil
+}
+
+// Write buffers 'p' and seals every chunk that is complete. A full chunk is only sealed once more data
+// arrives, since the last chunk must be shorter than the chunk size.
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(ew.buf) == cap(ew.buf) {
+			if err := ew.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	ew.n += int64(written)
+	return written, nil
+}
+
+// Close seals the buffered plaintext, followed by the padding set with WithPadding, as the final chunk.
+// If the buffer holds a full chunk, it is sealed first and followed by an empty final chunk.
+func (ew *encryptWriter) Close() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	if ew.pad > 0 {
+		if _, err := ew.Write(padding(ew.n, ew.pad)); err != nil {
+			return err
+		}
+	}
+	if len(ew.buf) == cap(ew.buf) {
+		if err := ew.seal(false); err != nil {
+			return err
+		}
+	}
+	if err := ew.seal(true); err != nil {
+		return err
+	}
+	ew.err = ErrClosed
+	return nil
+}
+
+// decryptWriter decrypts a stream in the format of EncryptStream written to it chunk by chunk.
+type decryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	extra  []byte // additional data set with WithAAD
+	header []byte // stream header, complete once 'buf' has been allocated
+	buf    []byte // ciphertext of the current chunk, at most chunk size + overhead bytes, decrypted in place
+	chunk  uint64
+	err    error     // sticky error, ErrClosed after Close
+	limit  int64     // plaintext limit set with WithMaxSize, 0 for none
+	total  int64     // plaintext written so far
+	unpad  *unpadder // strips the padding set with WithPadding, nil without it
+}
+
+// DecryptWriter returns an io.WriteCloser that decrypts a stream produced by EncryptStream, EncryptWriter or
+// EncryptReader written to it using AES-GCM decryption with the provided key, and writes the plaintext to 'w'.
+// An error is only returned if the key is weak.
+//
+// Ciphertext is buffered until a chunk is complete, which is authenticated before its plaintext is written to 'w'.
+// Close must be called to authenticate the final chunk; it doesn't close 'w'. If the stream is malformed,
+// Write or Close return an error wrapping ErrStreamTruncated or ErrAuthenticationFailed, and every further
+// call returns that error. Plaintext of earlier chunks has been written to 'w' at that point, so callers
+// must discard it. Write and Close after Close return ErrClosed.
+func DecryptWriter(w io.Writer, key string) (io.WriteCloser, error) {
+	c, err := newDecryptCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.DecryptWriter(w), nil
+}
+
+// DecryptWriter returns an io.WriteCloser that decrypts a stream written to it and writes the plaintext to 'w'.
+// The additional data set with WithAAD is authenticated with every chunk. See the package-level DecryptWriter for details.
+func (c *Cipher) DecryptWriter(w io.Writer) io.WriteCloser {
+	if err := c.acquire(); err != nil {
+		return &decryptWriter{err: err}
+	}
+	defer c.release()
+	return &decryptWriter{
+		w:      w,
+		aead:   c.aead,
+		extra:  c.opts.aad,
+		header: make([]byte, 0, streamHeaderSize+c.aead.NonceSize()),
+		limit:  c.opts.maxSize,
+		unpad:  c.newUnpadder(),
+	}
+}
+
+// Write buffers 'p' and decrypts every chunk that is complete. A full chunk is only decrypted once more data
+// arrives, since it is the final chunk if the stream ends after it.
+func (dw *decryptWriter) Write(p []byte) (int, error) {
+	if dw.err != nil {
+		return 0, dw.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if dw.buf == nil {
+			n := copy(dw.header[len(dw.header):cap(dw.header)], p)
+			dw.header = dw.header[:len(dw.header)+n]
+			p = p[n:]
+			written += n
+			if len(dw.header) == cap(dw.header) {
+				if dw.err = dw.parseHeader(); dw.err != nil {
+					return written, dw.err
+				}
+			}
+			continue
+		}
+		if len(dw.buf) == cap(dw.buf) {
+			if dw.err = dw.open(false); dw.err != nil {
+				return written, dw.err
+			}
+		}
+		n := copy(dw.buf[len(dw.buf):cap(dw.buf)], p)
+		dw.buf = dw.buf[:len(dw.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// parseHeader validates the complete stream header and allocates the chunk buffer.
+func (dw *decryptWriter) parseHeader() error {
+	_, chunkSize, err := readStreamHeader(dw.aead, bytes.NewReader(dw.header))
+	if err != nil {
+		return err
+	}
+	dw.buf = make([]byte, 0, chunkSize+dw.aead.Overhead())
+	return nil
+}
+
+// open authenticates and decrypts the buffered chunk and writes its plaintext.
+func (dw *decryptWriter) open(last bool) error {
+	if len(dw.buf) < dw.aead.Overhead() {
+		return fmt.Errorf("%w in chunk %d", ErrStreamTruncated, dw.chunk)
+	}
+	plain, err := openChunk(dw.aead, dw.header, dw.chunk, dw.buf, last, dw.extra)
+	if err != nil {
+		return err
+	}
+	defer clear(dw.buf)
+	if dw.unpad != nil {
+		if plain, err = dw.unpad.next(plain, last); err != nil {
+			return err
+		}
+	}
+	if err := countPlaintext(&dw.total, len(plain), dw.limit); err != nil {
+		return err
+	}
+	dw.buf = dw.buf[:0]
+	dw.chunk++
+	return writeFull(dw.w, plain)
+}
+
+// Close authenticates and decrypts the buffered ciphertext as the final chunk. It fails if the stream ended
+// before its header or final chunk.
+func (dw *decryptWriter) Close() error {
+	if dw.err != nil {
+		dw.unpad.wipe()
+		return dw.err
+	}
+	switch {
+	case dw.buf == nil:
+		dw.err = fmt.Errorf("%w, could not read header", ErrStreamTruncated)
+	case len(dw.buf) == cap(dw.buf):
+		if dw.err = dw.open(false); dw.err == nil {
+			dw.err = fmt.Errorf("%w, missing final chunk after chunk %d", ErrStreamTruncated, dw.chunk-1)
+		}
+	default:
+		dw.err = dw.open(true)
+	}
+	dw.unpad.wipe()
+	if dw.err == nil {
+		dw.err = ErrClosed
+		return nil
+	}
+	return dw.err
+}
@@ -0,0 +1,264 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_NewEncryptWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes int // size of the individual writes
+	}{
+		{"empty", 0, 1},
+		{"small", 12, 5},
+		{"chunk", DefaultChunkSize, 1000},
+		{"chunk in one write", DefaultChunkSize, DefaultChunkSize},
+		{"chunk + 1", DefaultChunkSize + 1, 4096},
+		{"multiple chunks", 3*DefaultChunkSize + 17, 3*DefaultChunkSize + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			var encrypted bytes.Buffer
+			w, err := NewEncryptWriter(&encrypted, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create encrypt writer: %s\n", err)
+			}
+			for p := data; len(p) > 0; {
+				n, err := w.Write(p[:min(tt.writes, len(p))])
+				if err != nil {
+					t.Fatalf("could not write: %s\n", err)
+				}
+				p = p[n:]
+			}
+
+			unclosed := bytes.Clone(encrypted.Bytes())
+			if err := DecryptStream(io.Discard, bytes.NewReader(unclosed), "myKey123"); err == nil || !strings.Contains(err.Error(), "truncated") {
+				t.Errorf("expected truncation error for unclosed writer: %v: got %v\n", tt.name, err)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("could not close: %s\n", err)
+			}
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, &encrypted, "myKey123"); err != nil {
+				t.Fatalf("could not decrypt stream: %s\n", err)
+			}
+			if !bytes.Equal(data, decrypted.Bytes()) {
+				t.Errorf("encrypt writer/decrypt stream failed: %v: plaintext mismatch\n", tt.name)
+			}
+
+			if _, err := w.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+				t.Errorf("expected ErrClosed for write after close, got %v\n", err)
+			}
+			if err := w.Close(); !errors.Is(err, ErrClosed) {
+				t.Errorf("expected ErrClosed for close after close, got %v\n", err)
+			}
+		})
+	}
+}
+
+// shortWriter accepts at most 'limit' bytes in total and reports short writes without an error.
+type shortWriter struct{ limit int }
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.limit)
+	w.limit -= n
+	return n, nil
+}
+
+func Test_NewEncryptWriter_shortWrite(t *testing.T) {
+	if _, err := NewEncryptWriter(&shortWriter{limit: 5}, "myKey123"); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("expected io.ErrShortWrite for short header write, got %v\n", err)
+	}
+
+	w, err := NewEncryptWriter(&shortWriter{limit: 30}, "myKey123")
+	if err != nil {
+		t.Fatalf("could not create encrypt writer: %s\n", err)
+	}
+	if _, err := w.Write([]byte("Hello World!")); err != nil {
+		t.Fatalf("buffered write failed: %s\n", err)
+	}
+	if err := w.Close(); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("expected io.ErrShortWrite on close, got %v\n", err)
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("expected sticky io.ErrShortWrite, got %v\n", err)
+	}
+
+	if _, err := NewEncryptWriter(io.Discard, ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+}
+
+// writeIn writes 'data' to 'w' in pieces of at most 'size' bytes.
+func writeIn(w io.Writer, data []byte, size int) error {
+	for len(data) > 0 {
+		n, err := w.Write(data[:min(size, len(data))])
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func Test_DecryptWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes int // size of the individual writes
+	}{
+		{"empty", 0, 1},
+		{"small", 12, 5},
+		{"chunk", DefaultChunkSize, 1000},
+		{"chunk in one write", DefaultChunkSize, 2 * DefaultChunkSize},
+		{"chunk + 1", DefaultChunkSize + 1, 4096},
+		{"multiple chunks", 3*DefaultChunkSize + 17, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			var encrypted bytes.Buffer
+			ew, err := EncryptWriter(&encrypted, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create encrypt writer: %s\n", err)
+			}
+			if err := writeIn(ew, data, tt.writes); err != nil {
+				t.Fatalf("could not write: %s\n", err)
+			}
+			if err := ew.Close(); err != nil {
+				t.Fatalf("could not close: %s\n", err)
+			}
+			e := encrypted.Bytes()
+
+			var decrypted bytes.Buffer
+			dw, err := DecryptWriter(&decrypted, "myKey123")
+			if err != nil {
+				t.Fatalf("could not create decrypt writer: %s\n", err)
+			}
+			if err := writeIn(dw, e, tt.writes); err != nil {
+				t.Fatalf("could not write: %s\n", err)
+			}
+			if err := dw.Close(); err != nil {
+				t.Fatalf("could not close: %s\n", err)
+			}
+			if !bytes.Equal(data, decrypted.Bytes()) {
+				t.Errorf("encrypt writer/decrypt writer failed: %v: plaintext mismatch\n", tt.name)
+			}
+			if _, err := dw.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+				t.Errorf("expected ErrClosed for write after close, got %v\n", err)
+			}
+
+			for _, cut := range []int{1, 17, len(e) - 5} {
+				dw, _ := DecryptWriter(io.Discard, "myKey123")
+				err := writeIn(dw, e[:len(e)-cut], tt.writes)
+				if err == nil {
+					err = dw.Close()
+				}
+				if !errors.Is(err, ErrStreamTruncated) && !errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("expected truncation error when cutting %d bytes: %v: got %v\n", cut, tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_DecryptWriter_errors(t *testing.T) {
+	data := make([]byte, 2*DefaultChunkSize)
+	var encrypted bytes.Buffer
+	ew, _ := EncryptWriter(&encrypted, "myKey123")
+	_, _ = ew.Write(data)
+	_ = ew.Close()
+	e := encrypted.Bytes()
+
+	// dropping the final (empty) chunk leaves only full chunks
+	var decrypted bytes.Buffer
+	dw, _ := DecryptWriter(&decrypted, "myKey123")
+	_, _ = dw.Write(e[:len(e)-16])
+	if err := dw.Close(); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for missing final chunk, got %v\n", err)
+	}
+	if decrypted.Len() != len(data) {
+		t.Errorf("expected the authenticated chunks to be written, got %d bytes\n", decrypted.Len())
+	}
+
+	// a tampered second chunk must not release any of its plaintext
+	tampered := bytes.Clone(e)
+	tampered[len(tampered)-17] ^= 0xff
+	decrypted.Reset()
+	dw, _ = DecryptWriter(&decrypted, "myKey123")
+	_, err := dw.Write(tampered)
+	if err == nil {
+		err = dw.Close()
+	}
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed for tampered chunk, got %v\n", err)
+	}
+	if decrypted.Len() != DefaultChunkSize {
+		t.Errorf("expected only the first chunk to be written, got %d bytes\n", decrypted.Len())
+	}
+	if err2 := dw.Close(); err2 != err {
+		t.Errorf("expected sticky error %v, got %v\n", err, err2)
+	}
+
+	dw, _ = DecryptWriter(io.Discard, "wrongKey")
+	if _, err := dw.Write(e); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed for wrong key, got %v\n", err)
+	}
+	dw, _ = DecryptWriter(io.Discard, "myKey123")
+	if _, err := dw.Write(bytes.Repeat([]byte("x"), 64)); err == nil {
+		t.Errorf("expected error for a stream without header\n")
+	}
+	dw, _ = DecryptWriter(io.Discard, "myKey123")
+	_, _ = dw.Write(e[:5])
+	if err := dw.Close(); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for truncated header, got %v\n", err)
+	}
+	dw, _ = DecryptWriter(&shortWriter{limit: 5}, "myKey123")
+	if _, err := dw.Write(e); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("expected io.ErrShortWrite, got %v\n", err)
+	}
+	if _, err := DecryptWriter(io.Discard, ""); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("expected ErrWeakKey, got %v\n", err)
+	}
+}
+
+func Test_EncryptWriter_options(t *testing.T) {
+	c, err := New("myKey123", WithChunkSize(1024), WithAAD([]byte("user-42")))
+	if err != nil {
+		t.Fatalf("could not create cipher: %s\n", err)
+	}
+	data := bytes.Repeat([]byte("a"), 5000)
+	var encrypted bytes.Buffer
+	ew, err := c.EncryptWriter(&encrypted)
+	if err != nil {
+		t.Fatalf("could not create encrypt writer: %s\n", err)
+	}
+	_, _ = ew.Write(data)
+	if err := ew.Close(); err != nil {
+		t.Fatalf("could not close: %s\n", err)
+	}
+	if want := streamHeaderSize + 12 + len(data) + (len(data)/1024+1)*16; encrypted.Len() != want {
+		t.Errorf("expected %d encrypted bytes, got %d\n", want, encrypted.Len())
+	}
+	var decrypted bytes.Buffer
+	dw := c.DecryptWriter(&decrypted)
+	_, _ = dw.Write(encrypted.Bytes())
+	if err := dw.Close(); err != nil || !bytes.Equal(data, decrypted.Bytes()) {
+		t.Errorf("cipher encrypt writer/decrypt writer failed: %v\n", err)
+	}
+	if err := DecryptStream(io.Discard, bytes.NewReader(encrypted.Bytes()), "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("expected ErrAuthenticationFailed without the AAD, got %v\n", err)
+	}
+}
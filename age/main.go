@@ -0,0 +1,147 @@
+// Package age encrypts and decrypts data in the age format (https://age-encryption.org), so it can be
+// exchanged with the age CLI and the other implementations of the format. It wraps filippo.io/age.
+//
+// Recipients are age public keys ("age1...") and identities are age private keys ("AGE-SECRET-KEY-1..."),
+// as generated by GenerateKeyPair or age-keygen. Both may also hold several keys, one per line, in the
+// format of age recipient and identity files, where empty lines and lines starting with '#' are ignored.
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// GenerateKeyPair generates a new X25519 age key pair.
+// It returns the recipient ("age1...") and the identity ("AGE-SECRET-KEY-1...") and any error encountered.
+func GenerateKeyPair() (recipient, identity string, err error) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", err
+	}
+	return id.Recipient().String(), id.String(), nil
+}
+
+// parseRecipients parses the recipients in 'recipient'.
+func parseRecipients(recipient string) ([]age.Recipient, error) {
+	recipients, err := age.ParseRecipients(strings.NewReader(recipient))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return recipients, nil
+}
+
+// parseIdentities parses the identities in 'identity'.
+func parseIdentities(identity string) ([]age.Identity, error) {
+	identities, err := age.ParseIdentities(strings.NewReader(identity))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	return identities, nil
+}
+
+// encrypt encrypts the data read from 'r' for 'recipients' and writes it to 'w', ASCII-armored if 'armored' is set.
+func encrypt(w io.Writer, r io.Reader, armored bool, recipients []age.Recipient) error {
+	var a io.WriteCloser
+	if armored {
+		a = armor.NewWriter(w)
+		w = a
+	}
+	ew, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ew, r); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	if a != nil {
+		return a.Close()
+	}
+	return nil
+}
+
+// decrypt decrypts the age data read from 'r', binary or ASCII-armored, with 'identities' and writes the plaintext to 'w'.
+func decrypt(w io.Writer, r io.Reader, identities []age.Identity) error {
+	br := bufio.NewReader(r)
+	if isArmored(br) {
+		r = armor.NewReader(br)
+	} else {
+		r = br
+	}
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+	return err
+}
+
+// isArmored reports whether the data buffered by 'br' starts with the armor header, optionally preceded by whitespace.
+func isArmored(br *bufio.Reader) bool {
+	head, _ := br.Peek(br.Size())
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte(armor.Header))
+}
+
+// EncryptAge encrypts the given plaintext in the age format for 'recipient'.
+// It returns the ASCII-armored ciphertext, as written by 'age --armor', and any error encountered.
+func EncryptAge(plaintext, recipient string) (string, error) {
+	recipients, err := parseRecipients(recipient)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := encrypt(&buf, strings.NewReader(plaintext), true, recipients); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DecryptAge decrypts the given age ciphertext, binary or ASCII-armored, with 'identity'.
+// It returns the decrypted plaintext and any error encountered, which is an *age.NoIdentityMatchError
+// if none of the identities can decrypt the ciphertext.
+func DecryptAge(ciphertext, identity string) (string, error) {
+	identities, err := parseIdentities(identity)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := decrypt(&buf, strings.NewReader(ciphertext), identities); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// EncryptFileAge encrypts the file located at 'path' in place in the binary age format for 'recipient',
+// so it can be decrypted with 'age --decrypt'. It returns an error if the file doesn't exist or if any
+// encryption operation fails, in which case the file is left intact.
+func EncryptFileAge(path, recipient string) error {
+	recipients, err := parseRecipients(recipient)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Transform("encrypt", path, func(w io.Writer, r io.Reader) error {
+		return encrypt(w, r, false, recipients)
+	})
+}
+
+// DecryptFileAge decrypts the age-encrypted file located at 'path' in place with 'identity'. The file may be
+// binary or ASCII-armored, as written by the age CLI. It returns an error if the file doesn't exist or if any
+// decryption operation fails, in which case the file is left intact.
+func DecryptFileAge(path, identity string) error {
+	identities, err := parseIdentities(identity)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Transform("decrypt", path, func(w io.Writer, r io.Reader) error {
+		return decrypt(w, r, identities)
+	})
+}
@@ -0,0 +1,154 @@
+package age
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func Test_test(t *testing.T) {
+	aliceRecipient, aliceIdentity, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	bobRecipient, bobIdentity, _ := GenerateKeyPair()
+	_, eveIdentity, _ := GenerateKeyPair()
+	if !strings.HasPrefix(aliceRecipient, "age1") || !strings.HasPrefix(aliceIdentity, "AGE-SECRET-KEY-1") {
+		t.Errorf("GenerateKeyPair() = %s, %s, want age keys\n", aliceRecipient, aliceIdentity)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"short", "Hello World!"},
+		{"long", strings.Repeat("Hello World!\n", 10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptAge(tt.text, aliceRecipient+"\n# bob\n"+bobRecipient)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if !strings.HasPrefix(e, armor.Header) {
+				t.Errorf("expected an armored ciphertext, got %q\n", e[:min(len(e), 40)])
+			}
+			for _, identity := range []string{aliceIdentity, bobIdentity, eveIdentity + "\n" + bobIdentity} {
+				if d, err := DecryptAge(e, identity); err != nil || d != tt.text {
+					t.Errorf("DecryptAge() = %d bytes, %v, want %d bytes\n", len(d), err, len(tt.text))
+				}
+			}
+			var noMatch *age.NoIdentityMatchError
+			if _, err := DecryptAge(e, eveIdentity); !errors.As(err, &noMatch) {
+				t.Errorf("DecryptAge() with the wrong identity error = %v, want NoIdentityMatchError\n", err)
+			}
+		})
+	}
+
+	if _, err := EncryptAge("secret", "invalid"); err == nil {
+		t.Errorf("EncryptAge() with an invalid recipient expected an error\n")
+	}
+	if _, err := DecryptAge("secret", "invalid"); err == nil {
+		t.Errorf("DecryptAge() with an invalid identity expected an error\n")
+	}
+}
+
+func Test_interop(t *testing.T) {
+	recipient, identity, _ := GenerateKeyPair()
+	id, _ := age.ParseX25519Identity(identity)
+
+	// data written by this package decrypts with the reference implementation
+	e, _ := EncryptAge("Hello World!", recipient)
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(e)), id)
+	if err != nil {
+		t.Fatalf("age.Decrypt() error = %v\n", err)
+	}
+	if d, _ := io.ReadAll(r); string(d) != "Hello World!" {
+		t.Errorf("age.Decrypt() = %q\n", d)
+	}
+
+	// and vice versa, binary as well as armored
+	var binary, armored bytes.Buffer
+	w, _ := age.Encrypt(&binary, id.Recipient())
+	_, _ = io.WriteString(w, "Hello age!")
+	_ = w.Close()
+	a := armor.NewWriter(&armored)
+	w, _ = age.Encrypt(a, id.Recipient())
+	_, _ = io.WriteString(w, "Hello age!")
+	_ = w.Close()
+	_ = a.Close()
+	for name, data := range map[string]string{"binary": binary.String(), "armored": "\n" + armored.String()} {
+		if d, err := DecryptAge(data, identity); err != nil || d != "Hello age!" {
+			t.Errorf("DecryptAge() of %s data = %q, %v\n", name, d, err)
+		}
+	}
+}
+
+func Test_file(t *testing.T) {
+	recipient, identity, _ := GenerateKeyPair()
+	_, eveIdentity, _ := GenerateKeyPair()
+	id, _ := age.ParseX25519Identity(identity)
+	path := filepath.Join(t.TempDir(), "data.txt")
+	text := strings.Repeat("Hello World!\n", 10000)
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+
+	if err := EncryptFileAge(path, recipient); err != nil {
+		t.Fatalf("EncryptFileAge() error = %v\n", err)
+	}
+	e, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(e, []byte("age-encryption.org/v1\n")) {
+		t.Errorf("expected the binary age format\n")
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Errorf("EncryptFileAge() changed the permissions to %v\n", fi.Mode().Perm())
+	}
+	r, err := age.Decrypt(bytes.NewReader(e), id)
+	if err != nil {
+		t.Fatalf("age.Decrypt() error = %v\n", err)
+	}
+	if d, _ := io.ReadAll(r); string(d) != text {
+		t.Errorf("age.Decrypt() of the file failed\n")
+	}
+
+	if err := DecryptFileAge(path, eveIdentity); err == nil {
+		t.Errorf("DecryptFileAge() with the wrong identity expected an error\n")
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, e) {
+		t.Errorf("a failed DecryptFileAge() modified the file\n")
+	}
+	if err := DecryptFileAge(path, identity); err != nil {
+		t.Fatalf("DecryptFileAge() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != text {
+		t.Errorf("encrypt/decrypt file failed\n")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+
+	// armored files as written by 'age --armor'
+	armored, _ := EncryptAge(text, recipient)
+	if err := os.WriteFile(path, []byte(armored), 0600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := DecryptFileAge(path, identity); err != nil {
+		t.Fatalf("DecryptFileAge() of an armored file error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != text {
+		t.Errorf("DecryptFileAge() of an armored file failed\n")
+	}
+
+	if err := EncryptFileAge(filepath.Join(t.TempDir(), "missing"), recipient); err == nil {
+		t.Errorf("EncryptFileAge() of a missing file expected an error\n")
+	}
+}
@@ -0,0 +1,79 @@
+// Package authcrypt combines ECDSA signatures with AES-GCM encryption, so the recipient of a message knows
+// that it hasn't been tampered with and who sent it. Senders sign with ECDSA keys of the sign/ecdsa package,
+// messages are encrypted for recipients holding X25519 keys of the x25519 package.
+package authcrypt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/toxyl/cipherutils/sign/ecdsa"
+	"github.com/toxyl/cipherutils/x25519"
+)
+
+// ErrInvalidSignature is returned by DecryptAndVerify when the message decrypts but its signature doesn't match
+// the sender's public key, which means it has been signed by someone else or for another recipient.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// signedMessage returns the message that is signed for 'recipientPublicKey'. Binding the recipient prevents
+// a recipient from re-encrypting a signed message for a third party as if the sender had addressed it to them.
+func signedMessage(plaintext, recipientPublicKey string) string {
+	// base64-encoded keys never contain a NUL byte, so the recipient can't be confused with the plaintext
+	return recipientPublicKey + "\x00" + plaintext
+}
+
+// SignAndEncrypt signs 'plaintext' with the sender's PEM-encoded ECDSA private key and encrypts the signature
+// and the plaintext with AES-256-GCM for the recipient's base64-encoded X25519 public key.
+// It returns the ciphertext and any error encountered.
+//
+// The key is derived from an ephemeral X25519 key pair and the recipient's public key, so only the recipient can
+// decrypt the message, and the ciphertext has the form "<ephemeral public key>.<ciphertext>", both base64-encoded.
+// The signature covers the plaintext and the recipient's public key.
+func SignAndEncrypt(plaintext, senderPrivatePEM, recipientPublicKey string) (string, error) {
+	signature, err := ecdsa.Sign(signedMessage(plaintext, recipientPublicKey), senderPrivatePEM)
+	if err != nil {
+		return "", fmt.Errorf("can't sign message: %w", err)
+	}
+	ephemeralPublic, ephemeralPrivate, err := x25519.GenerateKeyPair()
+	if err != nil {
+		return "", err
+	}
+	// the base64-encoded signature never contains a newline
+	ciphertext, err := x25519.DeriveAndEncrypt(signature+"\n"+plaintext, ephemeralPrivate, recipientPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("can't encrypt message: %w", err)
+	}
+	return ephemeralPublic + "." + ciphertext, nil
+}
+
+// DecryptAndVerify decrypts a ciphertext produced by SignAndEncrypt with the recipient's base64-encoded X25519
+// private key and verifies its signature with the sender's PEM-encoded ECDSA public key. It returns the plaintext
+// only if both succeed, an error wrapping ErrInvalidSignature if the message wasn't signed by the sender for this
+// recipient, or an error if the ciphertext is malformed or has been tampered with.
+func DecryptAndVerify(ciphertext, recipientPrivateKey, senderPublicPEM string) (string, error) {
+	ephemeralPublic, sealed, ok := strings.Cut(ciphertext, ".")
+	if !ok {
+		return "", fmt.Errorf("invalid ciphertext, missing the ephemeral public key")
+	}
+	bundle, err := x25519.DeriveAndDecrypt(sealed, recipientPrivateKey, ephemeralPublic)
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt message: %w", err)
+	}
+	signature, plaintext, ok := strings.Cut(bundle, "\n")
+	if !ok {
+		return "", fmt.Errorf("invalid message, missing the signature")
+	}
+	recipientPublicKey, err := x25519.PublicKey(recipientPrivateKey)
+	if err != nil {
+		return "", err
+	}
+	valid, err := ecdsa.Verify(signedMessage(plaintext, recipientPublicKey), signature, senderPublicPEM)
+	if err != nil {
+		return "", err
+	}
+	if !valid {
+		return "", ErrInvalidSignature
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,95 @@
+package authcrypt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toxyl/cipherutils/sign/ecdsa"
+	"github.com/toxyl/cipherutils/x25519"
+)
+
+func Test_SignAndEncrypt(t *testing.T) {
+	alicePub, alicePriv, err := ecdsa.GenerateKeyPair("P-256")
+	if err != nil {
+		t.Fatalf("could not generate signing key pair: %s\n", err)
+	}
+	malloryPub, malloryPriv, _ := ecdsa.GenerateKeyPair("P-256")
+	bobPub, bobPriv, err := x25519.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate encryption key pair: %s\n", err)
+	}
+	evePub, evePriv, _ := x25519.GenerateKeyPair()
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"message 1", "Hello Bob!"},
+		{"message 2", ""},
+		{"message 3", "line 1\nline 2\x00binary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := SignAndEncrypt(tt.text, alicePriv, bobPub)
+			if err != nil {
+				t.Fatalf("could not sign and encrypt: %s\n", err)
+			}
+			if tt.text != "" && strings.Contains(e, tt.text) {
+				t.Errorf("ciphertext contains the plaintext\n")
+			}
+			if d, err := DecryptAndVerify(e, bobPriv, alicePub); err != nil || d != tt.text {
+				t.Errorf("DecryptAndVerify() = %q, %v, want %q\n", d, err, tt.text)
+			}
+			if _, err := DecryptAndVerify(e, bobPriv, malloryPub); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature for another sender, got %v\n", err)
+			}
+			if _, err := DecryptAndVerify(e, evePriv, alicePub); err == nil {
+				t.Errorf("DecryptAndVerify() by another recipient expected an error\n")
+			}
+		})
+	}
+
+	t.Run("forged", func(t *testing.T) {
+		e, _ := SignAndEncrypt("Hello Bob!", malloryPriv, bobPub)
+		if _, err := DecryptAndVerify(e, bobPriv, alicePub); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature for a forged sender, got %v\n", err)
+		}
+	})
+
+	t.Run("forwarded", func(t *testing.T) {
+		// Bob re-encrypts Alice's signed message for Eve, Eve must not accept it as addressed to her
+		e, _ := SignAndEncrypt("Hello Bob!", alicePriv, bobPub)
+		ephemeral, sealed, _ := strings.Cut(e, ".")
+		bundle, err := x25519.DeriveAndDecrypt(sealed, bobPriv, ephemeral)
+		if err != nil {
+			t.Fatalf("could not decrypt bundle: %s\n", err)
+		}
+		ephemeralPub, ephemeralPriv, _ := x25519.GenerateKeyPair()
+		resealed, _ := x25519.DeriveAndEncrypt(bundle, ephemeralPriv, evePub)
+		if _, err := DecryptAndVerify(ephemeralPub+"."+resealed, evePriv, alicePub); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature for a forwarded message, got %v\n", err)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		e, _ := SignAndEncrypt("Hello Bob!", alicePriv, bobPub)
+		i := len(e) - 5
+		c := byte('A')
+		if e[i] == c {
+			c = 'B'
+		}
+		for _, tampered := range []string{"no separator", e[:i] + string(c) + e[i+1:], "." + e} {
+			if _, err := DecryptAndVerify(tampered, bobPriv, alicePub); err == nil {
+				t.Errorf("DecryptAndVerify() of a tampered ciphertext expected an error\n")
+			}
+		}
+	})
+
+	if _, err := SignAndEncrypt("Hello Bob!", "invalid", bobPub); err == nil {
+		t.Errorf("SignAndEncrypt() with an invalid private key expected an error\n")
+	}
+	if _, err := SignAndEncrypt("Hello Bob!", alicePriv, "invalid"); err == nil {
+		t.Errorf("SignAndEncrypt() with an invalid public key expected an error\n")
+	}
+}
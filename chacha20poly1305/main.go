@@ -0,0 +1,255 @@
+// Package chacha20poly1305 provides encryption and decryption functionalities using ChaCha20-Poly1305.
+// It supports encryption and decryption of data and files using a provided key and mirrors the API
+// of the aesgcm package, so callers can swap cipher suites by changing only the import.
+package chacha20poly1305
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+	"github.com/toxyl/keys"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	// ErrKeyDerivation is returned when the key can't be derived from the provided key or password.
+	ErrKeyDerivation = errors.New("key derivation failed")
+
+	// ErrWeakKey is returned when a key is empty, consists only of whitespace or
+	// control characters, or is shorter than MinKeyLength.
+	ErrWeakKey = errors.New("weak key")
+
+	// ErrKeyTooShort is returned when a key has fewer than MinKeyLength characters, including empty keys.
+	// It wraps ErrWeakKey.
+	ErrKeyTooShort = fmt.Errorf("%w: key too short", ErrWeakKey)
+
+	// ErrCiphertextTooShort is returned when a ciphertext is too short to hold the nonce.
+	ErrCiphertextTooShort = errors.New("data too short")
+
+	// ErrAuthenticationFailed is returned when a ciphertext can't be authenticated,
+	// which means the key is wrong or the ciphertext has been tampered with.
+	ErrAuthenticationFailed = errors.New("authentication failed")
+)
+
+// MinKeyLength is the minimum number of characters a key must have, 8 by default.
+// Callers can raise it to enforce stronger keys.
+var MinKeyLength = 8
+
+// keyCipher represents a structure holding the ChaCha20-Poly1305 key for encryption and decryption.
+type keyCipher struct {
+	key []byte
+}
+
+// validateKey checks that the key has at least MinKeyLength characters and doesn't consist only of
+// whitespace or control characters. It returns an error wrapping ErrKeyTooShort or ErrWeakKey otherwise.
+func validateKey(key string) error {
+	if n := utf8.RuneCountInString(key); n < MinKeyLength {
+		return fmt.Errorf("%w: key has %d characters, at least %d are required", ErrKeyTooShort, n, MinKeyLength)
+	}
+	if strings.TrimFunc(key, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) == "" {
+		return fmt.Errorf("%w: key is empty or consists only of whitespace or control characters", ErrWeakKey)
+	}
+	return nil
+}
+
+// newKeyCipher creates a new keyCipher instance initialized with a scrambled key.
+// It returns an error if the key is weak or if key scrambling fails.
+func newKeyCipher(key string) (*keyCipher, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	k, err := keys.WeakKeyScrambler(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyDerivation, err)
+	}
+	return &keyCipher{key: []byte(k)}, nil
+}
+
+// encrypt encrypts the provided data using ChaCha20-Poly1305 encryption.
+// It returns the encrypted ciphertext along with any error encountered.
+func (c *keyCipher) encrypt(data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// decrypt decrypts the provided ChaCha20-Poly1305 encrypted data.
+// It returns the decrypted plaintext along with any error encountered.
+func (c *keyCipher) decrypt(data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, ErrCiphertextTooShort
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+// Encrypt encrypts the given plaintext using ChaCha20-Poly1305 encryption with the provided key.
+// It returns the base64-encoded encrypted ciphertext and any error encountered.
+//
+// The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
+// which is the key size required by ChaCha20-Poly1305.
+//
+// Note: The input key is not directly usable with other ChaCha20-Poly1305 implementations or tools,
+// as it undergoes specific scrambling tailored for this package's usage.
+func Encrypt(plaintext, key string) (string, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := cipher.encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// EncryptBytes encrypts the given bytes using ChaCha20-Poly1305 encryption with the provided key.
+// It returns the encrypted bytes and any error encountered.
+func EncryptBytes(bytes []byte, key string) ([]byte, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := cipher.encrypt(bytes)
+	if err != nil {
+		return nil, err
+	}
+	return encrypted, nil
+}
+
+// Decrypt decrypts the given base64-encoded encrypted text using ChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted plaintext and any error encountered.
+func Decrypt(text, key string) (string, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return "", err
+	}
+	encryptedData, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := cipher.decrypt(encryptedData)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// DecryptBytes decrypts the given encrypted bytes using ChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted bytes and any error encountered.
+func DecryptBytes(bytes []byte, key string) ([]byte, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := cipher.decrypt(bytes)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted, nil
+}
+
+// EncryptFile encrypts the file located at 'path' using ChaCha20-Poly1305 encryption with the provided key.
+// The file is replaced atomically. It returns an error if the file doesn't exist or if any encryption operation fails.
+func EncryptFile(path, key string) error {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Transform("encrypt", path, func(w io.Writer, r io.Reader) error {
+		return cipher.transform(w, r, cipher.encrypt)
+	})
+}
+
+// EncryptToFile encrypts the given `bytes` using ChaCha20-Poly1305 encryption with the provided key and writes the result to 'path'.
+// Missing parent directories are created and the file is replaced atomically.
+// It returns an error if the file can't be written or if any encryption operation fails.
+func EncryptToFile(bytes []byte, path, key string) error {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return err
+	}
+	encrypted, err := cipher.encrypt(bytes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteWith(path, atomicfile.Options{Overwrite: true}, func(w io.Writer) error {
+		_, err := w.Write(encrypted)
+		return err
+	})
+}
+
+// DecryptFile decrypts the file located at 'path' using ChaCha20-Poly1305 decryption with the provided key.
+// The file is replaced atomically. It returns an error if the file doesn't exist or if any decryption operation fails.
+func DecryptFile(path, key string) error {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Transform("decrypt", path, func(w io.Writer, r io.Reader) error {
+		return cipher.transform(w, r, cipher.decrypt)
+	})
+}
+
+// DecryptFromFile decrypts the file located at 'path' using ChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted file bytes or nil and an error if the file doesn't exist or if any decryption operation fails.
+func DecryptFromFile(path, key string) ([]byte, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("can't decrypt, file '%s' does not exist", path)
+		}
+		return nil, err
+	}
+	return cipher.decrypt(data)
+}
+
+// transform reads all of 'r', applies 'fn' to it and writes the result to 'w'.
+func (c *keyCipher) transform(w io.Writer, r io.Reader, fn func(data []byte) ([]byte, error)) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	out, err := fn(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
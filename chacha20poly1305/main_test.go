@@ -0,0 +1,91 @@
+package chacha20poly1305
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_test(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+		key  string
+	}{
+		{"test 1", "../test_data/chacha1.txt", "Hello World!", "myKey123"},
+		{"test 2", "../test_data/chacha2.txt", "Hello World!", "12345678"},
+		{"test 3", "../test_data/chacha3.txt", "Hello World!", "1234567890"},
+		{"test 4", "../test_data/chacha4.txt", "Hello World!", "11111111"},
+		{"test 5", "../test_data/chacha.bin", "Hello World!", "12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := Encrypt(tt.text, tt.key)
+			d, _ := Decrypt(e, tt.key)
+			if tt.text != d {
+				t.Errorf("encrypt/decrypt failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			} else {
+				t.Logf("encrypt/decrypt succesful: %v (%s - %s)\n", tt.name, d, e)
+			}
+
+			if err := flo.File(tt.file).StoreBytes([]byte(tt.text)); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFile(tt.file, tt.key); err != nil {
+				t.Errorf("could not encrypt file: %s\n", err)
+			}
+			encrypted := flo.File(tt.file).AsString()
+
+			if err := DecryptFile(tt.file, tt.key); err != nil {
+				t.Errorf("could not decrypt file: %s\n", err)
+			}
+			decrypted := flo.File(tt.file).AsString()
+			_ = flo.File(tt.file).Remove()
+			if decrypted != tt.text {
+				t.Errorf("decryption failed, expected %s but got (%s - %s)\n", tt.text, decrypted, encrypted)
+			} else {
+				t.Logf("encrypt/decrypt file succesful: %v (%s - %s)\n", tt.name, decrypted, encrypted)
+			}
+		})
+	}
+}
+
+func Test_errors(t *testing.T) {
+	for _, key := range []string{"", "1234", "        ", "\t\n\t\n\t\n\t\n"} {
+		if _, err := Encrypt("Hello World!", key); !errors.Is(err, ErrWeakKey) {
+			t.Errorf("Encrypt() with key %q error = %v, want ErrWeakKey\n", key, err)
+		}
+	}
+	if _, err := Encrypt("Hello World!", "1234"); !errors.Is(err, ErrKeyTooShort) {
+		t.Errorf("Encrypt() error = %v, want ErrKeyTooShort\n", err)
+	}
+
+	e, err := EncryptBytes([]byte("Hello World!"), "myKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt bytes: %s\n", err)
+	}
+	if _, err := DecryptBytes(e, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptBytes() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	if _, err := DecryptBytes(e[:4], "myKey123"); !errors.Is(err, ErrCiphertextTooShort) {
+		t.Errorf("DecryptBytes() of truncated data error = %v, want ErrCiphertextTooShort\n", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "file.bin")
+	if err := EncryptToFile([]byte("Hello World!"), path, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt to file: %s\n", err)
+	}
+	if err := DecryptFile(path, "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptFile() with the wrong key error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	if d, err := DecryptFromFile(path, "myKey123"); err != nil || string(d) != "Hello World!" {
+		t.Errorf("DecryptFromFile() = %q, %v, want %q\n", d, err, "Hello World!")
+	}
+	if err := EncryptFile(filepath.Join(dir, "missing.bin"), "myKey123"); err == nil {
+		t.Errorf("EncryptFile() of a missing file expected an error\n")
+	}
+}
@@ -3,12 +3,18 @@ module github.com/toxyl/cipherutils
 go 1.22.4
 
 require (
-	github.com/toxyl/errors v0.0.0-20240410073853-96b96b437ed5
+	filippo.io/age v1.2.1
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/klauspost/compress v1.18.0
 	github.com/toxyl/flo v0.0.0-20240412132929-869b69ff6976
 	github.com/toxyl/keys v0.0.1-alpha
+	golang.org/x/crypto v0.24.0
 )
 
 require (
+	github.com/cloudflare/circl v1.3.7 // indirect
+	github.com/toxyl/errors v0.0.0-20240410073853-96b96b437ed5 // indirect
 	github.com/toxyl/glog v1.0.0-alpha.15 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
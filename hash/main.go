@@ -0,0 +1,104 @@
+// Package hash provides hashing of strings, bytes and files using SHA-256, SHA-512, BLAKE2b and SHA-3.
+// All string outputs are lowercase hex.
+package hash
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// algorithms maps the algorithm names accepted by FileHash to their constructors.
+var algorithms = map[string]func() (hash.Hash, error){
+	"sha256":      func() (hash.Hash, error) { return sha256.New(), nil },
+	"sha512":      func() (hash.Hash, error) { return sha512.New(), nil },
+	"blake2b-256": func() (hash.Hash, error) { return blake2b.New256(nil) },
+	"blake2b-512": func() (hash.Hash, error) { return blake2b.New512(nil) },
+	"sha3-256":    func() (hash.Hash, error) { return sha3.New256(), nil },
+	"sha3-512":    func() (hash.Hash, error) { return sha3.New512(), nil },
+}
+
+// SHA256 returns the hex-encoded SHA-256 digest of the given string.
+func SHA256(data string) string {
+	return hex.EncodeToString(SHA256Bytes([]byte(data)))
+}
+
+// SHA256Bytes returns the raw SHA-256 digest of the given bytes.
+func SHA256Bytes(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// SHA512 returns the hex-encoded SHA-512 digest of the given string.
+func SHA512(data string) string {
+	sum := sha512.Sum512([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// BLAKE2b256 returns the hex-encoded unkeyed BLAKE2b-256 digest of the given string and any error encountered.
+func BLAKE2b256(data string) (string, error) {
+	return hashString("blake2b-256", data)
+}
+
+// BLAKE2b512 returns the hex-encoded unkeyed BLAKE2b-512 digest of the given string and any error encountered.
+func BLAKE2b512(data string) (string, error) {
+	return hashString("blake2b-512", data)
+}
+
+// SHA3_256 returns the hex-encoded SHA3-256 digest of the given string.
+func SHA3_256(data string) string {
+	sum := sha3.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA3_512 returns the hex-encoded SHA3-512 digest of the given string.
+func SHA3_512(data string) string {
+	sum := sha3.Sum512([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// newHash creates a hash for the algorithm name, which is matched case-insensitively.
+func newHash(algorithm string) (hash.Hash, error) {
+	newFn, ok := algorithms[strings.ToLower(algorithm)]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm '%s', expected sha256, sha512, blake2b-256, blake2b-512, sha3-256 or sha3-512", algorithm)
+	}
+	return newFn()
+}
+
+// hashString returns the hex-encoded digest of 'data' using the named algorithm.
+func hashString(algorithm, data string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FileHash streams the file located at 'path' through the named algorithm, which is one of
+// "sha256", "sha512", "blake2b-256", "blake2b-512", "sha3-256" or "sha3-512" (case-insensitive).
+// It returns the hex-encoded digest and an error if the algorithm is unknown or the file can't be read.
+func FileHash(path, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,71 @@
+package hash
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	blake256, _ := BLAKE2b256("abc")
+	blake512, _ := BLAKE2b512("abc")
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"sha256", SHA256("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha256 bytes", hex.EncodeToString(SHA256Bytes([]byte("abc"))), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha512", SHA512("abc"), "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{"blake2b-256", blake256, "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{"blake2b-512", blake512, "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{"sha3-256", SHA3_256("abc"), "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{"sha3-512", SHA3_512("abc"), "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("%v: expected %v, got %v!\n", tt.name, tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func Test_FileHash(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hash.txt")
+	if err := os.WriteFile(file, []byte("abc"), 0o600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	blake256, _ := BLAKE2b256("abc")
+	blake512, _ := BLAKE2b512("abc")
+	tests := []struct {
+		algorithm string
+		want      string
+		wantErr   bool
+	}{
+		{"sha256", SHA256("abc"), false},
+		{"SHA256", SHA256("abc"), false},
+		{"sha512", SHA512("abc"), false},
+		{"blake2b-256", blake256, false},
+		{"blake2b-512", blake512, false},
+		{"sha3-256", SHA3_256("abc"), false},
+		{"sha3-512", SHA3_512("abc"), false},
+		{"md5", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			got, err := FileHash(file, tt.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FileHash() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FileHash() = %v, expected %v\n", got, tt.want)
+			}
+		})
+	}
+	if _, err := FileHash(filepath.Join(t.TempDir(), "missing.txt"), "sha256"); err == nil {
+		t.Errorf("expected error for missing file\n")
+	}
+}
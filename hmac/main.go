@@ -0,0 +1,91 @@
+// Package hmac provides message authentication codes using HMAC-SHA256.
+// Keys undergo scrambling using keys.WeakKeyScrambler, just like with the aesgcm package.
+package hmac
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/toxyl/keys"
+)
+
+// newMAC creates a new HMAC-SHA256 instance for the scrambled key.
+// It returns an error if the key is empty or if key scrambling fails.
+func newMAC(key string) (hash.Hash, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key must not be empty")
+	}
+	k, err := keys.WeakKeyScrambler(key)
+	if err != nil {
+		return nil, err
+	}
+	return hmac.New(sha256.New, []byte(k)), nil
+}
+
+// SignBytes computes the HMAC-SHA256 of the given data with the provided key.
+// It returns the raw 32-byte MAC and any error encountered.
+func SignBytes(data []byte, key string) ([]byte, error) {
+	mac, err := newMAC(key)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// VerifyBytes checks in constant time whether 'mac' is the HMAC-SHA256 of the given data with the provided key.
+// It returns false and no error if the MAC doesn't match, errors are only returned if the key is invalid.
+func VerifyBytes(data, mac []byte, key string) (bool, error) {
+	expected, err := SignBytes(data, key)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(mac, expected), nil
+}
+
+// Sign computes the HMAC-SHA256 of the given message with the provided key.
+// It returns the base64-encoded MAC and any error encountered.
+func Sign(message, key string) (string, error) {
+	mac, err := SignBytes([]byte(message), key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac), nil
+}
+
+// Verify checks in constant time whether the base64-encoded 'mac', as produced by Sign, belongs to the given
+// message and key. It returns false and no error if the MAC doesn't match, errors are only returned if the key is invalid.
+func Verify(message, mac, key string) (bool, error) {
+	m, err := base64.StdEncoding.DecodeString(mac)
+	if err != nil {
+		if _, err := newMAC(key); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return VerifyBytes([]byte(message), m, key)
+}
+
+// SignFile computes the HMAC-SHA256 of the contents of the file located at 'path' with the provided key.
+// The file is streamed, so files of any size can be signed without loading them into memory.
+// It returns the base64-encoded MAC, which can be checked with Verify against the file contents, and any error encountered.
+func SignFile(path, key string) (string, error) {
+	mac, err := newMAC(key)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(mac, f); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
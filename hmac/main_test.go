@@ -0,0 +1,80 @@
+package hmac
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"test 1", "Hello World!", "myKey123"},
+		{"test 2", "", "12345678"},
+		{"test 3", strings.Repeat("Hello World! ", 100000), "1111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Sign(tt.text, tt.key)
+			if err != nil {
+				t.Fatalf("could not sign: %s\n", err)
+			}
+			if again, _ := Sign(tt.text, tt.key); again != m {
+				t.Errorf("MAC not deterministic: %v\n", tt.name)
+			}
+			if ok, err := Verify(tt.text, m, tt.key); !ok || err != nil {
+				t.Errorf("sign/verify failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text+"!", m, tt.key); ok || err != nil {
+				t.Errorf("verify of modified message: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text, m, tt.key+"!"); ok || err != nil {
+				t.Errorf("verify with wrong key: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text, "not base64!", tt.key); ok || err != nil {
+				t.Errorf("verify of malformed MAC: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+
+			b, err := SignBytes([]byte(tt.text), tt.key)
+			if err != nil {
+				t.Fatalf("could not sign bytes: %s\n", err)
+			}
+			if len(b) != 32 {
+				t.Errorf("expected a 32-byte MAC, got %d bytes\n", len(b))
+			}
+			if ok, err := VerifyBytes([]byte(tt.text), b, tt.key); !ok || err != nil {
+				t.Errorf("sign/verify bytes failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, _ := VerifyBytes([]byte(tt.text), b[:31], tt.key); ok {
+				t.Errorf("verify of truncated MAC succeeded: %v\n", tt.name)
+			}
+
+			file := filepath.Join(dir, tt.name+".txt")
+			if err := os.WriteFile(file, []byte(tt.text), 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			fm, err := SignFile(file, tt.key)
+			if err != nil {
+				t.Fatalf("could not sign file: %s\n", err)
+			}
+			if fm != m {
+				t.Errorf("MAC of file differs from MAC of its contents: %v\n", tt.name)
+			}
+		})
+	}
+
+	if _, err := Sign("Hello World!", ""); err == nil {
+		t.Errorf("expected error for empty key\n")
+	}
+	if _, err := Verify("Hello World!", "", ""); err == nil {
+		t.Errorf("expected error for empty key\n")
+	}
+	if _, err := SignFile(filepath.Join(dir, "missing.txt"), "myKey123"); err == nil {
+		t.Errorf("expected error when signing a missing file\n")
+	}
+}
@@ -0,0 +1,197 @@
+// Package atomicfile writes files atomically: the contents are written to a temporary file next to the target,
+// which is renamed to it once it has been flushed to disk, so readers never see a partially written file and
+// the original is left intact if anything fails.
+package atomicfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StaleTempAge is the time after which an unmodified temporary file is considered left over
+// from a crashed run and is removed by the next write to the same path.
+const StaleTempAge = time.Hour
+
+// bufferSize is the size of the buffer in front of the temporary file.
+const bufferSize = 64 * 1024
+
+// rename moves the temporary file into place, tests replace it to simulate failures.
+var rename = os.Rename
+
+// Options controls the attributes of a file written by WriteWith.
+type Options struct {
+	Like      fs.FileInfo // file whose permissions and modification time are copied, if not nil
+	Ownership bool        // also copy the owner and group of Like when running as root
+	Overwrite bool        // replace an existing file instead of failing
+	Perm      fs.FileMode // permissions of the file without Like, 0 keeps those of an existing file or uses 0644
+}
+
+// Write writes the file located at 'path' with the contents written by 'fn' and the permissions 'perm'.
+// Unless 'overwrite' is set, Write fails with an error wrapping fs.ErrExist if 'path' exists.
+// See WriteWith for details.
+func Write(path string, perm fs.FileMode, overwrite bool, fn func(w io.Writer) error) error {
+	return WriteWith(path, Options{Perm: perm, Overwrite: overwrite}, fn)
+}
+
+// WriteWith writes the file located at 'path' with the contents written by 'fn' and the attributes set by 'o'.
+// The contents are written to a temporary file next to 'path', which is removed if any step fails, and moved
+// to 'path' once they have been flushed to disk, followed by the directory. Temporary files of earlier writes
+// to 'path' that have not been modified for StaleTempAge are removed first.
+// Unless 'o.Overwrite' is set, WriteWith fails with an error wrapping fs.ErrExist if 'path' exists.
+func WriteWith(path string, o Options, fn func(w io.Writer) error) (err error) {
+	mode := o.Perm
+	if o.Like != nil {
+		mode = o.Like.Mode().Perm()
+	} else if mode == 0 {
+		mode = 0644
+		if fi, err := os.Stat(path); err == nil {
+			mode = fi.Mode().Perm()
+		}
+	}
+
+	removeStaleTemps(path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPattern(path))
+	if err != nil {
+		return fmt.Errorf("can't create temporary file for '%s', the directory must be writable: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	bw := bufio.NewWriterSize(tmp, bufferSize)
+	if err = fn(bw); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	if o.Like != nil && o.Ownership {
+		if err = chownLike(tmp, o.Like); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if o.Like != nil {
+		if err = os.Chtimes(tmp.Name(), time.Time{}, o.Like.ModTime()); err != nil {
+			return err
+		}
+	}
+	if o.Overwrite {
+		err = rename(tmp.Name(), path)
+	} else {
+		err = MoveNoReplace(tmp.Name(), path)
+	}
+	if err == nil {
+		syncDir(filepath.Dir(path))
+	}
+	return err
+}
+
+// Transform streams the contents of the file located at 'path' through 'fn' and replaces the file with
+// the result, keeping its permissions. 'op' names the operation in the errors returned, which report
+// a missing file and wrap the errors of 'fn'. The file is left intact if any step fails.
+func Transform(op, path string, fn func(w io.Writer, r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("can't %s, file '%s' does not exist", op, path)
+		}
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return Write(path, fi.Mode().Perm(), true, func(w io.Writer) error {
+		if err := fn(w, f); err != nil {
+			return fmt.Errorf("can't %s '%s': %w", op, path, err)
+		}
+		return nil
+	})
+}
+
+// syncDir flushes the directory 'dir' to disk, so a file just moved into it survives a crash.
+// Errors are ignored: the file has been written either way, and not every platform can sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// tempPattern returns the os.CreateTemp pattern of temporary files written for 'path'.
+func tempPattern(path string) string {
+	return "." + filepath.Base(path) + ".tmp-*"
+}
+
+// IsTempFile reports whether 'path' looks like a temporary file written by WriteWith.
+func IsTempFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// removeStaleTemps removes temporary files for 'path' that have not been modified for StaleTempAge,
+// which are left over from runs that crashed before the file was moved into place.
+// Younger files may belong to a concurrent write and are kept. Errors are ignored.
+func removeStaleTemps(path string) {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	prefix := strings.TrimSuffix(tempPattern(path), "*")
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		if fi, err := e.Info(); err == nil && time.Since(fi.ModTime()) > StaleTempAge {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// MoveNoReplace moves the file 'from' to 'to' unless 'to' exists, without a window in which another process
+// could create 'to' in between. A hard link fails if 'to' exists. File systems without hard links get 'to'
+// created exclusively, which is then replaced by renaming 'from'. It fails with an error wrapping fs.ErrExist
+// if 'to' exists.
+func MoveNoReplace(from, to string) error {
+	err := os.Link(from, to)
+	if err == nil {
+		_ = os.Remove(from)
+		return nil
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	f, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	if err := rename(from, to); err != nil {
+		_ = os.Remove(to)
+		return err
+	}
+	return nil
+}
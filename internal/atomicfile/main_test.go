@@ -0,0 +1,125 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Write(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	write := func(s string) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		}
+	}
+	if err := Write(path, 0600, false, write("Hello World!")); err != nil {
+		t.Fatalf("Write() error = %v\n", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected a file with 0600 permissions: %v\n", err)
+	}
+	if err := Write(path, 0600, false, write("Hello Again!")); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Write() without overwrite on an existing file error = %v, want fs.ErrExist\n", err)
+	}
+	if err := Write(path, 0600, true, func(w io.Writer) error { return errors.New("failed") }); err == nil {
+		t.Errorf("Write() with a failing writer expected an error\n")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "Hello World!" {
+		t.Errorf("failed writes changed the file: %q\n", data)
+	}
+	if err := Write(path, 0600, true, write("Hello Again!")); err != nil {
+		t.Fatalf("Write() with overwrite error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "Hello Again!" {
+		t.Errorf("Write() with overwrite = %q, want %q\n", data, "Hello Again!")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+}
+
+func Test_WriteWith(t *testing.T) {
+	dir := t.TempDir()
+	like := filepath.Join(dir, "like.txt")
+	if err := os.WriteFile(like, []byte("like"), 0640); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	mtime := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(like, mtime, mtime); err != nil {
+		t.Fatalf("could not change file times: %s\n", err)
+	}
+	fi, err := os.Stat(like)
+	if err != nil {
+		t.Fatalf("could not stat file: %s\n", err)
+	}
+
+	path := filepath.Join(dir, "file.txt")
+	if err := WriteWith(path, Options{Like: fi}, func(w io.Writer) error { return nil }); err != nil {
+		t.Fatalf("WriteWith() error = %v\n", err)
+	}
+	got, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat file: %s\n", err)
+	}
+	if got.Mode().Perm() != 0640 || !got.ModTime().Equal(mtime) {
+		t.Errorf("WriteWith() attributes = %v %v, want %v %v\n", got.Mode().Perm(), got.ModTime(), fs.FileMode(0640), mtime)
+	}
+
+	failure := errors.New("simulated crash")
+	rename = func(string, string) error { return failure }
+	defer func() { rename = os.Rename }()
+	if err := WriteWith(path, Options{Overwrite: true}, func(w io.Writer) error { return nil }); !errors.Is(err, failure) {
+		t.Errorf("WriteWith() with a failing rename error = %v, want %v\n", err, failure)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected the temporary file to be removed, got %d entries\n", len(entries))
+	}
+}
+
+func Test_Transform(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0640); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	upper := func(w io.Writer, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(strings.ToUpper(string(data))))
+		return err
+	}
+	if err := Transform("upper", path, upper); err != nil {
+		t.Fatalf("Transform() error = %v\n", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("expected the permissions to be kept: %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "HELLO" {
+		t.Errorf("Transform() = %q, want %q\n", data, "HELLO")
+	}
+
+	failed := errors.New("failed")
+	err := Transform("fail", path, func(w io.Writer, r io.Reader) error { return failed })
+	if !errors.Is(err, failed) || !strings.Contains(err.Error(), "can't fail") {
+		t.Errorf("Transform() with a failing function error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "HELLO" {
+		t.Errorf("a failed Transform() changed the file: %q\n", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+	if err := Transform("upper", filepath.Join(dir, "missing.txt"), upper); err == nil {
+		t.Errorf("Transform() of a missing file expected an error\n")
+	}
+}
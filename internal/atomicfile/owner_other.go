@@ -0,0 +1,10 @@
+//go:build !unix
+
+package atomicfile
+
+import "os"
+
+// chownLike is a no-op on platforms without Unix file ownership.
+func chownLike(f *os.File, fi os.FileInfo) error {
+	return nil
+}
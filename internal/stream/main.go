@@ -0,0 +1,126 @@
+// Package stream implements the chunked stream format shared by the stream functions of the aesgcm and
+// xchacha20 packages, which only differ in the AEAD and the magic bytes identifying their streams.
+//
+// All integers are big-endian:
+//
+//	header:  magic (4 bytes) | version (1 byte) | chunk size C (uint32) | base nonce N (nonce size of the AEAD)
+//	chunk i: AEAD ciphertext of up to C plaintext bytes followed by its tag
+//
+// Chunk i is sealed with the nonce N + i, where i is added to the last 8 bytes of N, and with the header
+// followed by a single byte as additional data: 1 for the last chunk, 0 for all others, optionally followed
+// by additional data of the caller. Every chunk but the last holds exactly C plaintext bytes, the last one
+// holds fewer (it may be empty), which allows detecting truncated streams.
+package stream
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// HeaderSize is the size of the stream header without the base nonce: magic, version and chunk size.
+	HeaderSize = 4 + 1 + 4
+
+	// MaxChunkSize limits the chunk size accepted from a stream header to keep memory usage bounded.
+	MaxChunkSize = 16 * 1024 * 1024
+)
+
+// ErrTruncated is returned when a stream ends before its final chunk.
+var ErrTruncated = errors.New("stream truncated")
+
+// ChunkNonce derives the nonce of chunk 'i' by adding 'i' to the last 8 bytes of the base nonce.
+func ChunkNonce(base []byte, i uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)+i)
+	return nonce
+}
+
+// ChunkAAD returns the additional data of a chunk, which binds the chunk to the stream header
+// and marks whether it is the final chunk. This prevents reordering, truncation and header tampering.
+// The additional data 'extra' of the caller, if any, follows.
+func ChunkAAD(header []byte, last bool, extra []byte) []byte {
+	aad := make([]byte, len(header)+1, len(header)+1+len(extra))
+	copy(aad, header)
+	if last {
+		aad[len(header)] = 1
+	}
+	return append(aad, extra...)
+}
+
+// NewHeader creates the header of a stream identified by the 4-byte 'magic' and 'version' for 'chunkSize',
+// with a base nonce of the nonce size of 'aead' read from 'random'.
+func NewHeader(aead cipher.AEAD, magic []byte, version byte, random io.Reader, chunkSize int) ([]byte, error) {
+	header := make([]byte, HeaderSize+aead.NonceSize())
+	copy(header, magic)
+	header[4] = version
+	binary.BigEndian.PutUint32(header[5:], uint32(chunkSize))
+	if _, err := io.ReadFull(random, header[HeaderSize:]); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// ReadHeader reads and validates the header of a stream identified by 'magic' and 'version' from 'r'.
+// It returns the header, including the base nonce, and the chunk size.
+func ReadHeader(aead cipher.AEAD, r io.Reader, magic []byte, version byte) ([]byte, int, error) {
+	header := make([]byte, HeaderSize+aead.NonceSize())
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, fmt.Errorf("%w, could not read header: %w", ErrTruncated, err)
+	}
+	if string(header[:4]) != string(magic) {
+		return nil, 0, fmt.Errorf("not an encrypted stream")
+	}
+	if header[4] != version {
+		return nil, 0, fmt.Errorf("unsupported stream version %d", header[4])
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:]))
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, 0, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	return header, chunkSize, nil
+}
+
+// EncryptChunks seals the plaintext read from 'r' chunk by chunk and writes the chunks to 'w'.
+func EncryptChunks(aead cipher.AEAD, header, extra []byte, r io.Reader, w io.Writer, chunkSize int) error {
+	baseNonce := header[HeaderSize:]
+	buf := make([]byte, chunkSize)
+	defer clear(buf)
+	sealed := make([]byte, 0, chunkSize+aead.Overhead())
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		sealed = aead.Seal(sealed[:0], ChunkNonce(baseNonce, i), buf[:n], ChunkAAD(header, last, extra))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// ReadChunk reads the sealed chunk 'i' from 'r' into 'buf', which holds a chunk sealed with 'aead', and returns
+// its length and whether it is the final chunk. It returns an error wrapping ErrTruncated if the stream ends
+// before the final chunk or within a tag.
+func ReadChunk(aead cipher.AEAD, r io.Reader, buf []byte, i uint64) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF {
+		return 0, false, fmt.Errorf("%w, missing final chunk after chunk %d", ErrTruncated, i)
+	}
+	last := err == io.ErrUnexpectedEOF
+	if err != nil && !last {
+		return 0, false, err
+	}
+	if n < aead.Overhead() {
+		return 0, false, fmt.Errorf("%w in chunk %d", ErrTruncated, i)
+	}
+	return n, last, nil
+}
@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func Test_ChunkNonce(t *testing.T) {
+	base := []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0xff}
+	if got, want := ChunkNonce(base, 1), []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 1, 0}; !bytes.Equal(got, want) {
+		t.Errorf("ChunkNonce() = %x, want %x\n", got, want)
+	}
+	if base[11] != 0xff {
+		t.Errorf("ChunkNonce() modified the base nonce\n")
+	}
+}
+
+func Test_EncryptChunks(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	aead, _ := cipher.NewGCM(block)
+	magic := []byte("TEST")
+	data := make([]byte, 250)
+	_, _ = rand.Read(data)
+
+	header, err := NewHeader(aead, magic, 1, rand.Reader, 100)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v\n", err)
+	}
+	var buf bytes.Buffer
+	buf.Write(header)
+	if err := EncryptChunks(aead, header, []byte("aad"), bytes.NewReader(data), &buf, 100); err != nil {
+		t.Fatalf("EncryptChunks() error = %v\n", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if _, _, err := ReadHeader(aead, bytes.NewReader(buf.Bytes()), magic, 2); err == nil {
+		t.Errorf("ReadHeader() with the wrong version expected an error\n")
+	}
+	got, chunkSize, err := ReadHeader(aead, r, magic, 1)
+	if err != nil || !bytes.Equal(got, header) || chunkSize != 100 {
+		t.Fatalf("ReadHeader() = %x, %d, %v, want %x, 100\n", got, chunkSize, err, header)
+	}
+	var plain []byte
+	sealed := make([]byte, chunkSize+aead.Overhead())
+	for i := uint64(0); ; i++ {
+		n, last, err := ReadChunk(aead, r, sealed, i)
+		if err != nil {
+			t.Fatalf("ReadChunk() error = %v\n", err)
+		}
+		p, err := aead.Open(nil, ChunkNonce(header[HeaderSize:], i), sealed[:n], ChunkAAD(header, last, []byte("aad")))
+		if err != nil {
+			t.Fatalf("could not open chunk %d: %s\n", i, err)
+		}
+		plain = append(plain, p...)
+		if last {
+			break
+		}
+	}
+	if !bytes.Equal(plain, data) {
+		t.Errorf("EncryptChunks() plaintext mismatch\n")
+	}
+	if _, _, err := ReadChunk(aead, r, sealed, 3); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ReadChunk() after the final chunk error = %v, want ErrTruncated\n", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package keygen provides generation, persistence and password-based derivation of keys
+// for use with the encryption packages of this module.
+package keygen
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+	"golang.org/x/crypto/argon2"
+)
+
+// minSaltLength is the minimum salt length accepted by DeriveFromPassword, as recommended by RFC 9106.
+const minSaltLength = 8
+
+// Generate returns a cryptographically random key of the given bit length, which must be 128, 192 or 256,
+// base64-encoded. It returns an error if the bit length is invalid or if the random source fails.
+func Generate(bits int) (string, error) {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return "", fmt.Errorf("invalid key length %d bits, must be 128, 192 or 256", bits)
+	}
+	key := make([]byte, bits/8)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// GenerateToFile generates a key like Generate and writes it to a new file at 'path' with 0600 permissions.
+// It returns an error if the file already exists, so an existing key is never overwritten by accident.
+func GenerateToFile(path string, bits int) error {
+	key, err := Generate(bits)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFromFile reads the key stored in the file located at 'path', such as one written by GenerateToFile.
+// Surrounding whitespace is removed. It returns an error if the file can't be read or holds no key.
+func LoadFromFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", fmt.Errorf("no key found in '%s'", path)
+	}
+	return key, nil
+}
+
+// DeriveFromPassword derives a 256-bit key from the password and salt using Argon2id with
+// aesgcm.DefaultArgon2Params and returns it base64-encoded. The same password and salt always
+// yield the same key. The salt should be random and unique per password, it must have at least 8 bytes.
+func DeriveFromPassword(password, salt string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password must not be empty")
+	}
+	if len(salt) < minSaltLength {
+		return "", fmt.Errorf("salt has %d bytes, at least %d are required", len(salt), minSaltLength)
+	}
+	p := aesgcm.DefaultArgon2Params
+	key := argon2.IDKey([]byte(password), []byte(salt), p.Time, p.Memory, p.Threads, 32)
+	return base64.StdEncoding.EncodeToString(key), nil
+}
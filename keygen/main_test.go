@@ -0,0 +1,114 @@
+package keygen
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+)
+
+func Test_Generate(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		wantErr bool
+	}{
+		{"128", 128, false},
+		{"192", 192, false},
+		{"256", 256, false},
+		{"64", 64, true},
+		{"512", 512, true},
+		{"zero", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Generate(tt.bits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != tt.bits/8 {
+				t.Errorf("expected %d base64-encoded bytes, got %d (%v)\n", tt.bits/8, len(b), err)
+			}
+			if other, _ := Generate(tt.bits); other == key {
+				t.Errorf("two generated keys are equal\n")
+			}
+			e, err := aesgcm.Encrypt("Hello World!", key)
+			if err != nil {
+				t.Fatalf("could not encrypt with generated key: %s\n", err)
+			}
+			if d, err := aesgcm.Decrypt(e, key); err != nil || d != "Hello World!" {
+				t.Errorf("encrypt/decrypt with generated key failed: %v, %v\n", d, err)
+			}
+		})
+	}
+}
+
+func Test_GenerateToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := GenerateToFile(path, 256); err != nil {
+		t.Fatalf("could not generate key file: %s\n", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat key file: %s\n", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected permissions 0600, got %o\n", perm)
+	}
+	key, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("could not load key: %s\n", err)
+	}
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 32 {
+		t.Errorf("expected 32 base64-encoded bytes, got %d (%v)\n", len(b), err)
+	}
+
+	if err := GenerateToFile(path, 256); err == nil {
+		t.Errorf("expected error when overwriting an existing key file\n")
+	}
+	if again, _ := LoadFromFile(path); again != key {
+		t.Errorf("existing key file was modified\n")
+	}
+	if err := GenerateToFile(filepath.Join(t.TempDir(), "bad"), 100); err == nil {
+		t.Errorf("expected error for invalid bit length\n")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	_ = os.WriteFile(empty, []byte(" \n"), 0600)
+	if _, err := LoadFromFile(empty); err == nil {
+		t.Errorf("expected error for empty key file\n")
+	}
+	if _, err := LoadFromFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected error for missing key file\n")
+	}
+}
+
+func Test_DeriveFromPassword(t *testing.T) {
+	a, err := DeriveFromPassword("correct horse", "saltsalt")
+	if err != nil {
+		t.Fatalf("could not derive key: %s\n", err)
+	}
+	if b, _ := base64.StdEncoding.DecodeString(a); len(b) != 32 {
+		t.Errorf("expected a 32-byte key, got %d bytes\n", len(b))
+	}
+	if again, _ := DeriveFromPassword("correct horse", "saltsalt"); again != a {
+		t.Errorf("derivation is not deterministic\n")
+	}
+	if other, _ := DeriveFromPassword("correct horse", "saltsal2"); other == a {
+		t.Errorf("different salts derived the same key\n")
+	}
+	if other, _ := DeriveFromPassword("correct horsf", "saltsalt"); other == a {
+		t.Errorf("different passwords derived the same key\n")
+	}
+	if _, err := DeriveFromPassword("correct horse", "short"); err == nil {
+		t.Errorf("expected error for short salt\n")
+	}
+	if _, err := DeriveFromPassword("", "saltsalt"); err == nil {
+		t.Errorf("expected error for empty password\n")
+	}
+}
@@ -0,0 +1,87 @@
+// Package multikey encrypts data for several recipients at once, so any one of their keys can decrypt it.
+package multikey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+)
+
+// bundleVersion is the version of the bundle format written by EncryptMultiKey.
+const bundleVersion = 1
+
+// dekBytes is the length of the random data encryption keys generated by EncryptMultiKey.
+const dekBytes = 32
+
+// ErrNoMatchingKey is returned by DecryptMultiKey when none of the slots of a bundle can be decrypted with the key.
+var ErrNoMatchingKey = errors.New("no slot matches the key")
+
+// bundle is the JSON structure of a ciphertext produced by EncryptMultiKey.
+type bundle struct {
+	Version int      `json:"version"`
+	Slots   []string `json:"slots"`   // the data encryption key, encrypted with each of the keys
+	Payload string   `json:"payload"` // the plaintext, encrypted with the data encryption key
+}
+
+// EncryptMultiKey encrypts 'plaintext' so that any one of 'keys' can decrypt it with DecryptMultiKey.
+// A random 32-byte data encryption key (DEK) encrypts the plaintext with aesgcm, and the DEK is encrypted
+// separately with every key. It returns a JSON bundle holding one DEK slot per key, in the order of 'keys',
+// and the encrypted payload. It returns an error if 'keys' is empty or a key is weak.
+//
+// The bundle doesn't reveal which keys it was encrypted for, but it does reveal their number.
+func EncryptMultiKey(plaintext string, keys []string) (string, error) {
+	if len(keys) == 0 {
+		return "", fmt.Errorf("at least one key is required")
+	}
+	dek, err := aesgcm.GenerateKey(dekBytes)
+	if err != nil {
+		return "", err
+	}
+	b := bundle{Version: bundleVersion, Slots: make([]string, len(keys))}
+	for i, key := range keys {
+		if b.Slots[i], err = aesgcm.Encrypt(dek, key); err != nil {
+			return "", fmt.Errorf("can't encrypt data encryption key for key %d: %w", i, err)
+		}
+	}
+	if b.Payload, err = aesgcm.Encrypt(plaintext, dek); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecryptMultiKey decrypts a bundle produced by EncryptMultiKey with any one of the keys it was encrypted for.
+// Every DEK slot is tried with 'key' until one decrypts, which then decrypts the payload. It returns
+// the plaintext, an error wrapping ErrNoMatchingKey if 'key' matches no slot, or an error if the bundle
+// is malformed or has been tampered with.
+func DecryptMultiKey(ciphertext, key string) (string, error) {
+	var b bundle
+	if err := json.Unmarshal([]byte(ciphertext), &b); err != nil {
+		return "", fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Version != bundleVersion {
+		return "", fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	c, err := aesgcm.New(key)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	for _, slot := range b.Slots {
+		dek, err := c.Decrypt(slot)
+		if err != nil {
+			continue
+		}
+		plaintext, err := aesgcm.Decrypt(b.Payload, dek)
+		if err != nil {
+			return "", fmt.Errorf("can't decrypt payload: %w", err)
+		}
+		return plaintext, nil
+	}
+	return "", ErrNoMatchingKey
+}
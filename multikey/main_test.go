@@ -0,0 +1,92 @@
+package multikey
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_MultiKey(t *testing.T) {
+	keys := []string{"alice-key", "bob-key1", "carol-key"}
+	tests := []struct {
+		name string
+		text string
+		keys []string
+	}{
+		{"single key", "Hello World!", keys[:1]},
+		{"three keys", "Hello Team!", keys},
+		{"empty plaintext", "", keys},
+		{"duplicate keys", "Hello Twins!", []string{"alice-key", "alice-key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptMultiKey(tt.text, tt.keys)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if strings.Contains(e, tt.text) && tt.text != "" {
+				t.Errorf("bundle contains the plaintext\n")
+			}
+			var b bundle
+			if err := json.Unmarshal([]byte(e), &b); err != nil || len(b.Slots) != len(tt.keys) {
+				t.Errorf("expected a JSON bundle with %d slots: %v\n", len(tt.keys), err)
+			}
+			for _, key := range tt.keys {
+				d, err := DecryptMultiKey(e, key)
+				if err != nil || d != tt.text {
+					t.Errorf("DecryptMultiKey() with %s = %q, %v, want %q\n", key, d, err, tt.text)
+				}
+			}
+			if _, err := DecryptMultiKey(e, "mallory-key"); !errors.Is(err, ErrNoMatchingKey) {
+				t.Errorf("expected ErrNoMatchingKey for an unknown key, got %v\n", err)
+			}
+		})
+	}
+}
+
+func Test_MultiKey_errors(t *testing.T) {
+	if _, err := EncryptMultiKey("Hello World!", nil); err == nil {
+		t.Errorf("EncryptMultiKey() without keys expected an error\n")
+	}
+	if _, err := EncryptMultiKey("Hello World!", []string{"alice-key", ""}); err == nil {
+		t.Errorf("EncryptMultiKey() with an empty key expected an error\n")
+	}
+
+	e, _ := EncryptMultiKey("Hello World!", []string{"alice-key", "bob-key1"})
+	var b bundle
+	_ = json.Unmarshal([]byte(e), &b)
+	other, _ := EncryptMultiKey("Other!", []string{"alice-key"})
+	var o bundle
+	_ = json.Unmarshal([]byte(other), &o)
+
+	flip := func(c byte) string {
+		if c == 'A' {
+			return "B"
+		}
+		return "A"
+	}
+	tampered := func(f func(b *bundle)) string {
+		c := b
+		c.Slots = append([]string{}, b.Slots...)
+		f(&c)
+		data, _ := json.Marshal(c)
+		return string(data)
+	}
+	tests := []struct {
+		name       string
+		ciphertext string
+	}{
+		{"not json", "Hello World!"},
+		{"version", tampered(func(b *bundle) { b.Version = 2 })},
+		{"payload swapped", tampered(func(b *bundle) { b.Payload = o.Payload })},
+		{"payload corrupted", tampered(func(b *bundle) { b.Payload = b.Payload[:10] + flip(b.Payload[10]) + b.Payload[11:] })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptMultiKey(tt.ciphertext, "alice-key"); err == nil {
+				t.Errorf("DecryptMultiKey() expected an error\n")
+			}
+		})
+	}
+}
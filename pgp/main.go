@@ -0,0 +1,220 @@
+// Package pgp encrypts and decrypts data with a passphrase in the OpenPGP format, compatible with the
+// '--symmetric' mode of GnuPG. It only implements the symmetric path of OpenPGP, not public keys or signatures.
+//
+// Data is encrypted with AES-256 and integrity-protected with a modification detection code (MDC),
+// the key is derived from the passphrase with the iterated and salted S2K function using SHA-256.
+// Messages without MDC, as written by very old implementations, are rejected with ErrNoIntegrity.
+//
+// It is built on github.com/ProtonMail/go-crypto/openpgp, the maintained fork of the deprecated
+// golang.org/x/crypto/openpgp. The fork doesn't check the quick check bytes of a message,
+// as they allow oracle attacks, so a wrong passphrase may only be noticed once the whole message has
+// been read, and the error then wraps both ErrWrongPassphrase and ErrTampered.
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// messageType is the armor block type of OpenPGP messages.
+const messageType = "PGP MESSAGE"
+
+// s2kCount is the number of bytes hashed to derive the key from the passphrase, the maximum OpenPGP can represent.
+const s2kCount = 65011712
+
+var (
+	// ErrWrongPassphrase is returned when a message can't be decrypted with the passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+
+	// ErrNoIntegrity is returned for messages without modification detection code, which could have been tampered with.
+	ErrNoIntegrity = errors.New("message is not integrity protected")
+
+	// ErrNotSymmetric is returned for data that isn't a message encrypted with a passphrase.
+	ErrNotSymmetric = errors.New("not a symmetrically encrypted message")
+
+	// ErrTampered is returned when the modification detection code of a message doesn't match its contents.
+	ErrTampered = errors.New("message has been tampered with")
+)
+
+// config holds the algorithms used for encryption.
+var config = &packet.Config{
+	DefaultCipher: packet.CipherAES256,
+	DefaultHash:   crypto.SHA256,
+	S2KConfig: &s2k.Config{
+		S2KMode:  s2k.IteratedSaltedS2K,
+		Hash:     crypto.SHA256,
+		S2KCount: s2kCount,
+	},
+}
+
+// encrypt encrypts the data read from 'r' with 'passphrase' and writes it to 'w', ASCII-armored if 'armored' is set.
+func encrypt(w io.Writer, r io.Reader, armored bool, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	var a io.WriteCloser
+	if armored {
+		var err error
+		if a, err = armor.Encode(w, messageType, nil); err != nil {
+			return err
+		}
+		w = a
+	}
+	ew, err := openpgp.SymmetricallyEncrypt(w, []byte(passphrase), &openpgp.FileHints{IsBinary: true}, config)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ew, r); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	if a != nil {
+		return a.Close()
+	}
+	return nil
+}
+
+// decrypt decrypts the OpenPGP message read from 'r', binary or ASCII-armored, with 'passphrase' and writes the
+// plaintext to 'w'. The integrity of the message is only verified once it has been read completely, so the
+// plaintext written to 'w' must be discarded if an error is returned.
+func decrypt(w io.Writer, r io.Reader, passphrase string) error {
+	br := bufio.NewReader(r)
+	if isArmored(br) {
+		block, err := armor.Decode(br)
+		if err != nil {
+			return err
+		}
+		if block.Type != messageType {
+			return fmt.Errorf("%w: armor type %s", ErrNotSymmetric, block.Type)
+		}
+		r = block.Body
+	} else {
+		r = br
+	}
+
+	packets := packet.NewReader(r)
+	var keys []*packet.SymmetricKeyEncrypted
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			return ErrNotSymmetric
+		}
+		if err != nil {
+			return err
+		}
+		switch p := p.(type) {
+		case *packet.SymmetricKeyEncrypted:
+			keys = append(keys, p)
+		case *packet.EncryptedKey:
+			// encrypted for a public key, which may be accompanied by a passphrase
+		case *packet.SymmetricallyEncrypted:
+			if !p.IntegrityProtected {
+				return ErrNoIntegrity
+			}
+			if len(keys) == 0 {
+				return ErrNotSymmetric
+			}
+			return decryptData(w, p, keys, passphrase)
+		default:
+			return ErrNotSymmetric
+		}
+	}
+}
+
+// decryptData decrypts the integrity-protected data 'se' with the first of 'keys' that 'passphrase' decrypts,
+// writes the literal data it holds to 'w' and verifies the modification detection code.
+//
+// A key encrypted with a wrong passphrase usually fails to decrypt, but the session key of a message written
+// by 'gpg --symmetric' is the key derived from the passphrase, so a wrong passphrase looks like tampering.
+func decryptData(w io.Writer, se *packet.SymmetricallyEncrypted, keys []*packet.SymmetricKeyEncrypted, passphrase string) error {
+	var data io.ReadCloser
+	for _, ske := range keys {
+		key, cipherFunc, err := ske.Decrypt([]byte(passphrase))
+		if err != nil {
+			continue
+		}
+		if data, err = se.Decrypt(cipherFunc, key); err == nil {
+			break
+		}
+		if err != pgperrors.ErrKeyIncorrect {
+			return err
+		}
+	}
+	if data == nil {
+		return ErrWrongPassphrase
+	}
+	md, err := openpgp.ReadMessage(data, openpgp.EntityList(nil), nil, nil)
+	if err != nil {
+		return fmt.Errorf("%w or %w: %w", ErrWrongPassphrase, ErrTampered, err)
+	}
+	if md.IsEncrypted || md.IsSigned {
+		return fmt.Errorf("%w: nested encrypted or signed messages are not supported", ErrNotSymmetric)
+	}
+	if _, err := io.Copy(w, md.UnverifiedBody); err != nil {
+		return fmt.Errorf("%w or %w: %w", ErrWrongPassphrase, ErrTampered, err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("%w or %w: %w", ErrWrongPassphrase, ErrTampered, err)
+	}
+	return nil
+}
+
+// isArmored reports whether the data buffered by 'br' starts with an armor header, optionally preceded by whitespace.
+func isArmored(br *bufio.Reader) bool {
+	head, _ := br.Peek(br.Size())
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("-----BEGIN PGP "))
+}
+
+// EncryptSymmetric encrypts the given plaintext with 'passphrase' in the OpenPGP format.
+// It returns the ASCII-armored message, as written by 'gpg --symmetric --armor', and any error encountered.
+func EncryptSymmetric(plaintext, passphrase string) (string, error) {
+	var buf strings.Builder
+	if err := encrypt(&buf, strings.NewReader(plaintext), true, passphrase); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DecryptSymmetric decrypts the given OpenPGP message, binary or ASCII-armored, with 'passphrase'.
+// It returns the decrypted plaintext and any error encountered, which wraps ErrWrongPassphrase if the passphrase
+// is wrong and ErrTampered if the message has been modified. See the package documentation for why an error
+// may wrap both.
+func DecryptSymmetric(ciphertext, passphrase string) (string, error) {
+	var buf strings.Builder
+	if err := decrypt(&buf, strings.NewReader(ciphertext), passphrase); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// EncryptFileSymmetric encrypts the file located at 'path' in place with 'passphrase' in the binary OpenPGP
+// format, as written by 'gpg --symmetric'. It returns an error if the file doesn't exist or if any encryption
+// operation fails, in which case the file is left intact.
+func EncryptFileSymmetric(path, passphrase string) error {
+	return atomicfile.Transform("encrypt", path, func(w io.Writer, r io.Reader) error {
+		return encrypt(w, r, false, passphrase)
+	})
+}
+
+// DecryptFileSymmetric decrypts the OpenPGP-encrypted file located at 'path' in place with 'passphrase'.
+// The file may be binary or ASCII-armored. It returns an error if the file doesn't exist or if any
+// decryption operation fails, in which case the file is left intact.
+func DecryptFileSymmetric(path, passphrase string) error {
+	return atomicfile.Transform("decrypt", path, func(w io.Writer, r io.Reader) error {
+		return decrypt(w, r, passphrase)
+	})
+}
@@ -0,0 +1,146 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"short", "Hello World!"},
+		{"binary", "\x00\xff\r\n\x80"},
+		{"long", strings.Repeat("Hello World!\n", 10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := EncryptSymmetric(tt.text, "correct horse")
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if !strings.HasPrefix(e, "-----BEGIN PGP MESSAGE-----") {
+				t.Errorf("expected an armored message, got %q\n", e[:min(len(e), 40)])
+			}
+			d, err := DecryptSymmetric(e, "correct horse")
+			if err != nil || d != tt.text {
+				t.Errorf("DecryptSymmetric() = %d bytes, %v, want %d bytes\n", len(d), err, len(tt.text))
+			}
+			if _, err := DecryptSymmetric(e, "battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("DecryptSymmetric() with the wrong passphrase error = %v, want ErrWrongPassphrase\n", err)
+			}
+		})
+	}
+
+	if _, err := EncryptSymmetric("secret", ""); err == nil {
+		t.Errorf("EncryptSymmetric() with an empty passphrase expected an error\n")
+	}
+	if _, err := DecryptSymmetric("not a message", "correct horse"); err == nil {
+		t.Errorf("DecryptSymmetric() of garbage expected an error\n")
+	}
+}
+
+func Test_tampered(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encrypt(&buf, strings.NewReader("Hello World!"), false, "correct horse"); err != nil {
+		t.Fatalf("could not encrypt: %s\n", err)
+	}
+	e := buf.Bytes()
+	for _, i := range []int{len(e) - 1, len(e) - 25, len(e) - 30} {
+		tampered := bytes.Clone(e)
+		tampered[i] ^= 0x01
+		if d, err := DecryptSymmetric(string(tampered), "correct horse"); err == nil {
+			t.Errorf("DecryptSymmetric() of a message tampered at %d = %q, expected an error\n", i, d)
+		}
+	}
+	if d, err := DecryptSymmetric(string(e[:len(e)-5]), "correct horse"); err == nil {
+		t.Errorf("DecryptSymmetric() of a truncated message = %q, expected an error\n", d)
+	}
+}
+
+func Test_file(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	text := strings.Repeat("Hello World!\n", 10000)
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	if err := EncryptFileSymmetric(path, "correct horse"); err != nil {
+		t.Fatalf("EncryptFileSymmetric() error = %v\n", err)
+	}
+	e, _ := os.ReadFile(path)
+	if bytes.Contains(e, []byte("Hello")) || bytes.HasPrefix(e, []byte("-----")) {
+		t.Errorf("expected a binary message\n")
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Errorf("EncryptFileSymmetric() changed the permissions to %v\n", fi.Mode().Perm())
+	}
+	if err := DecryptFileSymmetric(path, "battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("DecryptFileSymmetric() with the wrong passphrase error = %v, want ErrWrongPassphrase\n", err)
+	}
+	if d, _ := os.ReadFile(path); !bytes.Equal(d, e) {
+		t.Errorf("a failed DecryptFileSymmetric() modified the file\n")
+	}
+	if err := DecryptFileSymmetric(path, "correct horse"); err != nil {
+		t.Fatalf("DecryptFileSymmetric() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != text {
+		t.Errorf("encrypt/decrypt file failed\n")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+	if err := EncryptFileSymmetric(filepath.Join(t.TempDir(), "missing"), "correct horse"); err == nil {
+		t.Errorf("EncryptFileSymmetric() of a missing file expected an error\n")
+	}
+}
+
+func Test_gnupg(t *testing.T) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) ([]byte, error) {
+		args = append([]string{"--homedir", dir, "--batch", "--quiet", "--pinentry-mode", "loopback", "--passphrase", "correct horse"}, args...)
+		return exec.Command(gpg, args...).Output()
+	}
+
+	// GnuPG decrypts our messages
+	path := filepath.Join(dir, "ours.txt")
+	_ = os.WriteFile(path, []byte("Hello GnuPG!"), 0600)
+	if err := EncryptFileSymmetric(path, "correct horse"); err != nil {
+		t.Fatalf("EncryptFileSymmetric() error = %v\n", err)
+	}
+	armored, _ := EncryptSymmetric("Hello GnuPG!", "correct horse")
+	_ = os.WriteFile(path+".asc", []byte(armored), 0600)
+	for _, p := range []string{path, path + ".asc"} {
+		if out, err := run("--decrypt", p); err != nil || string(out) != "Hello GnuPG!" {
+			t.Errorf("gpg --decrypt %s = %q, %v\n", filepath.Base(p), out, err)
+		}
+	}
+
+	// and we decrypt those of GnuPG
+	theirs := filepath.Join(dir, "theirs.txt")
+	_ = os.WriteFile(theirs, []byte("Hello cipherutils!"), 0600)
+	for _, args := range [][]string{{"--symmetric"}, {"--symmetric", "--armor"}, {"--symmetric", "--cipher-algo", "AES128", "--compress-algo", "zlib"}} {
+		out := filepath.Join(dir, "theirs.gpg")
+		_ = os.Remove(out)
+		if _, err := run(append(args, "--output", out, theirs)...); err != nil {
+			t.Fatalf("gpg %v error = %v\n", args, err)
+		}
+		if err := DecryptFileSymmetric(out, "correct horse"); err != nil {
+			t.Errorf("DecryptFileSymmetric() of gpg %v error = %v\n", args, err)
+			continue
+		}
+		if d, _ := os.ReadFile(out); string(d) != "Hello cipherutils!" {
+			t.Errorf("DecryptFileSymmetric() of gpg %v = %q\n", args, d)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package rotate encrypts files with versioned keys, so files can be rotated to a new key version
+// one at a time while the old version is still in use.
+package rotate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// headerPrefix starts the plaintext header "cipherutils:v<version>:" of every file written by this package.
+const headerPrefix = "cipherutils:v"
+
+// maxHeaderSize is the length of the longest header, with a version of 19 digits.
+const maxHeaderSize = len(headerPrefix) + 19 + 1
+
+var (
+	// ErrNoVersion is returned when a file doesn't start with a key version header.
+	ErrNoVersion = errors.New("no key version header")
+
+	// ErrUnknownVersion is returned when none of the given keys has the version recorded in a file.
+	ErrUnknownVersion = errors.New("unknown key version")
+)
+
+// KeyVersion is a key along with its version number, which is recorded in the header of the files encrypted with it.
+type KeyVersion struct {
+	Version int
+	Key     string
+}
+
+// header returns the plaintext header of files encrypted with the key version.
+func (kv KeyVersion) header() []byte {
+	return []byte(headerPrefix + strconv.Itoa(kv.Version) + ":")
+}
+
+// cipher returns an aesgcm.Cipher for the key that authenticates the header as additional data,
+// so the recorded version can't be changed without failing decryption.
+func (kv KeyVersion) cipher() (*aesgcm.Cipher, error) {
+	if kv.Version < 0 {
+		return nil, fmt.Errorf("key version must not be negative, got %d", kv.Version)
+	}
+	return aesgcm.New(kv.Key, aesgcm.WithAAD(kv.header()))
+}
+
+// EncryptFile encrypts the file located at 'path' in place with the key version 'kv'.
+// The file starts with the plaintext header "cipherutils:v<version>:" followed by the contents encrypted with
+// aesgcm in the format of aesgcm.EncryptStream, with the header authenticated as additional data.
+// The file is replaced atomically, so the original is left intact if encryption fails.
+func EncryptFile(path string, kv KeyVersion) error {
+	c, err := kv.cipher()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return atomicfile.Transform("encrypt", path, func(w io.Writer, r io.Reader) error {
+		if _, err := w.Write(kv.header()); err != nil {
+			return err
+		}
+		return c.EncryptStream(w, r)
+	})
+}
+
+// DecryptFile decrypts the file located at 'path', written by EncryptFile or RotateFile, in place with the one of
+// 'keys' whose version is recorded in the file. It returns an error wrapping ErrUnknownVersion if there is none,
+// or an error if decryption fails, in which case the file is left intact.
+func DecryptFile(path string, keys ...KeyVersion) error {
+	version, err := DetectVersion(path)
+	if err != nil {
+		return err
+	}
+	for _, kv := range keys {
+		if kv.Version != version {
+			continue
+		}
+		c, err := kv.cipher()
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return atomicfile.Transform("decrypt", path, func(w io.Writer, r io.Reader) error {
+			return decryptTo(w, r, kv, c)
+		})
+	}
+	return fmt.Errorf("can't decrypt '%s', %w %d", path, ErrUnknownVersion, version)
+}
+
+// RotateFile re-encrypts the file located at 'path' from the key version 'old' to 'new' in place.
+// It returns an error wrapping ErrUnknownVersion if the file isn't encrypted with the version of 'old',
+// so files that have already been rotated are detected, and an error if 'new' has the same version as 'old'.
+//
+// The plaintext is never written to disk: it is piped from decryption to encryption into a temporary file,
+// which replaces the file atomically once it is complete. The file is left intact if anything fails.
+func RotateFile(path string, old, new KeyVersion) error {
+	if old.Version == new.Version {
+		return fmt.Errorf("can't rotate '%s', both keys have version %d", path, old.Version)
+	}
+	version, err := DetectVersion(path)
+	if err != nil {
+		return err
+	}
+	if version != old.Version {
+		return fmt.Errorf("can't rotate '%s' from version %d, %w %d", path, old.Version, ErrUnknownVersion, version)
+	}
+	oldCipher, err := old.cipher()
+	if err != nil {
+		return err
+	}
+	defer oldCipher.Close()
+	newCipher, err := new.cipher()
+	if err != nil {
+		return err
+	}
+	defer newCipher.Close()
+	return atomicfile.Transform("rotate", path, func(w io.Writer, r io.Reader) error {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(decryptTo(pw, r, old, oldCipher))
+		}()
+		if _, err := w.Write(new.header()); err != nil {
+			pr.CloseWithError(err)
+			return err
+		}
+		err := newCipher.EncryptStream(w, pr)
+		pr.CloseWithError(err)
+		return err
+	})
+}
+
+// DetectVersion returns the key version recorded in the header of the file located at 'path'. Only the header
+// is read, the file isn't decrypted. It returns an error wrapping ErrNoVersion if the file has no valid header.
+func DetectVersion(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	version, err := readHeader(bufio.NewReaderSize(f, maxHeaderSize))
+	if err != nil {
+		return 0, fmt.Errorf("%w: '%s'", err, path)
+	}
+	return version, nil
+}
+
+// readHeader reads the header from 'r' and returns the recorded version.
+func readHeader(r *bufio.Reader) (int, error) {
+	head, _ := r.Peek(maxHeaderSize)
+	if !bytes.HasPrefix(head, []byte(headerPrefix)) {
+		return 0, ErrNoVersion
+	}
+	digits := head[len(headerPrefix):]
+	end := bytes.IndexByte(digits, ':')
+	if end < 1 {
+		return 0, ErrNoVersion
+	}
+	version, err := strconv.Atoi(string(digits[:end]))
+	if err != nil || version < 0 || strconv.Itoa(version) != string(digits[:end]) {
+		return 0, ErrNoVersion
+	}
+	_, err = r.Discard(len(headerPrefix) + end + 1)
+	return version, err
+}
+
+// decryptTo decrypts a file written with the key version 'kv' from 'r' with its cipher 'c' and writes the plaintext to 'w'.
+func decryptTo(w io.Writer, r io.Reader, kv KeyVersion, c *aesgcm.Cipher) error {
+	br := bufio.NewReaderSize(r, maxHeaderSize)
+	version, err := readHeader(br)
+	if err != nil {
+		return err
+	}
+	if version != kv.Version {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	return c.DecryptStream(w, br)
+}
@@ -0,0 +1,124 @@
+package rotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func plainFile(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(path, []byte(text), 0640); err != nil {
+		t.Fatalf("could not create file: %s\n", err)
+	}
+	return path
+}
+
+func Test_RotateFile(t *testing.T) {
+	v1 := KeyVersion{Version: 1, Key: "myKey123"}
+	v2 := KeyVersion{Version: 2, Key: "myNewKey456"}
+	text := strings.Repeat("Hello World! ", 10000)
+	path := plainFile(t, text)
+
+	if _, err := DetectVersion(path); !errors.Is(err, ErrNoVersion) {
+		t.Errorf("DetectVersion() of a plaintext file error = %v, want ErrNoVersion\n", err)
+	}
+	if err := EncryptFile(path, v1); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "cipherutils:v1:") || strings.Contains(string(data), "Hello") {
+		t.Errorf("expected an encrypted file with a version header, got %q\n", data[:min(len(data), 32)])
+	}
+	if v, err := DetectVersion(path); err != nil || v != 1 {
+		t.Errorf("DetectVersion() = %d, %v, want 1\n", v, err)
+	}
+
+	if err := RotateFile(path, v1, v1); err == nil {
+		t.Errorf("RotateFile() to the same version expected an error\n")
+	}
+	if err := RotateFile(path, KeyVersion{Version: 1, Key: "wrongKey"}, v2); err == nil {
+		t.Errorf("RotateFile() with the wrong key expected an error\n")
+	}
+	if d, _ := os.ReadFile(path); string(d) != string(data) {
+		t.Errorf("RotateFile() with the wrong key changed the file\n")
+	}
+	if err := RotateFile(path, v1, v2); err != nil {
+		t.Fatalf("RotateFile() error = %v\n", err)
+	}
+	if v, err := DetectVersion(path); err != nil || v != 2 {
+		t.Errorf("DetectVersion() after rotation = %d, %v, want 2\n", v, err)
+	}
+	if err := RotateFile(path, v1, v2); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("RotateFile() of a rotated file error = %v, want ErrUnknownVersion\n", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("expected the permissions to be preserved: %v\n", err)
+	}
+
+	if err := DecryptFile(path, v1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("DecryptFile() without the key version error = %v, want ErrUnknownVersion\n", err)
+	}
+	if err := DecryptFile(path, v1, v2); err != nil {
+		t.Fatalf("DecryptFile() error = %v\n", err)
+	}
+	if d, _ := os.ReadFile(path); string(d) != text {
+		t.Errorf("rotate/decrypt failed: got %d bytes, want %d\n", len(d), len(text))
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+}
+
+func Test_DetectVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr bool
+	}{
+		{"version 0", "cipherutils:v0:data", 0, false},
+		{"version 42", "cipherutils:v42:", 42, false},
+		{"empty", "", 0, true},
+		{"no version", "cipherutils:v:data", 0, true},
+		{"no terminator", "cipherutils:v12", 0, true},
+		{"negative", "cipherutils:v-1:data", 0, true},
+		{"leading zero", "cipherutils:v01:data", 0, true},
+		{"too long", "cipherutils:v" + strings.Repeat("9", 30) + ":", 0, true},
+		{"other prefix", "other:v1:data", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectVersion(plainFile(t, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectVersion() error = %v, wantErr %v\n", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectVersion() = %d, want %d\n", got, tt.want)
+			}
+		})
+	}
+	if _, err := DetectVersion(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("DetectVersion() of a missing file expected an error\n")
+	}
+}
+
+func Test_RotateFile_tamperedVersion(t *testing.T) {
+	v1 := KeyVersion{Version: 1, Key: "myKey123"}
+	path := plainFile(t, "Hello World!")
+	if err := EncryptFile(path, v1); err != nil {
+		t.Fatalf("EncryptFile() error = %v\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), "cipherutils:v1:", "cipherutils:v3:", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0640); err != nil {
+		t.Fatalf("could not write file: %s\n", err)
+	}
+	if err := DecryptFile(path, KeyVersion{Version: 3, Key: v1.Key}); err == nil {
+		t.Errorf("DecryptFile() of a file with a tampered version expected an error\n")
+	}
+}
@@ -0,0 +1,176 @@
+// Package rsa provides asymmetric encryption and decryption using RSA-OAEP with SHA-256.
+// Plaintexts that are too large for RSA-OAEP are transparently encrypted with a hybrid scheme
+// which encrypts the plaintext with a random key using the aesgcm package and the key with RSA-OAEP.
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+)
+
+const (
+	modeDirect byte = 1 // the plaintext is encrypted with RSA-OAEP
+	modeHybrid byte = 2 // the plaintext is encrypted with aesgcm, the aesgcm key with RSA-OAEP
+
+	hybridKeySize = 32 // bytes of randomness in the aesgcm key of the hybrid mode
+)
+
+// GenerateKeyPair generates a new RSA key pair with the given number of bits.
+// It returns the PKIX public key and the PKCS #8 private key, both PEM-encoded, and any error encountered.
+func GenerateKeyPair(bits int) (publicPEM, privatePEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}))
+	return publicPEM, privatePEM, nil
+}
+
+// parsePublicKey parses a PEM-encoded PKIX RSA public key.
+func parsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key, no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key, expected an RSA key but got %T", key)
+	}
+	return pub, nil
+}
+
+// parsePrivateKey parses a PEM-encoded PKCS #8 or PKCS #1 RSA private key.
+func parsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key, no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key, expected an RSA key but got %T", key)
+	}
+	return priv, nil
+}
+
+// maxDirectSize returns the largest plaintext RSA-OAEP with SHA-256 can encrypt with 'pub'.
+func maxDirectSize(pub *rsa.PublicKey) int {
+	return pub.Size() - 2*sha256.Size - 2
+}
+
+// Encrypt encrypts the given plaintext with the PEM-encoded RSA public key using RSA-OAEP with SHA-256.
+// It returns the base64-encoded ciphertext envelope and any error encountered.
+//
+// If the plaintext is too large for RSA-OAEP, a random key is generated, the plaintext is encrypted with it
+// using aesgcm and the key is encrypted with RSA-OAEP. The envelope starts with a mode byte, so Decrypt can
+// tell both forms apart.
+func Encrypt(plaintext, publicPEM string) (string, error) {
+	pub, err := parsePublicKey(publicPEM)
+	if err != nil {
+		return "", err
+	}
+
+	if len(plaintext) <= maxDirectSize(pub) {
+		encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, []byte(plaintext), nil)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(append([]byte{modeDirect}, encrypted...)), nil
+	}
+
+	keyBytes := make([]byte, hybridKeySize)
+	if _, err := io.ReadFull(rand.Reader, keyBytes); err != nil {
+		return "", err
+	}
+	// the raw key is wrapped, so hybrid mode works with keys too small for its hex encoding
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, keyBytes, nil)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := aesgcm.EncryptRaw([]byte(plaintext), hex.EncodeToString(keyBytes))
+	if err != nil {
+		return "", err
+	}
+
+	envelope := make([]byte, 3, 3+len(wrappedKey)+len(encrypted))
+	envelope[0] = modeHybrid
+	binary.BigEndian.PutUint16(envelope[1:], uint16(len(wrappedKey)))
+	envelope = append(append(envelope, wrappedKey...), encrypted...)
+	return base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// Decrypt decrypts the given base64-encoded ciphertext envelope, as produced by Encrypt,
+// with the PEM-encoded RSA private key. It returns the decrypted plaintext and any error encountered.
+func Decrypt(ciphertext, privatePEM string) (string, error) {
+	priv, err := parsePrivateKey(privatePEM)
+	if err != nil {
+		return "", err
+	}
+	envelope, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(envelope) < 1 {
+		return "", fmt.Errorf("data too short")
+	}
+
+	switch envelope[0] {
+	case modeDirect:
+		decrypted, err := rsa.DecryptOAEP(sha256.New(), nil, priv, envelope[1:], nil)
+		if err != nil {
+			return "", err
+		}
+		return string(decrypted), nil
+	case modeHybrid:
+		if len(envelope) < 3 {
+			return "", fmt.Errorf("data too short")
+		}
+		n := int(binary.BigEndian.Uint16(envelope[1:]))
+		if len(envelope) < 3+n {
+			return "", fmt.Errorf("data too short")
+		}
+		key, err := rsa.DecryptOAEP(sha256.New(), nil, priv, envelope[3:3+n], nil)
+		if err != nil {
+			return "", err
+		}
+		// earlier versions wrapped the hex-encoded key
+		if len(key) == hybridKeySize {
+			key = []byte(hex.EncodeToString(key))
+		}
+		decrypted, err := aesgcm.DecryptRaw(envelope[3+n:], string(key))
+		if err != nil {
+			return "", err
+		}
+		return string(decrypted), nil
+	}
+	return "", fmt.Errorf("unknown envelope mode %d", envelope[0])
+}
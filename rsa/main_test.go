@@ -0,0 +1,70 @@
+package rsa
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	pub, priv, err := GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	_, otherPriv, _ := GenerateKeyPair(2048)
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"short", "Hello World!"},
+		{"max direct", strings.Repeat("x", 190)},
+		{"hybrid", strings.Repeat("x", 191)},
+		{"large", strings.Repeat("Hello World! ", 10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt(tt.text, pub)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			d, err := Decrypt(e, priv)
+			if err != nil {
+				t.Fatalf("could not decrypt: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt failed: %v: expected %d bytes, got %d bytes!\n", tt.name, len(tt.text), len(d))
+			}
+			if _, err := Decrypt(e, otherPriv); err == nil {
+				t.Errorf("decrypt with wrong private key succeeded: %v\n", tt.name)
+			}
+		})
+	}
+
+	if _, err := Encrypt("Hello World!", "not a key"); err == nil {
+		t.Errorf("expected error for invalid public key\n")
+	}
+	if _, err := Encrypt("Hello World!", priv); err == nil {
+		t.Errorf("expected error when encrypting with a private key\n")
+	}
+}
+
+func Test_smallKey(t *testing.T) {
+	pub, priv, err := GenerateKeyPair(1024)
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	for _, text := range []string{"Hello World!", strings.Repeat("x", 1000)} {
+		e, err := Encrypt(text, pub)
+		if err != nil {
+			t.Fatalf("could not encrypt %d bytes: %s\n", len(text), err)
+		}
+		d, err := Decrypt(e, priv)
+		if err != nil {
+			t.Fatalf("could not decrypt %d bytes: %s\n", len(text), err)
+		}
+		if d != text {
+			t.Errorf("encrypt/decrypt failed: expected %d bytes, got %d bytes!\n", len(text), len(d))
+		}
+	}
+}
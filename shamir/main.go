@@ -0,0 +1,160 @@
+// Package shamir implements Shamir's Secret Sharing over GF(256), splitting a secret into shares
+// of which a threshold number is required to reconstruct it.
+package shamir
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// MaxShares is the largest number of shares a secret can be split into, as every share needs
+// a distinct non-zero x-coordinate in GF(256).
+const MaxShares = 255
+
+// shareHeaderSize is the length of the header preceding the share data: the index and the threshold.
+const shareHeaderSize = 2
+
+var (
+	expTable [255]byte
+	logTable [256]byte
+)
+
+func init() {
+	// 3 generates the multiplicative group of GF(256) with the AES polynomial x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		x ^= x<<1 ^ (x>>7)*0x1b // x *= 3
+	}
+}
+
+// mul multiplies 'a' and 'b' in GF(256).
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+// div divides 'a' by 'b' in GF(256), 'b' must not be 0.
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// Split splits 'secret' into 'n' shares of which any 'k' reconstruct it with Combine.
+// See SplitBytes for the requirements on 'n' and 'k'.
+func Split(secret string, n, k int) ([]string, error) {
+	return SplitBytes([]byte(secret), n, k)
+}
+
+// SplitBytes splits 'secret' into 'n' base64-encoded shares of which any 'k' reconstruct it with CombineBytes,
+// while fewer than 'k' shares reveal nothing about it. Every share records its index and the threshold 'k',
+// so shares can be combined in any order. It returns an error if the secret is empty, if 'k' is less than 2,
+// if 'k' is greater than 'n', if 'n' is greater than MaxShares or if the random source fails.
+func SplitBytes(secret []byte, n, k int) ([]string, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, fmt.Errorf("secret must not be empty")
+	case k < 2:
+		return nil, fmt.Errorf("threshold %d is too small, at least 2 shares must be required", k)
+	case k > n:
+		return nil, fmt.Errorf("threshold %d exceeds the number of shares %d", k, n)
+	case n > MaxShares:
+		return nil, fmt.Errorf("%d shares requested, at most %d are supported", n, MaxShares)
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, shareHeaderSize+len(secret))
+		shares[i][0] = byte(i + 1)
+		shares[i][1] = byte(k)
+	}
+	// every byte of the secret is the constant term of its own random polynomial of degree k-1
+	coeffs := make([]byte, k)
+	defer clear(coeffs)
+	for j, s := range secret {
+		coeffs[0] = s
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			x, y := share[0], byte(0)
+			for c := k - 1; c >= 0; c-- {
+				y = mul(y, x) ^ coeffs[c]
+			}
+			share[shareHeaderSize+j] = y
+		}
+	}
+
+	encoded := make([]string, n)
+	for i, share := range shares {
+		encoded[i] = base64.StdEncoding.EncodeToString(share)
+	}
+	return encoded, nil
+}
+
+// Combine reconstructs a secret split with Split from at least the threshold number of 'shares', in any order.
+// See CombineBytes for the errors returned.
+func Combine(shares []string) (string, error) {
+	secret, err := CombineBytes(shares)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// CombineBytes reconstructs a secret split with SplitBytes from at least the threshold number of 'shares',
+// in any order. It returns an error if a share is malformed, if shares are duplicated or belong to
+// different secrets, or if fewer shares than the threshold are given.
+//
+// Shares carry no integrity protection: a share that has been tampered with, or the right number of shares
+// from different splits of equally long secrets with the same threshold, yield a wrong secret without an error.
+func CombineBytes(shares []string) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no shares given")
+	}
+	points := make([][]byte, len(shares))
+	seen := map[byte]bool{}
+	for i, s := range shares {
+		p, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("malformed share %d: %w", i, err)
+		}
+		if len(p) <= shareHeaderSize || p[0] == 0 || p[1] < 2 {
+			return nil, fmt.Errorf("malformed share %d", i)
+		}
+		if i > 0 && (len(p) != len(points[0]) || p[1] != points[0][1]) {
+			return nil, fmt.Errorf("share %d belongs to a different secret", i)
+		}
+		if seen[p[0]] {
+			return nil, fmt.Errorf("share %d is a duplicate of share index %d", i, p[0])
+		}
+		seen[p[0]] = true
+		points[i] = p
+	}
+	if k := int(points[0][1]); len(points) < k {
+		return nil, fmt.Errorf("%d shares given, %d are required", len(points), k)
+	}
+	points = points[:points[0][1]]
+
+	// Lagrange interpolation at x = 0
+	secret := make([]byte, len(points[0])-shareHeaderSize)
+	for i, pi := range points {
+		basis := byte(1)
+		for j, pj := range points {
+			if i != j {
+				basis = mul(basis, div(pj[0], pj[0]^pi[0]))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(basis, pi[shareHeaderSize+b])
+		}
+	}
+	return secret, nil
+}
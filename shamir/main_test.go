@@ -0,0 +1,128 @@
+package shamir
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/rand"
+	"testing"
+)
+
+func Test_gf256(t *testing.T) {
+	// test vectors from FIPS-197, section 4.2
+	if got := mul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("mul(0x57, 0x83) = %#x, want 0xc1\n", got)
+	}
+	if got := mul(0x57, 0x13); got != 0xfe {
+		t.Errorf("mul(0x57, 0x13) = %#x, want 0xfe\n", got)
+	}
+	for a := 1; a < 256; a++ {
+		for _, b := range []byte{1, 2, 0x53, 0xca, 0xff} {
+			if got := div(mul(byte(a), b), b); got != byte(a) {
+				t.Errorf("div(mul(%#x, %#x), %#x) = %#x\n", a, b, b, got)
+			}
+		}
+	}
+}
+
+func Test_SplitCombine(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		n, k   int
+	}{
+		{"2 of 2", "Hello World!", 2, 2},
+		{"3 of 5", "correct horse battery staple", 5, 3},
+		{"5 of 5", "x", 5, 5},
+		{"2 of 255", "many shares", 255, 2},
+		{"10 of 20", string(bytes.Repeat([]byte{0x00, 0xff}, 100)), 20, 10},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Split(tt.secret, tt.n, tt.k)
+			if err != nil {
+				t.Fatalf("Split() error = %v\n", err)
+			}
+			if len(shares) != tt.n {
+				t.Fatalf("expected %d shares, got %d\n", tt.n, len(shares))
+			}
+			for i := 0; i < 10; i++ {
+				rnd.Shuffle(len(shares), func(i, j int) { shares[i], shares[j] = shares[j], shares[i] })
+				subset := shares[:tt.k+rnd.Intn(tt.n-tt.k+1)]
+				if got, err := Combine(subset); err != nil || got != tt.secret {
+					t.Errorf("Combine() of %d shares = %q, %v, want %q\n", len(subset), got, err, tt.secret)
+				}
+			}
+			if _, err := Combine(shares[:tt.k-1]); err == nil {
+				t.Errorf("Combine() of %d shares expected an error\n", tt.k-1)
+			}
+		})
+	}
+}
+
+func Test_SplitBytes(t *testing.T) {
+	secret := make([]byte, 64)
+	for i := range secret {
+		secret[i] = byte(i * 7)
+	}
+	shares, err := SplitBytes(secret, 4, 3)
+	if err != nil {
+		t.Fatalf("SplitBytes() error = %v\n", err)
+	}
+	if got, err := CombineBytes([]string{shares[3], shares[0], shares[2]}); err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("CombineBytes() = %x, %v, want %x\n", got, err, secret)
+	}
+	again, _ := SplitBytes(secret, 4, 3)
+	if again[0] == shares[0] {
+		t.Errorf("expected shares to be randomized\n")
+	}
+}
+
+func Test_Split_invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		n, k   int
+	}{
+		{"empty secret", "", 3, 2},
+		{"threshold 1", "secret", 3, 1},
+		{"threshold 0", "secret", 3, 0},
+		{"threshold above shares", "secret", 3, 4},
+		{"too many shares", "secret", 256, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Split(tt.secret, tt.n, tt.k); err == nil {
+				t.Errorf("Split() expected an error\n")
+			}
+		})
+	}
+}
+
+func Test_Combine_invalid(t *testing.T) {
+	shares, _ := Split("Hello World!", 3, 2)
+	other, _ := Split("Hello World!", 3, 3)
+	longer, _ := Split("Hello World!!", 3, 2)
+	raw, _ := base64.StdEncoding.DecodeString(shares[0])
+	zeroIndex := append([]byte{0}, raw[1:]...)
+	tests := []struct {
+		name   string
+		shares []string
+	}{
+		{"none", nil},
+		{"not base64", []string{"not base64!", shares[1]}},
+		{"too short", []string{base64.StdEncoding.EncodeToString(raw[:2]), shares[1]}},
+		{"zero index", []string{base64.StdEncoding.EncodeToString(zeroIndex), shares[1]}},
+		{"duplicate", []string{shares[0], shares[0]}},
+		{"different threshold", []string{shares[0], other[1]}},
+		{"different length", []string{shares[0], longer[1]}},
+		{"below threshold", other[:2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Combine(tt.shares); err == nil {
+				t.Errorf("Combine() expected an error\n")
+			}
+		})
+	}
+}
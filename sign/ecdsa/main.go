@@ -0,0 +1,112 @@
+// Package ecdsa provides signing and verification of messages using ECDSA with SHA-256.
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+)
+
+// curves maps the supported curve names to their implementations.
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// GenerateKeyPair generates a new ECDSA key pair on the given curve, which must be one of "P-256", "P-384" or "P-521".
+// It returns the PKIX public key and the PKCS #8 private key, both PEM-encoded, and any error encountered.
+func GenerateKeyPair(curve string) (publicPEM, privatePEM string, err error) {
+	c, ok := curves[curve]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported curve '%s', expected P-256, P-384 or P-521", curve)
+	}
+	key, err := ecdsa.GenerateKey(c, rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}))
+	return publicPEM, privatePEM, nil
+}
+
+// parsePublicKey parses a PEM-encoded PKIX ECDSA public key.
+func parsePublicKey(publicPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key, no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key, expected an ECDSA key but got %T", key)
+	}
+	return pub, nil
+}
+
+// parsePrivateKey parses a PEM-encoded PKCS #8 or SEC 1 ECDSA private key.
+func parsePrivateKey(privatePEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key, no PEM data found")
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key, expected an ECDSA key but got %T", key)
+	}
+	return priv, nil
+}
+
+// Sign signs the SHA-256 hash of the given message with the PEM-encoded ECDSA private key.
+// It returns the base64-encoded ASN.1 DER signature and any error encountered.
+func Sign(message, privatePEM string) (string, error) {
+	priv, err := parsePrivateKey(privatePEM)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(message))
+	signature, err := ecdsa.SignASN1(rand.Reader, priv, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Verify verifies the base64-encoded ASN.1 DER signature of the given message, as produced by Sign,
+// with the PEM-encoded ECDSA public key. It returns false and no error if the signature is invalid,
+// errors are only returned if the public key can't be parsed.
+func Verify(message, signature, publicPEM string) (bool, error) {
+	pub, err := parsePublicKey(publicPEM)
+	if err != nil {
+		return false, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, nil
+	}
+	hash := sha256.Sum256([]byte(message))
+	return ecdsa.VerifyASN1(pub, hash[:], sig), nil
+}
@@ -0,0 +1,58 @@
+package ecdsa
+
+import (
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve string
+		text  string
+	}{
+		{"P-256", "P-256", "Hello World!"},
+		{"P-384", "P-384", "Hello World!"},
+		{"P-521", "P-521", "Hello World!"},
+		{"empty", "P-256", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, priv, err := GenerateKeyPair(tt.curve)
+			if err != nil {
+				t.Fatalf("could not generate key pair: %s\n", err)
+			}
+			otherPub, _, _ := GenerateKeyPair(tt.curve)
+
+			s, err := Sign(tt.text, priv)
+			if err != nil {
+				t.Fatalf("could not sign: %s\n", err)
+			}
+			if ok, err := Verify(tt.text, s, pub); !ok || err != nil {
+				t.Errorf("sign/verify failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text+"!", s, pub); ok || err != nil {
+				t.Errorf("verify of modified message: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text, s, otherPub); ok || err != nil {
+				t.Errorf("verify with wrong public key: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text, "not a signature", pub); ok || err != nil {
+				t.Errorf("verify of malformed signature: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+		})
+	}
+
+	if _, _, err := GenerateKeyPair("P-224"); err == nil {
+		t.Errorf("expected error for unsupported curve\n")
+	}
+	pub, priv, _ := GenerateKeyPair("P-256")
+	if _, err := Sign("Hello World!", pub); err == nil {
+		t.Errorf("expected error when signing with a public key\n")
+	}
+	if _, err := Verify("Hello World!", "", "not a key"); err == nil {
+		t.Errorf("expected error for invalid public key\n")
+	}
+	if _, err := Verify("Hello World!", "", priv); err == nil {
+		t.Errorf("expected error when verifying with a private key\n")
+	}
+}
@@ -0,0 +1,127 @@
+// Package ed25519 provides signing and verification of messages and files using Ed25519.
+package ed25519
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+)
+
+// GenerateKeyPair generates a new Ed25519 key pair.
+// It returns the base64-encoded 32-byte public key and 64-byte private key and any error encountered.
+func GenerateKeyPair() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv), nil
+}
+
+// parsePublicKey decodes a base64-encoded Ed25519 public key.
+func parsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key, expected %d bytes but got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// parsePrivateKey decodes a base64-encoded Ed25519 private key.
+func parsePrivateKey(privateKey string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key, expected %d bytes but got %d", ed25519.PrivateKeySize, len(b))
+	}
+	return ed25519.PrivateKey(b), nil
+}
+
+// Sign signs the given message with the base64-encoded private key.
+// It returns the base64-encoded signature and any error encountered.
+func Sign(message, privateKey string) (string, error) {
+	priv, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(message))), nil
+}
+
+// Verify verifies the base64-encoded signature of the given message, as produced by Sign,
+// with the base64-encoded public key. It returns false and no error if the signature is invalid,
+// errors are only returned if the public key can't be parsed.
+func Verify(message, signature, publicKey string) (bool, error) {
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		return false, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, nil
+	}
+	return ed25519.Verify(pub, []byte(message), sig), nil
+}
+
+// hashFile returns the SHA-512 digest of the file located at 'path'.
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SignFile signs the file located at 'path' with the base64-encoded private key.
+// It returns the base64-encoded signature and any error encountered.
+//
+// The file is streamed through SHA-512 and the digest is signed with Ed25519ph (RFC 8032),
+// so files of any size can be signed without loading them into memory.
+// Signatures of files can only be verified with VerifyFile, not with Verify.
+func SignFile(path, privateKey string) (string, error) {
+	priv, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	digest, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig, err := priv.Sign(nil, digest, &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyFile verifies the base64-encoded signature of the file located at 'path', as produced by SignFile,
+// with the base64-encoded public key. It returns false and no error if the signature is invalid,
+// errors are returned if the public key can't be parsed or the file can't be read.
+func VerifyFile(path, signature, publicKey string) (bool, error) {
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		return false, err
+	}
+	digest, err := hashFile(path)
+	if err != nil {
+		return false, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, nil
+	}
+	return ed25519.VerifyWithOptions(pub, digest, sig, &ed25519.Options{Hash: crypto.SHA512}) == nil, nil
+}
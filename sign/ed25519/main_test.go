@@ -0,0 +1,80 @@
+package ed25519
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	otherPub, _, _ := GenerateKeyPair()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"short", "Hello World!"},
+		{"large", strings.Repeat("Hello World! ", 100000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Sign(tt.text, priv)
+			if err != nil {
+				t.Fatalf("could not sign: %s\n", err)
+			}
+			if ok, err := Verify(tt.text, s, pub); !ok || err != nil {
+				t.Errorf("sign/verify failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text+"!", s, pub); ok || err != nil {
+				t.Errorf("verify of modified message: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := Verify(tt.text, s, otherPub); ok || err != nil {
+				t.Errorf("verify with wrong public key: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+
+			file := filepath.Join(dir, tt.name+".txt")
+			if err := os.WriteFile(file, []byte(tt.text), 0o600); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			fs, err := SignFile(file, priv)
+			if err != nil {
+				t.Fatalf("could not sign file: %s\n", err)
+			}
+			if ok, err := VerifyFile(file, fs, pub); !ok || err != nil {
+				t.Errorf("sign/verify file failed: %v: %v, %v\n", tt.name, ok, err)
+			}
+			if ok, err := VerifyFile(file, fs, otherPub); ok || err != nil {
+				t.Errorf("verify file with wrong public key: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+			if ok, _ := Verify(tt.text, fs, pub); ok {
+				t.Errorf("file signature verified as message signature: %v\n", tt.name)
+			}
+			if err := os.WriteFile(file, []byte(tt.text+"!"), 0o600); err != nil {
+				t.Fatalf("could not modify file: %s\n", err)
+			}
+			if ok, err := VerifyFile(file, fs, pub); ok || err != nil {
+				t.Errorf("verify of modified file: %v: expected false, nil, got %v, %v\n", tt.name, ok, err)
+			}
+		})
+	}
+
+	if _, err := Sign("Hello World!", pub); err == nil {
+		t.Errorf("expected error when signing with a public key\n")
+	}
+	if _, err := Verify("Hello World!", "", "not a key"); err == nil {
+		t.Errorf("expected error for invalid public key\n")
+	}
+	if _, err := SignFile(filepath.Join(dir, "missing.txt"), priv); err == nil {
+		t.Errorf("expected error when signing a missing file\n")
+	}
+	if _, err := VerifyFile(filepath.Join(dir, "missing.txt"), "", pub); err == nil {
+		t.Errorf("expected error when verifying a missing file\n")
+	}
+}
@@ -0,0 +1,138 @@
+// Package vault stores multiple named secrets in a single file encrypted with aesgcm.
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/toxyl/cipherutils/aesgcm"
+	"github.com/toxyl/cipherutils/internal/atomicfile"
+)
+
+// ErrNotFound is returned when a secret does not exist in the vault.
+var ErrNotFound = errors.New("secret not found")
+
+// Vault holds named secrets in memory and persists them to an encrypted file with Save.
+// It is safe for concurrent use within a single process.
+type Vault struct {
+	mu      sync.RWMutex
+	path    string
+	cipher  *aesgcm.Cipher
+	secrets map[string]string
+}
+
+// Create creates a new, empty vault at 'path' protected by 'masterKey' and saves it.
+// It returns an error if the file already exists or can't be written.
+func Create(path, masterKey string) (*Vault, error) {
+	c, err := aesgcm.New(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	v := &Vault{path: path, cipher: c, secrets: map[string]string{}}
+	if err := v.save(false); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("can't create vault, file already exists: '%s'", path)
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// Open loads the vault stored at 'path' using 'masterKey'.
+// It returns an error if the file can't be read, the key is wrong or the file has been tampered with.
+func Open(path, masterKey string) (*Vault, error) {
+	c, err := aesgcm.New(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := c.DecryptBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("can't open vault '%s': %w", path, err)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("can't open vault '%s', invalid contents: %w", path, err)
+	}
+	return &Vault{path: path, cipher: c, secrets: secrets}, nil
+}
+
+// Set stores 'value' under 'name', replacing an existing secret. Call Save to persist the change.
+// It returns an error if 'name' is empty.
+func (v *Vault) Set(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name must not be empty")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secrets[name] = value
+	return nil
+}
+
+// Get returns the secret stored under 'name' or an error wrapping ErrNotFound if there is none.
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: '%s'", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// Delete removes the secret stored under 'name'. Call Save to persist the change.
+// It returns an error wrapping ErrNotFound if there is no such secret.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.secrets[name]; !ok {
+		return fmt.Errorf("%w: '%s'", ErrNotFound, name)
+	}
+	delete(v.secrets, name)
+	return nil
+}
+
+// List returns the names of all secrets in the vault, sorted alphabetically.
+func (v *Vault) List() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the secrets and writes them to the vault file with 0600 permissions.
+// The file is replaced atomically, so it always holds either the previous or the new contents.
+func (v *Vault) Save() error {
+	return v.save(true)
+}
+
+// save is Save, which fails with an error wrapping fs.ErrExist if the file exists unless 'overwrite' is set.
+func (v *Vault) save(overwrite bool) error {
+	v.mu.RLock()
+	plain, err := json.Marshal(v.secrets)
+	v.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	data, err := v.cipher.EncryptBytes(plain)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(v.path, 0600, overwrite, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_Vault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	v, err := Create(path, "master-key")
+	if err != nil {
+		t.Fatalf("Create() error = %v\n", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected a vault file with 0600 permissions: %v\n", err)
+	}
+	created, _ := os.ReadFile(path)
+	if _, err := Create(path, "master-key"); err == nil {
+		t.Errorf("Create() on an existing file expected an error\n")
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, created) {
+		t.Errorf("Create() on an existing file modified it\n")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %d entries\n", len(entries))
+	}
+
+	secrets := map[string]string{
+		"db/password": "hunter2",
+		"api/token":   "abc123",
+		"empty":       "",
+	}
+	for name, value := range secrets {
+		if err := v.Set(name, value); err != nil {
+			t.Fatalf("Set(%q) error = %v\n", name, err)
+		}
+	}
+	if err := v.Set("", "value"); err == nil {
+		t.Errorf("Set() with an empty name expected an error\n")
+	}
+	if err := v.Delete("empty"); err != nil {
+		t.Errorf("Delete() error = %v\n", err)
+	}
+	if err := v.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() error = %v, want ErrNotFound\n", err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatalf("Save() error = %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "hunter2") {
+		t.Errorf("vault file contains a plaintext secret\n")
+	}
+
+	o, err := Open(path, "master-key")
+	if err != nil {
+		t.Fatalf("Open() error = %v\n", err)
+	}
+	if got, want := o.List(), []string{"api/token", "db/password"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v\n", got, want)
+	}
+	if got, err := o.Get("db/password"); err != nil || got != "hunter2" {
+		t.Errorf("Get() = %q, %v, want %q\n", got, err, "hunter2")
+	}
+	if _, err := o.Get("empty"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound\n", err)
+	}
+
+	if _, err := Open(path, "wrong-key"); err == nil {
+		t.Errorf("Open() with the wrong key expected an error\n")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.vault"), "master-key"); err == nil {
+		t.Errorf("Open() of a missing file expected an error\n")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the vault file, got %d entries\n", len(entries))
+	}
+}
+
+func Test_Vault_concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	v, err := Create(path, "master-key")
+	if err != nil {
+		t.Fatalf("Create() error = %v\n", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("secret-%d", i)
+			_ = v.Set(name, name)
+			_, _ = v.Get(name)
+			_ = v.List()
+			if err := v.Save(); err != nil {
+				t.Errorf("Save() error = %v\n", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := v.Save(); err != nil {
+		t.Fatalf("Save() error = %v\n", err)
+	}
+	o, err := Open(path, "master-key")
+	if err != nil {
+		t.Fatalf("Open() error = %v\n", err)
+	}
+	if n := len(o.List()); n != 8 {
+		t.Errorf("expected 8 secrets, got %d\n", n)
+	}
+}
+
+func Test_Create_concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Create(path, "master-key"); err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Errorf("expected exactly one concurrent Create() to succeed, got %d\n", created)
+	}
+	if _, err := Open(path, "master-key"); err != nil {
+		t.Errorf("Open() error = %v\n", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package x25519 provides Diffie-Hellman key exchange using X25519 and encryption with the derived shared secret.
+// The shared secret is passed through HKDF-SHA256, which yields a 32-byte key suitable for AES-256-GCM.
+package x25519
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	keySize = 32 // bytes of public keys, private keys and derived shared secrets
+
+	// hkdfInfo binds derived shared secrets to this package.
+	hkdfInfo = "github.com/toxyl/cipherutils/x25519 shared secret v1"
+)
+
+// GenerateKeyPair generates a new X25519 key pair.
+// It returns the base64-encoded 32-byte public and private keys and any error encountered.
+func GenerateKeyPair() (publicKey, privateKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), base64.StdEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// PublicKey returns the base64-encoded public key belonging to the base64-encoded private key 'privateKey'.
+func PublicKey(privateKey string) (string, error) {
+	priv, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()), nil
+}
+
+// parsePublicKey decodes a base64-encoded X25519 public key.
+func parsePublicKey(publicKey string) (*ecdh.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(b) != keySize {
+		return nil, fmt.Errorf("invalid public key, expected %d bytes but got %d", keySize, len(b))
+	}
+	return ecdh.X25519().NewPublicKey(b)
+}
+
+// parsePrivateKey decodes a base64-encoded X25519 private key.
+func parsePrivateKey(privateKey string) (*ecdh.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if len(b) != keySize {
+		return nil, fmt.Errorf("invalid private key, expected %d bytes but got %d", keySize, len(b))
+	}
+	return ecdh.X25519().NewPrivateKey(b)
+}
+
+// deriveKey performs the X25519 key exchange and passes the result through HKDF-SHA256.
+func deriveKey(myPrivateKey, theirPublicKey string) ([]byte, error) {
+	priv, err := parsePrivateKey(myPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parsePublicKey(theirPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(hkdfInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeriveSharedSecret derives the secret shared between the owner of 'myPrivateKey' and the owner of the
+// private key belonging to 'theirPublicKey'. Both sides derive the same secret from their own private key
+// and the other side's public key. It returns the base64-encoded 32-byte secret and any error encountered.
+func DeriveSharedSecret(myPrivateKey, theirPublicKey string) (string, error) {
+	key, err := deriveKey(myPrivateKey, theirPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// newAEAD derives the shared secret and creates the AES-GCM AEAD instance for it.
+func newAEAD(myPrivateKey, theirPublicKey string) (cipher.AEAD, error) {
+	key, err := deriveKey(myPrivateKey, theirPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DeriveAndEncrypt encrypts the given plaintext using AES-256-GCM with the shared secret derived from
+// 'myPrivateKey' and 'theirPublicKey'. It returns the base64-encoded nonce||ciphertext and any error encountered.
+func DeriveAndEncrypt(plaintext, myPrivateKey, theirPublicKey string) (string, error) {
+	aesGCM, err := newAEAD(myPrivateKey, theirPublicKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aesGCM.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// DeriveAndDecrypt decrypts the given base64-encoded ciphertext, as produced by DeriveAndEncrypt,
+// with the shared secret derived from 'myPrivateKey' and 'theirPublicKey'.
+// It returns the decrypted plaintext and any error encountered.
+func DeriveAndDecrypt(ciphertext, myPrivateKey, theirPublicKey string) (string, error) {
+	aesGCM, err := newAEAD(myPrivateKey, theirPublicKey)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := aesGCM.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("data too short")
+	}
+	decrypted, err := aesGCM.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
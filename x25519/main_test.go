@@ -0,0 +1,81 @@
+package x25519
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func Test_test(t *testing.T) {
+	alicePub, alicePriv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	bobPub, bobPriv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate key pair: %s\n", err)
+	}
+	evePub, evePriv, _ := GenerateKeyPair()
+	if pub, err := PublicKey(alicePriv); err != nil || pub != alicePub {
+		t.Errorf("PublicKey() = %s, %v, want %s\n", pub, err, alicePub)
+	}
+	if _, err := PublicKey("invalid"); err == nil {
+		t.Errorf("PublicKey() of an invalid key expected an error\n")
+	}
+
+	a, err := DeriveSharedSecret(alicePriv, bobPub)
+	if err != nil {
+		t.Fatalf("could not derive shared secret: %s\n", err)
+	}
+	b, err := DeriveSharedSecret(bobPriv, alicePub)
+	if err != nil {
+		t.Fatalf("could not derive shared secret: %s\n", err)
+	}
+	if a != b {
+		t.Errorf("shared secrets differ: %s != %s\n", a, b)
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(a); len(raw) != 32 {
+		t.Errorf("expected a 32-byte secret, got %d bytes\n", len(raw))
+	}
+	if e, _ := DeriveSharedSecret(evePriv, bobPub); e == a {
+		t.Errorf("different key pairs derived the same secret\n")
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"short", "Hello World!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := DeriveAndEncrypt(tt.text, alicePriv, bobPub)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			d, err := DeriveAndDecrypt(e, bobPriv, alicePub)
+			if err != nil {
+				t.Fatalf("could not decrypt: %s\n", err)
+			}
+			if d != tt.text {
+				t.Errorf("encrypt/decrypt failed: %v: expected %v, got %v!\n", tt.name, tt.text, d)
+			}
+			if _, err := DeriveAndDecrypt(e, evePriv, alicePub); err == nil {
+				t.Errorf("decrypt with wrong private key succeeded: %v\n", tt.name)
+			}
+			if _, err := DeriveAndDecrypt(e, bobPriv, evePub); err == nil {
+				t.Errorf("decrypt with wrong public key succeeded: %v\n", tt.name)
+			}
+		})
+	}
+
+	if _, err := DeriveSharedSecret("not a key", bobPub); err == nil {
+		t.Errorf("expected error for invalid private key\n")
+	}
+	if _, err := DeriveSharedSecret(alicePriv, base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Errorf("expected error for short public key\n")
+	}
+	if _, err := DeriveSharedSecret(alicePriv, base64.StdEncoding.EncodeToString(make([]byte, 32))); err == nil {
+		t.Errorf("expected error for low-order public key\n")
+	}
+}
@@ -0,0 +1,172 @@
+// Package xchacha20 provides encryption and decryption functionalities using XChaCha20-Poly1305.
+// It supports encryption and decryption of data, files and streams using a provided key and mirrors the API
+// of the chacha20poly1305 package, so callers can swap cipher suites by changing only the import.
+//
+// XChaCha20-Poly1305 extends the nonce of ChaCha20-Poly1305 from 96 to 192 bits. The aesgcm and chacha20poly1305
+// packages draw a random 96-bit nonce for every message, which means that after about 2^32 messages under the
+// same key the chance of a nonce collision, which breaks confidentiality and authenticity, becomes unacceptable.
+// With 192-bit nonces, random nonces can be used for practically unlimited numbers of messages under the same key.
+package xchacha20
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/flo"
+	"github.com/toxyl/keys"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// keyCipher represents a structure holding the XChaCha20-Poly1305 key for encryption and decryption.
+type keyCipher struct {
+	key []byte
+}
+
+// newKeyCipher creates a new keyCipher instance initialized with a scrambled key.
+// It returns an error if key scrambling fails.
+func newKeyCipher(key string) (*keyCipher, error) {
+	k, err := keys.WeakKeyScrambler(key)
+	if err != nil {
+		return nil, err
+	}
+	return &keyCipher{key: []byte(k)}, nil
+}
+
+// encrypt encrypts the provided data using XChaCha20-Poly1305 encryption.
+// It returns the encrypted ciphertext along with any error encountered.
+func (c *keyCipher) encrypt(data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// decrypt decrypts the provided XChaCha20-Poly1305 encrypted data.
+// It returns the decrypted plaintext along with any error encountered.
+func (c *keyCipher) decrypt(data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("data too short")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
+
+// Encrypt encrypts the given plaintext using XChaCha20-Poly1305 encryption with the provided key.
+// It returns the base64-encoded encrypted ciphertext, the 24-byte nonce followed by the sealed data,
+// and any error encountered.
+//
+// The provided key undergoes scrambling using keys.WeakKeyScrambler to ensure it is 32 bytes long,
+// which is the key size required by XChaCha20-Poly1305.
+//
+// Note: The input key is not directly usable with other XChaCha20-Poly1305 implementations or tools,
+// as it undergoes specific scrambling tailored for this package's usage.
+func Encrypt(plaintext, key string) (string, error) {
+	encrypted, err := EncryptBytes([]byte(plaintext), key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// EncryptBytes encrypts the given bytes using XChaCha20-Poly1305 encryption with the provided key.
+// It returns the encrypted bytes and any error encountered.
+func EncryptBytes(bytes []byte, key string) ([]byte, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.encrypt(bytes)
+}
+
+// Decrypt decrypts the given base64-encoded encrypted text using XChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted plaintext and any error encountered.
+func Decrypt(text, key string) (string, error) {
+	encryptedData, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := DecryptBytes(encryptedData, key)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// DecryptBytes decrypts the given encrypted bytes using XChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted bytes and any error encountered.
+func DecryptBytes(bytes []byte, key string) ([]byte, error) {
+	cipher, err := newKeyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.decrypt(bytes)
+}
+
+// EncryptFile encrypts the file located at 'path' using XChaCha20-Poly1305 encryption with the provided key.
+// It returns an error if the file doesn't exist or if any encryption operation fails.
+func EncryptFile(path, key string) error {
+	f := flo.File(path)
+	if !f.Exists() {
+		return fmt.Errorf("can't encrypt, file '%s' does not exist", f.Path())
+	}
+	encrypted, err := EncryptBytes(f.AsBytes(), key)
+	if err != nil {
+		return err
+	}
+	return f.StoreBytes(encrypted)
+}
+
+// EncryptToFile encrypts the given `bytes` using XChaCha20-Poly1305 encryption with the provided key and writes the result to 'path'.
+// It returns an error if any encryption operation fails.
+func EncryptToFile(bytes []byte, path, key string) error {
+	encrypted, err := EncryptBytes(bytes, key)
+	if err != nil {
+		return err
+	}
+	return flo.File(path).StoreBytes(encrypted)
+}
+
+// DecryptFile decrypts the file located at 'path' using XChaCha20-Poly1305 decryption with the provided key.
+// It returns an error if the file doesn't exist or if any decryption operation fails.
+func DecryptFile(path, key string) error {
+	f := flo.File(path)
+	if !f.Exists() {
+		return fmt.Errorf("can't decrypt, file '%s' does not exist", f.Path())
+	}
+	decrypted, err := DecryptBytes(f.AsBytes(), key)
+	if err != nil {
+		return err
+	}
+	return f.StoreBytes(decrypted)
+}
+
+// DecryptFromFile decrypts the file located at 'path' using XChaCha20-Poly1305 decryption with the provided key.
+// It returns the decrypted file bytes or nil and an error if the file doesn't exist or if any decryption operation fails.
+func DecryptFromFile(path, key string) ([]byte, error) {
+	f := flo.File(path)
+	if !f.Exists() {
+		return nil, fmt.Errorf("can't decrypt, file '%s' does not exist", f.Path())
+	}
+	return DecryptBytes(f.AsBytes(), key)
+}
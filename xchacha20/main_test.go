@@ -0,0 +1,83 @@
+package xchacha20
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/toxyl/flo"
+)
+
+func Test_test(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		key  string
+	}{
+		{"test 1", "Hello World!", "myKey123"},
+		{"test 2", "Hello World!", "12345678"},
+		{"test 3", "", "1234567890"},
+		{"test 4", "Hello World!", "1111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Encrypt(tt.text, tt.key)
+			if err != nil {
+				t.Fatalf("could not encrypt: %s\n", err)
+			}
+			if raw, _ := base64.StdEncoding.DecodeString(e); len(raw) != 24+len(tt.text)+16 {
+				t.Errorf("expected a 24-byte nonce and a 16-byte tag, got %d bytes\n", len(raw))
+			}
+			d, err := Decrypt(e, tt.key)
+			if err != nil || tt.text != d {
+				t.Errorf("encrypt/decrypt failed: %v: expected %v, got %v (%v)!\n", tt.name, tt.text, d, err)
+			}
+			if _, err := Decrypt(e, tt.key+"x"); err == nil {
+				t.Errorf("expected error for the wrong key\n")
+			}
+
+			file := filepath.Join(t.TempDir(), "xchacha.txt")
+			if err := flo.File(file).StoreBytes([]byte(tt.text)); err != nil {
+				t.Fatalf("could not create file: %s\n", err)
+			}
+			if err := EncryptFile(file, tt.key); err != nil {
+				t.Errorf("could not encrypt file: %s\n", err)
+			}
+			if d, err := DecryptFromFile(file, tt.key); err != nil || string(d) != tt.text {
+				t.Errorf("decrypt from file failed, expected %s but got %s (%v)\n", tt.text, d, err)
+			}
+			if err := DecryptFile(file, tt.key); err != nil {
+				t.Errorf("could not decrypt file: %s\n", err)
+			}
+			if decrypted := flo.File(file).AsString(); decrypted != tt.text {
+				t.Errorf("decryption failed, expected %s but got %s\n", tt.text, decrypted)
+			}
+		})
+	}
+}
+
+func Test_bytes(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 255}
+	e, err := EncryptBytes(data, "myKey123")
+	if err != nil {
+		t.Fatalf("could not encrypt bytes: %s\n", err)
+	}
+	if d, err := DecryptBytes(e, "myKey123"); err != nil || !bytes.Equal(d, data) {
+		t.Errorf("encrypt/decrypt bytes failed: got %v (%v)\n", d, err)
+	}
+	if _, err := DecryptBytes(e[:10], "myKey123"); err == nil {
+		t.Errorf("expected error for truncated data\n")
+	}
+
+	file := filepath.Join(t.TempDir(), "xchacha.bin")
+	if err := EncryptToFile(data, file, "myKey123"); err != nil {
+		t.Fatalf("could not encrypt to file: %s\n", err)
+	}
+	if d, err := DecryptFromFile(file, "myKey123"); err != nil || !bytes.Equal(d, data) {
+		t.Errorf("encrypt to file/decrypt from file failed: got %v (%v)\n", d, err)
+	}
+	if err := EncryptFile(filepath.Join(t.TempDir(), "missing"), "myKey123"); err == nil {
+		t.Errorf("expected error for a missing file\n")
+	}
+}
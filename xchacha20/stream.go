@@ -0,0 +1,162 @@
+package xchacha20
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/toxyl/cipherutils/internal/stream"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// DefaultChunkSize is the amount of plaintext sealed per chunk by EncryptStream.
+	DefaultChunkSize = 64 * 1024
+
+	streamVersion = 1
+)
+
+// streamMagic identifies streams produced by EncryptStream.
+var streamMagic = []byte("XCCS")
+
+var (
+	// ErrStreamTruncated is returned when a stream ends before its final chunk.
+	ErrStreamTruncated = stream.ErrTruncated
+	// ErrAuthenticationFailed is returned when a chunk fails authentication,
+	// because the key or additional data is wrong or the stream has been tampered with.
+	ErrAuthenticationFailed = errors.New("authentication failed")
+)
+
+// options holds the settings of the stream functions.
+type options struct {
+	chunkSize int
+	aad       []byte
+}
+
+// Option configures the stream functions.
+type Option func(*options) error
+
+// newOptions returns the default options with 'opts' applied.
+func newOptions(opts ...Option) (*options, error) {
+	o := &options{chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// WithChunkSize sets the amount of plaintext sealed per chunk by EncryptStream, which defaults to
+// DefaultChunkSize. DecryptStream reads the chunk size from the stream, so it has no effect there.
+func WithChunkSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 || n > stream.MaxChunkSize {
+			return fmt.Errorf("invalid chunk size %d, must be between 1 and %d", n, stream.MaxChunkSize)
+		}
+		o.chunkSize = n
+		return nil
+	}
+}
+
+// WithAAD sets the additional authenticated data (AAD) every chunk of the stream is bound to.
+// The AAD is not stored inside the stream, the same value must be passed for decryption.
+func WithAAD(aad []byte) Option {
+	return func(o *options) error {
+		o.aad = aad
+		return nil
+	}
+}
+
+// aead returns the XChaCha20-Poly1305 AEAD for the key.
+func (c *keyCipher) aead() (cipher.AEAD, error) {
+	return chacha20poly1305.NewX(c.key)
+}
+
+// EncryptStream reads plaintext from 'src', encrypts it using XChaCha20-Poly1305 encryption with the provided key
+// and writes the result to 'dst'. It returns an error if reading, encrypting or writing fails.
+// The chunk size and additional authenticated data can be changed with 'opts'.
+//
+// The plaintext is processed in chunks of DefaultChunkSize bytes, unless WithChunkSize is passed, so memory usage stays constant
+// regardless of the input size. The last chunk is always shorter than the chunk size (it may be empty),
+// which allows DecryptStream to detect truncated streams.
+//
+// The format is stable and, apart from the magic and the nonce size, the one of aesgcm.EncryptStream.
+// All integers are big-endian:
+//
+//	header:  "XCCS" (4 bytes) | version 1 (1 byte) | chunk size C (uint32) | base nonce N (24 bytes)
+//	chunk i: XChaCha20-Poly1305 ciphertext of up to C plaintext bytes followed by its 16-byte tag
+//
+// Chunk i is sealed with the nonce N + i, where i is added to the last 8 bytes of N, and with the header
+// followed by a single byte as additional data: 1 for the last chunk, 0 for all others. With WithAAD,
+// the additional data of every chunk ends with the given AAD.
+func EncryptStream(dst io.Writer, src io.Reader, key string, opts ...Option) error {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return err
+	}
+	c, err := newKeyCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := c.aead()
+	if err != nil {
+		return err
+	}
+
+	header, err := stream.NewHeader(aead, streamMagic, streamVersion, rand.Reader, o.chunkSize)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	return stream.EncryptChunks(aead, header, o.aad, src, dst, o.chunkSize)
+}
+
+// DecryptStream reads a stream produced by EncryptStream from 'src', decrypts it using XChaCha20-Poly1305 decryption
+// with the provided key and writes the plaintext to 'dst'. It returns an error wrapping ErrStreamTruncated if the
+// stream ends early and ErrAuthenticationFailed if the key or the additional data set with WithAAD is wrong or
+// the stream has been tampered with.
+//
+// Note: Plaintext of already authenticated chunks is written to 'dst' before the whole stream has been verified,
+// callers must discard the output if an error is returned.
+func DecryptStream(dst io.Writer, src io.Reader, key string, opts ...Option) error {
+	o, err := newOptions(opts...)
+	if err != nil {
+		return err
+	}
+	c, err := newKeyCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := c.aead()
+	if err != nil {
+		return err
+	}
+
+	header, chunkSize, err := stream.ReadHeader(aead, src, streamMagic, streamVersion)
+	if err != nil {
+		return err
+	}
+	baseNonce := header[stream.HeaderSize:]
+	buf := make([]byte, chunkSize+aead.Overhead())
+	for i := uint64(0); ; i++ {
+		n, last, err := stream.ReadChunk(aead, src, buf, i)
+		if err != nil {
+			return err
+		}
+		plain, err := aead.Open(buf[:0], stream.ChunkNonce(baseNonce, i), buf[:n], stream.ChunkAAD(header, last, o.aad))
+		if err != nil {
+			return fmt.Errorf("can't decrypt chunk %d, stream truncated or corrupted: %w: %w", i, ErrAuthenticationFailed, err)
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package xchacha20
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func Test_stream(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"small", 12},
+		{"chunk", DefaultChunkSize},
+		{"chunk + 1", DefaultChunkSize + 1},
+		{"multiple chunks", 3*DefaultChunkSize + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			_, _ = rand.Read(data)
+
+			var encrypted bytes.Buffer
+			if err := EncryptStream(&encrypted, bytes.NewReader(data), "myKey123"); err != nil {
+				t.Fatalf("could not encrypt stream: %s\n", err)
+			}
+			e := encrypted.Bytes()
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(e), "myKey123"); err != nil {
+				t.Fatalf("could not decrypt stream: %s\n", err)
+			}
+			if !bytes.Equal(data, decrypted.Bytes()) {
+				t.Errorf("encrypt/decrypt stream failed: %v: plaintext mismatch\n", tt.name)
+			}
+
+			if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e), "wrongKey"); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("expected ErrAuthenticationFailed for wrong key, got %v\n", err)
+			}
+			for _, cut := range []int{1, 17, len(e) - 5} {
+				err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e[:len(e)-cut]), "myKey123")
+				if !errors.Is(err, ErrStreamTruncated) && !errors.Is(err, ErrAuthenticationFailed) {
+					t.Errorf("expected truncation error when cutting %d bytes: %v: got %v\n", cut, tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_stream_errors(t *testing.T) {
+	data := make([]byte, 2*DefaultChunkSize)
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(data), "myKey123"); err != nil {
+		t.Fatalf("could not encrypt stream: %s\n", err)
+	}
+	e := encrypted.Bytes()
+
+	// dropping the final (empty) chunk leaves only full chunks
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e[:len(e)-16]), "myKey123"); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected ErrStreamTruncated for missing final chunk, got %v\n", err)
+	}
+	tampered := bytes.Clone(e)
+	tampered[4] = 2
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(tampered), "myKey123"); err == nil {
+		t.Errorf("expected error for unsupported version\n")
+	}
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(bytes.Repeat([]byte("x"), 64)), "myKey123"); err == nil {
+		t.Errorf("expected error for a stream without header\n")
+	}
+}
+
+func Test_stream_options(t *testing.T) {
+	data := make([]byte, 1000)
+	_, _ = rand.Read(data)
+	aad := []byte("context")
+
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(data), "myKey123", WithChunkSize(100), WithAAD(aad)); err != nil {
+		t.Fatalf("could not encrypt stream: %s\n", err)
+	}
+	e := encrypted.Bytes()
+	if want := 9 + 24 + len(data) + (len(data)/100+1)*16; len(e) != want {
+		t.Errorf("EncryptStream() with WithChunkSize(100) = %d bytes, want %d\n", len(e), want)
+	}
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, bytes.NewReader(e), "myKey123", WithAAD(aad)); err != nil {
+		t.Fatalf("could not decrypt stream: %s\n", err)
+	}
+	if !bytes.Equal(data, decrypted.Bytes()) {
+		t.Errorf("encrypt/decrypt stream with options failed: plaintext mismatch\n")
+	}
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(e), "myKey123"); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptStream() without the AAD error = %v, want ErrAuthenticationFailed\n", err)
+	}
+	for _, n := range []int{0, -1, 16*1024*1024 + 1} {
+		if err := EncryptStream(&bytes.Buffer{}, bytes.NewReader(data), "myKey123", WithChunkSize(n)); err == nil {
+			t.Errorf("EncryptStream() with WithChunkSize(%d) expected an error\n", n)
+		}
+	}
+}